@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/controllers"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/middleware"
+)
+
+func RegisterCertificateRoutes(router *mux.Router) {
+	router.HandleFunc("/verify/{serial}", controllers.VerifyCertificate).Methods("GET", "OPTIONS")
+
+	certificateRouter := router.PathPrefix("/certificates").Subrouter()
+
+	certificateRouter.HandleFunc("/{serial}/revoke", middleware.RequireAuth(middleware.AdminOnly(controllers.RevokeCertificate))).Methods("POST", "OPTIONS")
+}
@@ -14,7 +14,16 @@ func RegisterCourseRoutes(router *mux.Router) {
 	courseRouter.HandleFunc("/search", controllers.SearchCourses).Methods("GET", "OPTIONS")
 	courseRouter.HandleFunc("/featured", controllers.GetFeaturedCourses).Methods("GET", "OPTIONS")
 	courseRouter.HandleFunc("/{id}/enroll", middleware.RequireAuth(controllers.EnrollCourse)).Methods("POST", "OPTIONS")
-	courseRouter.HandleFunc("/{id}/analytics", controllers.GetCourseAnalytics).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{id}/enroll", middleware.RequireAuth(controllers.UnenrollCourse)).Methods("DELETE", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/students", middleware.RequireAuth(middleware.TeacherOnly(controllers.GetCourseStudents))).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/completion-rule", middleware.RequireAuth(middleware.TeacherOnly(controllers.UpdateCompletionRule))).Methods("PUT", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/certificate", middleware.RequireAuth(controllers.DownloadCertificate)).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/certificate-template", middleware.RequireAuth(middleware.TeacherOnly(controllers.GetCertificateTemplate))).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/certificate-template", middleware.RequireAuth(middleware.TeacherOnly(controllers.UpdateCertificateTemplate))).Methods("PUT", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/analytics", middleware.RequireAuth(middleware.TeacherOnly(controllers.GetCourseAnalytics))).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/at-risk", middleware.RequireAuth(middleware.TeacherOnly(controllers.GetAtRiskStudents))).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/risk-settings", middleware.RequireAuth(middleware.TeacherOnly(controllers.GetRiskSettings))).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/risk-settings", middleware.RequireAuth(middleware.TeacherOnly(controllers.UpdateRiskSettings))).Methods("PUT", "OPTIONS")
 	courseRouter.HandleFunc("/teacher/my-courses", middleware.RequireAuth(controllers.GetTeacherCourses)).Methods("GET", "OPTIONS")
 
 	//course routes
@@ -25,23 +34,92 @@ func RegisterCourseRoutes(router *mux.Router) {
 	courseRouter.HandleFunc("/{id}", middleware.RequireAuth(controllers.DeleteCourse)).Methods("DELETE", "OPTIONS")
 
 	//lesson routes
-	courseRouter.HandleFunc("/{courseId}/lessons", controllers.GetLessons).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/lessons", middleware.RequireAuth(controllers.GetLessons)).Methods("GET", "OPTIONS")
 	courseRouter.HandleFunc("/{courseId}/lessons", middleware.RequireAuth(middleware.TeacherOnly(controllers.CreateLesson))).Methods("POST", "OPTIONS")
-	courseRouter.HandleFunc("/{courseId}/lessons/{lessonId}", controllers.GetLesson).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/lessons/{lessonId}", middleware.RequireAuth(controllers.GetLesson)).Methods("GET", "OPTIONS")
 	courseRouter.HandleFunc("/{courseId}/lessons/{lessonId}", middleware.RequireAuth(middleware.TeacherOnly(controllers.UpdateLesson))).Methods("PUT", "OPTIONS")
 	courseRouter.HandleFunc("/{courseId}/lessons/{lessonId}", middleware.RequireAuth(middleware.TeacherOnly(controllers.DeleteLesson))).Methods("DELETE", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/lessons/{lessonId}/progress", middleware.RequireAuth(controllers.LessonHeartbeat)).Methods("POST", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/lessons/{lessonId}/complete", middleware.RequireAuth(controllers.CompleteLesson)).Methods("POST", "OPTIONS")
 
 	//test routes
+	courseRouter.HandleFunc("/{courseId}/tests/import", middleware.RequireAuth(middleware.TeacherOnly(controllers.ImportTest))).Methods("POST", "OPTIONS")
 	courseRouter.HandleFunc("/{courseId}/tests", middleware.RequireAuth(middleware.TeacherOnly(controllers.CreateTest))).Methods("POST", "OPTIONS")
 	courseRouter.HandleFunc("/{courseId}/tests/{testId}", controllers.GetTest).Methods("GET", "OPTIONS")
 	courseRouter.HandleFunc("/{courseId}/tests/{testId}", middleware.RequireAuth(middleware.TeacherOnly(controllers.UpdateTest))).Methods("PUT", "OPTIONS")
 	courseRouter.HandleFunc("/{courseId}/tests/{testId}", middleware.RequireAuth(middleware.TeacherOnly(controllers.DeleteTest))).Methods("DELETE", "OPTIONS")
 	courseRouter.HandleFunc("/{courseId}/tests/{testId}/submit", middleware.RequireAuth(controllers.SubmitTest)).Methods("POST", "OPTIONS")
 	courseRouter.HandleFunc("/{courseId}/tests/{testId}/results", middleware.RequireAuth(controllers.GetTestResults)).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/tests/{testId}/attempts", middleware.RequireAuth(controllers.StartTestAttempt)).Methods("POST", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/tests/{testId}/attempts/{attemptId}", middleware.RequireAuth(controllers.GetTestAttempt)).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/tests/{testId}/attempts/{attemptId}/events", middleware.RequireAuth(controllers.RecordIntegrityEvents)).Methods("POST", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/tests/{testId}/attempts/{attemptId}/integrity", middleware.RequireAuth(middleware.TeacherOnly(controllers.GetAttemptIntegrity))).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/tests/{testId}/integrity", middleware.RequireAuth(middleware.TeacherOnly(controllers.GetTestIntegrity))).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/tests/{testId}/integrity-policy", middleware.RequireAuth(middleware.TeacherOnly(controllers.UpdateIntegrityPolicy))).Methods("PUT", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/tests/{testId}/rules", middleware.RequireAuth(middleware.TeacherOnly(controllers.GetTestRules))).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/tests/{testId}/rules", middleware.RequireAuth(middleware.TeacherOnly(controllers.UpdateTestRules))).Methods("PUT", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/tests/{testId}/export", middleware.RequireAuth(middleware.TeacherOnly(controllers.ExportTest))).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/tests/{testId}/item-analysis", middleware.RequireAuth(middleware.TeacherOnly(controllers.GetItemAnalysis))).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/tests/{testId}/item-analysis/export", middleware.RequireAuth(middleware.TeacherOnly(controllers.ExportItemAnalysis))).Methods("GET", "OPTIONS")
+
+	//adaptive practice routes
+	courseRouter.HandleFunc("/{courseId}/practice/sessions", middleware.RequireAuth(controllers.StartPracticeSession)).Methods("POST", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/practice/sessions/{sessionId}", middleware.RequireAuth(controllers.GetPracticeSession)).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/practice/sessions/{sessionId}/answers", middleware.RequireAuth(controllers.AnswerPracticeQuestion)).Methods("POST", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/practice/abilities", middleware.RequireAuth(controllers.GetPracticeAbilities)).Methods("GET", "OPTIONS")
+
+	//question bank routes
+	courseRouter.HandleFunc("/{courseId}/question-bank", middleware.RequireAuth(middleware.TeacherOnly(controllers.GetQuestionBank))).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/question-bank", middleware.RequireAuth(middleware.TeacherOnly(controllers.CreateBankQuestion))).Methods("POST", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/question-bank/import", middleware.RequireAuth(middleware.TeacherOnly(controllers.ImportBankQuestions))).Methods("POST", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/question-bank/export", middleware.RequireAuth(middleware.TeacherOnly(controllers.ExportQuestionBank))).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/question-bank/{questionId}", middleware.RequireAuth(middleware.TeacherOnly(controllers.UpdateBankQuestion))).Methods("PUT", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/question-bank/{questionId}", middleware.RequireAuth(middleware.TeacherOnly(controllers.DeleteBankQuestion))).Methods("DELETE", "OPTIONS")
+
+	//flashcard routes
+	courseRouter.HandleFunc("/{courseId}/decks", middleware.RequireAuth(controllers.GetDecks)).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/decks", middleware.RequireAuth(middleware.TeacherOnly(controllers.CreateDeck))).Methods("POST", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/decks/{deckId}", middleware.RequireAuth(controllers.GetDeck)).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/decks/{deckId}", middleware.RequireAuth(middleware.TeacherOnly(controllers.UpdateDeck))).Methods("PUT", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/decks/{deckId}", middleware.RequireAuth(middleware.TeacherOnly(controllers.DeleteDeck))).Methods("DELETE", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/decks/{deckId}/generate", middleware.RequireAuth(middleware.TeacherOnly(controllers.GenerateFlashcards))).Methods("POST", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/decks/{deckId}/due", middleware.RequireAuth(controllers.GetDeckDueFlashcards)).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/decks/{deckId}/stats", middleware.RequireAuth(controllers.GetDeckStats)).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/decks/{deckId}/cards", middleware.RequireAuth(middleware.TeacherOnly(controllers.CreateFlashcard))).Methods("POST", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/decks/{deckId}/cards/{cardId}", middleware.RequireAuth(middleware.TeacherOnly(controllers.UpdateFlashcard))).Methods("PUT", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/decks/{deckId}/cards/{cardId}", middleware.RequireAuth(middleware.TeacherOnly(controllers.DeleteFlashcard))).Methods("DELETE", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/decks/{deckId}/cards/{cardId}/review", middleware.RequireAuth(controllers.ReviewFlashcard)).Methods("POST", "OPTIONS")
 
 	//discussion routes
-	courseRouter.HandleFunc("/{courseId}/discussions", controllers.GetDiscussions).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/discussions", middleware.RequireAuth(controllers.GetDiscussions)).Methods("GET", "OPTIONS")
 	courseRouter.HandleFunc("/{courseId}/discussions", middleware.RequireAuth(controllers.CreateDiscussion)).Methods("POST", "OPTIONS")
-	courseRouter.HandleFunc("/{courseId}/discussions/{discussionId}", controllers.GetDiscussion).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/discussions/unanswered", middleware.RequireAuth(controllers.GetUnansweredDiscussions)).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/discussions/{discussionId}", middleware.RequireAuth(controllers.GetDiscussion)).Methods("GET", "OPTIONS")
 	courseRouter.HandleFunc("/{courseId}/discussions/{discussionId}/replies", middleware.RequireAuth(controllers.ReplyToDiscussion)).Methods("POST", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/discussions/{discussionId}", middleware.RequireAuth(controllers.UpdateDiscussion)).Methods("PUT", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/discussions/{discussionId}/history", middleware.RequireAuth(controllers.GetDiscussionHistory)).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/discussions/{discussionId}/replies/{replyId}", middleware.RequireAuth(controllers.UpdateDiscussionReply)).Methods("PUT", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/discussions/{discussionId}/replies/{replyId}", middleware.RequireAuth(controllers.DeleteDiscussionReply)).Methods("DELETE", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/discussions/{discussionId}/replies/{replyId}/history", middleware.RequireAuth(controllers.GetDiscussionReplyHistory)).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/discussions/{discussionId}/report", middleware.RequireAuth(controllers.ReportPost)).Methods("POST", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/discussions/{discussionId}/replies/{replyId}/report", middleware.RequireAuth(controllers.ReportPost)).Methods("POST", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/discussions/{discussionId}/helpful", middleware.RequireAuth(controllers.VoteHelpful)).Methods("POST", "DELETE", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/discussions/{discussionId}/solution", middleware.RequireAuth(controllers.UnmarkDiscussionSolution)).Methods("DELETE", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/discussions/{discussionId}/replies/{replyId}/solution", middleware.RequireAuth(controllers.MarkDiscussionSolution)).Methods("POST", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/discussions/{discussionId}/replies/{replyId}/helpful", middleware.RequireAuth(controllers.VoteHelpful)).Methods("POST", "DELETE", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/discussions/{discussionId}/subscription", middleware.RequireAuth(controllers.SubscribeDiscussion)).Methods("POST", "DELETE", "OPTIONS")
+
+	//announcement routes
+	courseRouter.HandleFunc("/{courseId}/announcements", middleware.RequireAuth(controllers.GetAnnouncements)).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/announcements", middleware.RequireAuth(middleware.TeacherOnly(controllers.CreateAnnouncement))).Methods("POST", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/announcements/{announcementId}", middleware.RequireAuth(controllers.GetAnnouncement)).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/announcements/{announcementId}", middleware.RequireAuth(middleware.TeacherOnly(controllers.UpdateAnnouncement))).Methods("PUT", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/announcements/{announcementId}", middleware.RequireAuth(middleware.TeacherOnly(controllers.DeleteAnnouncement))).Methods("DELETE", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/announcements/{announcementId}/read", middleware.RequireAuth(controllers.MarkAnnouncementRead)).Methods("POST", "OPTIONS")
+
+	//moderation routes
+	courseRouter.HandleFunc("/{courseId}/moderation/queue", middleware.RequireAuth(controllers.GetModerationQueue)).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/moderation/log", middleware.RequireAuth(controllers.GetModerationLog)).Methods("GET", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/moderation/actions", middleware.RequireAuth(controllers.ModerateDiscussions)).Methods("POST", "OPTIONS")
+	courseRouter.HandleFunc("/{courseId}/moderation/bans", middleware.RequireAuth(controllers.GetForumBans)).Methods("GET", "OPTIONS")
 }
@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/controllers"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/middleware"
+)
+
+// RegisterForumRoutes registers the platform forum. Topics are discussions without a course,
+// so they share the course discussion handlers; likes are helpful votes.
+func RegisterForumRoutes(router *mux.Router) {
+	forumRouter := router.PathPrefix("/forum").Subrouter()
+
+	forumRouter.HandleFunc("/categories", middleware.RequireAuth(controllers.GetForumCategories)).Methods("GET", "OPTIONS")
+	forumRouter.HandleFunc("/categories", middleware.RequireAuth(middleware.AdminOnly(controllers.CreateForumCategory))).Methods("POST", "OPTIONS")
+	forumRouter.HandleFunc("/categories/manage", middleware.RequireAuth(middleware.AdminOnly(controllers.GetManagedForumCategories))).Methods("GET", "OPTIONS")
+	forumRouter.HandleFunc("/categories/{categoryId}", middleware.RequireAuth(middleware.AdminOnly(controllers.UpdateForumCategory))).Methods("PUT", "OPTIONS")
+	forumRouter.HandleFunc("/categories/{categoryId}", middleware.RequireAuth(middleware.AdminOnly(controllers.DeleteForumCategory))).Methods("DELETE", "OPTIONS")
+
+	//topic routes
+	forumRouter.HandleFunc("/topics", middleware.RequireAuth(controllers.GetDiscussions)).Methods("GET", "OPTIONS")
+	forumRouter.HandleFunc("/topics", middleware.RequireAuth(controllers.CreateDiscussion)).Methods("POST", "OPTIONS")
+	forumRouter.HandleFunc("/topics/{discussionId}", middleware.RequireAuth(controllers.GetDiscussion)).Methods("GET", "OPTIONS")
+	forumRouter.HandleFunc("/topics/{discussionId}", middleware.RequireAuth(controllers.UpdateDiscussion)).Methods("PUT", "OPTIONS")
+	forumRouter.HandleFunc("/topics/{discussionId}/history", middleware.RequireAuth(controllers.GetDiscussionHistory)).Methods("GET", "OPTIONS")
+	forumRouter.HandleFunc("/topics/{discussionId}/replies", middleware.RequireAuth(controllers.ReplyToDiscussion)).Methods("POST", "OPTIONS")
+	forumRouter.HandleFunc("/topics/{discussionId}/replies/{replyId}", middleware.RequireAuth(controllers.UpdateDiscussionReply)).Methods("PUT", "OPTIONS")
+	forumRouter.HandleFunc("/topics/{discussionId}/replies/{replyId}", middleware.RequireAuth(controllers.DeleteDiscussionReply)).Methods("DELETE", "OPTIONS")
+	forumRouter.HandleFunc("/topics/{discussionId}/replies/{replyId}/history", middleware.RequireAuth(controllers.GetDiscussionReplyHistory)).Methods("GET", "OPTIONS")
+	forumRouter.HandleFunc("/topics/{discussionId}/like", middleware.RequireAuth(controllers.VoteHelpful)).Methods("POST", "DELETE", "OPTIONS")
+	forumRouter.HandleFunc("/topics/{discussionId}/replies/{replyId}/like", middleware.RequireAuth(controllers.VoteHelpful)).Methods("POST", "DELETE", "OPTIONS")
+	forumRouter.HandleFunc("/topics/{discussionId}/solution", middleware.RequireAuth(controllers.UnmarkDiscussionSolution)).Methods("DELETE", "OPTIONS")
+	forumRouter.HandleFunc("/topics/{discussionId}/replies/{replyId}/solution", middleware.RequireAuth(controllers.MarkDiscussionSolution)).Methods("POST", "OPTIONS")
+	forumRouter.HandleFunc("/topics/{discussionId}/report", middleware.RequireAuth(controllers.ReportPost)).Methods("POST", "OPTIONS")
+	forumRouter.HandleFunc("/topics/{discussionId}/replies/{replyId}/report", middleware.RequireAuth(controllers.ReportPost)).Methods("POST", "OPTIONS")
+	forumRouter.HandleFunc("/topics/{discussionId}/subscription", middleware.RequireAuth(controllers.SubscribeDiscussion)).Methods("POST", "DELETE", "OPTIONS")
+
+	//moderation routes
+	forumRouter.HandleFunc("/moderation/actions", middleware.RequireAuth(middleware.AdminOnly(controllers.ModerateDiscussions))).Methods("POST", "OPTIONS")
+	forumRouter.HandleFunc("/moderation/bans", middleware.RequireAuth(middleware.AdminOnly(controllers.GetForumBans))).Methods("GET", "OPTIONS")
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/controllers"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/middleware"
+)
+
+func RegisterLeaderboardRoutes(router *mux.Router) {
+	router.HandleFunc("/leaderboards", middleware.RequireAuth(controllers.GetLeaderboard)).Methods("GET", "OPTIONS")
+
+	classGroupRouter := router.PathPrefix("/class-groups").Subrouter()
+
+	classGroupRouter.HandleFunc("", middleware.RequireAuth(controllers.GetMyClassGroups)).Methods("GET", "OPTIONS")
+	classGroupRouter.HandleFunc("", middleware.RequireAuth(middleware.TeacherOnly(controllers.CreateClassGroup))).Methods("POST", "OPTIONS")
+	classGroupRouter.HandleFunc("/join", middleware.RequireAuth(controllers.JoinClassGroup)).Methods("POST", "OPTIONS")
+	classGroupRouter.HandleFunc("/{groupId}/leave", middleware.RequireAuth(controllers.LeaveClassGroup)).Methods("POST", "OPTIONS")
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/controllers"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/middleware"
+)
+
+func RegisterModerationRoutes(router *mux.Router) {
+	moderationRouter := router.PathPrefix("/moderation").Subrouter()
+
+	moderationRouter.HandleFunc("/queue", middleware.RequireAuth(middleware.AdminOnly(controllers.GetModerationQueue))).Methods("GET", "OPTIONS")
+	moderationRouter.HandleFunc("/log", middleware.RequireAuth(middleware.AdminOnly(controllers.GetModerationLog))).Methods("GET", "OPTIONS")
+	moderationRouter.HandleFunc("/words", middleware.RequireAuth(middleware.AdminOnly(controllers.GetModerationWords))).Methods("GET", "OPTIONS")
+	moderationRouter.HandleFunc("/words", middleware.RequireAuth(middleware.AdminOnly(controllers.AddModerationWord))).Methods("POST", "OPTIONS")
+	moderationRouter.HandleFunc("/words/{wordId}", middleware.RequireAuth(middleware.AdminOnly(controllers.DeleteModerationWord))).Methods("DELETE", "OPTIONS")
+}
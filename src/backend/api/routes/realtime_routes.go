@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/controllers"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/middleware"
+)
+
+func RegisterRealtimeRoutes(router *mux.Router) {
+	router.HandleFunc("/events", middleware.RequireAuth(controllers.StreamEvents)).Methods("GET", "OPTIONS")
+}
@@ -2,58 +2,31 @@
 package routes
 
 import (
-	"encoding/json"
-	"net/http"
-
 	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/controllers"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/middleware"
 )
 
 func RegisterUserRoutes(router *mux.Router) {
 	userRouter := router.PathPrefix("/user").Subrouter()
 
-	userRouter.HandleFunc("/progress", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": true,
-			"data": map[string]interface{}{
-				"coursesCompleted": 2,
-				"lessonsCompleted": 15,
-				"testsCompleted":   8,
-				"averageScore":     85,
-			},
-		})
-	}).Methods("GET", "OPTIONS")
+	userRouter.HandleFunc("/progress", middleware.RequireAuth(controllers.GetUserProgress)).Methods("GET", "OPTIONS")
+	userRouter.HandleFunc("/certificates", middleware.RequireAuth(controllers.GetMyCertificates)).Methods("GET", "OPTIONS")
+	userRouter.HandleFunc("/gamification", middleware.RequireAuth(controllers.GetMyGamification)).Methods("GET", "OPTIONS")
+	userRouter.HandleFunc("/badges", middleware.RequireAuth(controllers.GetMyBadges)).Methods("GET", "OPTIONS")
+	userRouter.HandleFunc("/flashcards/due", middleware.RequireAuth(controllers.GetDueFlashcards)).Methods("GET", "OPTIONS")
+	userRouter.HandleFunc("/settings", middleware.RequireAuth(controllers.GetUserSettings)).Methods("GET", "OPTIONS")
+	userRouter.HandleFunc("/settings", middleware.RequireAuth(controllers.UpdateUserSettings)).Methods("PUT", "OPTIONS")
+	userRouter.HandleFunc("/username", middleware.RequireAuth(controllers.UpdateUsername)).Methods("PUT", "OPTIONS")
+	userRouter.HandleFunc("/notifications", middleware.RequireAuth(controllers.GetNotifications)).Methods("GET", "OPTIONS")
+	userRouter.HandleFunc("/notifications/read-all", middleware.RequireAuth(controllers.MarkAllNotificationsRead)).Methods("POST", "OPTIONS")
+	userRouter.HandleFunc("/notifications/{notificationId}/read", middleware.RequireAuth(controllers.MarkNotificationRead)).Methods("POST", "OPTIONS")
+	userRouter.HandleFunc("/notifications/{notificationId}", middleware.RequireAuth(controllers.DeleteNotification)).Methods("DELETE", "OPTIONS")
+	userRouter.HandleFunc("/notification-preferences", middleware.RequireAuth(controllers.GetNotificationPreferences)).Methods("GET", "OPTIONS")
+	userRouter.HandleFunc("/notification-preferences", middleware.RequireAuth(controllers.UpdateNotificationPreferences)).Methods("PUT", "OPTIONS")
+	userRouter.HandleFunc("/risk-digests", middleware.RequireAuth(middleware.TeacherOnly(controllers.GetRiskDigests))).Methods("GET", "OPTIONS")
 
-	enrollmentsRouter := router.PathPrefix("/api/enrollments").Subrouter()
+	enrollmentsRouter := router.PathPrefix("/enrollments").Subrouter()
 
-	enrollmentsRouter.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": true,
-			"data": []map[string]interface{}{
-				{
-					"id":        1,
-					"course_id": 1,
-					"user_id":   1,
-					"title":     "Математика для начинающих",
-					"progress":  75,
-				},
-				{
-					"id":        2,
-					"course_id": 2,
-					"user_id":   1,
-					"title":     "Основы программирования",
-					"progress":  50,
-				},
-			},
-		})
-	}).Methods("GET", "OPTIONS")
+	enrollmentsRouter.HandleFunc("", middleware.RequireAuth(controllers.GetMyEnrollments)).Methods("GET", "OPTIONS")
 }
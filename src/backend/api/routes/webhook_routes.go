@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/controllers"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/middleware"
+)
+
+func RegisterWebhookRoutes(router *mux.Router) {
+	webhookRouter := router.PathPrefix("/webhooks").Subrouter()
+
+	webhookRouter.HandleFunc("", middleware.RequireAuth(middleware.AdminOnly(controllers.GetWebhooks))).Methods("GET", "OPTIONS")
+	webhookRouter.HandleFunc("", middleware.RequireAuth(middleware.AdminOnly(controllers.CreateWebhook))).Methods("POST", "OPTIONS")
+	webhookRouter.HandleFunc("/{webhookId}", middleware.RequireAuth(middleware.AdminOnly(controllers.GetWebhook))).Methods("GET", "OPTIONS")
+	webhookRouter.HandleFunc("/{webhookId}", middleware.RequireAuth(middleware.AdminOnly(controllers.UpdateWebhook))).Methods("PUT", "OPTIONS")
+	webhookRouter.HandleFunc("/{webhookId}", middleware.RequireAuth(middleware.AdminOnly(controllers.DeleteWebhook))).Methods("DELETE", "OPTIONS")
+	webhookRouter.HandleFunc("/{webhookId}/deliveries", middleware.RequireAuth(middleware.AdminOnly(controllers.GetWebhookDeliveries))).Methods("GET", "OPTIONS")
+	webhookRouter.HandleFunc("/{webhookId}/deliveries/{deliveryId}/redeliver", middleware.RequireAuth(middleware.AdminOnly(controllers.RedeliverWebhook))).Methods("POST", "OPTIONS")
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+// currentUser loads the authenticated user, writing an error response when that fails.
+func currentUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	userID, _ := r.Context().Value(models.UserContextKey).(string)
+
+	user, err := repository.GetUserByID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "user doesnt exists", http.StatusInternalServerError)
+		return nil, false
+	}
+	return user, true
+}
+
+// requireCourseStaff allows the course teacher and admins through.
+func requireCourseStaff(w http.ResponseWriter, r *http.Request, courseID string) (*models.User, bool) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	teacherID, err := repository.GetCourseTeacherID(r.Context(), courseID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Course not found", http.StatusNotFound)
+		} else {
+			log.Printf("Get course teacher error: %v", err)
+			http.Error(w, "Server error while retrieving course", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+
+	if teacherID != user.ID && user.Role != "admin" {
+		http.Error(w, "Not authorized to manage this course", http.StatusForbidden)
+		return nil, false
+	}
+	return user, true
+}
+
+// requireAdmin allows admins only through.
+func requireAdmin(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return nil, false
+	}
+	if user.Role != "admin" {
+		http.Error(w, "Admin access required", http.StatusForbidden)
+		return nil, false
+	}
+	return user, true
+}
+
+// requireCourseAccess allows course staff and enrolled students through. For students the
+// enrollment's last access time is refreshed.
+func requireCourseAccess(w http.ResponseWriter, r *http.Request, courseID string) (*models.User, bool) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	staff, enrolled, err := courseRole(r, user, courseID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Course not found", http.StatusNotFound)
+		} else {
+			log.Printf("Check course access error: %v", err)
+			http.Error(w, "Server error while checking course access", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+
+	if !staff && !enrolled {
+		http.Error(w, "Enroll in the course to access its content", http.StatusForbidden)
+		return nil, false
+	}
+	if enrolled {
+		if err := repository.TouchEnrollment(r.Context(), user.ID, courseID); err != nil {
+			log.Printf("Touch enrollment error: %v", err)
+		}
+	}
+	return user, true
+}
+
+// courseRole reports whether the user teaches the course (admins count as staff) and whether
+// they are enrolled in it. ErrNotFound means the course does not exist.
+func courseRole(r *http.Request, user *models.User, courseID string) (bool, bool, error) {
+	teacherID, err := repository.GetCourseTeacherID(r.Context(), courseID)
+	if err != nil {
+		return false, false, err
+	}
+	if teacherID == user.ID || user.Role == "admin" {
+		return true, false, nil
+	}
+
+	_, err = repository.GetEnrollment(r.Context(), user.ID, courseID)
+	if errors.Is(err, models.ErrNotFound) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	return false, true, nil
+}
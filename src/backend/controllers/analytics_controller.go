@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// GetCourseAnalytics returns the course report for its staff together with one page of
+// per-student progress (?page=, ?limit=). ?refresh=true bypasses the cached report.
+func GetCourseAnalytics(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return
+	}
+
+	page, limit := pagination(r)
+	analytics, err := services.GetCourseAnalytics(r.Context(), courseID, r.URL.Query().Get("refresh") == "true")
+	if err != nil {
+		log.Printf("Get course analytics error: %v", err)
+		http.Error(w, "Server error while retrieving analytics", http.StatusInternalServerError)
+		return
+	}
+
+	students, total, err := repository.GetStudentProgressPage(r.Context(), courseID, limit, (page-1)*limit)
+	if err != nil {
+		log.Printf("Get student progress error: %v", err)
+		http.Error(w, "Server error while retrieving analytics", http.StatusInternalServerError)
+		return
+	}
+
+	// The cached report is shared, attach the page to a copy.
+	report := *analytics
+	report.Students = &models.StudentProgressPage{Items: students, Page: page, Limit: limit, Total: total}
+
+	response := models.Response{
+		Success: true,
+		Data:    report,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// pagination reads ?page= (from 1) and ?limit=, falling back to defaults on missing or
+// invalid values.
+func pagination(r *http.Request) (int, int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+	return page, limit
+}
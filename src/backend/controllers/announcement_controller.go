@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
+)
+
+// GetAnnouncements lists a course's current announcements, or all of them with read counts
+// for the course staff.
+func GetAnnouncements(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+	user, staff, ok := requireDiscussionAccess(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	announcements, err := services.GetAnnouncements(r.Context(), courseID, user.ID, staff)
+	if err != nil {
+		log.Printf("Get announcements error: %v", err)
+		http.Error(w, "Server error while retrieving announcements", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    announcements,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetAnnouncement returns an announcement. Viewing it marks it read for a student.
+func GetAnnouncement(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID := params["courseId"]
+	user, staff, ok := requireDiscussionAccess(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	announcement, err := services.ViewAnnouncement(r.Context(), courseID, params["announcementId"], user.ID, staff)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Announcement not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Get announcement error: %v", err)
+		http.Error(w, "Server error while retrieving announcement", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    announcement,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func CreateAnnouncement(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+	user, ok := requireCourseStaff(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	var req models.AnnouncementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	announcement, err := services.CreateAnnouncement(r.Context(), courseID, user.ID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAnnouncement) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Create announcement error: %v", err)
+		http.Error(w, "Server error while creating announcement", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Announcement created",
+		Data:    announcement,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+func UpdateAnnouncement(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID := params["courseId"]
+	user, ok := requireCourseStaff(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	var req models.AnnouncementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	announcement, err := services.UpdateAnnouncement(r.Context(), courseID, params["announcementId"], user.ID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidAnnouncement):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, models.ErrNotFound):
+			http.Error(w, "Announcement not found", http.StatusNotFound)
+		default:
+			log.Printf("Update announcement error: %v", err)
+			http.Error(w, "Server error while updating announcement", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Announcement updated",
+		Data:    announcement,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func DeleteAnnouncement(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID := params["courseId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return
+	}
+
+	if err := repository.DeleteAnnouncement(r.Context(), courseID, params["announcementId"]); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Announcement not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Delete announcement error: %v", err)
+		http.Error(w, "Server error while deleting announcement", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Announcement deleted",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// MarkAnnouncementRead records that the current student has seen an announcement, for
+// clients that show announcements in the list without opening them.
+func MarkAnnouncementRead(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID := params["courseId"]
+	user, staff, ok := requireDiscussionAccess(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	if !staff {
+		if err := services.MarkAnnouncementRead(r.Context(), courseID, params["announcementId"], user.ID); err != nil {
+			if errors.Is(err, models.ErrNotFound) {
+				http.Error(w, "Announcement not found", http.StatusNotFound)
+				return
+			}
+			log.Printf("Mark announcement read error: %v", err)
+			http.Error(w, "Server error while updating announcement", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Announcement marked as read",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
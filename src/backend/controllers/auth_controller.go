@@ -10,6 +10,7 @@ import (
 	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
 	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
 	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -72,6 +73,10 @@ func Register(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("User registered successfully: %s", user.Email)
 
+	if _, err := services.AssignUsername(r.Context(), user); err != nil {
+		log.Printf("Assign username error: %v", err)
+	}
+
 	token, err := helper.GenerateJWT(user.ID, user.Role)
 	if err != nil {
 		log.Printf("Error generating token: %v", err)
@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
+)
+
+// DownloadCertificate serves the current user's certificate for a completed course as a PDF
+// in ?lang= (kk, ru or en; the user's language by default).
+func DownloadCertificate(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	cert, err := services.EnsureCertificate(r.Context(), user.ID, courseID)
+	if err != nil {
+		if errors.Is(err, services.ErrCourseNotCompleted) {
+			http.Error(w, "Complete the course to receive a certificate", http.StatusForbidden)
+			return
+		}
+		log.Printf("Get certificate error: %v", err)
+		http.Error(w, "Server error while retrieving certificate", http.StatusInternalServerError)
+		return
+	}
+
+	tmpl, err := services.GetCertificateTemplate(r.Context(), courseID)
+	if err != nil {
+		log.Printf("Get certificate template error: %v", err)
+		http.Error(w, "Server error while retrieving certificate", http.StatusInternalServerError)
+		return
+	}
+
+	lang := services.CertificateLanguage(r.URL.Query().Get("lang"), user.LanguagePreference)
+	pdf, err := services.RenderCertificatePDF(cert, tmpl, lang)
+	if err != nil {
+		if errors.Is(err, services.ErrCertificateRevoked) {
+			http.Error(w, "This certificate has been revoked", http.StatusGone)
+			return
+		}
+		if errors.Is(err, services.ErrCertificateFontMissing) {
+			log.Printf("Render certificate error: %v", err)
+			http.Error(w, "Certificates can't be generated right now", http.StatusServiceUnavailable)
+			return
+		}
+		log.Printf("Render certificate error: %v", err)
+		http.Error(w, "Server error while generating certificate", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="certificate-%s-%s.pdf"`, cert.Serial, lang))
+	w.Write(pdf)
+}
+
+func GetMyCertificates(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	certs, err := repository.GetUserCertificates(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Get certificates error: %v", err)
+		http.Error(w, "Server error while retrieving certificates", http.StatusInternalServerError)
+		return
+	}
+	for i := range certs {
+		certs[i].VerifyURL = services.CertificateVerifyURL(certs[i].Serial)
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    certs,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// VerifyCertificate is the public authenticity check behind the certificate QR code.
+func VerifyCertificate(w http.ResponseWriter, r *http.Request) {
+	verification, err := services.VerifyCertificate(r.Context(), mux.Vars(r)["serial"])
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Certificate not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Verify certificate error: %v", err)
+		http.Error(w, "Server error while verifying certificate", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    verification,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// RevokeCertificate lets an admin revoke a certificate; verification then reports it invalid.
+func RevokeCertificate(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	if user.Role != "admin" {
+		http.Error(w, "Only administrators can revoke certificates", http.StatusForbidden)
+		return
+	}
+
+	var req models.RevokeCertificateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+	if req.Reason == "" {
+		http.Error(w, "reason is required", http.StatusBadRequest)
+		return
+	}
+
+	cert, err := repository.RevokeCertificate(r.Context(), mux.Vars(r)["serial"], user.ID, req.Reason)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Certificate not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Revoke certificate error: %v", err)
+		http.Error(w, "Server error while revoking certificate", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Certificate revoked",
+		Data:    cert,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetCertificateTemplate(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return
+	}
+
+	tmpl, err := services.GetCertificateTemplate(r.Context(), courseID)
+	if err != nil {
+		log.Printf("Get certificate template error: %v", err)
+		http.Error(w, "Server error while retrieving certificate template", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    tmpl,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateCertificateTemplate sets the accent color and per-language wording of the course's
+// certificates. Certificates are rendered on download, so the change applies to issued ones too.
+func UpdateCertificateTemplate(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return
+	}
+
+	var tmpl models.CertificateTemplate
+	if err := json.NewDecoder(r.Body).Decode(&tmpl); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+	tmpl.CourseID = courseID
+
+	if err := services.SaveCertificateTemplate(r.Context(), tmpl); err != nil {
+		if errors.Is(err, services.ErrInvalidCertificateTemplate) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Update certificate template error: %v", err)
+		http.Error(w, "Server error while updating certificate template", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Certificate template updated",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
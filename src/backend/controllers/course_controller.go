@@ -8,6 +8,7 @@ import (
 
 	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
 	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
 
 	"github.com/gorilla/mux"
 )
@@ -170,6 +171,7 @@ func UpdateCourse(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Server error during course update", http.StatusInternalServerError)
 		return
 	}
+	services.NotifyCourseUpdate(updatedCourse)
 
 	response := models.Response{
 		Success: true,
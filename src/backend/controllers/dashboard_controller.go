@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
+)
+
+// GetUserProgress returns the current user's dashboard: totals, weekly study time, streak,
+// recent activity and upcoming deadlines.
+func GetUserProgress(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	dashboard, err := services.BuildStudentDashboard(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Get user progress error: %v", err)
+		http.Error(w, "Server error while retrieving progress", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    dashboard,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
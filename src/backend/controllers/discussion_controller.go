@@ -2,79 +2,73 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
-	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
 )
 
+// GetDiscussions serves ?sort=newest|helpful|unanswered&page=&limit=, and for the platform
+// forum ?category=. Pinned discussions come first.
 func GetDiscussions(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	courseID := params["courseId"]
+	courseID := mux.Vars(r)["courseId"]
+	user, moderator, ok := requireDiscussionAccess(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	page, limit := pagination(r)
+	categoryID := ""
+	if courseID == "" {
+		categoryID = r.URL.Query().Get("category")
+	}
+	discussions, err := services.GetDiscussionPage(r.Context(), courseID, categoryID, r.URL.Query().Get("sort"), user.ID, moderator, page, limit)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidDiscussion) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Get discussions error: %v", err)
+		http.Error(w, "Server error while retrieving discussions", http.StatusInternalServerError)
+		return
+	}
 
 	response := models.Response{
 		Success: true,
-		Data: []map[string]interface{}{
-			{
-				"id":         1,
-				"course_id":  courseID,
-				"user_id":    1,
-				"title":      "Помогите с заданием",
-				"content":    "У меня возникла проблема с заданием 5, можете помочь?",
-				"created_at": time.Now().Format(time.RFC3339),
-				"user_name":  "Студент 1",
-			},
-			{
-				"id":         2,
-				"course_id":  courseID,
-				"user_id":    2,
-				"title":      "Вопрос по лекции",
-				"content":    "Не понял часть про алгоритмы в последней лекции",
-				"created_at": time.Now().Add(-24 * time.Hour).Format(time.RFC3339),
-				"user_name":  "Студент 2",
-			},
-		},
+		Data:    discussions,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// GetDiscussion returns a discussion with its replies and counts the view.
 func GetDiscussion(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	courseID := params["courseId"]
-	discussionID := params["discussionId"]
+	user, moderator, ok := requireDiscussionAccess(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	thread, err := services.ViewDiscussion(r.Context(), courseID, params["discussionId"], user.ID, moderator)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Discussion not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Get discussion error: %v", err)
+		http.Error(w, "Server error while retrieving discussion", http.StatusInternalServerError)
+		return
+	}
 
 	response := models.Response{
 		Success: true,
-		Data: map[string]interface{}{
-			"id":         discussionID,
-			"course_id":  courseID,
-			"user_id":    1,
-			"title":      "Помогите с заданием",
-			"content":    "У меня возникла проблема с заданием 5, можете помочь?",
-			"created_at": time.Now().Format(time.RFC3339),
-			"user_name":  "Студент 1",
-			"replies": []map[string]interface{}{
-				{
-					"id":            1,
-					"discussion_id": discussionID,
-					"user_id":       3,
-					"content":       "Я тоже столкнулся с этой проблемой",
-					"created_at":    time.Now().Add(-1 * time.Hour).Format(time.RFC3339),
-					"user_name":     "Студент 3",
-				},
-				{
-					"id":            2,
-					"discussion_id": discussionID,
-					"user_id":       4,
-					"content":       "Попробуйте подход, описанный в лекции 3",
-					"created_at":    time.Now().Add(-30 * time.Minute).Format(time.RFC3339),
-					"user_name":     "Преподаватель",
-				},
-			},
-		},
+		Data:    thread,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -82,21 +76,36 @@ func GetDiscussion(w http.ResponseWriter, r *http.Request) {
 }
 
 func CreateDiscussion(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	courseID := params["courseId"]
+	courseID := mux.Vars(r)["courseId"]
+	user, _, ok := requireDiscussionAccess(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	var req models.DiscussionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	discussion, err := services.CreateDiscussion(r.Context(), courseID, user.ID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidDiscussion):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrForumBanned):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			log.Printf("Create discussion error: %v", err)
+			http.Error(w, "Server error while creating discussion", http.StatusInternalServerError)
+		}
+		return
+	}
 
 	response := models.Response{
 		Success: true,
-		Message: "Discussion created successfully for course " + courseID,
-		Data: map[string]interface{}{
-			"id":         3,
-			"course_id":  courseID,
-			"user_id":    1,
-			"title":      "Новое обсуждение",
-			"content":    "Содержание нового обсуждения",
-			"created_at": time.Now().Format(time.RFC3339),
-			"user_name":  "Студент 1",
-		},
+		Message: postCreatedMessage("Discussion created", discussion.ModerationStatus),
+		Data:    discussion,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -107,22 +116,397 @@ func CreateDiscussion(w http.ResponseWriter, r *http.Request) {
 func ReplyToDiscussion(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	courseID := params["courseId"]
-	discussionID := params["discussionId"]
+	user, _, ok := requireDiscussionAccess(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	var req models.DiscussionReplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	reply, err := services.ReplyToDiscussion(r.Context(), courseID, params["discussionId"], user.ID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidDiscussion):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrForumBanned):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, services.ErrDiscussionClosed):
+			http.Error(w, "Discussion is closed", http.StatusConflict)
+		case errors.Is(err, models.ErrNotFound):
+			http.Error(w, "Discussion not found", http.StatusNotFound)
+		default:
+			log.Printf("Reply to discussion error: %v", err)
+			http.Error(w, "Server error while adding reply", http.StatusInternalServerError)
+		}
+		return
+	}
 
 	response := models.Response{
 		Success: true,
-		Message: "Reply added to discussion " + discussionID + " in course " + courseID,
-		Data: map[string]interface{}{
-			"id":            3,
-			"discussion_id": discussionID,
-			"user_id":       1,
-			"content":       "Новый ответ на обсуждение",
-			"created_at":    time.Now().Format(time.RFC3339),
-			"user_name":     "Студент 1",
-		},
+		Message: postCreatedMessage("Reply added", reply.ModerationStatus),
+		Data:    reply,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(response)
 }
+
+// GetUnansweredDiscussions lists for course staff the open discussions without a solution or
+// a reply from the teacher, the longest waiting first.
+func GetUnansweredDiscussions(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return
+	}
+
+	page, limit := pagination(r)
+	discussions, err := services.GetUnansweredDiscussionPage(r.Context(), courseID, page, limit)
+	if err != nil {
+		log.Printf("Get unanswered discussions error: %v", err)
+		http.Error(w, "Server error while retrieving discussions", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    discussions,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// postCreatedMessage tells the author when their new post waits for a moderator.
+func postCreatedMessage(message, status string) string {
+	if status == models.PostHeld {
+		return message + " and is awaiting moderation"
+	}
+	return message
+}
+
+// requireDiscussionAccess is requireCourseAccess that also reports whether the user moderates
+// the course's discussions, which course staff do. Without a courseID it guards the platform
+// forum, open to every user and moderated by admins.
+func requireDiscussionAccess(w http.ResponseWriter, r *http.Request, courseID string) (*models.User, bool, bool) {
+	if courseID == "" {
+		user, ok := currentUser(w, r)
+		if !ok {
+			return nil, false, false
+		}
+		return user, user.Role == "admin", true
+	}
+
+	user, ok := requireCourseAccess(w, r, courseID)
+	if !ok {
+		return nil, false, false
+	}
+
+	staff, _, err := courseRole(r, user, courseID)
+	if err != nil {
+		log.Printf("Check course access error: %v", err)
+		http.Error(w, "Server error while checking course access", http.StatusInternalServerError)
+		return nil, false, false
+	}
+	return user, staff, true
+}
+
+// requireDiscussionOwner loads the discussion from the route and allows its author and the
+// course staff through.
+func requireDiscussionOwner(w http.ResponseWriter, r *http.Request) (*models.Discussion, bool) {
+	params := mux.Vars(r)
+	courseID := params["courseId"]
+	user, moderator, ok := requireDiscussionAccess(w, r, courseID)
+	if !ok {
+		return nil, false
+	}
+
+	discussion, err := repository.GetDiscussion(r.Context(), courseID, params["discussionId"])
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Discussion not found", http.StatusNotFound)
+		} else {
+			log.Printf("Get discussion error: %v", err)
+			http.Error(w, "Server error while retrieving discussion", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	if discussion.UserID != user.ID && !moderator {
+		http.Error(w, "Only the discussion author or a moderator can do this", http.StatusForbidden)
+		return nil, false
+	}
+	return discussion, true
+}
+
+// MarkDiscussionSolution accepts a reply as the solution of its discussion.
+func MarkDiscussionSolution(w http.ResponseWriter, r *http.Request) {
+	discussion, ok := requireDiscussionOwner(w, r)
+	if !ok {
+		return
+	}
+
+	reply, err := services.MarkDiscussionSolution(r.Context(), discussion, mux.Vars(r)["replyId"])
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Reply not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Mark discussion solution error: %v", err)
+		http.Error(w, "Server error while marking solution", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Solution marked",
+		Data:    reply,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func UnmarkDiscussionSolution(w http.ResponseWriter, r *http.Request) {
+	discussion, ok := requireDiscussionOwner(w, r)
+	if !ok {
+		return
+	}
+
+	if err := services.UnmarkDiscussionSolution(r.Context(), discussion.ID); err != nil {
+		log.Printf("Unmark discussion solution error: %v", err)
+		http.Error(w, "Server error while unmarking solution", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Solution unmarked",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// VoteHelpful serves POST (vote) and DELETE (withdraw the vote) on
+// /discussions/{discussionId}/helpful and /discussions/{discussionId}/replies/{replyId}/helpful.
+func VoteHelpful(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID := params["courseId"]
+	user, _, ok := requireDiscussionAccess(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	vote, err := services.VoteHelpful(r.Context(), courseID, params["discussionId"], params["replyId"],
+		user.ID, r.Method != http.MethodDelete)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrOwnPostVote):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, models.ErrNotFound):
+			http.Error(w, "Discussion or reply not found", http.StatusNotFound)
+		default:
+			log.Printf("Helpful vote error: %v", err)
+			http.Error(w, "Server error while saving vote", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    vote,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// writeDiscussionError maps errors from editing, deleting and reading the history of posts to
+// responses, logging unexpected ones.
+func writeDiscussionError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidDiscussion):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrNotPostAuthor), errors.Is(err, services.ErrEditWindowClosed),
+		errors.Is(err, services.ErrForumBanned):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, models.ErrNotFound):
+		http.Error(w, "Discussion or reply not found", http.StatusNotFound)
+	default:
+		log.Printf("%s error: %v", action, err)
+		http.Error(w, "Server error while processing discussion", http.StatusInternalServerError)
+	}
+}
+
+// UpdateDiscussion lets the author edit their discussion within the edit window.
+func UpdateDiscussion(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID := params["courseId"]
+	user, _, ok := requireDiscussionAccess(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	var req models.DiscussionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	discussion, err := services.EditDiscussion(r.Context(), courseID, params["discussionId"], user.ID, req)
+	if err != nil {
+		writeDiscussionError(w, err, "Update discussion")
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Discussion updated",
+		Data:    discussion,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateDiscussionReply lets the author edit their reply within the edit window.
+func UpdateDiscussionReply(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID := params["courseId"]
+	user, _, ok := requireDiscussionAccess(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	var req models.DiscussionReplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	reply, err := services.EditDiscussionReply(r.Context(), courseID, params["discussionId"], params["replyId"], user.ID, req.Content)
+	if err != nil {
+		writeDiscussionError(w, err, "Update discussion reply")
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Reply updated",
+		Data:    reply,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteDiscussionReply soft-deletes a reply; it stays in the thread as "[deleted]".
+func DeleteDiscussionReply(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID := params["courseId"]
+	user, moderator, ok := requireDiscussionAccess(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	err := services.DeleteDiscussionReply(r.Context(), courseID, params["discussionId"], params["replyId"], user.ID, moderator)
+	if err != nil {
+		writeDiscussionError(w, err, "Delete discussion reply")
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Reply deleted",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetDiscussionHistory returns the previous versions of a discussion to its author and
+// moderators.
+func GetDiscussionHistory(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID := params["courseId"]
+	user, moderator, ok := requireDiscussionAccess(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	edits, err := services.GetDiscussionEdits(r.Context(), courseID, params["discussionId"], user.ID, moderator)
+	if err != nil {
+		writeDiscussionError(w, err, "Get discussion history")
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    edits,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetDiscussionReplyHistory returns the previous versions of a reply to its author and
+// moderators.
+func GetDiscussionReplyHistory(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID := params["courseId"]
+	user, moderator, ok := requireDiscussionAccess(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	edits, err := services.GetDiscussionReplyEdits(r.Context(), courseID, params["discussionId"], params["replyId"], user.ID, moderator)
+	if err != nil {
+		writeDiscussionError(w, err, "Get discussion reply history")
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    edits,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// SubscribeDiscussion follows a discussion on POST and stops following it on DELETE.
+func SubscribeDiscussion(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID := params["courseId"]
+	user, moderator, ok := requireDiscussionAccess(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	subscribe := r.Method != http.MethodDelete
+	var err error
+	if subscribe {
+		err = services.SubscribeDiscussion(r.Context(), courseID, params["discussionId"], user.ID, moderator)
+	} else {
+		err = services.UnsubscribeDiscussion(r.Context(), courseID, params["discussionId"], user.ID)
+	}
+	if err != nil {
+		writeDiscussionError(w, err, "Discussion subscription")
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Unsubscribed from discussion",
+	}
+	if subscribe {
+		response.Message = "Subscribed to discussion"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -2,19 +2,167 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
 )
 
+// EnrollCourse enrolls the current user in a course. Repeating the request is harmless and
+// returns the existing enrollment.
 func EnrollCourse(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	courseID := params["id"]
+	courseID := mux.Vars(r)["id"]
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := repository.GetCourseTeacherID(r.Context(), courseID); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Course not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Get course error: %v", err)
+		http.Error(w, "Server error during enrollment", http.StatusInternalServerError)
+		return
+	}
+
+	enrollment, created, err := repository.Enroll(r.Context(), user.ID, courseID)
+	if err != nil {
+		log.Printf("Enroll course error: %v", err)
+		http.Error(w, "Server error during enrollment", http.StatusInternalServerError)
+		return
+	}
 
 	response := models.Response{
 		Success: true,
-		Message: "Successfully enrolled in course " + courseID,
+		Message: "Already enrolled in course",
+		Data:    enrollment,
+	}
+
+	if created {
+		services.InvalidateCourseAnalytics(courseID)
+		services.NotifyEnrollment(r.Context(), courseID, user)
+		services.EmitEnrollmentCreated(r.Context(), enrollment, user)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if created {
+		response.Message = "Successfully enrolled in course"
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// UnenrollCourse removes the current user from a course. Unenrolling when not enrolled succeeds.
+func UnenrollCourse(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["id"]
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	removed, err := repository.Unenroll(r.Context(), user.ID, courseID)
+	if err != nil {
+		log.Printf("Unenroll course error: %v", err)
+		http.Error(w, "Server error while leaving course", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Not enrolled in course",
+	}
+	if removed {
+		services.InvalidateCourseAnalytics(courseID)
+		response.Message = "Successfully left course"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetMyEnrollments(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	enrollments, err := repository.GetUserEnrollments(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Get enrollments error: %v", err)
+		http.Error(w, "Server error while retrieving enrollments", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    enrollments,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetCourseStudents lists the students enrolled in a course for its teacher.
+func GetCourseStudents(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return
+	}
+
+	students, err := repository.GetCourseStudents(r.Context(), courseID)
+	if err != nil {
+		log.Printf("Get course students error: %v", err)
+		http.Error(w, "Server error while retrieving students", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    students,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateCompletionRule sets what counts towards course progress and the percentage that
+// completes the course, then recomputes every enrolled student.
+func UpdateCompletionRule(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return
+	}
+
+	var req models.CompletionRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+	if !services.IsValidCompletionRule(req.Rule) {
+		http.Error(w, "rule must be one of lessons, tests, lessons_and_tests", http.StatusBadRequest)
+		return
+	}
+	if req.Threshold < 1 || req.Threshold > 100 {
+		http.Error(w, "threshold must be between 1 and 100", http.StatusBadRequest)
+		return
+	}
+
+	if err := services.UpdateCompletionRule(r.Context(), courseID, req); err != nil {
+		log.Printf("Update completion rule error: %v", err)
+		http.Error(w, "Server error while updating completion rule", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Completion rule updated",
+		Data:    req,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -45,46 +193,3 @@ func GetFeaturedCourses(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
-
-func GetCourseAnalytics(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	courseID := params["id"]
-
-	response := models.Response{
-		Success: true,
-		Data: map[string]interface{}{
-			"course_id":       courseID,
-			"total_students":  45,
-			"average_score":   78.5,
-			"completion_rate": 0.65,
-			"engagement_metrics": map[string]interface{}{
-				"lessons_viewed": 320,
-				"tests_taken":    98,
-				"discussions":    34,
-			},
-			"student_progress": []map[string]interface{}{
-				{
-					"user_id":  1,
-					"name":     "Студент 1",
-					"progress": 0.9,
-					"score":    85,
-				},
-				{
-					"user_id":  2,
-					"name":     "Студент 2",
-					"progress": 0.75,
-					"score":    72,
-				},
-				{
-					"user_id":  3,
-					"name":     "Студент 3",
-					"progress": 0.6,
-					"score":    68,
-				},
-			},
-		},
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
@@ -0,0 +1,445 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
+)
+
+// courseDeck loads a deck of the course, writing an error response when that fails.
+func courseDeck(w http.ResponseWriter, r *http.Request, courseID, deckID string) (*models.FlashcardDeck, bool) {
+	deck, err := repository.GetDeck(r.Context(), courseID, deckID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Deck not found", http.StatusNotFound)
+		} else {
+			log.Printf("Get deck error: %v", err)
+			http.Error(w, "Server error while retrieving deck", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	return deck, true
+}
+
+func GetDecks(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+	if _, ok := requireCourseAccess(w, r, courseID); !ok {
+		return
+	}
+
+	decks, err := repository.GetCourseDecks(r.Context(), courseID)
+	if err != nil {
+		log.Printf("Get decks error: %v", err)
+		http.Error(w, "Server error while retrieving decks", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    decks,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func CreateDeck(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+	user, ok := requireCourseStaff(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	var req models.FlashcardDeckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+	if err := services.ValidateFlashcardDeck(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	deck, err := repository.CreateDeck(r.Context(), courseID, user.ID, req)
+	if err != nil {
+		log.Printf("Create deck error: %v", err)
+		http.Error(w, "Server error while creating deck", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Deck created",
+		Data:    deck,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetDeck returns a deck with all its cards in both languages.
+func GetDeck(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID, deckID := params["courseId"], params["deckId"]
+	if _, ok := requireCourseAccess(w, r, courseID); !ok {
+		return
+	}
+	deck, ok := courseDeck(w, r, courseID, deckID)
+	if !ok {
+		return
+	}
+
+	cards, err := repository.GetDeckCards(r.Context(), deck.ID)
+	if err != nil {
+		log.Printf("Get flashcards error: %v", err)
+		http.Error(w, "Server error while retrieving deck", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"deck":  deck,
+			"cards": cards,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func UpdateDeck(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID, deckID := params["courseId"], params["deckId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return
+	}
+
+	var req models.FlashcardDeckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+	if err := services.ValidateFlashcardDeck(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	deck, err := repository.UpdateDeck(r.Context(), courseID, deckID, req)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Deck not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Update deck error: %v", err)
+		http.Error(w, "Server error while updating deck", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Deck updated",
+		Data:    deck,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func DeleteDeck(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID, deckID := params["courseId"], params["deckId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return
+	}
+
+	if err := repository.DeleteDeck(r.Context(), courseID, deckID); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Deck not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Delete deck error: %v", err)
+		http.Error(w, "Server error while deleting deck", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Deck deleted",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func CreateFlashcard(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID, deckID := params["courseId"], params["deckId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return
+	}
+	if _, ok := courseDeck(w, r, courseID, deckID); !ok {
+		return
+	}
+
+	var req models.FlashcardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	card, err := services.CreateFlashcard(r.Context(), deckID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFlashcard) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Create flashcard error: %v", err)
+		http.Error(w, "Server error while creating flashcard", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Flashcard created",
+		Data:    card,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+func UpdateFlashcard(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID, deckID := params["courseId"], params["deckId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return
+	}
+	if _, ok := courseDeck(w, r, courseID, deckID); !ok {
+		return
+	}
+
+	var req models.FlashcardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	card, err := services.UpdateFlashcard(r.Context(), deckID, params["cardId"], req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidFlashcard):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, models.ErrNotFound):
+			http.Error(w, "Flashcard not found", http.StatusNotFound)
+		default:
+			log.Printf("Update flashcard error: %v", err)
+			http.Error(w, "Server error while updating flashcard", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Flashcard updated",
+		Data:    card,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func DeleteFlashcard(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID, deckID := params["courseId"], params["deckId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return
+	}
+	if _, ok := courseDeck(w, r, courseID, deckID); !ok {
+		return
+	}
+
+	if err := repository.DeleteFlashcard(r.Context(), deckID, params["cardId"]); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Flashcard not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Delete flashcard error: %v", err)
+		http.Error(w, "Server error while deleting flashcard", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Flashcard deleted",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GenerateFlashcards derives cards from the course's questions and lessons.
+func GenerateFlashcards(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID, deckID := params["courseId"], params["deckId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return
+	}
+	if _, ok := courseDeck(w, r, courseID, deckID); !ok {
+		return
+	}
+
+	var req models.GenerateFlashcardsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	created, err := services.GenerateFlashcards(r.Context(), courseID, deckID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFlashcard) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Generate flashcards error: %v", err)
+		http.Error(w, "Server error while generating flashcards", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Flashcards generated",
+		Data:    map[string]int{"created": created},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetDeckDueFlashcards returns the deck's cards the current user should study today.
+func GetDeckDueFlashcards(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID, deckID := params["courseId"], params["deckId"]
+	user, ok := requireCourseAccess(w, r, courseID)
+	if !ok {
+		return
+	}
+	if _, ok := courseDeck(w, r, courseID, deckID); !ok {
+		return
+	}
+
+	writeDueFlashcards(w, r, user, deckID)
+}
+
+// GetDueFlashcards returns the cards the current user should study today across the decks of
+// their enrolled courses.
+func GetDueFlashcards(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	writeDueFlashcards(w, r, user, "")
+}
+
+func writeDueFlashcards(w http.ResponseWriter, r *http.Request, user *models.User, deckID string) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	cards, err := services.GetDueFlashcards(r.Context(), user, deckID, limit)
+	if err != nil {
+		log.Printf("Get due flashcards error: %v", err)
+		http.Error(w, "Server error while retrieving flashcards", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    cards,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ReviewFlashcard records the current user's recall of a card, graded 0 to 5.
+func ReviewFlashcard(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID, deckID, cardID := params["courseId"], params["deckId"], params["cardId"]
+	user, ok := requireCourseAccess(w, r, courseID)
+	if !ok {
+		return
+	}
+	if _, ok := courseDeck(w, r, courseID, deckID); !ok {
+		return
+	}
+	if _, err := repository.GetFlashcard(r.Context(), deckID, cardID); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Flashcard not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Get flashcard error: %v", err)
+		http.Error(w, "Server error while retrieving flashcard", http.StatusInternalServerError)
+		return
+	}
+
+	var req models.FlashcardReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	schedule, err := services.ReviewFlashcard(r.Context(), user.ID, cardID, req.Grade)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidGrade) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Review flashcard error: %v", err)
+		http.Error(w, "Server error while recording review", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    schedule,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetDeckStats returns the current user's progress and retention in a deck.
+func GetDeckStats(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID, deckID := params["courseId"], params["deckId"]
+	user, ok := requireCourseAccess(w, r, courseID)
+	if !ok {
+		return
+	}
+	if _, ok := courseDeck(w, r, courseID, deckID); !ok {
+		return
+	}
+
+	stats, err := services.GetDeckStats(r.Context(), user.ID, deckID)
+	if err != nil {
+		log.Printf("Get deck stats error: %v", err)
+		http.Error(w, "Server error while retrieving deck statistics", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    stats,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
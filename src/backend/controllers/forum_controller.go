@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
+)
+
+// GetForumCategories lists the open forum categories in ?lang=, or the user's language.
+func GetForumCategories(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	lang := r.URL.Query().Get("lang")
+	if lang == "" {
+		lang = user.LanguagePreference
+	}
+	categories, err := services.GetForumCategories(r.Context(), lang)
+	if err != nil {
+		log.Printf("Get forum categories error: %v", err)
+		http.Error(w, "Server error while retrieving forum categories", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    categories,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetManagedForumCategories lists every category, archived ones included, in both languages.
+func GetManagedForumCategories(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := requireModerator(w, r); !ok {
+		return
+	}
+
+	categories, err := repository.GetForumCategories(r.Context(), true)
+	if err != nil {
+		log.Printf("Get forum categories error: %v", err)
+		http.Error(w, "Server error while retrieving forum categories", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    categories,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func writeForumCategoryError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidForumCategory):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, models.ErrConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, models.ErrNotFound):
+		http.Error(w, "Forum category not found", http.StatusNotFound)
+	default:
+		log.Printf("%s error: %v", action, err)
+		http.Error(w, "Server error while saving forum category", http.StatusInternalServerError)
+	}
+}
+
+func CreateForumCategory(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := requireModerator(w, r); !ok {
+		return
+	}
+
+	var req models.ForumCategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	category, err := services.CreateForumCategory(r.Context(), req)
+	if err != nil {
+		writeForumCategoryError(w, err, "Create forum category")
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Forum category created",
+		Data:    category,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+func UpdateForumCategory(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := requireModerator(w, r); !ok {
+		return
+	}
+
+	var req models.ForumCategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	category, err := services.UpdateForumCategory(r.Context(), mux.Vars(r)["categoryId"], req)
+	if err != nil {
+		writeForumCategoryError(w, err, "Update forum category")
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Forum category updated",
+		Data:    category,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func DeleteForumCategory(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := requireModerator(w, r); !ok {
+		return
+	}
+
+	if err := services.DeleteForumCategory(r.Context(), mux.Vars(r)["categoryId"]); err != nil {
+		writeForumCategoryError(w, err, "Delete forum category")
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Forum category deleted",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
+)
+
+// GetMyGamification returns the current user's XP, level, streak and latest XP awards.
+func GetMyGamification(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	progress, err := services.GetGamificationProgress(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Get gamification progress error: %v", err)
+		http.Error(w, "Server error while retrieving XP", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    progress,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetMyBadges returns the badge catalog with the badges the current user earned.
+func GetMyBadges(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	badges, err := services.GetUserBadges(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Get badges error: %v", err)
+		http.Error(w, "Server error while retrieving badges", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    badges,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
+)
+
+// RecordIntegrityEvents accepts a batch of focus, tab, clipboard and fullscreen events for the
+// student's own running attempt. The response tells the client whether the attempt was terminated.
+func RecordIntegrityEvents(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	attempt, ok := loadAttempt(w, r, user, params["testId"], params["attemptId"])
+	if !ok {
+		return
+	}
+	if attempt.UserID != user.ID {
+		http.Error(w, "Not authorized to report events for this attempt", http.StatusForbidden)
+		return
+	}
+
+	var req models.IntegrityEventsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := services.RecordIntegrityEvents(r.Context(), attempt, req.Events)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAttemptClosed):
+			http.Error(w, "Attempt is already completed", http.StatusConflict)
+		case errors.Is(err, services.ErrInvalidIntegrityEvent):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			log.Printf("Record integrity events error: %v", err)
+			http.Error(w, "Server error while recording events", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"status":          updated.Status,
+			"suspicion_score": updated.SuspicionScore,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetAttemptIntegrity returns the integrity timeline of an attempt for course staff.
+func GetAttemptIntegrity(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	user, ok := requireCourseStaff(w, r, params["courseId"])
+	if !ok {
+		return
+	}
+
+	attempt, ok := loadAttempt(w, r, user, params["testId"], params["attemptId"])
+	if !ok {
+		return
+	}
+
+	report, err := services.BuildIntegrityReport(r.Context(), attempt)
+	if err != nil {
+		log.Printf("Get attempt integrity error: %v", err)
+		http.Error(w, "Server error while retrieving integrity report", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    report,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetTestIntegrity lists the attempts of a test, most suspicious first.
+func GetTestIntegrity(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	if _, ok := requireCourseStaff(w, r, params["courseId"]); !ok {
+		return
+	}
+
+	test, ok := loadTest(w, r, params["courseId"], params["testId"])
+	if !ok {
+		return
+	}
+
+	attempts, err := repository.GetAttemptsBySuspicion(r.Context(), test.ID)
+	if err != nil {
+		log.Printf("Get test integrity error: %v", err)
+		http.Error(w, "Server error while retrieving attempts", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"integrity_policy":    test.IntegrityPolicy,
+			"integrity_threshold": test.IntegrityThreshold,
+			"attempts":            attempts,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func UpdateIntegrityPolicy(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	if _, ok := requireCourseStaff(w, r, params["courseId"]); !ok {
+		return
+	}
+
+	test, ok := loadTest(w, r, params["courseId"], params["testId"])
+	if !ok {
+		return
+	}
+
+	var req models.IntegrityPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+	if !services.IsValidIntegrityPolicy(req.Policy) {
+		http.Error(w, "policy must be one of none, flag, terminate", http.StatusBadRequest)
+		return
+	}
+	if req.Threshold < 1 || req.Threshold > 100 {
+		http.Error(w, "threshold must be between 1 and 100", http.StatusBadRequest)
+		return
+	}
+
+	if err := repository.UpdateIntegrityPolicy(r.Context(), test.ID, req); err != nil {
+		log.Printf("Update integrity policy error: %v", err)
+		http.Error(w, "Server error while updating integrity policy", http.StatusInternalServerError)
+		return
+	}
+	test.IntegrityPolicy = req.Policy
+	test.IntegrityThreshold = req.Threshold
+
+	response := models.Response{
+		Success: true,
+		Message: "Integrity policy updated",
+		Data:    test,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
+)
+
+// GetItemAnalysis returns the question statistics of a test. The stored snapshot is kept up to
+// date as attempts complete; refresh=true recomputes it on the spot.
+func GetItemAnalysis(w http.ResponseWriter, r *http.Request) {
+	analysis, ok := loadItemAnalysis(w, r)
+	if !ok {
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    analysis,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func ExportItemAnalysis(w http.ResponseWriter, r *http.Request) {
+	analysis, ok := loadItemAnalysis(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := services.ItemAnalysisCSV(analysis)
+	if err != nil {
+		log.Printf("Export item analysis error: %v", err)
+		http.Error(w, "Server error during item analysis export", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="item-analysis-%s.csv"`, analysis.TestID))
+	w.Write(body)
+}
+
+func loadItemAnalysis(w http.ResponseWriter, r *http.Request) (*models.ItemAnalysis, bool) {
+	params := mux.Vars(r)
+	courseID := params["courseId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return nil, false
+	}
+
+	test, ok := loadTest(w, r, courseID, params["testId"])
+	if !ok {
+		return nil, false
+	}
+
+	analysis, err := repository.GetItemAnalysis(r.Context(), test.ID)
+	if errors.Is(err, models.ErrNotFound) || (err == nil && r.URL.Query().Get("refresh") == "true") {
+		analysis, err = services.RefreshItemAnalysis(r.Context(), test.ID)
+	}
+	if err != nil {
+		log.Printf("Get item analysis error: %v", err)
+		http.Error(w, "Server error while computing item analysis", http.StatusInternalServerError)
+		return nil, false
+	}
+	return analysis, true
+}
@@ -0,0 +1,293 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
+)
+
+// GetLeaderboard serves /leaderboards?scope=platform|course|class|region&id=&metric=xp|score
+// &period=week|month|all&limit=. Course boards are open to the course's students and staff,
+// class boards to the class and its teacher.
+func GetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := models.LeaderboardQuery{
+		Scope:   query.Get("scope"),
+		ScopeID: query.Get("id"),
+		Metric:  query.Get("metric"),
+		Period:  query.Get("period"),
+	}
+	if q.Scope == "" {
+		q.Scope = models.LeaderboardScopePlatform
+	}
+	q.Limit, _ = strconv.Atoi(query.Get("limit"))
+
+	var user *models.User
+	var ok bool
+	switch q.Scope {
+	case models.LeaderboardScopeCourse:
+		user, ok = requireCourseAccess(w, r, q.ScopeID)
+	case models.LeaderboardScopeClass:
+		user, ok = requireClassGroupAccess(w, r, q.ScopeID)
+	default:
+		user, ok = currentUser(w, r)
+	}
+	if !ok {
+		return
+	}
+	q.UserID = user.ID
+
+	board, err := services.GetLeaderboard(r.Context(), q)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidLeaderboard) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Get leaderboard error: %v", err)
+		http.Error(w, "Server error while retrieving leaderboard", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    board,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// requireClassGroupAccess allows the group's teacher, its members and admins through.
+func requireClassGroupAccess(w http.ResponseWriter, r *http.Request, groupID string) (*models.User, bool) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	teacherID, member, err := repository.GetClassGroupAccess(r.Context(), groupID, user.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Class group not found", http.StatusNotFound)
+		} else {
+			log.Printf("Get class group error: %v", err)
+			http.Error(w, "Server error while retrieving class group", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+
+	if !member && teacherID != user.ID && user.Role != "admin" {
+		http.Error(w, "Not a member of this class group", http.StatusForbidden)
+		return nil, false
+	}
+	return user, true
+}
+
+func GetUserSettings(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	settings, err := services.GetUserSettings(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Get user settings error: %v", err)
+		http.Error(w, "Server error while retrieving settings", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    settings,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateUserSettings replaces the current user's settings, including the leaderboard opt-out
+// and region.
+func UpdateUserSettings(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var settings models.UserSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	if err := services.SaveUserSettings(r.Context(), user.ID, settings); err != nil {
+		if errors.Is(err, services.ErrInvalidSettings) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Update user settings error: %v", err)
+		http.Error(w, "Server error while updating settings", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Settings updated",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateUsername changes the current user's username, the name others @mention them by.
+func UpdateUsername(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UsernameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	username, err := services.ChangeUsername(r.Context(), user.ID, req.Username)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidUsername) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Update username error: %v", err)
+		http.Error(w, "Server error while updating username", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Username updated",
+		Data:    models.UsernameRequest{Username: username},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func CreateClassGroup(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	if user.Role != "teacher" && user.Role != "admin" {
+		http.Error(w, "Only teachers can create class groups", http.StatusForbidden)
+		return
+	}
+
+	var req models.ClassGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	group, err := services.CreateClassGroup(r.Context(), user.ID, req.Name)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidClassGroup) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Create class group error: %v", err)
+		http.Error(w, "Server error while creating class group", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Class group created",
+		Data:    group,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetMyClassGroups(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	groups, err := repository.GetUserClassGroups(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Get class groups error: %v", err)
+		http.Error(w, "Server error while retrieving class groups", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    groups,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func JoinClassGroup(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.JoinClassGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	group, err := services.JoinClassGroup(r.Context(), req.Code, user.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Invalid join code", http.StatusNotFound)
+			return
+		}
+		log.Printf("Join class group error: %v", err)
+		http.Error(w, "Server error while joining class group", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Joined class group",
+		Data:    group,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func LeaveClassGroup(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := repository.LeaveClassGroup(r.Context(), mux.Vars(r)["groupId"], user.ID); err != nil {
+		log.Printf("Leave class group error: %v", err)
+		http.Error(w, "Server error while leaving class group", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Left class group",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
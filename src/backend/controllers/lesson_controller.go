@@ -2,12 +2,14 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
 	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
 
 	"github.com/gorilla/mux"
 )
@@ -58,6 +60,7 @@ func CreateLesson(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Ошибка сервера при создании урока", http.StatusInternalServerError)
 		return
 	}
+	services.EmitWebhookEvent(r.Context(), models.WebhookLessonPublished, courseID, lesson)
 
 	response := models.LessonResponse{
 		Success: true,
@@ -203,6 +206,26 @@ func GetLessons(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Без записи на курс виден только план: содержимое закрытых уроков не отдаётся.
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	staff, enrolled, err := courseRole(r, user, courseID)
+	if err != nil {
+		log.Printf("Ошибка при проверке доступа к курсу: %v", err)
+		http.Error(w, "Ошибка сервера при получении уроков", http.StatusInternalServerError)
+		return
+	}
+	if !staff && !enrolled {
+		for i := range lessons {
+			if !lessons[i].IsPublic {
+				lessons[i].Content = ""
+				lessons[i].VideoURL = ""
+			}
+		}
+	}
+
 	response := models.LessonResponse{
 		Success: true,
 		Data:    lessons,
@@ -213,28 +236,108 @@ func GetLessons(w http.ResponseWriter, r *http.Request) {
 }
 
 func GetLesson(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	courseID := params["courseId"]
-	lessonID := params["lessonId"]
+	user, lesson, ok := loadLessonForUser(w, r)
+	if !ok {
+		return
+	}
 
-	lessonIDInt, err := strconv.Atoi(lessonID)
+	progress, err := repository.GetLessonProgress(r.Context(), user.ID, lesson.ID)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		log.Printf("Ошибка при получении прогресса урока: %v", err)
+		http.Error(w, "Ошибка сервера при получении урока", http.StatusInternalServerError)
+		return
+	}
+	lesson.Progress = progress
+
+	response := models.LessonResponse{
+		Success: true,
+		Data:    lesson,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// LessonHeartbeat сохраняет позицию плеера и время просмотра; плеер вызывает его периодически.
+func LessonHeartbeat(w http.ResponseWriter, r *http.Request) {
+	user, lesson, ok := loadLessonForUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.LessonHeartbeatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+
+	progress, err := services.RecordLessonHeartbeat(r.Context(), user.ID, lesson.ID, req)
 	if err != nil {
-		http.Error(w, "Неверный ID урока", http.StatusBadRequest)
+		log.Printf("Ошибка при сохранении прогресса урока: %v", err)
+		http.Error(w, "Ошибка сервера при сохранении прогресса", http.StatusInternalServerError)
 		return
 	}
 
-	lesson, err := repository.GetLesson(r.Context(), courseID, lessonIDInt)
+	response := models.LessonResponse{
+		Success: true,
+		Data:    progress,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func CompleteLesson(w http.ResponseWriter, r *http.Request) {
+	user, lesson, ok := loadLessonForUser(w, r)
+	if !ok {
+		return
+	}
+
+	progress, err := services.CompleteLesson(r.Context(), user.ID, lesson.CourseID, lesson.ID)
 	if err != nil {
-		log.Printf("Ошибка при получении урока: %v", err)
-		http.Error(w, "Урок не найден", http.StatusNotFound)
+		log.Printf("Ошибка при завершении урока: %v", err)
+		http.Error(w, "Ошибка сервера при завершении урока", http.StatusInternalServerError)
 		return
 	}
 
 	response := models.LessonResponse{
 		Success: true,
-		Data:    lesson,
+		Message: "Урок отмечен как пройденный",
+		Data:    progress,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+// loadLessonForUser загружает урок из URL и проверяет доступ: открытые уроки доступны всем,
+// остальные — только записанным на курс и преподавателям.
+func loadLessonForUser(w http.ResponseWriter, r *http.Request) (*models.User, models.Lesson, bool) {
+	params := mux.Vars(r)
+	courseID := params["courseId"]
+
+	lessonIDInt, err := strconv.Atoi(params["lessonId"])
+	if err != nil {
+		http.Error(w, "Неверный ID урока", http.StatusBadRequest)
+		return nil, models.Lesson{}, false
+	}
+
+	lesson, err := repository.GetLesson(r.Context(), courseID, lessonIDInt)
+	if err != nil {
+		log.Printf("Ошибка при получении урока: %v", err)
+		http.Error(w, "Урок не найден", http.StatusNotFound)
+		return nil, models.Lesson{}, false
+	}
+
+	var user *models.User
+	var ok bool
+	if lesson.IsPublic {
+		user, ok = currentUser(w, r)
+	} else {
+		user, ok = requireCourseAccess(w, r, courseID)
+	}
+	if !ok {
+		return nil, models.Lesson{}, false
+	}
+	return user, lesson, true
+}
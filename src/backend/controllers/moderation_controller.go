@@ -0,0 +1,258 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
+)
+
+// ReportPost reports a discussion, or one of its replies when the route names one, to the
+// course moderators.
+func ReportPost(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID := params["courseId"]
+	user, _, ok := requireDiscussionAccess(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	var req models.ReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	err := services.ReportPost(r.Context(), courseID, params["discussionId"], params["replyId"], user.ID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidReport):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, models.ErrNotFound):
+			http.Error(w, "Discussion or reply not found", http.StatusNotFound)
+		default:
+			log.Printf("Report post error: %v", err)
+			http.Error(w, "Server error while reporting post", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Report sent to the moderators",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// requireModerator allows the course staff through on course routes, and admins only on the
+// platform-wide /moderation routes. It returns the course, empty for the latter.
+func requireModerator(w http.ResponseWriter, r *http.Request) (*models.User, string, bool) {
+	courseID := mux.Vars(r)["courseId"]
+	if courseID != "" {
+		user, ok := requireCourseStaff(w, r, courseID)
+		return user, courseID, ok
+	}
+
+	user, ok := currentUser(w, r)
+	if !ok {
+		return nil, "", false
+	}
+	if user.Role != "admin" {
+		http.Error(w, "Admin access required", http.StatusForbidden)
+		return nil, "", false
+	}
+	return user, "", true
+}
+
+// GetModerationQueue lists held and reported posts of a course, or of all courses for admins.
+func GetModerationQueue(w http.ResponseWriter, r *http.Request) {
+	_, courseID, ok := requireModerator(w, r)
+	if !ok {
+		return
+	}
+
+	page, limit := pagination(r)
+	queue, err := services.GetModerationQueue(r.Context(), courseID, page, limit)
+	if err != nil {
+		log.Printf("Get moderation queue error: %v", err)
+		http.Error(w, "Server error while retrieving moderation queue", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    queue,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetModerationLog lists moderation actions in a course, or in all courses for admins.
+func GetModerationLog(w http.ResponseWriter, r *http.Request) {
+	_, courseID, ok := requireModerator(w, r)
+	if !ok {
+		return
+	}
+
+	page, limit := pagination(r)
+	entries, err := services.GetModerationLog(r.Context(), courseID, page, limit)
+	if err != nil {
+		log.Printf("Get moderation log error: %v", err)
+		http.Error(w, "Server error while retrieving moderation log", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    entries,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ModerateDiscussions hides, restores, locks, pins or dismisses reports of posts and bans
+// users from the course forum, or on forum routes from the platform forum.
+func ModerateDiscussions(w http.ResponseWriter, r *http.Request) {
+	user, courseID, ok := requireModerator(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ModerationActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	if err := services.Moderate(r.Context(), courseID, user.ID, req); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidModeration):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, models.ErrNotFound):
+			http.Error(w, "Target not found in this forum", http.StatusNotFound)
+		default:
+			log.Printf("Moderation error: %v", err)
+			http.Error(w, "Server error while moderating", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Moderation action applied",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetForumBans(w http.ResponseWriter, r *http.Request) {
+	_, courseID, ok := requireModerator(w, r)
+	if !ok {
+		return
+	}
+
+	bans, err := repository.GetForumBans(r.Context(), courseID)
+	if err != nil {
+		log.Printf("Get forum bans error: %v", err)
+		http.Error(w, "Server error while retrieving bans", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    bans,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetModerationWords(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := requireModerator(w, r); !ok {
+		return
+	}
+
+	words, err := repository.GetModerationWords(r.Context())
+	if err != nil {
+		log.Printf("Get moderation words error: %v", err)
+		http.Error(w, "Server error while retrieving filter words", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    words,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func AddModerationWord(w http.ResponseWriter, r *http.Request) {
+	user, _, ok := requireModerator(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ModerationWordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	word, err := services.AddModerationWord(r.Context(), user.ID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFilterWord) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Add moderation word error: %v", err)
+		http.Error(w, "Server error while adding filter word", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Filter word added",
+		Data:    word,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+func DeleteModerationWord(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := requireModerator(w, r); !ok {
+		return
+	}
+
+	if err := services.DeleteModerationWord(r.Context(), mux.Vars(r)["wordId"]); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Filter word not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Delete moderation word error: %v", err)
+		http.Error(w, "Server error while deleting filter word", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Filter word deleted",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
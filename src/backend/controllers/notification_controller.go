@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
+)
+
+// GetNotifications returns a page of the current user's notifications, newest first, with
+// their unread count. ?unread=true lists only the unread ones.
+func GetNotifications(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	page, limit := pagination(r)
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+	notifications, err := services.GetNotifications(r.Context(), user.ID, unreadOnly, page, limit)
+	if err != nil {
+		log.Printf("Get notifications error: %v", err)
+		http.Error(w, "Server error while fetching notifications", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    notifications,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := repository.MarkNotificationRead(r.Context(), user.ID, mux.Vars(r)["notificationId"]); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Notification not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Mark notification read error: %v", err)
+		http.Error(w, "Server error while updating notification", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Notification marked as read",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	marked, err := repository.MarkAllNotificationsRead(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Mark all notifications read error: %v", err)
+		http.Error(w, "Server error while updating notifications", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "All notifications marked as read",
+		Data:    map[string]int64{"marked": marked},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func DeleteNotification(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := repository.DeleteNotification(r.Context(), user.ID, mux.Vars(r)["notificationId"]); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Notification not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Delete notification error: %v", err)
+		http.Error(w, "Server error while deleting notification", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Notification deleted",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetNotificationPreferences returns the current user's opt-ins for every notification type
+// and channel.
+func GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	prefs, err := services.GetNotificationPreferences(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Get notification preferences error: %v", err)
+		http.Error(w, "Server error while retrieving notification preferences", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    prefs,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateNotificationPreferences replaces the current user's opt-ins. Types left out are on.
+func UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var prefs models.NotificationPreferences
+	if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	if err := services.SaveNotificationPreferences(r.Context(), user.ID, prefs); err != nil {
+		if errors.Is(err, services.ErrInvalidNotificationPreferences) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Update notification preferences error: %v", err)
+		http.Error(w, "Server error while updating notification preferences", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Notification preferences updated",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
+)
+
+// StartPracticeSession begins an adaptive practice session on a subject and, optionally, a topic
+// of the course question bank. The response carries the first question.
+func StartPracticeSession(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+	user, ok := requireCourseAccess(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	var req models.PracticeSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Subject == "" {
+		http.Error(w, "subject is required", http.StatusBadRequest)
+		return
+	}
+
+	session, err := services.StartPracticeSession(r.Context(), user.ID, courseID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrNoPracticeQuestions) {
+			http.Error(w, "No questions available for this topic", http.StatusBadRequest)
+			return
+		}
+		log.Printf("Start practice session error: %v", err)
+		http.Error(w, "Server error while starting practice", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    session,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetPracticeSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := loadPracticeSession(w, r)
+	if !ok {
+		return
+	}
+
+	if err := services.LoadPracticeSessionView(r.Context(), session); err != nil {
+		log.Printf("Get practice session error: %v", err)
+		http.Error(w, "Server error while retrieving practice session", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    session,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func AnswerPracticeQuestion(w http.ResponseWriter, r *http.Request) {
+	session, ok := loadPracticeSession(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.PracticeAnswerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := services.AnswerPracticeQuestion(r.Context(), session, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSessionClosed):
+			http.Error(w, "Practice session is already completed", http.StatusConflict)
+		case errors.Is(err, services.ErrNotCurrentQuestion):
+			http.Error(w, "This question is not the current one", http.StatusConflict)
+		default:
+			log.Printf("Answer practice question error: %v", err)
+			http.Error(w, "Server error while saving answer", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    result,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetPracticeAbilities lists the student's latest ability and ENT score estimate per topic.
+func GetPracticeAbilities(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	abilities, err := repository.GetAbilities(r.Context(), user.ID, mux.Vars(r)["courseId"])
+	if err != nil {
+		log.Printf("Get abilities error: %v", err)
+		http.Error(w, "Server error while retrieving abilities", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    abilities,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func loadPracticeSession(w http.ResponseWriter, r *http.Request) (*models.PracticeSession, bool) {
+	params := mux.Vars(r)
+	user, ok := currentUser(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	session, err := repository.GetPracticeSession(r.Context(), params["sessionId"])
+	if err != nil || session.CourseID != params["courseId"] || session.UserID != user.ID {
+		if err == nil || errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Practice session not found", http.StatusNotFound)
+		} else {
+			log.Printf("Get practice session error: %v", err)
+			http.Error(w, "Server error while retrieving practice session", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	return session, true
+}
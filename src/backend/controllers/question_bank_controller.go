@@ -0,0 +1,282 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
+)
+
+func GetQuestionBank(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return
+	}
+
+	filter := models.QuestionBankFilter{
+		Subject:    r.URL.Query().Get("subject"),
+		Topic:      r.URL.Query().Get("topic"),
+		Difficulty: r.URL.Query().Get("difficulty"),
+	}
+
+	questions, err := repository.GetBankQuestions(r.Context(), courseID, filter)
+	if err != nil {
+		log.Printf("Get question bank error: %v", err)
+		http.Error(w, "Server error while retrieving question bank", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"count":     len(questions),
+			"questions": questions,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func CreateBankQuestion(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+	user, ok := requireCourseStaff(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	var req models.QuestionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+	if msg := services.ValidateQuestionRequest(&req); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	question, err := repository.CreateQuestion(r.Context(), "", courseID, user.ID, req)
+	if err != nil {
+		log.Printf("Create bank question error: %v", err)
+		http.Error(w, "Server error during question creation", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    question,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateBankQuestion saves an edit to a bank question. A question that attempts have already
+// used is archived and the edit comes back as a new question with its own id.
+func UpdateBankQuestion(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID := params["courseId"]
+	user, ok := requireCourseStaff(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	question, ok := loadBankQuestion(w, r, courseID, user, params["questionId"])
+	if !ok {
+		return
+	}
+
+	var req models.QuestionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+	if msg := services.ValidateQuestionRequest(&req); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	updated, err := repository.UpdateQuestion(r.Context(), question.ID, req)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Question not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Update bank question error: %v", err)
+		http.Error(w, "Server error during question update", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    updated,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteBankQuestion removes a bank question, or archives it when attempts have used it.
+func DeleteBankQuestion(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID := params["courseId"]
+	user, ok := requireCourseStaff(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	question, ok := loadBankQuestion(w, r, courseID, user, params["questionId"])
+	if !ok {
+		return
+	}
+
+	if err := repository.DeleteQuestion(r.Context(), question.ID); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Question not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Delete bank question error: %v", err)
+		http.Error(w, "Server error during question deletion", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Question deleted successfully",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetTestRules(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID := params["courseId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return
+	}
+
+	test, ok := loadTest(w, r, courseID, params["testId"])
+	if !ok {
+		return
+	}
+
+	rules, err := repository.GetQuestionRules(r.Context(), test.ID)
+	if err != nil {
+		log.Printf("Get test rules error: %v", err)
+		http.Error(w, "Server error while retrieving test rules", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    rules,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateTestRules replaces the random-draw rules of a test. The rules must be satisfiable
+// together from the bank as it is now, otherwise attempts could not be started.
+func UpdateTestRules(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID := params["courseId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return
+	}
+
+	test, ok := loadTest(w, r, courseID, params["testId"])
+	if !ok {
+		return
+	}
+
+	var req []models.QuestionRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	for i, rule := range req {
+		if rule.QuestionCount <= 0 {
+			http.Error(w, fmt.Sprintf("Rule %d: question_count must be positive", i+1), http.StatusBadRequest)
+			return
+		}
+		if rule.Difficulty != "" && !services.IsValidDifficulty(rule.Difficulty) {
+			http.Error(w, fmt.Sprintf("Rule %d: unknown difficulty %q", i+1, rule.Difficulty), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := services.CheckQuestionRules(r.Context(), courseID, req); err != nil {
+		if errors.Is(err, services.ErrRulesUnsatisfiable) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Check test rules error: %v", err)
+		http.Error(w, "Server error while saving test rules", http.StatusInternalServerError)
+		return
+	}
+
+	rules, err := repository.ReplaceQuestionRules(r.Context(), test.ID, req)
+	if err != nil {
+		log.Printf("Update test rules error: %v", err)
+		http.Error(w, "Server error while saving test rules", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    rules,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// loadBankQuestion fetches a bank question that the course staff may edit: one from the
+// course's own bank, or any bank question for admins.
+func loadBankQuestion(w http.ResponseWriter, r *http.Request, courseID string, user *models.User, questionID string) (*models.Question, bool) {
+	question, err := repository.GetQuestionByID(r.Context(), questionID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Question not found", http.StatusNotFound)
+		} else {
+			log.Printf("Get question error: %v", err)
+			http.Error(w, "Server error while retrieving question", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+
+	if question.TestID != nil || question.ArchivedAt != nil {
+		http.Error(w, "Question not found", http.StatusNotFound)
+		return nil, false
+	}
+	ownBank := question.CourseID != nil && *question.CourseID == courseID
+	if !ownBank && user.Role != "admin" {
+		http.Error(w, "Not authorized to edit this question", http.StatusForbidden)
+		return nil, false
+	}
+	return question, true
+}
+
+func loadTest(w http.ResponseWriter, r *http.Request, courseID, testID string) (*models.Test, bool) {
+	test, err := repository.GetTestByID(r.Context(), courseID, testID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Test not found", http.StatusNotFound)
+		} else {
+			log.Printf("Get test error: %v", err)
+			http.Error(w, "Server error while retrieving test", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	return test, true
+}
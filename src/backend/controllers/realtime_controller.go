@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
+)
+
+// maxStreamDiscussions caps how many threads one stream can follow.
+const maxStreamDiscussions = 20
+
+// streamHeartbeat keeps proxies from closing an idle stream.
+const streamHeartbeat = 25 * time.Second
+
+// streamTopics returns the topics of a stream: the user's own, for notifications and grading
+// results, and one for every thread in ?discussions= the user can read. Forum topics are
+// open to everyone.
+func streamTopics(w http.ResponseWriter, r *http.Request, user *models.User) ([]string, bool) {
+	topics := []string{services.UserTopic(user.ID)}
+	param := r.URL.Query().Get("discussions")
+	if param == "" {
+		return topics, true
+	}
+
+	ids := strings.Split(param, ",")
+	if len(ids) > maxStreamDiscussions {
+		http.Error(w, fmt.Sprintf("At most %d discussions per stream", maxStreamDiscussions), http.StatusBadRequest)
+		return nil, false
+	}
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		courseID, err := repository.GetDiscussionCourseID(r.Context(), id)
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Discussion not found", http.StatusNotFound)
+			return nil, false
+		}
+		if err != nil {
+			log.Printf("Event stream error: %v", err)
+			http.Error(w, "Server error while opening event stream", http.StatusInternalServerError)
+			return nil, false
+		}
+		if courseID != "" {
+			staff, enrolled, err := courseRole(r, user, courseID)
+			if err != nil {
+				log.Printf("Event stream error: %v", err)
+				http.Error(w, "Server error while opening event stream", http.StatusInternalServerError)
+				return nil, false
+			}
+			if !staff && !enrolled {
+				http.Error(w, "You are not enrolled in this course", http.StatusForbidden)
+				return nil, false
+			}
+		}
+		topics = append(topics, services.DiscussionTopic(id))
+	}
+	return topics, true
+}
+
+// StreamEvents is a Server-Sent Events stream of the user's notifications and grading
+// results, and of new replies in the threads listed in ?discussions=. Clients reconnecting
+// with Last-Event-ID (or ?last_event_id=) first get the events they missed.
+func StreamEvents(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	topics, ok := streamTopics(w, r, user)
+	if !ok {
+		return
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	sent, _ := strconv.ParseInt(lastID, 10, 64)
+
+	stream, err := services.OpenEventStream(r.Context(), user.ID, topics, sent)
+	if err != nil {
+		if errors.Is(err, services.ErrTooManyStreams) {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		log.Printf("Event stream error: %v", err)
+		http.Error(w, "Server error while opening event stream", http.StatusInternalServerError)
+		return
+	}
+	defer services.CloseEventStream(stream)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if stream.Reset {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range stream.Backlog {
+		writeStreamEvent(w, event)
+		sent = event.ID
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case event, ok := <-stream.Events:
+			if !ok {
+				// Dropped for falling behind; the client reconnects and replays.
+				return
+			}
+			// Events arrive in the order their IDs were committed, so one at or below sent
+			// already came with the backlog.
+			if event.ID <= sent {
+				continue
+			}
+			writeStreamEvent(w, event)
+			sent = event.ID
+		}
+		flusher.Flush()
+	}
+}
+
+func writeStreamEvent(w http.ResponseWriter, event models.RealtimeEvent) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
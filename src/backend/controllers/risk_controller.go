@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
+)
+
+// GetAtRiskStudents lists the students the last early warning scan flagged, with the reasons.
+func GetAtRiskStudents(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return
+	}
+
+	students, err := repository.GetAtRiskStudents(r.Context(), courseID)
+	if err != nil {
+		log.Printf("Get at-risk students error: %v", err)
+		http.Error(w, "Server error while retrieving at-risk students", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    students,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetRiskSettings(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return
+	}
+
+	settings, err := repository.GetRiskSettings(r.Context(), courseID)
+	if err != nil {
+		log.Printf("Get risk settings error: %v", err)
+		http.Error(w, "Server error while retrieving risk settings", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    settings,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateRiskSettings changes the course's early warning thresholds. They apply from the next scan.
+func UpdateRiskSettings(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return
+	}
+
+	var req models.RiskSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+	if err := services.ValidateRiskSettings(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := repository.SaveRiskSettings(r.Context(), courseID, req); err != nil {
+		log.Printf("Update risk settings error: %v", err)
+		http.Error(w, "Server error while updating risk settings", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Risk settings updated",
+		Data:    req,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetRiskDigests returns the current teacher's weekly at-risk digests, newest first.
+func GetRiskDigests(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	digests, err := services.GetRiskDigests(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Get risk digests error: %v", err)
+		http.Error(w, "Server error while retrieving digests", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    digests,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
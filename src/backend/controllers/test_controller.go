@@ -2,10 +2,14 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
 )
 
 func CreateTest(w http.ResponseWriter, r *http.Request) {
@@ -87,19 +91,142 @@ func DeleteTest(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// StartTestAttempt draws the question set for a new attempt. The drawn questions and their
+// option order are stored, so the attempt can be graded and reviewed exactly as it was shown.
+// Unpublished tests can only be tried out by course staff.
+func StartTestAttempt(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	test, ok := loadTest(w, r, params["courseId"], params["testId"])
+	if !ok {
+		return
+	}
+	if !test.IsPublished {
+		staff, _, err := courseRole(r, user, test.CourseID)
+		if err != nil && !errors.Is(err, models.ErrNotFound) {
+			log.Printf("Check course access error: %v", err)
+			http.Error(w, "Server error while checking course access", http.StatusInternalServerError)
+			return
+		}
+		if !staff {
+			http.Error(w, "Test not found", http.StatusNotFound)
+			return
+		}
+	}
+	if !test.IsPublic {
+		if _, ok := requireCourseAccess(w, r, test.CourseID); !ok {
+			return
+		}
+	}
+
+	attempt, err := services.StartTestAttempt(r.Context(), user.ID, test)
+	if err != nil {
+		if errors.Is(err, services.ErrNoQuestions) {
+			http.Error(w, "Test has no questions", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, services.ErrNotEnoughQuestions) {
+			http.Error(w, "The question bank no longer has enough questions for this test", http.StatusConflict)
+			return
+		}
+		log.Printf("Start test attempt error: %v", err)
+		http.Error(w, "Server error while starting test", http.StatusInternalServerError)
+		return
+	}
+	services.HideAnswerKey(attempt.Questions)
+
+	response := models.Response{
+		Success: true,
+		Data:    attempt,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetTestAttempt returns an attempt with its drawn questions. The answer key is only
+// included once the attempt is completed.
+func GetTestAttempt(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	attempt, ok := loadAttempt(w, r, user, params["testId"], params["attemptId"])
+	if !ok {
+		return
+	}
+
+	questions, err := repository.GetAttemptQuestions(r.Context(), attempt.ID)
+	if err != nil {
+		log.Printf("Get attempt questions error: %v", err)
+		http.Error(w, "Server error while retrieving attempt", http.StatusInternalServerError)
+		return
+	}
+	if attempt.Status == "in_progress" {
+		services.HideAnswerKey(questions)
+	}
+	attempt.Questions = questions
+
+	response := models.Response{
+		Success: true,
+		Data:    attempt,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 func SubmitTest(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
-	courseID := params["courseId"]
-	testID := params["testId"]
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.SubmitTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+	if req.AttemptID == "" {
+		http.Error(w, "attempt_id is required", http.StatusBadRequest)
+		return
+	}
+
+	attempt, ok := loadAttempt(w, r, user, params["testId"], req.AttemptID)
+	if !ok {
+		return
+	}
+	if attempt.UserID != user.ID {
+		http.Error(w, "Not authorized to submit this attempt", http.StatusForbidden)
+		return
+	}
+
+	completed, err := services.SubmitTestAttempt(r.Context(), attempt, req.Answers)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAttemptClosed):
+			http.Error(w, "Attempt is already completed", http.StatusConflict)
+		case errors.Is(err, services.ErrQuestionNotInTest):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			log.Printf("Submit test error: %v", err)
+			http.Error(w, "Server error while submitting test", http.StatusInternalServerError)
+		}
+		return
+	}
 
 	response := models.Response{
 		Success: true,
-		Message: "Test " + testID + " submitted successfully for course " + courseID,
-		Data: map[string]interface{}{
-			"score":      85,
-			"total":      100,
-			"percentage": 85,
-		},
+		Message: "Test submitted successfully",
+		Data:    completed,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -108,33 +235,54 @@ func SubmitTest(w http.ResponseWriter, r *http.Request) {
 
 func GetTestResults(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
-	courseID := params["courseId"]
-	testID := params["testId"]
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	test, ok := loadTest(w, r, params["courseId"], params["testId"])
+	if !ok {
+		return
+	}
+
+	attempts, err := repository.GetUserAttempts(r.Context(), user.ID, test.ID)
+	if err != nil {
+		log.Printf("Get test results error: %v", err)
+		http.Error(w, "Server error while retrieving test results", http.StatusInternalServerError)
+		return
+	}
 
 	response := models.Response{
 		Success: true,
 		Data: map[string]interface{}{
-			"id":         testID,
-			"course_id":  courseID,
-			"score":      85,
-			"total":      100,
-			"percentage": 85,
-			"completed":  true,
-			"answers": []map[string]interface{}{
-				{
-					"question_id": 1,
-					"selected":    1,
-					"correct":     true,
-				},
-				{
-					"question_id": 2,
-					"selected":    2,
-					"correct":     true,
-				},
-			},
+			"test":     test,
+			"attempts": attempts,
 		},
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+// loadAttempt fetches an attempt of the given test that the user may see: their own, or any
+// attempt for course staff.
+func loadAttempt(w http.ResponseWriter, r *http.Request, user *models.User, testID, attemptID string) (*models.TestAttempt, bool) {
+	attempt, err := repository.GetAttempt(r.Context(), attemptID)
+	if err != nil || attempt.TestID != testID {
+		if err == nil || errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Attempt not found", http.StatusNotFound)
+		} else {
+			log.Printf("Get attempt error: %v", err)
+			http.Error(w, "Server error while retrieving attempt", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+
+	if attempt.UserID != user.ID {
+		courseID := mux.Vars(r)["courseId"]
+		if _, ok := requireCourseStaff(w, r, courseID); !ok {
+			return nil, false
+		}
+	}
+	return attempt, true
+}
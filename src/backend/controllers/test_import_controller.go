@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
+)
+
+const maxImportSize = 5 << 20
+
+// ImportTest creates a new test from a GIFT, Moodle XML or CSV file. With dry_run=true only
+// the report is returned, listing everything that would be dropped or skipped.
+func ImportTest(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+	user, ok := requireCourseStaff(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	test := models.TestRequest{
+		Title:            query.Get("title"),
+		TitleKK:          query.Get("title_kk"),
+		TimeLimitMinutes: 30,
+		PassingScore:     70,
+		IsPublic:         query.Get("is_public") == "true",
+	}
+	if test.Title == "" {
+		http.Error(w, "title is required", http.StatusBadRequest)
+		return
+	}
+	if due := query.Get("due_at"); due != "" {
+		dueAt, err := time.Parse(time.RFC3339, due)
+		if err != nil {
+			http.Error(w, "due_at must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		test.DueAt = &dueAt
+	}
+
+	questions, report, ok := parseImport(w, r)
+	if !ok {
+		return
+	}
+
+	if !report.DryRun {
+		if len(questions) == 0 {
+			http.Error(w, "The file contains no importable questions", http.StatusBadRequest)
+			return
+		}
+		created, err := repository.CreateTestWithQuestions(r.Context(), courseID, user.ID, test, questions)
+		if err != nil {
+			log.Printf("Import test error: %v", err)
+			http.Error(w, "Server error during test import", http.StatusInternalServerError)
+			return
+		}
+		report.TestID = created.ID
+	}
+
+	writeImportReport(w, report)
+}
+
+// ImportBankQuestions adds the questions of a file to the course question bank.
+func ImportBankQuestions(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+	user, ok := requireCourseStaff(w, r, courseID)
+	if !ok {
+		return
+	}
+
+	questions, report, ok := parseImport(w, r)
+	if !ok {
+		return
+	}
+
+	if !report.DryRun && len(questions) > 0 {
+		if err := repository.CreateBankQuestions(r.Context(), courseID, user.ID, questions); err != nil {
+			log.Printf("Import bank questions error: %v", err)
+			http.Error(w, "Server error during question import", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	writeImportReport(w, report)
+}
+
+func ExportTest(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	courseID := params["courseId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return
+	}
+
+	test, ok := loadTest(w, r, courseID, params["testId"])
+	if !ok {
+		return
+	}
+
+	questions, err := repository.GetTestQuestions(r.Context(), test.ID)
+	if err != nil {
+		log.Printf("Export test error: %v", err)
+		http.Error(w, "Server error during test export", http.StatusInternalServerError)
+		return
+	}
+
+	writeExport(w, r, "test-"+test.ID, questions)
+}
+
+func ExportQuestionBank(w http.ResponseWriter, r *http.Request) {
+	courseID := mux.Vars(r)["courseId"]
+	if _, ok := requireCourseStaff(w, r, courseID); !ok {
+		return
+	}
+
+	filter := models.QuestionBankFilter{
+		Subject:    r.URL.Query().Get("subject"),
+		Topic:      r.URL.Query().Get("topic"),
+		Difficulty: r.URL.Query().Get("difficulty"),
+	}
+	questions, err := repository.GetBankQuestions(r.Context(), courseID, filter)
+	if err != nil {
+		log.Printf("Export question bank error: %v", err)
+		http.Error(w, "Server error during question bank export", http.StatusInternalServerError)
+		return
+	}
+
+	writeExport(w, r, "question-bank-"+courseID, questions)
+}
+
+// parseImport reads the uploaded file, either as the multipart field "file" or as the raw
+// request body, and runs it through the importer selected by the format query parameter.
+func parseImport(w http.ResponseWriter, r *http.Request) ([]models.QuestionRequest, *models.ImportReport, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	var data []byte
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, ferr := r.FormFile("file")
+		if ferr != nil {
+			http.Error(w, "file is required", http.StatusBadRequest)
+			return nil, nil, false
+		}
+		defer file.Close()
+		data, err = io.ReadAll(file)
+	} else {
+		data, err = io.ReadAll(r.Body)
+	}
+	if err != nil {
+		http.Error(w, "Could not read the uploaded file", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	questions, report, err := services.ImportQuestions(r.URL.Query().Get("format"), data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, nil, false
+	}
+	report.DryRun = r.URL.Query().Get("dry_run") == "true"
+	if report.DryRun {
+		report.Questions = questions
+	}
+	return questions, report, true
+}
+
+func writeImportReport(w http.ResponseWriter, report *models.ImportReport) {
+	response := models.Response{
+		Success: true,
+		Data:    report,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !report.DryRun && report.Imported > 0 {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func writeExport(w http.ResponseWriter, r *http.Request, name string, questions []models.Question) {
+	body, contentType, ext, err := services.ExportQuestions(r.URL.Query().Get("format"), questions)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, ext))
+	w.Write(body)
+}
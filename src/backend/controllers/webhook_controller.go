@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
+)
+
+// writeWebhookError answers a failed webhook request, logging unexpected errors.
+func writeWebhookError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidWebhook):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, models.ErrNotFound):
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+	default:
+		log.Printf("%s webhook error: %v", action, err)
+		http.Error(w, "Server error while processing webhook", http.StatusInternalServerError)
+	}
+}
+
+func GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	webhooks, err := services.GetWebhooks(r.Context())
+	if err != nil {
+		writeWebhookError(w, err, "Get")
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    webhooks,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetWebhook(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	webhook, err := services.GetWebhook(r.Context(), mux.Vars(r)["webhookId"])
+	if err != nil {
+		writeWebhookError(w, err, "Get")
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    webhook,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateWebhook adds a webhook. The response carries its signing secret, which is not shown
+// again.
+func CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := services.CreateWebhook(r.Context(), user.ID, req)
+	if err != nil {
+		writeWebhookError(w, err, "Create")
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Webhook created",
+		Data:    webhook,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+func UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	var req models.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := services.UpdateWebhook(r.Context(), mux.Vars(r)["webhookId"], req)
+	if err != nil {
+		writeWebhookError(w, err, "Update")
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Webhook updated",
+		Data:    webhook,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	if err := repository.DeleteWebhook(r.Context(), mux.Vars(r)["webhookId"]); err != nil {
+		writeWebhookError(w, err, "Delete")
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Webhook deleted",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetWebhookDeliveries lists a webhook's deliveries with their response codes, newest first.
+func GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	page, limit := pagination(r)
+	deliveries, err := services.GetWebhookDeliveries(r.Context(), mux.Vars(r)["webhookId"], page, limit)
+	if err != nil {
+		writeWebhookError(w, err, "Get deliveries")
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Data:    deliveries,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// RedeliverWebhook queues an earlier delivery to be sent again as a new delivery.
+func RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	params := mux.Vars(r)
+	delivery, err := services.RedeliverWebhook(r.Context(), params["webhookId"], params["deliveryId"])
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Delivery not found", http.StatusNotFound)
+			return
+		}
+		writeWebhookError(w, err, "Redeliver")
+		return
+	}
+
+	response := models.Response{
+		Success: true,
+		Message: "Delivery queued",
+		Data:    delivery,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
@@ -8,11 +8,14 @@ import (
 	"os"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 var DB *sql.DB
 
+// connString is kept for connections outside the pool, such as LISTEN.
+var connString string
+
 func InitDB() error {
 	user := os.Getenv("DB_USER")
 	password := os.Getenv("DB_PASSWORD")
@@ -24,6 +27,8 @@ func InitDB() error {
 	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		host, port, user, password, dbname, sslMode)
 
+	connString = connStr
+
 	var err error
 	DB, err = sql.Open("postgres", connStr)
 	if err != nil {
@@ -56,6 +61,8 @@ func InitDB() error {
 	return nil
 }
 
+// ensureTablesExist applies schema.sql on every start. All statements in the schema are
+// idempotent (IF NOT EXISTS), so existing databases pick up tables and columns added later.
 func ensureTablesExist(ctx context.Context) error {
 	var exists bool
 	err := DB.QueryRowContext(ctx, "SELECT EXISTS (SELECT FROM information_schema.tables WHERE table_name = 'users')").Scan(&exists)
@@ -65,21 +72,23 @@ func ensureTablesExist(ctx context.Context) error {
 
 	if !exists {
 		log.Println("Creating database tables...")
+	} else {
+		log.Println("Applying schema updates...")
+	}
 
-		schema, err := os.ReadFile("database/schema.sql")
-		if err != nil {
-			schema, err = os.ReadFile("./database/schema.sql")
-			if err != nil {
-				return fmt.Errorf("error reading schema.sql: %w", err)
-			}
-		}
-		_, err = DB.ExecContext(ctx, string(schema))
+	schema, err := os.ReadFile("database/schema.sql")
+	if err != nil {
+		schema, err = os.ReadFile("./database/schema.sql")
 		if err != nil {
-			return fmt.Errorf("error executing schema.sql: %w", err)
+			return fmt.Errorf("error reading schema.sql: %w", err)
 		}
-
-		log.Println("Database tables created successfully")
 	}
+	_, err = DB.ExecContext(ctx, string(schema))
+	if err != nil {
+		return fmt.Errorf("error executing schema.sql: %w", err)
+	}
+
+	log.Println("Database schema is up to date")
 	return nil
 }
 
@@ -98,3 +107,9 @@ func ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Re
 func BeginTx(ctx context.Context) (*sql.Tx, error) {
 	return DB.BeginTx(ctx, nil)
 }
+
+// NewListener opens a dedicated connection for LISTEN, which reconnects on its own after
+// losing the connection.
+func NewListener(callback pq.EventCallbackType) *pq.Listener {
+	return pq.NewListener(connString, 10*time.Second, time.Minute, callback)
+}
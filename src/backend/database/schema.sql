@@ -78,6 +78,9 @@ CREATE TABLE
         updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
     );
 
+-- Handles for @mentions, assigned at registration
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username VARCHAR(30);
+
 -- Courses table
 CREATE TABLE
     IF NOT EXISTS courses (
@@ -239,6 +242,21 @@ CREATE TABLE
         helpful_count INTEGER DEFAULT 0
     );
 
+ALTER TABLE discussions ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP;
+ALTER TABLE discussion_replies ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES discussion_replies (id);
+ALTER TABLE discussion_replies ADD COLUMN IF NOT EXISTS depth INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE discussion_replies ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP;
+ALTER TABLE discussion_replies ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE discussion_replies ADD COLUMN IF NOT EXISTS deleted_by UUID REFERENCES users (id);
+ALTER TABLE discussions ADD COLUMN IF NOT EXISTS moderation_status VARCHAR(20) NOT NULL DEFAULT 'visible'; -- visible, held, hidden
+ALTER TABLE discussions ADD COLUMN IF NOT EXISTS moderation_reason VARCHAR(255);
+ALTER TABLE discussion_replies ADD COLUMN IF NOT EXISTS moderation_status VARCHAR(20) NOT NULL DEFAULT 'visible';
+ALTER TABLE discussion_replies ADD COLUMN IF NOT EXISTS moderation_reason VARCHAR(255);
+-- Whether a post's mentions and reply notifications went out; posts held when written wait
+-- for a moderator to release them.
+ALTER TABLE discussions ADD COLUMN IF NOT EXISTS announced BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE discussion_replies ADD COLUMN IF NOT EXISTS announced BOOLEAN NOT NULL DEFAULT TRUE;
+
 -- AI Assistant interactions table
 CREATE TABLE
     IF NOT EXISTS ai_assistant (
@@ -249,6 +267,554 @@ CREATE TABLE
         created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
     );
 
+-- Question bank: questions without a test_id belong to a course bank (course_id) or,
+-- when is_shared is set, to the platform-wide bank
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS course_id UUID REFERENCES courses (id) ON DELETE CASCADE;
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS subject VARCHAR(100) DEFAULT '';
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS topic VARCHAR(100) DEFAULT '';
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS is_shared BOOLEAN DEFAULT FALSE;
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS created_by UUID REFERENCES users (id);
+-- Questions used by attempts are archived instead of deleted or edited, so past attempts keep
+-- grading and reviewing against what the student saw
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP;
+
+-- Attempt numbers are unique per student and test; older databases may hold duplicates from
+-- concurrent starts, which are renumbered in start order
+UPDATE test_attempts t SET attempt_number = n.rn
+FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY test_id, user_id ORDER BY started_at, id) AS rn FROM test_attempts) n
+WHERE t.id = n.id AND (t.test_id, t.user_id) IN (
+    SELECT test_id, user_id FROM test_attempts GROUP BY test_id, user_id, attempt_number HAVING COUNT(*) > 1
+);
+
+-- Randomized test assembly rules ("5 random medium algebra questions")
+CREATE TABLE
+    IF NOT EXISTS test_question_rules (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        test_id UUID REFERENCES tests (id) ON DELETE CASCADE,
+        subject VARCHAR(100) DEFAULT '',
+        topic VARCHAR(100) DEFAULT '',
+        difficulty VARCHAR(20) DEFAULT '', -- empty matches any difficulty
+        question_count INTEGER NOT NULL,
+        order_index INTEGER NOT NULL
+    );
+
+-- Questions drawn for each attempt, with the shuffled option order that attempt saw
+CREATE TABLE
+    IF NOT EXISTS attempt_questions (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        attempt_id UUID REFERENCES test_attempts (id) ON DELETE CASCADE,
+        question_id UUID REFERENCES questions (id) ON DELETE CASCADE,
+        order_index INTEGER NOT NULL,
+        option_order UUID[] NOT NULL DEFAULT '{}',
+        UNIQUE (attempt_id, question_id)
+    );
+
+-- Per-answer timing reported by the test player, used by item analysis
+ALTER TABLE user_answers ADD COLUMN IF NOT EXISTS time_spent_seconds INTEGER;
+
+-- Item analysis snapshot per test, refreshed after attempts complete
+CREATE TABLE
+    IF NOT EXISTS test_item_analysis (
+        test_id UUID PRIMARY KEY REFERENCES tests (id) ON DELETE CASCADE,
+        attempts_count INTEGER NOT NULL DEFAULT 0,
+        cronbach_alpha DOUBLE PRECISION,
+        items JSONB NOT NULL DEFAULT '[]',
+        computed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE TABLE
+    IF NOT EXISTS irt_item_params (
+        question_id UUID PRIMARY KEY REFERENCES questions (id) ON DELETE CASCADE,
+        model VARCHAR(10) NOT NULL,
+        discrimination DOUBLE PRECISION NOT NULL DEFAULT 1,
+        difficulty DOUBLE PRECISION NOT NULL DEFAULT 0,
+        responses INTEGER NOT NULL DEFAULT 0,
+        calibrated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE TABLE
+    IF NOT EXISTS practice_sessions (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        course_id UUID REFERENCES courses (id) ON DELETE CASCADE,
+        subject VARCHAR(100) NOT NULL DEFAULT '',
+        topic VARCHAR(255) NOT NULL DEFAULT '',
+        status VARCHAR(20) DEFAULT 'in_progress',
+        theta DOUBLE PRECISION NOT NULL DEFAULT 0,
+        standard_error DOUBLE PRECISION NOT NULL DEFAULT 1,
+        question_count INTEGER NOT NULL DEFAULT 0,
+        current_question_id UUID REFERENCES questions (id) ON DELETE SET NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        completed_at TIMESTAMP
+    );
+
+CREATE TABLE
+    IF NOT EXISTS practice_responses (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        session_id UUID REFERENCES practice_sessions (id) ON DELETE CASCADE,
+        question_id UUID REFERENCES questions (id) ON DELETE CASCADE,
+        answer TEXT,
+        is_correct BOOLEAN NOT NULL,
+        theta_after DOUBLE PRECISION NOT NULL,
+        standard_error_after DOUBLE PRECISION NOT NULL,
+        answered_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (session_id, question_id)
+    );
+
+CREATE TABLE
+    IF NOT EXISTS student_abilities (
+        user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        course_id UUID REFERENCES courses (id) ON DELETE CASCADE,
+        subject VARCHAR(100) NOT NULL DEFAULT '',
+        topic VARCHAR(255) NOT NULL DEFAULT '',
+        theta DOUBLE PRECISION NOT NULL,
+        standard_error DOUBLE PRECISION NOT NULL,
+        ent_score INTEGER NOT NULL DEFAULT 0,
+        ent_max_score INTEGER NOT NULL DEFAULT 0,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (user_id, course_id, subject, topic)
+    );
+
+ALTER TABLE tests ADD COLUMN IF NOT EXISTS integrity_policy VARCHAR(20) NOT NULL DEFAULT 'none';
+ALTER TABLE tests ADD COLUMN IF NOT EXISTS integrity_threshold INTEGER NOT NULL DEFAULT 50;
+ALTER TABLE test_attempts ADD COLUMN IF NOT EXISTS suspicion_score INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE test_attempts ADD COLUMN IF NOT EXISTS flagged BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE
+    IF NOT EXISTS integrity_events (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        attempt_id UUID REFERENCES test_attempts (id) ON DELETE CASCADE,
+        event_type VARCHAR(30) NOT NULL,
+        details TEXT,
+        occurred_at TIMESTAMP NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+ALTER TABLE lessons ADD COLUMN IF NOT EXISTS is_public BOOLEAN DEFAULT FALSE;
+ALTER TABLE tests ADD COLUMN IF NOT EXISTS is_public BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Per-course settings kept apart from courses, whose queries select c.*
+CREATE TABLE
+    IF NOT EXISTS course_settings (
+        course_id UUID PRIMARY KEY REFERENCES courses (id) ON DELETE CASCADE,
+        completion_rule VARCHAR(20) NOT NULL DEFAULT 'lessons_and_tests', -- lessons, tests, lessons_and_tests
+        completion_threshold INTEGER NOT NULL DEFAULT 100, -- progress percentage that completes the course
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+ALTER TABLE tests ADD COLUMN IF NOT EXISTS due_at TIMESTAMP;
+
+-- Daily study time rollup behind the student dashboard
+CREATE TABLE
+    IF NOT EXISTS study_activity (
+        user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        activity_date DATE NOT NULL DEFAULT CURRENT_DATE,
+        seconds INTEGER NOT NULL DEFAULT 0,
+        PRIMARY KEY (user_id, activity_date)
+    );
+
+-- At-risk early warning: per-course thresholds, latest score per enrollment, weekly teacher digests
+ALTER TABLE course_settings ADD COLUMN IF NOT EXISTS risk_inactive_days INTEGER NOT NULL DEFAULT 7;
+ALTER TABLE course_settings ADD COLUMN IF NOT EXISTS risk_missed_lessons INTEGER NOT NULL DEFAULT 3;
+ALTER TABLE course_settings ADD COLUMN IF NOT EXISTS risk_score_drop INTEGER NOT NULL DEFAULT 15; -- percentage points
+ALTER TABLE course_settings ADD COLUMN IF NOT EXISTS risk_pace_gap INTEGER NOT NULL DEFAULT 20; -- percentage points behind schedule
+ALTER TABLE course_settings ADD COLUMN IF NOT EXISTS risk_threshold INTEGER NOT NULL DEFAULT 50;
+ALTER TABLE course_settings ADD COLUMN IF NOT EXISTS expected_weeks INTEGER NOT NULL DEFAULT 12; -- 0 disables schedule checks
+
+CREATE TABLE
+    IF NOT EXISTS enrollment_risk (
+        user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        course_id UUID REFERENCES courses (id) ON DELETE CASCADE,
+        score INTEGER NOT NULL DEFAULT 0,
+        flagged BOOLEAN NOT NULL DEFAULT FALSE,
+        reasons JSONB NOT NULL DEFAULT '[]',
+        computed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (user_id, course_id)
+    );
+
+CREATE TABLE
+    IF NOT EXISTS risk_digests (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        teacher_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        week_start DATE NOT NULL,
+        courses JSONB NOT NULL DEFAULT '[]',
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (teacher_id, week_start)
+    );
+
+-- A digest is delivered once its notification is saved and its e-mail queued; until then later
+-- scans of the week retry it.
+ALTER TABLE risk_digests ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMP;
+ALTER TABLE risk_digests ADD COLUMN IF NOT EXISTS delivery_error TEXT;
+
+-- Certificates of completion. Names are copied at issue so a certificate reads the same later.
+CREATE TABLE
+    IF NOT EXISTS certificates (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        serial VARCHAR(32) UNIQUE NOT NULL,
+        user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        course_id UUID REFERENCES courses (id) ON DELETE CASCADE,
+        student_name VARCHAR(255) NOT NULL,
+        course_title VARCHAR(255) NOT NULL,
+        teacher_name VARCHAR(255) NOT NULL DEFAULT '',
+        issued_at TIMESTAMP NOT NULL,
+        revoked_at TIMESTAMP,
+        revoked_by UUID REFERENCES users (id),
+        revocation_reason TEXT,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (user_id, course_id)
+    );
+
+CREATE TABLE
+    IF NOT EXISTS certificate_templates (
+        course_id UUID PRIMARY KEY REFERENCES courses (id) ON DELETE CASCADE,
+        accent_color VARCHAR(7) NOT NULL DEFAULT '#1F4E79',
+        texts JSONB NOT NULL DEFAULT '{}', -- per language: heading, intro, completion
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+-- Gamification. Every XP award is an event keyed by what earned it, so replaying an event
+-- cannot award it twice.
+CREATE TABLE
+    IF NOT EXISTS xp_events (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        kind VARCHAR(50) NOT NULL, -- lesson_completed, test_passed, solution_accepted, streak_day
+        source_id VARCHAR(100) NOT NULL,
+        course_id UUID REFERENCES courses (id) ON DELETE SET NULL,
+        xp INTEGER NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (user_id, kind, source_id)
+    );
+
+CREATE TABLE
+    IF NOT EXISTS user_badges (
+        user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        badge_id VARCHAR(50) NOT NULL,
+        awarded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (user_id, badge_id)
+    );
+
+-- Leaderboard preferences: users can leave the boards and pick the region they are ranked in.
+-- Users without a user_settings row use the defaults.
+ALTER TABLE user_settings ADD COLUMN IF NOT EXISTS leaderboard_opt_out BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE user_settings ADD COLUMN IF NOT EXISTS region VARCHAR(50);
+
+-- Per-type channel opt-ins, e.g. {"graded_test": {"in_app": true, "email": false}}
+ALTER TABLE user_settings ADD COLUMN IF NOT EXISTS notification_preferences JSONB NOT NULL DEFAULT '{}';
+
+-- E-mail digest of unread notifications: off, daily or weekly
+ALTER TABLE user_settings ADD COLUMN IF NOT EXISTS email_digest VARCHAR(10) NOT NULL DEFAULT 'off';
+ALTER TABLE user_settings ADD COLUMN IF NOT EXISTS last_digest_at TIMESTAMP;
+
+-- Class groups: a teacher's class that students join with a code, ranked together.
+CREATE TABLE
+    IF NOT EXISTS class_groups (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        name VARCHAR(255) NOT NULL,
+        teacher_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        join_code VARCHAR(16) UNIQUE NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE TABLE
+    IF NOT EXISTS class_group_members (
+        group_id UUID REFERENCES class_groups (id) ON DELETE CASCADE,
+        user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (group_id, user_id)
+    );
+
+-- Leaderboard aggregates, updated as XP is awarded and attempts are completed. scope is a
+-- course ID, or '' for the platform-wide totals that class and region boards also read.
+CREATE TABLE
+    IF NOT EXISTS leaderboard_scores (
+        user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        scope VARCHAR(36) NOT NULL,
+        period VARCHAR(10) NOT NULL, -- week, month, all
+        period_start DATE NOT NULL,
+        xp INTEGER NOT NULL DEFAULT 0,
+        score_sum INTEGER NOT NULL DEFAULT 0,
+        score_count INTEGER NOT NULL DEFAULT 0,
+        PRIMARY KEY (user_id, scope, period, period_start)
+    );
+
+-- Flashcard decks of a course. Cards derived from a question or lesson remember their source,
+-- so generating from it again does not duplicate them.
+CREATE TABLE
+    IF NOT EXISTS flashcard_decks (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        course_id UUID REFERENCES courses (id) ON DELETE CASCADE,
+        title VARCHAR(255) NOT NULL,
+        title_kk VARCHAR(255),
+        description TEXT,
+        created_by UUID REFERENCES users (id),
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE TABLE
+    IF NOT EXISTS flashcards (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        deck_id UUID REFERENCES flashcard_decks (id) ON DELETE CASCADE,
+        front TEXT NOT NULL,
+        front_kk TEXT,
+        back TEXT NOT NULL,
+        back_kk TEXT,
+        source_type VARCHAR(20) NOT NULL DEFAULT 'manual', -- manual, question, lesson
+        source_id VARCHAR(100),
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (deck_id, source_type, source_id)
+    );
+
+-- SM-2 scheduling state of a card for one student
+CREATE TABLE
+    IF NOT EXISTS flashcard_progress (
+        user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        card_id UUID REFERENCES flashcards (id) ON DELETE CASCADE,
+        ease_factor DOUBLE PRECISION NOT NULL DEFAULT 2.5,
+        interval_days INTEGER NOT NULL DEFAULT 0,
+        repetitions INTEGER NOT NULL DEFAULT 0,
+        lapses INTEGER NOT NULL DEFAULT 0,
+        due_at TIMESTAMP NOT NULL,
+        last_reviewed_at TIMESTAMP,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (user_id, card_id)
+    );
+
+CREATE TABLE
+    IF NOT EXISTS flashcard_reviews (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        card_id UUID REFERENCES flashcards (id) ON DELETE CASCADE,
+        grade INTEGER NOT NULL, -- 0-5, 3 and above is a successful recall
+        previous_interval_days INTEGER NOT NULL,
+        interval_days INTEGER NOT NULL,
+        ease_factor DOUBLE PRECISION NOT NULL,
+        reviewed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+-- Helpful votes on discussions and replies, one per user and item
+CREATE TABLE
+    IF NOT EXISTS discussion_votes (
+        user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        target_type VARCHAR(20) NOT NULL, -- discussion, reply
+        target_id UUID NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (user_id, target_type, target_id)
+    );
+
+-- Previous versions of edited discussions and replies
+CREATE TABLE
+    IF NOT EXISTS discussion_edits (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        target_type VARCHAR(20) NOT NULL, -- discussion, reply
+        target_id UUID NOT NULL,
+        title VARCHAR(255), -- discussions only
+        content TEXT NOT NULL,
+        edited_by UUID REFERENCES users (id),
+        edited_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+-- User reports of discussions and replies, one per user and post
+CREATE TABLE
+    IF NOT EXISTS discussion_reports (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        course_id UUID REFERENCES courses (id) ON DELETE CASCADE,
+        target_type VARCHAR(20) NOT NULL, -- discussion, reply
+        target_id UUID NOT NULL,
+        reporter_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        reason VARCHAR(30) NOT NULL,
+        details TEXT,
+        status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, actioned, dismissed
+        resolved_by UUID REFERENCES users (id),
+        resolved_at TIMESTAMP,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (reporter_id, target_type, target_id)
+    );
+
+-- Users banned from a course forum, until expires_at or for good
+CREATE TABLE
+    IF NOT EXISTS forum_bans (
+        course_id UUID REFERENCES courses (id) ON DELETE CASCADE,
+        user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        banned_by UUID REFERENCES users (id),
+        reason TEXT,
+        expires_at TIMESTAMP,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (course_id, user_id)
+    );
+
+-- Every moderation action; moderator_id is NULL for the automatic filter
+CREATE TABLE
+    IF NOT EXISTS moderation_log (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        course_id UUID REFERENCES courses (id) ON DELETE CASCADE,
+        moderator_id UUID REFERENCES users (id),
+        action VARCHAR(30) NOT NULL,
+        target_type VARCHAR(20), -- discussion, reply, user
+        target_id UUID,
+        target_user_id UUID REFERENCES users (id),
+        reason TEXT,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+-- Words that hold discussion posts for review, matched at the start of words
+CREATE TABLE
+    IF NOT EXISTS moderation_words (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        language VARCHAR(10) NOT NULL, -- ru, kk
+        word VARCHAR(100) NOT NULL,
+        created_by UUID REFERENCES users (id),
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (language, word)
+    );
+
+-- Users following a discussion; authors are subscribed to their own discussions
+CREATE TABLE
+    IF NOT EXISTS discussion_subscriptions (
+        discussion_id UUID REFERENCES discussions (id) ON DELETE CASCADE,
+        user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (discussion_id, user_id)
+    );
+
+-- Replies and mentions waiting to be batched into notifications
+CREATE TABLE
+    IF NOT EXISTS discussion_events (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        discussion_id UUID REFERENCES discussions (id) ON DELETE CASCADE,
+        reply_id UUID REFERENCES discussion_replies (id) ON DELETE CASCADE,
+        actor_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        kind VARCHAR(20) NOT NULL, -- reply, mention
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        notified_at TIMESTAMP
+    );
+
+-- In-app notifications. Unread notifications with the same group_key are merged.
+CREATE TABLE
+    IF NOT EXISTS notifications (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        type VARCHAR(50) NOT NULL,
+        title VARCHAR(255) NOT NULL,
+        body TEXT NOT NULL DEFAULT '',
+        link VARCHAR(255),
+        data JSONB NOT NULL DEFAULT '{}',
+        group_key VARCHAR(100),
+        read_at TIMESTAMP,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+-- Events pushed to live connections, kept for a while so reconnecting clients can replay them
+CREATE TABLE
+    IF NOT EXISTS realtime_events (
+        id BIGSERIAL PRIMARY KEY,
+        topic VARCHAR(100) NOT NULL,
+        type VARCHAR(50) NOT NULL,
+        data JSONB NOT NULL DEFAULT '{}',
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+-- Platform forum categories, one per ENT subject; names in Russian and Kazakh
+CREATE TABLE
+    IF NOT EXISTS forum_categories (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        slug VARCHAR(50) UNIQUE NOT NULL,
+        subject VARCHAR(50),
+        name VARCHAR(100) NOT NULL,
+        name_kk VARCHAR(100),
+        description TEXT,
+        description_kk TEXT,
+        order_index INTEGER NOT NULL DEFAULT 0,
+        is_archived BOOLEAN NOT NULL DEFAULT FALSE,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+-- Forum topics are discussions without a course, filed under a category
+ALTER TABLE discussions ADD COLUMN IF NOT EXISTS category_id UUID REFERENCES forum_categories (id);
+
+-- Bans from the platform forum have no course
+ALTER TABLE forum_bans DROP CONSTRAINT IF EXISTS forum_bans_pkey;
+ALTER TABLE forum_bans ALTER COLUMN course_id DROP NOT NULL;
+
+-- Course announcements. Scheduled ones are delivered once publish_at passes.
+CREATE TABLE
+    IF NOT EXISTS announcements (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        course_id UUID NOT NULL REFERENCES courses (id) ON DELETE CASCADE,
+        author_id UUID REFERENCES users (id) ON DELETE SET NULL,
+        title VARCHAR(255) NOT NULL,
+        body TEXT NOT NULL,
+        is_pinned BOOLEAN NOT NULL DEFAULT FALSE,
+        publish_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        expires_at TIMESTAMP,
+        delivered_at TIMESTAMP,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+-- Students who have seen an announcement
+CREATE TABLE
+    IF NOT EXISTS announcement_reads (
+        announcement_id UUID REFERENCES announcements (id) ON DELETE CASCADE,
+        user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        read_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (announcement_id, user_id)
+    );
+
+-- Outgoing e-mail, sent by a background worker with retries
+CREATE TABLE
+    IF NOT EXISTS email_outbox (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+        to_address VARCHAR(255) NOT NULL,
+        template VARCHAR(50) NOT NULL,
+        subject VARCHAR(255) NOT NULL,
+        html_body TEXT NOT NULL,
+        text_body TEXT NOT NULL,
+        status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+        attempts INTEGER NOT NULL DEFAULT 0,
+        last_error TEXT,
+        next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        sent_at TIMESTAMP,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+-- Outgoing webhooks of partner systems. Without a course a webhook receives events of every course.
+CREATE TABLE
+    IF NOT EXISTS webhooks (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        url VARCHAR(500) NOT NULL,
+        secret VARCHAR(100) NOT NULL,
+        event_types TEXT[] NOT NULL,
+        course_id UUID REFERENCES courses (id) ON DELETE CASCADE,
+        is_active BOOLEAN NOT NULL DEFAULT TRUE,
+        created_by UUID REFERENCES users (id) ON DELETE SET NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+-- One event sent, or waiting to be sent, to one webhook
+CREATE TABLE
+    IF NOT EXISTS webhook_deliveries (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        webhook_id UUID NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+        event_id VARCHAR(64) NOT NULL,
+        event_type VARCHAR(50) NOT NULL,
+        payload JSONB NOT NULL,
+        status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+        attempts INTEGER NOT NULL DEFAULT 0,
+        response_code INTEGER,
+        response_body TEXT,
+        last_error TEXT,
+        next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        delivered_at TIMESTAMP,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
 -- Create indexes for performance
 CREATE INDEX IF NOT EXISTS idx_enrollments_user_id ON enrollments (user_id);
 CREATE INDEX IF NOT EXISTS idx_enrollments_course_id ON enrollments (course_id);
@@ -267,3 +833,40 @@ CREATE INDEX IF NOT EXISTS idx_user_sessions_token ON user_sessions (session_tok
 CREATE INDEX IF NOT EXISTS idx_registration_logs_email ON registration_logs (email);
 CREATE INDEX IF NOT EXISTS idx_courses_teacher_id ON courses (teacher_id);
 CREATE INDEX IF NOT EXISTS idx_ai_assistant_user_id ON ai_assistant (user_id);
+CREATE INDEX IF NOT EXISTS idx_questions_bank ON questions (course_id, subject, topic, difficulty) WHERE test_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_test_attempts_number ON test_attempts (test_id, user_id, attempt_number);
+CREATE INDEX IF NOT EXISTS idx_test_question_rules_test_id ON test_question_rules (test_id);
+CREATE INDEX IF NOT EXISTS idx_attempt_questions_attempt_id ON attempt_questions (attempt_id);
+CREATE INDEX IF NOT EXISTS idx_user_answers_attempt_id ON user_answers (attempt_id);
+CREATE INDEX IF NOT EXISTS idx_practice_sessions_user_id ON practice_sessions (user_id);
+CREATE INDEX IF NOT EXISTS idx_practice_responses_session_id ON practice_responses (session_id);
+CREATE INDEX IF NOT EXISTS idx_integrity_events_attempt_id ON integrity_events (attempt_id);
+CREATE INDEX IF NOT EXISTS idx_tests_due_at ON tests (due_at) WHERE due_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_enrollment_risk_course_id ON enrollment_risk (course_id) WHERE flagged;
+CREATE INDEX IF NOT EXISTS idx_xp_events_user_id ON xp_events (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_class_group_members_user_id ON class_group_members (user_id);
+CREATE INDEX IF NOT EXISTS idx_leaderboard_scores_board ON leaderboard_scores (scope, period, period_start);
+CREATE INDEX IF NOT EXISTS idx_flashcard_decks_course_id ON flashcard_decks (course_id);
+CREATE INDEX IF NOT EXISTS idx_flashcards_deck_id ON flashcards (deck_id);
+CREATE INDEX IF NOT EXISTS idx_flashcard_progress_due ON flashcard_progress (user_id, due_at);
+CREATE INDEX IF NOT EXISTS idx_flashcard_reviews_card_id ON flashcard_reviews (card_id, reviewed_at);
+CREATE INDEX IF NOT EXISTS idx_discussion_replies_discussion_id ON discussion_replies (discussion_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_discussion_edits_target ON discussion_edits (target_type, target_id, edited_at);
+CREATE INDEX IF NOT EXISTS idx_discussion_reports_target ON discussion_reports (target_type, target_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_moderation_log_course_id ON moderation_log (course_id, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (LOWER(username));
+CREATE INDEX IF NOT EXISTS idx_discussion_subscriptions_user_id ON discussion_subscriptions (user_id);
+CREATE INDEX IF NOT EXISTS idx_discussion_events_pending ON discussion_events (created_at) WHERE notified_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications (user_id, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_unread_group ON notifications (user_id, group_key) WHERE read_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_realtime_events_topic ON realtime_events (topic, id);
+CREATE INDEX IF NOT EXISTS idx_realtime_events_created_at ON realtime_events (created_at);
+CREATE INDEX IF NOT EXISTS idx_discussions_category_id ON discussions (category_id, created_at) WHERE category_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_forum_bans_scope ON forum_bans ((COALESCE(course_id, '00000000-0000-0000-0000-000000000000')), user_id);
+CREATE INDEX IF NOT EXISTS idx_notifications_created_at ON notifications (created_at);
+CREATE INDEX IF NOT EXISTS idx_email_outbox_pending ON email_outbox (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_user_settings_email_digest ON user_settings (email_digest) WHERE email_digest <> 'off';
+CREATE INDEX IF NOT EXISTS idx_announcements_course_id ON announcements (course_id, publish_at);
+CREATE INDEX IF NOT EXISTS idx_announcements_undelivered ON announcements (publish_at) WHERE delivered_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
@@ -13,4 +13,4 @@ require (
 
 require github.com/golang-jwt/jwt/v5 v5.2.2
 
-require github.com/rs/cors v1.11.1 // indirect
+require github.com/rs/cors v1.11.1
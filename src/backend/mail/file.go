@@ -0,0 +1,34 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type fileTransport struct {
+	dir string
+}
+
+// NewFileTransport returns a transport that saves every message as an .eml file in dir, a
+// mailbox to inspect during development and tests instead of a real server.
+func NewFileTransport(dir string) Transport {
+	return &fileTransport{dir: dir}
+}
+
+func (t *fileTransport) Send(ctx context.Context, msg Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return fmt.Errorf("error creating mailbox: %w", err)
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000"), randomID()[:8])
+	if err := os.WriteFile(filepath.Join(t.dir, name), data, 0o644); err != nil {
+		return fmt.Errorf("error saving message: %w", err)
+	}
+	return nil
+}
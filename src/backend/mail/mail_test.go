@@ -0,0 +1,117 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func sampleMessage() Message {
+	return Message{
+		From:    "Shabyt <no-reply@shabyt.kz>",
+		To:      "student@example.com",
+		Subject: "Тест проверен: 90%",
+		HTML:    "<p>Здравствуйте! Длинная строка, которую quoted-printable должен перенести, потому что она заметно длиннее семидесяти шести символов.</p>",
+		Text:    "Здравствуйте!\nРезультат: 90%",
+	}
+}
+
+func TestMessageBytes(t *testing.T) {
+	m := sampleMessage()
+	data, err := m.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != m.Subject {
+		t.Errorf("subject = %q (%v), want %q", subject, err, m.Subject)
+	}
+	if parsed.Header.Get("From") != m.From || parsed.Header.Get("To") != m.To {
+		t.Errorf("from/to = %q/%q", parsed.Header.Get("From"), parsed.Header.Get("To"))
+	}
+	if id := parsed.Header.Get("Message-ID"); !strings.HasSuffix(id, "@shabyt.kz>") {
+		t.Errorf("message id = %q, want one on the sender's domain", id)
+	}
+	if _, err := parsed.Header.Date(); err != nil {
+		t.Errorf("date: %v", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("content type = %q (%v)", mediaType, err)
+	}
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	var types, bodies []string
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, _ := io.ReadAll(part)
+		for _, line := range strings.Split(string(raw), "\r\n") {
+			if len(line) > 76 {
+				t.Errorf("encoded line of %d characters, want at most 76", len(line))
+			}
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(bytes.NewReader(raw)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, part.Header.Get("Content-Type"))
+		bodies = append(bodies, string(body))
+	}
+	if len(types) != 2 || !strings.HasPrefix(types[0], "text/plain") || !strings.HasPrefix(types[1], "text/html") {
+		t.Fatalf("parts = %v, want plain text then HTML", types)
+	}
+	// Line breaks go out as CRLF, as e-mail requires.
+	if strings.ReplaceAll(bodies[0], "\r\n", "\n") != m.Text || bodies[1] != m.HTML {
+		t.Errorf("bodies changed in encoding:\n%q\n%q", bodies[0], bodies[1])
+	}
+}
+
+func TestMessageBytesRejectsBadAddresses(t *testing.T) {
+	m := sampleMessage()
+	m.To = "not an address"
+	if _, err := m.Bytes(); err == nil {
+		t.Error("expected an error for a bad recipient")
+	}
+	m = sampleMessage()
+	m.From = ""
+	if _, err := m.Bytes(); err == nil {
+		t.Error("expected an error for a missing sender")
+	}
+}
+
+func TestFileTransport(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mailbox")
+	if err := NewFileTransport(dir).Send(context.Background(), sampleMessage()); err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("mailbox holds %v (%v), want one message", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mail.ReadMessage(bytes.NewReader(data)); err != nil {
+		t.Errorf("saved message does not parse: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTPConfig describes the outgoing mail server. Port 465 uses implicit TLS; any other port
+// upgrades with STARTTLS when the server offers it.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+type smtpTransport struct {
+	config SMTPConfig
+}
+
+func NewSMTPTransport(config SMTPConfig) Transport {
+	return &smtpTransport{config: config}
+}
+
+func (t *smtpTransport) Send(ctx context.Context, msg Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	from, err := address(msg.From)
+	if err != nil {
+		return err
+	}
+	to, err := address(msg.To)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(t.config.Host, fmt.Sprint(t.config.Port))
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	var conn net.Conn
+	if t.config.Port == 465 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: t.config.Host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("error connecting to SMTP server: %w", err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(time.Minute)
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, t.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("error starting SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if t.config.Port != 465 {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: t.config.Host}); err != nil {
+				return fmt.Errorf("error starting TLS: %w", err)
+			}
+		}
+	}
+	if t.config.Username != "" {
+		auth := smtp.PlainAuth("", t.config.Username, t.config.Password, t.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("error authenticating with SMTP server: %w", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("error sending MAIL FROM: %w", err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("error sending RCPT TO: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("error sending DATA: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("error writing message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("error finishing message: %w", err)
+	}
+	return client.Quit()
+}
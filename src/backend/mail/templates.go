@@ -0,0 +1,117 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+)
+
+// Each e-mail has an HTML and a text template per language, templates/<name>.<lang>.html and
+// .txt. The HTML one defines "subject", "content" and "footer" for the shared layout; the text
+// one defines "subject" and is the body itself.
+
+//go:embed templates
+var templateFiles embed.FS
+
+// Template names.
+const (
+	TemplateNotification = "notification"
+	TemplateDigest       = "digest"
+)
+
+// DefaultLanguage is used for users without a supported language preference.
+const DefaultLanguage = "ru"
+
+var languages = map[string]bool{"ru": true, "kk": true, "en": true}
+
+// NotificationEmail is the data of the notification template.
+type NotificationEmail struct {
+	FirstName   string
+	Title       string
+	Body        string
+	Link        string
+	SettingsURL string
+}
+
+// DigestEmail is the data of the digest template. More counts the notifications left out of
+// Items.
+type DigestEmail struct {
+	FirstName        string
+	Weekly           bool
+	Count            int
+	More             int
+	Items            []DigestItem
+	NotificationsURL string
+	SettingsURL      string
+}
+
+type DigestItem struct {
+	Title string
+	Body  string
+	Link  string
+}
+
+type templateSet struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+var (
+	templatesMu sync.Mutex
+	templates   = map[string]*templateSet{}
+)
+
+func loadTemplate(name, lang string) (*templateSet, error) {
+	key := name + "." + lang
+	templatesMu.Lock()
+	defer templatesMu.Unlock()
+	if set, ok := templates[key]; ok {
+		return set, nil
+	}
+
+	funcs := htmltemplate.FuncMap{"lang": func() string { return lang }}
+	html, err := htmltemplate.New("layout.html").Funcs(funcs).
+		ParseFS(templateFiles, "templates/layout.html", "templates/"+key+".html")
+	if err != nil {
+		return nil, err
+	}
+	text, err := texttemplate.ParseFS(templateFiles, "templates/"+key+".txt")
+	if err != nil {
+		return nil, err
+	}
+	set := &templateSet{html: html, text: text}
+	templates[key] = set
+	return set, nil
+}
+
+// Render renders an e-mail in the language, falling back to DefaultLanguage. The returned
+// message has no sender or recipient yet.
+func Render(name, lang string, data any) (*Message, error) {
+	if !languages[lang] {
+		lang = DefaultLanguage
+	}
+	set, err := loadTemplate(name, lang)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s template: %w", name, err)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := set.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("error rendering %s subject: %w", name, err)
+	}
+	if err := set.text.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("error rendering %s text: %w", name, err)
+	}
+	if err := set.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return nil, fmt.Errorf("error rendering %s HTML: %w", name, err)
+	}
+	return &Message{
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		HTML:    html.String(),
+		Text:    text.String(),
+	}, nil
+}
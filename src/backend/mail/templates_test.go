@@ -0,0 +1,138 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+)
+
+func notificationSample() NotificationEmail {
+	return NotificationEmail{
+		FirstName:   "Айгерим",
+		Title:       "Алгебра <тест>",
+		Body:        "Результат: 90%",
+		Link:        "https://shabyt.kz/courses/1?tab=tests&x=1",
+		SettingsURL: "https://shabyt.kz/settings/notifications",
+	}
+}
+
+func digestSample(more int, weekly bool) DigestEmail {
+	return DigestEmail{
+		FirstName: "Айгерим",
+		Weekly:    weekly,
+		Count:     2 + more,
+		More:      more,
+		Items: []DigestItem{
+			{Title: "Новый ответ", Body: "Ответ на ваш вопрос", Link: "https://shabyt.kz/d/1"},
+			{Title: "Курс обновлён", Body: "Добавлен урок"},
+		},
+		NotificationsURL: "https://shabyt.kz/notifications",
+		SettingsURL:      "https://shabyt.kz/settings/notifications",
+	}
+}
+
+func TestRenderEveryTemplateAndLanguage(t *testing.T) {
+	for _, name := range []string{TemplateNotification, TemplateDigest} {
+		for lang := range languages {
+			t.Run(name+"."+lang, func(t *testing.T) {
+				var data any = notificationSample()
+				if name == TemplateDigest {
+					data = digestSample(3, true)
+				}
+				msg, err := Render(name, lang, data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if msg.Subject == "" || strings.ContainsAny(msg.Subject, "\r\n") {
+					t.Errorf("subject = %q, want one non-empty line", msg.Subject)
+				}
+				if !strings.Contains(msg.HTML, `<html lang="`+lang+`">`) {
+					t.Errorf("HTML is not marked as %s", lang)
+				}
+				for _, want := range []string{"Айгерим", "https://shabyt.kz/settings/notifications"} {
+					if !strings.Contains(msg.Text, want) || !strings.Contains(msg.HTML, want) {
+						t.Errorf("%q missing from the text or HTML part", want)
+					}
+				}
+				if strings.Contains(msg.HTML, "<no value>") || strings.Contains(msg.Text, "<no value>") {
+					t.Error("a template field is missing from the data")
+				}
+			})
+		}
+	}
+}
+
+func TestRenderNotification(t *testing.T) {
+	msg, err := Render(TemplateNotification, "en", notificationSample())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "Алгебра <тест>" {
+		t.Errorf("subject = %q", msg.Subject)
+	}
+	if !strings.Contains(msg.HTML, "Алгебра &lt;тест&gt;") || strings.Contains(msg.HTML, "<тест>") {
+		t.Error("title is not escaped in the HTML part")
+	}
+	if !strings.Contains(msg.HTML, `href="https://shabyt.kz/courses/1?tab=tests&amp;x=1"`) {
+		t.Error("link is missing from the HTML part")
+	}
+	if !strings.Contains(msg.Text, "Open: https://shabyt.kz/courses/1?tab=tests&x=1") {
+		t.Errorf("text part = %q, want the link unescaped", msg.Text)
+	}
+
+	noLink := notificationSample()
+	noLink.Link = ""
+	msg, err = Render(TemplateNotification, "en", noLink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(msg.Text, "Open:") {
+		t.Error("text part offers a link that isn't there")
+	}
+}
+
+func TestRenderDigest(t *testing.T) {
+	msg, err := Render(TemplateDigest, "en", digestSample(0, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "You have 2 unread notifications" {
+		t.Errorf("subject = %q", msg.Subject)
+	}
+	if !strings.Contains(msg.Text, "today") || strings.Contains(msg.Text, "this week") {
+		t.Error("daily digest is not worded as daily")
+	}
+	if strings.Contains(msg.Text, "more.") {
+		t.Error("digest mentions more notifications than it lists")
+	}
+	if strings.Count(msg.Text, "* ") != 2 {
+		t.Errorf("text part = %q, want both items", msg.Text)
+	}
+
+	msg, err = Render(TemplateDigest, "en", digestSample(5, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(msg.Text, "this week") || !strings.Contains(msg.Text, "And 5 more.") {
+		t.Errorf("text part = %q, want the weekly wording and the left-out count", msg.Text)
+	}
+}
+
+func TestRenderFallsBackToDefaultLanguage(t *testing.T) {
+	fallback, err := Render(TemplateDigest, "de", digestSample(0, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Render(TemplateDigest, DefaultLanguage, digestSample(0, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fallback.Subject != want.Subject || fallback.Text != want.Text {
+		t.Errorf("unsupported language rendered %q, want the %s e-mail %q", fallback.Subject, DefaultLanguage, want.Subject)
+	}
+}
+
+func TestRenderUnknownTemplate(t *testing.T) {
+	if _, err := Render("welcome", "en", nil); err == nil {
+		t.Error("expected an error for a template that doesn't exist")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/nnn20040/shabytdiplomwork/src/backend/api/routes"
 	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/services"
 	"github.com/rs/cors"
 )
 
@@ -21,6 +22,20 @@ func main() {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
+	if err := services.LoadCertificateFonts(); err != nil {
+		log.Printf("Certificate downloads are unavailable: %v", err)
+	}
+
+	services.StartRealtime()
+	services.StartMail()
+	services.StartIRTCalibration()
+	services.StartRiskScan()
+	services.StartDiscussionNotifier()
+	services.StartNotificationPruning()
+	services.StartAnnouncementScheduler()
+	services.StartWebhookDispatcher()
+	services.BackfillUsernames()
+
 	router := mux.NewRouter()
 
 	apiRouter := router.PathPrefix("/api").Subrouter()
@@ -35,6 +50,12 @@ func main() {
 	routes.RegisterCourseRoutes(apiRouter)
 	routes.RegisterAIAssistantRoutes(apiRouter)
 	routes.RegisterUserRoutes(apiRouter)
+	routes.RegisterCertificateRoutes(apiRouter)
+	routes.RegisterLeaderboardRoutes(apiRouter)
+	routes.RegisterModerationRoutes(apiRouter)
+	routes.RegisterForumRoutes(apiRouter)
+	routes.RegisterRealtimeRoutes(apiRouter)
+	routes.RegisterWebhookRoutes(apiRouter)
 
 	handler := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:8080"},
@@ -0,0 +1,91 @@
+package models
+
+import "time"
+
+// CourseAnalytics is the course-wide part of the analytics report. It is cached, the
+// per-student page is not.
+type CourseAnalytics struct {
+	CourseID          string               `json:"course_id"`
+	TotalStudents     int                  `json:"total_students"`
+	CompletedStudents int                  `json:"completed_students"`
+	CompletionRate    float64              `json:"completion_rate"`
+	AverageScore      float64              `json:"average_score"`
+	MedianScore       float64              `json:"median_score"`
+	Enrollments       []EnrollmentPoint    `json:"enrollments"`
+	LessonFunnel      []LessonFunnelStep   `json:"lesson_funnel"`
+	DropOffPoints     []LessonFunnelStep   `json:"drop_off_points"`
+	ScoreDistribution []ScoreBucket        `json:"score_distribution"`
+	Tests             []TestScoreSummary   `json:"tests"`
+	Discussions       DiscussionActivity   `json:"discussions"`
+	GeneratedAt       time.Time            `json:"generated_at"`
+	Students          *StudentProgressPage `json:"students,omitempty"`
+}
+
+// EnrollmentPoint is the number of enrollments in the week starting on Week.
+type EnrollmentPoint struct {
+	Week  string `json:"week"`
+	New   int    `json:"new"`
+	Total int    `json:"total"`
+}
+
+// LessonFunnelStep is how many enrolled students started and completed a lesson. DropOff is
+// the share of students who completed the previous step but not this one.
+type LessonFunnelStep struct {
+	LessonID       int     `json:"lesson_id"`
+	Title          string  `json:"title"`
+	OrderIndex     int     `json:"order_index"`
+	Started        int     `json:"started"`
+	Completed      int     `json:"completed"`
+	CompletionRate float64 `json:"completion_rate"`
+	DropOff        float64 `json:"drop_off"`
+}
+
+// ScoreBucket counts completed attempts scoring in [From, To]; the last bucket includes 100.
+type ScoreBucket struct {
+	From  int `json:"from"`
+	To    int `json:"to"`
+	Count int `json:"count"`
+}
+
+type TestScoreSummary struct {
+	TestID       string  `json:"test_id"`
+	Title        string  `json:"title"`
+	Attempts     int     `json:"attempts"`
+	AverageScore float64 `json:"average_score"`
+	MedianScore  float64 `json:"median_score"`
+}
+
+// TestScore is one completed attempt's score, the input to score statistics.
+type TestScore struct {
+	TestID string
+	Title  string
+	Score  int
+}
+
+type DiscussionActivity struct {
+	Threads       int `json:"threads"`
+	Replies       int `json:"replies"`
+	Participants  int `json:"participants"`
+	ThreadsLast30 int `json:"threads_last_30_days"`
+	RepliesLast30 int `json:"replies_last_30_days"`
+}
+
+type StudentProgress struct {
+	UserID           string     `json:"user_id"`
+	FirstName        string     `json:"first_name"`
+	LastName         string     `json:"last_name"`
+	Email            string     `json:"email"`
+	Progress         int        `json:"progress"`
+	LessonsCompleted int        `json:"lessons_completed"`
+	AttemptsCount    int        `json:"attempts"`
+	AverageScore     *float64   `json:"average_score,omitempty"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+	LastAccessedAt   *time.Time `json:"last_accessed_at,omitempty"`
+}
+
+type StudentProgressPage struct {
+	Items []StudentProgress `json:"items"`
+	Page  int               `json:"page"`
+	Limit int               `json:"limit"`
+	Total int               `json:"total"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Announcement is a teacher's message to the students of a course. It is shown from
+// PublishAt until ExpiresAt, and delivered as a notification once, when it is published.
+// Stats are only shown to the course staff.
+type Announcement struct {
+	ID          string             `json:"id"`
+	CourseID    string             `json:"course_id"`
+	AuthorID    string             `json:"author_id"`
+	AuthorName  string             `json:"author_name"`
+	Title       string             `json:"title"`
+	Body        string             `json:"body"`
+	IsPinned    bool               `json:"is_pinned"`
+	PublishAt   time.Time          `json:"publish_at"`
+	ExpiresAt   *time.Time         `json:"expires_at,omitempty"`
+	DeliveredAt *time.Time         `json:"delivered_at,omitempty"`
+	Read        bool               `json:"read"`
+	Stats       *AnnouncementStats `json:"stats,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// AnnouncementStats counts the enrolled students and how many of them have seen the
+// announcement.
+type AnnouncementStats struct {
+	Recipients int `json:"recipients"`
+	ReadCount  int `json:"read_count"`
+}
+
+// AnnouncementRequest creates or edits an announcement. Without PublishAt it is published
+// right away.
+type AnnouncementRequest struct {
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	IsPinned  bool       `json:"is_pinned"`
+	PublishAt *time.Time `json:"publish_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
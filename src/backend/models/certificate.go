@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+type Certificate struct {
+	ID               string     `json:"id"`
+	Serial           string     `json:"serial"`
+	UserID           string     `json:"user_id"`
+	CourseID         string     `json:"course_id"`
+	StudentName      string     `json:"student_name"`
+	CourseTitle      string     `json:"course_title"`
+	TeacherName      string     `json:"teacher_name"`
+	IssuedAt         time.Time  `json:"issued_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	RevocationReason string     `json:"revocation_reason,omitempty"`
+	VerifyURL        string     `json:"verify_url,omitempty"`
+}
+
+// CertificateVerification is the public answer to a serial lookup.
+type CertificateVerification struct {
+	Serial           string     `json:"serial"`
+	Valid            bool       `json:"valid"`
+	StudentName      string     `json:"student_name"`
+	CourseTitle      string     `json:"course_title"`
+	TeacherName      string     `json:"teacher_name"`
+	IssuedAt         time.Time  `json:"issued_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	RevocationReason string     `json:"revocation_reason,omitempty"`
+}
+
+// CertificateTemplate customizes a course's certificates. Texts are keyed by language (kk, ru,
+// en); empty fields fall back to the built-in wording.
+type CertificateTemplate struct {
+	CourseID    string                      `json:"course_id"`
+	AccentColor string                      `json:"accent_color"`
+	Texts       map[string]CertificateTexts `json:"texts"`
+}
+
+type CertificateTexts struct {
+	Heading    string `json:"heading,omitempty"`
+	Intro      string `json:"intro,omitempty"`
+	Completion string `json:"completion,omitempty"`
+}
+
+type RevokeCertificateRequest struct {
+	Reason string `json:"reason"`
+}
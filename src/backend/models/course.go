@@ -7,6 +7,7 @@ import (
 
 var (
 	ErrNotFound = errors.New("resource not found")
+	ErrConflict = errors.New("resource already exists")
 )
 
 type Course struct {
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// StudentDashboard is the payload of /api/user/progress. Keys stay camelCase for the
+// clients written against the original endpoint.
+type StudentDashboard struct {
+	CoursesEnrolled    int            `json:"coursesEnrolled"`
+	CoursesCompleted   int            `json:"coursesCompleted"`
+	LessonsCompleted   int            `json:"lessonsCompleted"`
+	TestsCompleted     int            `json:"testsCompleted"`
+	AverageScore       int            `json:"averageScore"`
+	WeeklyStudySeconds int            `json:"weeklyStudySeconds"`
+	WeeklyActivity     []DailyStudy   `json:"weeklyActivity"`
+	CurrentStreak      int            `json:"currentStreak"`
+	RecentActivity     []ActivityItem `json:"recentActivity"`
+	UpcomingDeadlines  []Deadline     `json:"upcomingDeadlines"`
+}
+
+// DashboardCounters are the totals of a student's dashboard, read in one query.
+type DashboardCounters struct {
+	CoursesEnrolled  int
+	CoursesCompleted int
+	LessonsCompleted int
+	TestsCompleted   int
+	AverageScore     float64
+}
+
+// DailyStudy is the time a student studied on one day.
+type DailyStudy struct {
+	Date    string `json:"date"`
+	Seconds int    `json:"seconds"`
+}
+
+// ActivityItem is a completed lesson or a finished test attempt.
+type ActivityItem struct {
+	Type        string    `json:"type"` // lesson_completed, test_completed
+	CourseID    string    `json:"courseId"`
+	CourseTitle string    `json:"courseTitle"`
+	Title       string    `json:"title"`
+	Score       *int      `json:"score,omitempty"`
+	At          time.Time `json:"at"`
+}
+
+// Deadline is a test due in one of the student's courses that they have not passed yet.
+type Deadline struct {
+	TestID      string    `json:"testId"`
+	CourseID    string    `json:"courseId"`
+	CourseTitle string    `json:"courseTitle"`
+	Title       string    `json:"title"`
+	DueAt       time.Time `json:"dueAt"`
+}
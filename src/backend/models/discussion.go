@@ -0,0 +1,117 @@
+package models
+
+import "time"
+
+// Discussion list orders. Pinned threads come first in every order.
+const (
+	DiscussionSortNewest     = "newest"
+	DiscussionSortHelpful    = "helpful"
+	DiscussionSortUnanswered = "unanswered"
+)
+
+// Kinds of posts that take helpful votes and keep an edit history.
+const (
+	VoteTargetDiscussion = "discussion"
+	VoteTargetReply      = "reply"
+)
+
+// DeletedPostContent and HiddenPostContent replace the content and author of deleted and
+// moderated replies for everyone but moderators.
+const (
+	DeletedPostContent = "[deleted]"
+	HiddenPostContent  = "[hidden]"
+)
+
+// Discussion is a course discussion or, with no course and a category, a topic of the
+// platform forum.
+type Discussion struct {
+	ID               string     `json:"id"`
+	CourseID         string     `json:"course_id,omitempty"`
+	CategoryID       string     `json:"category_id,omitempty"`
+	UserID           string     `json:"user_id"`
+	AuthorName       string     `json:"author_name"`
+	Title            string     `json:"title"`
+	Content          string     `json:"content"`
+	IsPinned         bool       `json:"is_pinned"`
+	IsClosed         bool       `json:"is_closed"`
+	ViewCount        int        `json:"view_count"`
+	HelpfulCount     int        `json:"helpful_count"`
+	ReplyCount       int        `json:"reply_count"`
+	HasSolution      bool       `json:"has_solution"`
+	ModerationStatus string     `json:"moderation_status"`
+	LastActivityAt   time.Time  `json:"last_activity_at"`
+	EditedAt         *time.Time `json:"edited_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// DiscussionReply is a reply to a discussion or, with ParentID set, to another reply. Depth
+// counts the replies above it. Replies holds the nested replies when shown as a tree.
+type DiscussionReply struct {
+	ID               string            `json:"id"`
+	DiscussionID     string            `json:"discussion_id"`
+	ParentID         *string           `json:"parent_id,omitempty"`
+	Depth            int               `json:"depth"`
+	UserID           string            `json:"user_id"`
+	AuthorName       string            `json:"author_name"`
+	Content          string            `json:"content"`
+	IsSolution       bool              `json:"is_solution"`
+	HelpfulCount     int               `json:"helpful_count"`
+	IsDeleted        bool              `json:"is_deleted"`
+	ModerationStatus string            `json:"moderation_status"`
+	DeletedAt        *time.Time        `json:"deleted_at,omitempty"`
+	EditedAt         *time.Time        `json:"edited_at,omitempty"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+	Replies          []DiscussionReply `json:"replies,omitempty"`
+}
+
+// DiscussionRequest starts or edits a discussion. CategoryID is required for, and only used
+// by, new forum topics.
+type DiscussionRequest struct {
+	Title      string `json:"title"`
+	Content    string `json:"content"`
+	CategoryID string `json:"category_id,omitempty"`
+}
+
+type DiscussionReplyRequest struct {
+	Content  string `json:"content"`
+	ParentID string `json:"parent_id"`
+}
+
+// DiscussionEdit is a previous version of an edited discussion or reply. Title is set for
+// discussions only.
+type DiscussionEdit struct {
+	ID         string    `json:"id"`
+	TargetType string    `json:"target_type"`
+	TargetID   string    `json:"target_id"`
+	Title      *string   `json:"title,omitempty"`
+	Content    string    `json:"content"`
+	EditedBy   string    `json:"edited_by"`
+	EditorName string    `json:"editor_name"`
+	EditedAt   time.Time `json:"edited_at"`
+}
+
+// DiscussionThread is a discussion with its replies as a tree, oldest first on each level.
+// HelpfulVotes lists the IDs of the discussion and replies the viewer found helpful;
+// Subscribed tells whether the viewer follows the discussion.
+type DiscussionThread struct {
+	Discussion
+	Replies      []DiscussionReply `json:"replies"`
+	HelpfulVotes []string          `json:"helpful_votes"`
+	Subscribed   bool              `json:"subscribed"`
+}
+
+type HelpfulVote struct {
+	TargetType   string `json:"target_type"`
+	TargetID     string `json:"target_id"`
+	Voted        bool   `json:"voted"`
+	HelpfulCount int    `json:"helpful_count"`
+}
+
+type DiscussionPage struct {
+	Items []Discussion `json:"items"`
+	Page  int          `json:"page"`
+	Limit int          `json:"limit"`
+	Total int          `json:"total"`
+}
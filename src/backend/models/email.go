@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Outbox e-mail statuses. Failed e-mails ran out of attempts.
+const (
+	EmailPending = "pending"
+	EmailSent    = "sent"
+	EmailFailed  = "failed"
+)
+
+// OutboxEmail is a rendered e-mail waiting in the outbox, or already sent.
+type OutboxEmail struct {
+	ID            string     `json:"id"`
+	UserID        *string    `json:"user_id,omitempty"`
+	ToAddress     string     `json:"to_address"`
+	Template      string     `json:"template"`
+	Subject       string     `json:"subject"`
+	HTMLBody      string     `json:"-"`
+	TextBody      string     `json:"-"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// DigestRecipient is a user due for an e-mail digest. Since is when the previous one was
+// sent, nil before the first.
+type DigestRecipient struct {
+	UserID    string
+	Frequency string
+	Since     *time.Time
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+type Enrollment struct {
+	ID             string     `json:"id"`
+	UserID         string     `json:"user_id"`
+	CourseID       string     `json:"course_id"`
+	EnrolledAt     time.Time  `json:"enrolled_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	Progress       int        `json:"progress"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+
+	CourseTitle    string `json:"title,omitempty"`
+	CourseCategory string `json:"category,omitempty"`
+	CourseImage    string `json:"image,omitempty"`
+}
+
+// EnrolledStudent is a row of the teacher's student list.
+type EnrolledStudent struct {
+	UserID         string     `json:"user_id"`
+	FirstName      string     `json:"first_name"`
+	LastName       string     `json:"last_name"`
+	Email          string     `json:"email"`
+	EnrolledAt     time.Time  `json:"enrolled_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	Progress       int        `json:"progress"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+}
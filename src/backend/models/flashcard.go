@@ -0,0 +1,103 @@
+package models
+
+import "time"
+
+// Flashcard sources.
+const (
+	FlashcardSourceManual   = "manual"
+	FlashcardSourceQuestion = "question"
+	FlashcardSourceLesson   = "lesson"
+)
+
+type FlashcardDeck struct {
+	ID          string    `json:"id"`
+	CourseID    string    `json:"course_id"`
+	Title       string    `json:"title"`
+	TitleKK     string    `json:"title_kk"`
+	Description string    `json:"description"`
+	CardCount   int       `json:"card_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type FlashcardDeckRequest struct {
+	Title       string `json:"title"`
+	TitleKK     string `json:"title_kk"`
+	Description string `json:"description"`
+}
+
+type Flashcard struct {
+	ID         string    `json:"id"`
+	DeckID     string    `json:"deck_id"`
+	Front      string    `json:"front"`
+	FrontKK    string    `json:"front_kk"`
+	Back       string    `json:"back"`
+	BackKK     string    `json:"back_kk"`
+	SourceType string    `json:"source_type"`
+	SourceID   *string   `json:"source_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type FlashcardRequest struct {
+	Front   string `json:"front"`
+	FrontKK string `json:"front_kk"`
+	Back    string `json:"back"`
+	BackKK  string `json:"back_kk"`
+}
+
+// GenerateFlashcardsRequest derives cards from course questions and lessons. TestID adds all
+// questions of a test.
+type GenerateFlashcardsRequest struct {
+	QuestionIDs []string `json:"question_ids"`
+	TestID      string   `json:"test_id"`
+	LessonIDs   []int    `json:"lesson_ids"`
+}
+
+// FlashcardSchedule is the SM-2 state of a card for one student.
+type FlashcardSchedule struct {
+	EaseFactor     float64    `json:"ease_factor"`
+	IntervalDays   int        `json:"interval_days"`
+	Repetitions    int        `json:"repetitions"`
+	Lapses         int        `json:"lapses"`
+	DueAt          time.Time  `json:"due_at"`
+	LastReviewedAt *time.Time `json:"last_reviewed_at,omitempty"`
+}
+
+// StudyCard is a card as shown to a student: one side per face in the student's language.
+// Schedule is nil for cards the student has never reviewed.
+type StudyCard struct {
+	ID        string             `json:"id"`
+	DeckID    string             `json:"deck_id"`
+	DeckTitle string             `json:"deck_title"`
+	Front     string             `json:"front"`
+	Back      string             `json:"back"`
+	Schedule  *FlashcardSchedule `json:"schedule"`
+}
+
+type FlashcardReviewRequest struct {
+	Grade int `json:"grade"`
+}
+
+// DeckStats describe a deck's cards and recall. Retention is the percentage of reviews of
+// already learned cards in the last RetentionDays days that were recalled, nil without any.
+type DeckStats struct {
+	DeckID        string   `json:"deck_id"`
+	Cards         int      `json:"cards"`
+	New           int      `json:"new"`
+	Learning      int      `json:"learning"`
+	Mature        int      `json:"mature"`
+	DueToday      int      `json:"due_today"`
+	Reviews       int      `json:"reviews"`
+	RetentionDays int      `json:"retention_days"`
+	Retention     *float64 `json:"retention"`
+}
+
+// DueFlashcard is a card to study with its deck title and the student's schedule, nil for a
+// new card.
+type DueFlashcard struct {
+	Flashcard
+	DeckTitle   string
+	DeckTitleKK string
+	Schedule    *FlashcardSchedule
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// ForumCategory is a section of the platform forum, usually one ENT subject, with its name
+// and description in Russian and Kazakh.
+type ForumCategory struct {
+	ID            string     `json:"id"`
+	Slug          string     `json:"slug"`
+	Subject       string     `json:"subject,omitempty"`
+	Name          string     `json:"name"`
+	NameKK        string     `json:"name_kk"`
+	Description   string     `json:"description"`
+	DescriptionKK string     `json:"description_kk"`
+	OrderIndex    int        `json:"order_index"`
+	IsArchived    bool       `json:"is_archived"`
+	TopicCount    int        `json:"topic_count"`
+	LastTopicAt   *time.Time `json:"last_topic_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type ForumCategoryRequest struct {
+	Slug          string `json:"slug"`
+	Subject       string `json:"subject"`
+	Name          string `json:"name"`
+	NameKK        string `json:"name_kk"`
+	Description   string `json:"description"`
+	DescriptionKK string `json:"description_kk"`
+	OrderIndex    int    `json:"order_index"`
+	IsArchived    bool   `json:"is_archived"`
+}
+
+// ForumCategoryView is a category as listed to users: name and description in their
+// language.
+type ForumCategoryView struct {
+	ID          string     `json:"id"`
+	Slug        string     `json:"slug"`
+	Subject     string     `json:"subject,omitempty"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	TopicCount  int        `json:"topic_count"`
+	LastTopicAt *time.Time `json:"last_topic_at,omitempty"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// XP event kinds.
+const (
+	XPLessonCompleted  = "lesson_completed"
+	XPTestPassed       = "test_passed"
+	XPSolutionAccepted = "solution_accepted"
+	XPStreakDay        = "streak_day"
+)
+
+// XPEvent is one XP award. SourceID identifies what earned it (a lesson, a test, a reply or a
+// date); a user is awarded each kind and source once.
+type XPEvent struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Kind      string    `json:"kind"`
+	SourceID  string    `json:"source_id"`
+	CourseID  *string   `json:"course_id,omitempty"`
+	XP        int       `json:"xp"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BadgeRule is the condition of a badge: Metric must reach Count. For tests_passed, MinScore
+// is the score each counted test needs; 0 means its passing score.
+type BadgeRule struct {
+	Metric   string `json:"metric"`
+	Count    int    `json:"count"`
+	MinScore int    `json:"min_score,omitempty"`
+}
+
+type Badge struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Rule        BadgeRule `json:"rule"`
+}
+
+// UserBadge is a catalog badge with whether the user earned it and their progress towards it.
+type UserBadge struct {
+	Badge
+	Earned    bool       `json:"earned"`
+	AwardedAt *time.Time `json:"awarded_at,omitempty"`
+	Progress  int        `json:"progress"`
+}
+
+// GamificationStats are what badge rules are evaluated against. TestScores holds the best
+// score of every passed test.
+type GamificationStats struct {
+	XP                int
+	LessonsCompleted  int
+	TestScores        []int
+	SolutionsAccepted int
+	CurrentStreak     int
+}
+
+// GamificationProgress is a user's XP, level and streak.
+type GamificationProgress struct {
+	XP             int       `json:"xp"`
+	Level          int       `json:"level"`
+	LevelXP        int       `json:"level_xp"`
+	NextLevelXP    *int      `json:"next_level_xp"`
+	CurrentStreak  int       `json:"current_streak"`
+	BadgesEarned   int       `json:"badges_earned"`
+	RecentXPEvents []XPEvent `json:"recent_xp_events"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+type IntegrityEvent struct {
+	ID         string    `json:"id"`
+	AttemptID  string    `json:"attempt_id"`
+	EventType  string    `json:"event_type"`
+	Details    string    `json:"details,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// IntegrityEventRequest is one event reported by the browser. OccurredAt is the client
+// timestamp; when missing or implausible the server time is used.
+type IntegrityEventRequest struct {
+	Type       string     `json:"type"`
+	OccurredAt *time.Time `json:"occurred_at"`
+	Details    string     `json:"details"`
+}
+
+type IntegrityEventsRequest struct {
+	Events []IntegrityEventRequest `json:"events"`
+}
+
+// IntegritySignal explains one contribution to an attempt's suspicion score.
+type IntegritySignal struct {
+	Kind    string `json:"kind"`
+	Count   int    `json:"count"`
+	Points  int    `json:"points"`
+	Message string `json:"message"`
+}
+
+// IntegrityReport is the teacher's view of an attempt: the score, what it is made of and the
+// events in the order they happened.
+type IntegrityReport struct {
+	Attempt  *TestAttempt      `json:"attempt"`
+	Signals  []IntegritySignal `json:"signals"`
+	Timeline []IntegrityEvent  `json:"timeline"`
+}
+
+type IntegrityPolicyRequest struct {
+	Policy    string `json:"policy"`
+	Threshold int    `json:"threshold"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// ItemResponse is one question as seen in one completed attempt. Answered is false when
+// the question was drawn but left blank.
+type ItemResponse struct {
+	AttemptID        string
+	QuestionID       string
+	Points           int
+	Answered         bool
+	Answer           string
+	IsCorrect        *bool
+	PointsEarned     int
+	TimeSpentSeconds int
+}
+
+// AttemptSummary is a completed attempt reduced to what item analysis needs.
+type AttemptSummary struct {
+	ID               string
+	Score            int
+	TimeSpentSeconds int
+	QuestionCount    int
+}
+
+type ItemAnalysis struct {
+	TestID        string           `json:"test_id"`
+	Attempts      int              `json:"attempts"`
+	CronbachAlpha *float64         `json:"cronbach_alpha"`
+	Items         []ItemStatistics `json:"items"`
+	ComputedAt    time.Time        `json:"computed_at"`
+}
+
+type ItemStatistics struct {
+	QuestionID   string `json:"question_id"`
+	QuestionText string `json:"question_text"`
+	QuestionType string `json:"question_type"`
+	Responses    int    `json:"responses"`
+	Unanswered   int    `json:"unanswered"`
+	// DifficultyIndex is the percentage of graded responses that were correct.
+	DifficultyIndex *float64 `json:"difficulty_index"`
+	// DiscriminationIndex is the difference in proportion correct between the top and
+	// bottom 27% of attempts by total score, from -1 to 1.
+	DiscriminationIndex *float64               `json:"discrimination_index"`
+	AvgTimeSeconds      float64                `json:"avg_time_seconds"`
+	Distractors         []DistractorStatistics `json:"distractors,omitempty"`
+	Flags               []string               `json:"flags"`
+}
+
+type DistractorStatistics struct {
+	OptionID   string  `json:"option_id"`
+	OptionText string  `json:"option_text"`
+	IsCorrect  bool    `json:"is_correct"`
+	Count      int     `json:"count"`
+	Frequency  float64 `json:"frequency"`
+}
@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// Leaderboard scopes, metrics and periods.
+const (
+	LeaderboardScopePlatform = "platform"
+	LeaderboardScopeCourse   = "course"
+	LeaderboardScopeClass    = "class"
+	LeaderboardScopeRegion   = "region"
+
+	LeaderboardMetricXP    = "xp"
+	LeaderboardMetricScore = "score"
+
+	LeaderboardPeriodWeek  = "week"
+	LeaderboardPeriodMonth = "month"
+	LeaderboardPeriodAll   = "all"
+)
+
+// LeaderboardQuery selects a board. ScopeID is the course or class group ID, or the region;
+// it is empty for the platform board.
+type LeaderboardQuery struct {
+	Scope       string
+	ScopeID     string
+	Metric      string
+	Period      string
+	PeriodStart time.Time
+	Limit       int
+	UserID      string
+}
+
+// LeaderboardEntry is one ranked user. Value is XP, or the average test score.
+type LeaderboardEntry struct {
+	Rank      int     `json:"rank"`
+	UserID    string  `json:"user_id"`
+	FirstName string  `json:"first_name"`
+	LastName  string  `json:"last_name"`
+	Value     float64 `json:"value"`
+}
+
+// Leaderboard is the top of a board. Me is the requesting user's own entry wherever they
+// rank, nil when they have not scored in the period or opted out.
+type Leaderboard struct {
+	Scope        string             `json:"scope"`
+	ScopeID      string             `json:"scope_id,omitempty"`
+	Metric       string             `json:"metric"`
+	Period       string             `json:"period"`
+	PeriodStart  string             `json:"period_start,omitempty"`
+	Participants int                `json:"participants"`
+	Entries      []LeaderboardEntry `json:"entries"`
+	Me           *LeaderboardEntry  `json:"me"`
+	OptedOut     bool               `json:"opted_out"`
+}
+
+// LeaderboardKey is one aggregate row a change is added to.
+type LeaderboardKey struct {
+	Scope       string
+	Period      string
+	PeriodStart time.Time
+}
+
+type UserSettings struct {
+	LeaderboardOptOut bool    `json:"leaderboard_opt_out"`
+	Region            *string `json:"region"`
+}
+
+type ClassGroup struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	TeacherID   string    `json:"teacher_id"`
+	JoinCode    string    `json:"join_code,omitempty"`
+	MemberCount int       `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ClassGroupRequest struct {
+	Name string `json:"name"`
+}
+
+type JoinClassGroupRequest struct {
+	Code string `json:"code"`
+}
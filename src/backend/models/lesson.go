@@ -15,6 +15,7 @@ type LessonRequest struct {
 	Content     string `json:"content"`
 	VideoURL    string `json:"video_url"`
 	OrderIndex  int    `json:"order_index"`
+	IsPublic    bool   `json:"is_public"`
 }
 
 type Lesson struct {
@@ -25,6 +26,45 @@ type Lesson struct {
 	Content     string    `json:"content"`
 	VideoURL    string    `json:"video_url"`
 	OrderIndex  int       `json:"order_index"`
+	IsPublic    bool      `json:"is_public"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	Progress *LessonProgress `json:"progress,omitempty"`
+}
+
+type LessonProgress struct {
+	LessonID            int        `json:"lesson_id"`
+	Status              string     `json:"status"`
+	TimeSpentSeconds    int        `json:"time_spent_seconds"`
+	LastPositionSeconds int        `json:"last_position_seconds"`
+	CompletionDate      *time.Time `json:"completion_date,omitempty"`
+}
+
+// LessonHeartbeatRequest is sent periodically by the lesson player. SecondsSpent is the time
+// watched since the previous heartbeat.
+type LessonHeartbeatRequest struct {
+	PositionSeconds int `json:"position_seconds"`
+	SecondsSpent    int `json:"seconds_spent"`
+}
+
+// CompletionCounts are the inputs of a course completion rule for one student.
+type CompletionCounts struct {
+	Rule             string
+	Threshold        int
+	Lessons          int
+	LessonsCompleted int
+	Tests            int
+	TestsPassed      int
+}
+
+// CourseCompletion is a student whose recomputed progress completed the course.
+type CourseCompletion struct {
+	UserID   string
+	Progress int
+}
+
+type CompletionRuleRequest struct {
+	Rule      string `json:"rule"`
+	Threshold int    `json:"threshold"`
 }
@@ -0,0 +1,124 @@
+package models
+
+import "time"
+
+// Moderation states of discussions and replies. Held posts wait for a moderator after the
+// automatic filter or user reports flagged them; only their author and moderators see them.
+const (
+	PostVisible = "visible"
+	PostHeld    = "held"
+	PostHidden  = "hidden"
+)
+
+// Moderation actions, as requested by moderators and recorded in the moderation log.
+// ModerationAutoHold is recorded when the filter or reports hold a post.
+const (
+	ModerationHide     = "hide"
+	ModerationRestore  = "restore"
+	ModerationLock     = "lock"
+	ModerationUnlock   = "unlock"
+	ModerationPin      = "pin"
+	ModerationUnpin    = "unpin"
+	ModerationBan      = "ban"
+	ModerationUnban    = "unban"
+	ModerationDismiss  = "dismiss"
+	ModerationAutoHold = "auto_hold"
+)
+
+// Report states.
+const (
+	ReportPending   = "pending"
+	ReportActioned  = "actioned"
+	ReportDismissed = "dismissed"
+)
+
+// ModerationTargetUser is the target type of bans in the moderation log.
+const ModerationTargetUser = "user"
+
+type ReportRequest struct {
+	Reason  string `json:"reason"`
+	Details string `json:"details"`
+}
+
+// ModerationActionRequest asks for one moderation action. TargetType and TargetID name the
+// post for hide, restore and dismiss, the discussion for lock, unlock, pin and unpin; UserID
+// names the user to ban or unban. A ban lasts DurationDays, or until lifted when zero.
+type ModerationActionRequest struct {
+	Action       string `json:"action"`
+	TargetType   string `json:"target_type"`
+	TargetID     string `json:"target_id"`
+	UserID       string `json:"user_id"`
+	Reason       string `json:"reason"`
+	DurationDays int    `json:"duration_days"`
+}
+
+// ModerationQueueItem is a post waiting for a moderator: held, or reported by users.
+type ModerationQueueItem struct {
+	TargetType       string     `json:"target_type"`
+	TargetID         string     `json:"target_id"`
+	CourseID         string     `json:"course_id"`
+	DiscussionID     string     `json:"discussion_id"`
+	DiscussionTitle  string     `json:"discussion_title"`
+	AuthorID         string     `json:"author_id"`
+	AuthorName       string     `json:"author_name"`
+	Content          string     `json:"content"`
+	ModerationStatus string     `json:"moderation_status"`
+	ModerationReason string     `json:"moderation_reason,omitempty"`
+	ReportCount      int        `json:"report_count"`
+	ReportReasons    []string   `json:"report_reasons"`
+	LastReportedAt   *time.Time `json:"last_reported_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+type ModerationQueuePage struct {
+	Items []ModerationQueueItem `json:"items"`
+	Page  int                   `json:"page"`
+	Limit int                   `json:"limit"`
+	Total int                   `json:"total"`
+}
+
+// ModerationLogEntry records one moderation action. ModeratorID is nil for actions taken by
+// the automatic filter.
+type ModerationLogEntry struct {
+	ID            string    `json:"id"`
+	CourseID      string    `json:"course_id"`
+	ModeratorID   *string   `json:"moderator_id"`
+	ModeratorName string    `json:"moderator_name"`
+	Action        string    `json:"action"`
+	TargetType    string    `json:"target_type"`
+	TargetID      *string   `json:"target_id,omitempty"`
+	TargetUserID  *string   `json:"target_user_id,omitempty"`
+	Reason        string    `json:"reason"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type ModerationLogPage struct {
+	Items []ModerationLogEntry `json:"items"`
+	Page  int                  `json:"page"`
+	Limit int                  `json:"limit"`
+	Total int                  `json:"total"`
+}
+
+type ForumBan struct {
+	CourseID  string     `json:"course_id"`
+	UserID    string     `json:"user_id"`
+	UserName  string     `json:"user_name"`
+	BannedBy  string     `json:"banned_by"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ModerationWord is a word of the automatic filter. It matches words that start with it, so
+// a stem catches its inflected forms.
+type ModerationWord struct {
+	ID        string    `json:"id"`
+	Language  string    `json:"language"`
+	Word      string    `json:"word"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ModerationWordRequest struct {
+	Language string `json:"language"`
+	Word     string `json:"word"`
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Notification types. Users can turn each of them off per channel.
+const (
+	NotificationGradedTest         = "graded_test"
+	NotificationDiscussionActivity = "discussion_activity"
+	NotificationEnrollment         = "enrollment"
+	NotificationCourseUpdate       = "course_update"
+	NotificationAnnouncement       = "announcement"
+	NotificationAtRiskStudents     = "at_risk_students"
+)
+
+var NotificationTypes = []string{
+	NotificationGradedTest, NotificationDiscussionActivity, NotificationEnrollment,
+	NotificationCourseUpdate, NotificationAnnouncement, NotificationAtRiskStudents,
+}
+
+// Notification channels.
+const (
+	ChannelInApp = "in_app"
+	ChannelEmail = "email"
+)
+
+// E-mail digest frequencies.
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// Kinds of discussion events.
+const (
+	DiscussionEventReply   = "reply"
+	DiscussionEventMention = "mention"
+)
+
+// Notification is an in-app notification. Data carries the type-specific details the client
+// needs to render and link it.
+type Notification struct {
+	ID        string          `json:"id"`
+	UserID    string          `json:"user_id"`
+	Type      string          `json:"type"`
+	Title     string          `json:"title"`
+	Body      string          `json:"body"`
+	Link      string          `json:"link,omitempty"`
+	Data      json.RawMessage `json:"data"`
+	GroupKey  string          `json:"-"`
+	ReadAt    *time.Time      `json:"read_at,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// DiscussionEvent is a reply to a followed discussion or a mention, waiting to be batched
+// into a notification for UserID.
+type DiscussionEvent struct {
+	UserID       string
+	DiscussionID string
+	ReplyID      *string
+	ActorID      string
+	Kind         string
+}
+
+// DiscussionDigest sums up a user's pending events in one discussion. It is the data of
+// discussion_activity notifications.
+type DiscussionDigest struct {
+	DiscussionID string   `json:"discussion_id"`
+	CourseID     string   `json:"course_id"`
+	Title        string   `json:"title"`
+	Replies      int      `json:"replies"`
+	Mentions     int      `json:"mentions"`
+	Actors       []string `json:"actors"`
+	UserID       string   `json:"-"`
+}
+
+type NotificationPage struct {
+	Items  []Notification `json:"items"`
+	Page   int            `json:"page"`
+	Limit  int            `json:"limit"`
+	Total  int            `json:"total"`
+	Unread int            `json:"unread"`
+}
+
+// ChannelPreferences tells on which channels a user gets one type of notification.
+type ChannelPreferences struct {
+	InApp bool `json:"in_app"`
+	Email bool `json:"email"`
+}
+
+// NotificationPreferences are a user's opt-ins per notification type and channel.
+// EmailNotifications is the overall e-mail switch; with it off no type is e-mailed and no
+// digest is sent. EmailDigest is the frequency of the e-mail summary of unread notifications.
+type NotificationPreferences struct {
+	EmailNotifications bool                          `json:"email_notifications"`
+	EmailDigest        string                        `json:"email_digest"`
+	Types              map[string]ChannelPreferences `json:"types"`
+}
+
+type UsernameRequest struct {
+	Username string `json:"username"`
+}
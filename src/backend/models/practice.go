@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// ItemParams are the IRT parameters of a question: discrimination (a) and difficulty (b)
+// on the ability scale. Under the 1PL model a is always 1.
+type ItemParams struct {
+	QuestionID     string    `json:"question_id"`
+	Model          string    `json:"model"`
+	Discrimination float64   `json:"discrimination"`
+	Difficulty     float64   `json:"difficulty"`
+	Responses      int       `json:"responses"`
+	CalibratedAt   time.Time `json:"calibrated_at"`
+}
+
+// CalibrationResponse is one graded answer used to calibrate item parameters.
+type CalibrationResponse struct {
+	UserID     string
+	QuestionID string
+	IsCorrect  bool
+}
+
+type PracticeSession struct {
+	ID                string     `json:"id"`
+	UserID            string     `json:"user_id"`
+	CourseID          string     `json:"course_id"`
+	Subject           string     `json:"subject"`
+	Topic             string     `json:"topic"`
+	Status            string     `json:"status"`
+	Theta             float64    `json:"theta"`
+	StandardError     float64    `json:"standard_error"`
+	QuestionCount     int        `json:"question_count"`
+	CurrentQuestionID *string    `json:"-"`
+	CurrentQuestion   *Question  `json:"current_question,omitempty"`
+	Ability           *Ability   `json:"ability,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+}
+
+type PracticeResponse struct {
+	SessionID          string    `json:"session_id"`
+	QuestionID         string    `json:"question_id"`
+	Answer             string    `json:"answer"`
+	IsCorrect          bool      `json:"is_correct"`
+	ThetaAfter         float64   `json:"theta_after"`
+	StandardErrorAfter float64   `json:"standard_error_after"`
+	AnsweredAt         time.Time `json:"answered_at"`
+}
+
+type PracticeSessionRequest struct {
+	Subject string `json:"subject"`
+	Topic   string `json:"topic"`
+}
+
+type PracticeAnswerRequest struct {
+	QuestionID string `json:"question_id"`
+	Answer     string `json:"answer"`
+}
+
+// PracticeAnswerResult is the feedback for one answer together with the updated session,
+// which carries the next question or, once finished, the ability estimate.
+type PracticeAnswerResult struct {
+	IsCorrect     bool             `json:"is_correct"`
+	CorrectAnswer string           `json:"correct_answer,omitempty"`
+	Explanation   string           `json:"explanation,omitempty"`
+	ExplanationKK string           `json:"explanation_kk,omitempty"`
+	Session       *PracticeSession `json:"session"`
+}
+
+// Ability is a student's latest ability estimate for a subject topic together with the
+// ENT score it corresponds to.
+type Ability struct {
+	CourseID      string    `json:"course_id"`
+	Subject       string    `json:"subject"`
+	Topic         string    `json:"topic"`
+	Theta         float64   `json:"theta"`
+	StandardError float64   `json:"standard_error"`
+	EntScore      int       `json:"ent_score"`
+	EntMaxScore   int       `json:"ent_max_score"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
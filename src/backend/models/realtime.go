@@ -0,0 +1,32 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Types of real-time events.
+const (
+	RealtimeDiscussionReply = "discussion_reply"
+	RealtimeNotification    = "notification"
+	RealtimeGradingResult   = "grading_result"
+)
+
+// RealtimeEvent is an event pushed to the live connections subscribed to its topic. IDs grow
+// with every event, so a reconnecting client can ask for the ones after the last it saw.
+type RealtimeEvent struct {
+	ID        int64           `json:"id"`
+	Topic     string          `json:"topic"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// GradingResult is the data of grading_result events, sent when a test attempt is scored.
+type GradingResult struct {
+	AttemptID string `json:"attempt_id"`
+	TestID    string `json:"test_id"`
+	CourseID  string `json:"course_id,omitempty"`
+	Score     *int   `json:"score,omitempty"`
+	Status    string `json:"status"`
+}
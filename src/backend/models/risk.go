@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// RiskSettings are a course's early warning thresholds. ExpectedWeeks is the length of the
+// course schedule that pace and missed lessons are measured against; 0 disables both checks.
+type RiskSettings struct {
+	InactiveDays  int `json:"inactive_days"`
+	MissedLessons int `json:"missed_lessons"`
+	ScoreDrop     int `json:"score_drop"`
+	PaceGap       int `json:"pace_gap"`
+	Threshold     int `json:"threshold"`
+	ExpectedWeeks int `json:"expected_weeks"`
+}
+
+// RiskInput is what an enrollment is scored on. Scores are the student's completed attempt
+// scores in the course, oldest first.
+type RiskInput struct {
+	UserID           string
+	CourseID         string
+	EnrolledAt       time.Time
+	LastAccessedAt   *time.Time
+	CompletedAt      *time.Time
+	Progress         int
+	Lessons          int
+	LessonsCompleted int
+	Scores           []int
+}
+
+// RiskReason explains one contribution to an enrollment's risk score.
+type RiskReason struct {
+	Kind    string `json:"kind"`
+	Points  int    `json:"points"`
+	Message string `json:"message"`
+}
+
+type EnrollmentRisk struct {
+	UserID     string       `json:"user_id"`
+	CourseID   string       `json:"course_id"`
+	FirstName  string       `json:"first_name,omitempty"`
+	LastName   string       `json:"last_name,omitempty"`
+	Email      string       `json:"email,omitempty"`
+	Score      int          `json:"score"`
+	Flagged    bool         `json:"flagged"`
+	Reasons    []RiskReason `json:"reasons"`
+	ComputedAt time.Time    `json:"computed_at"`
+}
+
+// RiskDigest is the weekly summary of a teacher's at-risk students, grouped by course.
+// DeliveryError is the reason the last delivery failed while DeliveredAt is still unset.
+type RiskDigest struct {
+	ID            string             `json:"id"`
+	TeacherID     string             `json:"teacher_id"`
+	WeekStart     string             `json:"week_start"`
+	Courses       []RiskDigestCourse `json:"courses"`
+	DeliveredAt   *time.Time         `json:"delivered_at"`
+	DeliveryError string             `json:"delivery_error,omitempty"`
+	CreatedAt     time.Time          `json:"created_at"`
+}
+
+type RiskDigestCourse struct {
+	CourseID    string           `json:"course_id"`
+	CourseTitle string           `json:"course_title"`
+	Students    []EnrollmentRisk `json:"students"`
+}
+
+// FlaggedEnrollment is a flagged student with the course and teacher the digest is built for.
+type FlaggedEnrollment struct {
+	TeacherID   string
+	CourseTitle string
+	Risk        EnrollmentRisk
+}
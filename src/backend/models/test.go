@@ -0,0 +1,173 @@
+package models
+
+import "time"
+
+type Test struct {
+	ID                 string     `json:"id"`
+	CourseID           string     `json:"course_id"`
+	Title              string     `json:"title"`
+	TitleKK            string     `json:"title_kk"`
+	Description        string     `json:"description"`
+	DescriptionKK      string     `json:"description_kk"`
+	TimeLimitMinutes   int        `json:"time_limit_minutes"`
+	PassingScore       int        `json:"passing_score"`
+	IsPublished        bool       `json:"is_published"`
+	IsPublic           bool       `json:"is_public"`
+	OrderIndex         int        `json:"order_index"`
+	IntegrityPolicy    string     `json:"integrity_policy"`
+	IntegrityThreshold int        `json:"integrity_threshold"`
+	DueAt              *time.Time `json:"due_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+type Question struct {
+	ID             string           `json:"id"`
+	TestID         *string          `json:"test_id,omitempty"`
+	CourseID       *string          `json:"course_id,omitempty"`
+	QuestionText   string           `json:"question_text"`
+	QuestionTextKK string           `json:"question_text_kk"`
+	QuestionType   string           `json:"question_type"`
+	CorrectAnswer  string           `json:"correct_answer,omitempty"`
+	Explanation    string           `json:"explanation,omitempty"`
+	ExplanationKK  string           `json:"explanation_kk,omitempty"`
+	Points         int              `json:"points"`
+	Difficulty     string           `json:"difficulty"`
+	Subject        string           `json:"subject"`
+	Topic          string           `json:"topic"`
+	IsShared       bool             `json:"is_shared"`
+	Options        []QuestionOption `json:"options"`
+	ArchivedAt     *time.Time       `json:"archived_at,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+}
+
+type QuestionOption struct {
+	ID           string `json:"id"`
+	QuestionID   string `json:"question_id"`
+	OptionText   string `json:"option_text"`
+	OptionTextKK string `json:"option_text_kk"`
+	IsCorrect    bool   `json:"is_correct"`
+	OrderIndex   int    `json:"order_index"`
+}
+
+type QuestionOptionRequest struct {
+	OptionText   string `json:"option_text"`
+	OptionTextKK string `json:"option_text_kk"`
+	IsCorrect    bool   `json:"is_correct"`
+}
+
+type QuestionRequest struct {
+	QuestionText   string                  `json:"question_text"`
+	QuestionTextKK string                  `json:"question_text_kk"`
+	QuestionType   string                  `json:"question_type"`
+	CorrectAnswer  string                  `json:"correct_answer"`
+	Explanation    string                  `json:"explanation"`
+	ExplanationKK  string                  `json:"explanation_kk"`
+	Points         int                     `json:"points"`
+	Difficulty     string                  `json:"difficulty"`
+	Subject        string                  `json:"subject"`
+	Topic          string                  `json:"topic"`
+	IsShared       bool                    `json:"is_shared"`
+	Options        []QuestionOptionRequest `json:"options"`
+}
+
+// QuestionBankFilter narrows bank listings and rule draws. Empty fields match anything.
+type QuestionBankFilter struct {
+	Subject    string
+	Topic      string
+	Difficulty string
+}
+
+// QuestionRule describes a randomized slice of a test, e.g. "5 random medium algebra questions".
+type QuestionRule struct {
+	ID            string `json:"id"`
+	TestID        string `json:"test_id"`
+	Subject       string `json:"subject"`
+	Topic         string `json:"topic"`
+	Difficulty    string `json:"difficulty"`
+	QuestionCount int    `json:"question_count"`
+	OrderIndex    int    `json:"order_index"`
+}
+
+type QuestionRuleRequest struct {
+	Subject       string `json:"subject"`
+	Topic         string `json:"topic"`
+	Difficulty    string `json:"difficulty"`
+	QuestionCount int    `json:"question_count"`
+}
+
+type TestAttempt struct {
+	ID               string            `json:"id"`
+	UserID           string            `json:"user_id"`
+	TestID           string            `json:"test_id"`
+	StartedAt        time.Time         `json:"started_at"`
+	CompletedAt      *time.Time        `json:"completed_at,omitempty"`
+	Score            *int              `json:"score,omitempty"`
+	TimeSpentSeconds *int              `json:"time_spent_seconds,omitempty"`
+	Status           string            `json:"status"`
+	AttemptNumber    int               `json:"attempt_number"`
+	SuspicionScore   int               `json:"suspicion_score"`
+	Flagged          bool              `json:"flagged"`
+	Questions        []AttemptQuestion `json:"questions,omitempty"`
+}
+
+// AttemptQuestion is a question as it was drawn for one attempt, with options in the
+// order that attempt saw them.
+type AttemptQuestion struct {
+	Question
+	OrderIndex int         `json:"order_index"`
+	Answer     *UserAnswer `json:"answer,omitempty"`
+}
+
+type UserAnswer struct {
+	ID               string `json:"id"`
+	AttemptID        string `json:"attempt_id"`
+	QuestionID       string `json:"question_id"`
+	Answer           string `json:"answer"`
+	IsCorrect        *bool  `json:"is_correct,omitempty"`
+	PointsEarned     int    `json:"points_earned"`
+	TimeSpentSeconds *int   `json:"time_spent_seconds,omitempty"`
+}
+
+type AnswerRequest struct {
+	QuestionID       string `json:"question_id"`
+	Answer           string `json:"answer"`
+	TimeSpentSeconds *int   `json:"time_spent_seconds,omitempty"`
+}
+
+type SubmitTestRequest struct {
+	AttemptID string          `json:"attempt_id"`
+	Answers   []AnswerRequest `json:"answers"`
+}
+
+type TestRequest struct {
+	Title            string     `json:"title"`
+	TitleKK          string     `json:"title_kk"`
+	Description      string     `json:"description"`
+	DescriptionKK    string     `json:"description_kk"`
+	TimeLimitMinutes int        `json:"time_limit_minutes"`
+	PassingScore     int        `json:"passing_score"`
+	OrderIndex       int        `json:"order_index"`
+	IsPublic         bool       `json:"is_public"`
+	DueAt            *time.Time `json:"due_at"`
+}
+
+// ImportIssue is one finding of an import, tied to the source line it came from.
+// Warnings are imported with changes, errors mean the question was skipped.
+type ImportIssue struct {
+	Line     int    `json:"line"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+type ImportReport struct {
+	Format    string            `json:"format"`
+	DryRun    bool              `json:"dry_run"`
+	Found     int               `json:"found"`
+	Imported  int               `json:"imported"`
+	Skipped   int               `json:"skipped"`
+	Issues    []ImportIssue     `json:"issues"`
+	Questions []QuestionRequest `json:"questions,omitempty"`
+	TestID    string            `json:"test_id,omitempty"`
+}
@@ -10,6 +10,7 @@ var UserContextKey = "user_id"
 type User struct {
 	ID                 string     `json:"id"`
 	Email              string     `json:"email"`
+	Username           string     `json:"username"`
 	Password           string     `json:"-"`
 	FirstName          string     `json:"first_name"`
 	LastName           string     `json:"last_name"`
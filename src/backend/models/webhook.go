@@ -0,0 +1,90 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook event types.
+const (
+	WebhookEnrollmentCreated = "enrollment.created"
+	WebhookAttemptGraded     = "attempt.graded"
+	WebhookCourseCompleted   = "course.completed"
+	WebhookLessonPublished   = "lesson.published"
+)
+
+var WebhookEventTypes = []string{
+	WebhookEnrollmentCreated, WebhookAttemptGraded, WebhookCourseCompleted, WebhookLessonPublished,
+}
+
+// Webhook delivery statuses. Failed deliveries ran out of attempts.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// Webhook is a partner's subscription to platform events. Without CourseID it receives the
+// events of every course. Secret is only shown when the webhook is created.
+type Webhook struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	CourseID   string    `json:"course_id,omitempty"`
+	IsActive   bool      `json:"is_active"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// WebhookRequest creates or edits a webhook. An empty Secret is generated on create and kept
+// on edit; IsActive defaults to true on create and is kept on edit when left out.
+type WebhookRequest struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"event_types"`
+	CourseID   string   `json:"course_id"`
+	IsActive   *bool    `json:"is_active"`
+}
+
+// WebhookDelivery is one event sent, or waiting to be sent, to a webhook, with the response
+// of the last attempt.
+type WebhookDelivery struct {
+	ID            string          `json:"id"`
+	WebhookID     string          `json:"webhook_id"`
+	EventID       string          `json:"event_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	ResponseCode  *int            `json:"response_code,omitempty"`
+	ResponseBody  string          `json:"response_body,omitempty"`
+	LastError     string          `json:"last_error,omitempty"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+type WebhookDeliveryPage struct {
+	Items []WebhookDelivery `json:"items"`
+	Page  int               `json:"page"`
+	Limit int               `json:"limit"`
+	Total int               `json:"total"`
+}
+
+// WebhookEvent is the body posted to webhooks.
+type WebhookEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookUser identifies a student in webhook events, so partners can match their records.
+type WebhookUser struct {
+	ID        string `json:"id"`
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+// GetEnrollmentCounts returns the number of students and how many of them completed the course.
+func GetEnrollmentCounts(ctx context.Context, courseID string) (int, int, error) {
+	var total, completed int
+	err := database.QueryRowContext(ctx,
+		`SELECT COUNT(*), COUNT(completed_at) FROM enrollments WHERE course_id = $1`,
+		courseID).Scan(&total, &completed)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error counting enrollments: %w", err)
+	}
+	return total, completed, nil
+}
+
+// GetWeeklyEnrollments counts new enrollments per week, oldest first. Weeks without
+// enrollments are omitted.
+func GetWeeklyEnrollments(ctx context.Context, courseID string) ([]models.EnrollmentPoint, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT to_char(date_trunc('week', enrolled_at), 'YYYY-MM-DD') AS week, COUNT(*)
+		FROM enrollments WHERE course_id = $1 AND enrolled_at IS NOT NULL
+		GROUP BY week ORDER BY week`,
+		courseID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting weekly enrollments: %w", err)
+	}
+	defer rows.Close()
+
+	points := []models.EnrollmentPoint{}
+	for rows.Next() {
+		var p models.EnrollmentPoint
+		if err := rows.Scan(&p.Week, &p.New); err != nil {
+			return nil, fmt.Errorf("error scanning weekly enrollments: %w", err)
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// GetLessonFunnel counts, for each published lesson in order, the enrolled students who
+// started and completed it.
+func GetLessonFunnel(ctx context.Context, courseID string) ([]models.LessonFunnelStep, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT l.id, l.title, l.order_index,
+			COUNT(e.user_id) FILTER (WHERE lp.status IN ('in_progress', 'completed')),
+			COUNT(e.user_id) FILTER (WHERE lp.status = 'completed')
+		FROM lessons l
+		LEFT JOIN lesson_progress lp ON lp.lesson_id = l.id
+		LEFT JOIN enrollments e ON e.user_id = lp.user_id AND e.course_id = l.course_id
+		WHERE l.course_id = $1 AND COALESCE(l.is_published, TRUE)
+		GROUP BY l.id, l.title, l.order_index
+		ORDER BY l.order_index, l.id`,
+		courseID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting lesson funnel: %w", err)
+	}
+	defer rows.Close()
+
+	steps := []models.LessonFunnelStep{}
+	for rows.Next() {
+		var s models.LessonFunnelStep
+		if err := rows.Scan(&s.LessonID, &s.Title, &s.OrderIndex, &s.Started, &s.Completed); err != nil {
+			return nil, fmt.Errorf("error scanning lesson funnel: %w", err)
+		}
+		steps = append(steps, s)
+	}
+	return steps, rows.Err()
+}
+
+// GetCourseTestScores lists the score of every completed attempt on the course's tests.
+func GetCourseTestScores(ctx context.Context, courseID string) ([]models.TestScore, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT t.id, t.title, ta.score
+		FROM test_attempts ta
+		JOIN tests t ON t.id = ta.test_id
+		WHERE t.course_id = $1 AND ta.status = 'completed' AND ta.score IS NOT NULL
+		ORDER BY t.order_index, t.id`,
+		courseID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting test scores: %w", err)
+	}
+	defer rows.Close()
+
+	var scores []models.TestScore
+	for rows.Next() {
+		var s models.TestScore
+		if err := rows.Scan(&s.TestID, &s.Title, &s.Score); err != nil {
+			return nil, fmt.Errorf("error scanning test score: %w", err)
+		}
+		scores = append(scores, s)
+	}
+	return scores, rows.Err()
+}
+
+func GetDiscussionActivity(ctx context.Context, courseID string) (*models.DiscussionActivity, error) {
+	var activity models.DiscussionActivity
+	err := database.QueryRowContext(ctx,
+		`WITH threads AS (
+			SELECT id, user_id, created_at FROM discussions WHERE course_id = $1
+		), replies AS (
+			SELECT r.user_id, r.created_at FROM discussion_replies r JOIN threads t ON t.id = r.discussion_id
+		)
+		SELECT
+			(SELECT COUNT(*) FROM threads),
+			(SELECT COUNT(*) FROM replies),
+			(SELECT COUNT(DISTINCT user_id) FROM (SELECT user_id FROM threads UNION SELECT user_id FROM replies) authors),
+			(SELECT COUNT(*) FROM threads WHERE created_at >= NOW() - INTERVAL '30 days'),
+			(SELECT COUNT(*) FROM replies WHERE created_at >= NOW() - INTERVAL '30 days')`,
+		courseID).Scan(&activity.Threads, &activity.Replies, &activity.Participants,
+		&activity.ThreadsLast30, &activity.RepliesLast30)
+	if err != nil {
+		return nil, fmt.Errorf("error getting discussion activity: %w", err)
+	}
+	return &activity, nil
+}
+
+// GetStudentProgressPage returns one page of the course's students ordered by name, with the
+// total number of students.
+func GetStudentProgressPage(ctx context.Context, courseID string, limit, offset int) ([]models.StudentProgress, int, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT u.id, u.first_name, u.last_name, u.email, COALESCE(e.progress, 0), e.completed_at, e.last_accessed_at,
+			(SELECT COUNT(*) FROM lesson_progress lp JOIN lessons l ON l.id = lp.lesson_id
+				WHERE lp.user_id = e.user_id AND l.course_id = e.course_id AND lp.status = 'completed'),
+			ts.attempts, ts.average,
+			COUNT(*) OVER ()
+		FROM enrollments e
+		JOIN users u ON u.id = e.user_id
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS attempts, AVG(ta.score)::float8 AS average
+			FROM test_attempts ta JOIN tests t ON t.id = ta.test_id
+			WHERE ta.user_id = e.user_id AND t.course_id = e.course_id AND ta.status = 'completed'
+		) ts
+		WHERE e.course_id = $1
+		ORDER BY u.last_name, u.first_name, u.id
+		LIMIT $2 OFFSET $3`,
+		courseID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting student progress: %w", err)
+	}
+	defer rows.Close()
+
+	students := []models.StudentProgress{}
+	total := 0
+	for rows.Next() {
+		var s models.StudentProgress
+		err := rows.Scan(&s.UserID, &s.FirstName, &s.LastName, &s.Email, &s.Progress, &s.CompletedAt, &s.LastAccessedAt,
+			&s.LessonsCompleted, &s.AttemptsCount, &s.AverageScore, &total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning student progress: %w", err)
+		}
+		students = append(students, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	total, err = pageTotal(ctx, len(students), offset, total, "students", "SELECT COUNT(*) FROM enrollments WHERE course_id = $1", courseID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return students, total, nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+// announcementColumns expects the viewer's ID as $1, for the read flag. Reads count only
+// while the reader is enrolled.
+const announcementColumns = `a.id, a.course_id, COALESCE(a.author_id::text, ''), COALESCE(u.first_name || ' ' || u.last_name, ''),
+	a.title, a.body, a.is_pinned, a.publish_at, a.expires_at, a.delivered_at,
+	EXISTS (SELECT 1 FROM announcement_reads r WHERE r.announcement_id = a.id AND r.user_id::text = $1),
+	(SELECT COUNT(*) FROM enrollments e WHERE e.course_id = a.course_id),
+	(SELECT COUNT(*) FROM announcement_reads r JOIN enrollments e ON e.user_id = r.user_id AND e.course_id = a.course_id
+		WHERE r.announcement_id = a.id),
+	a.created_at, a.updated_at`
+
+func scanAnnouncement(row rowScanner) (*models.Announcement, error) {
+	a := models.Announcement{Stats: &models.AnnouncementStats{}}
+	err := row.Scan(&a.ID, &a.CourseID, &a.AuthorID, &a.AuthorName, &a.Title, &a.Body, &a.IsPinned, &a.PublishAt,
+		&a.ExpiresAt, &a.DeliveredAt, &a.Read, &a.Stats.Recipients, &a.Stats.ReadCount, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func CreateAnnouncement(ctx context.Context, courseID, authorID string, req models.AnnouncementRequest) (*models.Announcement, error) {
+	a, err := scanAnnouncement(database.QueryRowContext(ctx,
+		`WITH a AS (
+			INSERT INTO announcements (course_id, author_id, title, body, is_pinned, publish_at, expires_at)
+			VALUES ($2, $1, $3, $4, $5, $6, $7)
+			RETURNING *
+		)
+		SELECT `+announcementColumns+` FROM a LEFT JOIN users u ON u.id = a.author_id`,
+		authorID, courseID, req.Title, req.Body, req.IsPinned, req.PublishAt, req.ExpiresAt))
+	if err != nil {
+		return nil, fmt.Errorf("error creating announcement: %w", err)
+	}
+	return a, nil
+}
+
+// UpdateAnnouncement edits an announcement; a nil PublishAt keeps the current one.
+func UpdateAnnouncement(ctx context.Context, courseID, announcementID, editorID string, req models.AnnouncementRequest) (*models.Announcement, error) {
+	a, err := scanAnnouncement(database.QueryRowContext(ctx,
+		`WITH a AS (
+			UPDATE announcements SET title = $4, body = $5, is_pinned = $6, publish_at = COALESCE($7, publish_at), expires_at = $8, updated_at = NOW()
+			WHERE id = $2 AND course_id = $3
+			RETURNING *
+		)
+		SELECT `+announcementColumns+` FROM a LEFT JOIN users u ON u.id = a.author_id`,
+		editorID, announcementID, courseID, req.Title, req.Body, req.IsPinned, req.PublishAt, req.ExpiresAt))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error updating announcement: %w", err)
+	}
+	return a, nil
+}
+
+func DeleteAnnouncement(ctx context.Context, courseID, announcementID string) error {
+	result, err := database.ExecContext(ctx,
+		"DELETE FROM announcements WHERE id = $1 AND course_id = $2", announcementID, courseID)
+	if err != nil {
+		return fmt.Errorf("error deleting announcement: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// GetAnnouncement returns an announcement of the course. Unless all is set, scheduled and
+// expired announcements are not found.
+func GetAnnouncement(ctx context.Context, courseID, announcementID, viewerID string, all bool) (*models.Announcement, error) {
+	a, err := scanAnnouncement(database.QueryRowContext(ctx,
+		`SELECT `+announcementColumns+` FROM announcements a LEFT JOIN users u ON u.id = a.author_id
+		WHERE a.id = $2 AND a.course_id = $3
+			AND ($4 OR (a.publish_at <= NOW() AND (a.expires_at IS NULL OR a.expires_at > NOW())))`,
+		viewerID, announcementID, courseID, all))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting announcement: %w", err)
+	}
+	return a, nil
+}
+
+// GetAnnouncements lists the announcements of a course, pinned first and then newest first.
+// Unless all is set, scheduled and expired announcements are left out.
+func GetAnnouncements(ctx context.Context, courseID, viewerID string, all bool) ([]models.Announcement, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT `+announcementColumns+` FROM announcements a LEFT JOIN users u ON u.id = a.author_id
+		WHERE a.course_id = $2
+			AND ($3 OR (a.publish_at <= NOW() AND (a.expires_at IS NULL OR a.expires_at > NOW())))
+		ORDER BY a.is_pinned DESC, a.publish_at DESC`,
+		viewerID, courseID, all)
+	if err != nil {
+		return nil, fmt.Errorf("error getting announcements: %w", err)
+	}
+	defer rows.Close()
+
+	announcements := []models.Announcement{}
+	for rows.Next() {
+		a, err := scanAnnouncement(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning announcement: %w", err)
+		}
+		announcements = append(announcements, *a)
+	}
+	return announcements, rows.Err()
+}
+
+func MarkAnnouncementRead(ctx context.Context, announcementID, userID string) error {
+	_, err := database.ExecContext(ctx,
+		`INSERT INTO announcement_reads (announcement_id, user_id) VALUES ($1, $2)
+		ON CONFLICT (announcement_id, user_id) DO NOTHING`,
+		announcementID, userID)
+	if err != nil {
+		return fmt.Errorf("error marking announcement read: %w", err)
+	}
+	return nil
+}
+
+// ClaimDueAnnouncements marks the published, unexpired announcements not yet delivered as
+// delivered and returns them, so each is delivered once.
+func ClaimDueAnnouncements(ctx context.Context) ([]models.Announcement, error) {
+	rows, err := database.QueryContext(ctx,
+		`WITH a AS (
+			UPDATE announcements SET delivered_at = NOW()
+			WHERE delivered_at IS NULL AND publish_at <= NOW() AND (expires_at IS NULL OR expires_at > NOW())
+			RETURNING *
+		)
+		SELECT `+announcementColumns+` FROM a LEFT JOIN users u ON u.id = a.author_id ORDER BY a.publish_at`,
+		"")
+	if err != nil {
+		return nil, fmt.Errorf("error claiming announcements: %w", err)
+	}
+	defer rows.Close()
+
+	announcements := []models.Announcement{}
+	for rows.Next() {
+		a, err := scanAnnouncement(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning announcement: %w", err)
+		}
+		announcements = append(announcements, *a)
+	}
+	return announcements, rows.Err()
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+const certificateColumns = `id, serial, user_id, course_id, student_name, course_title, teacher_name, issued_at, revoked_at,
+	COALESCE(revocation_reason, '')`
+
+func scanCertificate(row rowScanner) (*models.Certificate, error) {
+	var c models.Certificate
+	err := row.Scan(&c.ID, &c.Serial, &c.UserID, &c.CourseID, &c.StudentName, &c.CourseTitle, &c.TeacherName,
+		&c.IssuedAt, &c.RevokedAt, &c.RevocationReason)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// IssueCertificate creates the certificate of a completed enrollment, dated when the course
+// was completed. It returns ErrNotFound when the enrollment is not completed and the existing
+// certificate when one was already issued.
+func IssueCertificate(ctx context.Context, userID, courseID, serial string) (*models.Certificate, error) {
+	cert, err := scanCertificate(database.QueryRowContext(ctx,
+		`INSERT INTO certificates (serial, user_id, course_id, student_name, course_title, teacher_name, issued_at)
+		SELECT $3, e.user_id, e.course_id, u.first_name || ' ' || u.last_name, c.title,
+			COALESCE(t.first_name || ' ' || t.last_name, ''), e.completed_at
+		FROM enrollments e
+		JOIN users u ON u.id = e.user_id
+		JOIN courses c ON c.id = e.course_id
+		LEFT JOIN users t ON t.id = c.teacher_id
+		WHERE e.user_id = $1 AND e.course_id = $2 AND e.completed_at IS NOT NULL
+		ON CONFLICT (user_id, course_id) DO NOTHING
+		RETURNING `+certificateColumns,
+		userID, courseID, serial))
+	if err == nil {
+		return cert, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("error issuing certificate: %w", err)
+	}
+	return GetCertificate(ctx, userID, courseID)
+}
+
+func GetCertificate(ctx context.Context, userID, courseID string) (*models.Certificate, error) {
+	cert, err := scanCertificate(database.QueryRowContext(ctx,
+		"SELECT "+certificateColumns+" FROM certificates WHERE user_id = $1 AND course_id = $2",
+		userID, courseID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting certificate: %w", err)
+	}
+	return cert, nil
+}
+
+func GetCertificateBySerial(ctx context.Context, serial string) (*models.Certificate, error) {
+	cert, err := scanCertificate(database.QueryRowContext(ctx,
+		"SELECT "+certificateColumns+" FROM certificates WHERE serial = $1", serial))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting certificate: %w", err)
+	}
+	return cert, nil
+}
+
+func GetUserCertificates(ctx context.Context, userID string) ([]models.Certificate, error) {
+	rows, err := database.QueryContext(ctx,
+		"SELECT "+certificateColumns+" FROM certificates WHERE user_id = $1 ORDER BY issued_at DESC", userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting certificates: %w", err)
+	}
+	defer rows.Close()
+
+	certs := []models.Certificate{}
+	for rows.Next() {
+		cert, err := scanCertificate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning certificate: %w", err)
+		}
+		certs = append(certs, *cert)
+	}
+	return certs, rows.Err()
+}
+
+// RevokeCertificate marks a certificate revoked. Revoking twice keeps the first revocation.
+func RevokeCertificate(ctx context.Context, serial, adminID, reason string) (*models.Certificate, error) {
+	cert, err := scanCertificate(database.QueryRowContext(ctx,
+		`UPDATE certificates SET
+			revoked_at = COALESCE(revoked_at, NOW()),
+			revoked_by = COALESCE(revoked_by, $2),
+			revocation_reason = COALESCE(revocation_reason, $3)
+		WHERE serial = $1
+		RETURNING `+certificateColumns,
+		serial, adminID, reason))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error revoking certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// GetCertificateTemplate returns the course's template; ErrNotFound means the defaults apply.
+func GetCertificateTemplate(ctx context.Context, courseID string) (*models.CertificateTemplate, error) {
+	tmpl := models.CertificateTemplate{CourseID: courseID}
+	var texts []byte
+	err := database.QueryRowContext(ctx,
+		"SELECT accent_color, texts FROM certificate_templates WHERE course_id = $1",
+		courseID).Scan(&tmpl.AccentColor, &texts)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting certificate template: %w", err)
+	}
+	if err := json.Unmarshal(texts, &tmpl.Texts); err != nil {
+		return nil, fmt.Errorf("error decoding certificate template: %w", err)
+	}
+	return &tmpl, nil
+}
+
+func SaveCertificateTemplate(ctx context.Context, tmpl models.CertificateTemplate) error {
+	texts, err := json.Marshal(tmpl.Texts)
+	if err != nil {
+		return fmt.Errorf("error encoding certificate template: %w", err)
+	}
+
+	_, err = database.ExecContext(ctx,
+		`INSERT INTO certificate_templates (course_id, accent_color, texts) VALUES ($1, $2, $3)
+		ON CONFLICT (course_id) DO UPDATE SET accent_color = $2, texts = $3, updated_at = NOW()`,
+		tmpl.CourseID, tmpl.AccentColor, texts)
+	if err != nil {
+		return fmt.Errorf("error saving certificate template: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+func CreateClassGroup(ctx context.Context, name, teacherID, joinCode string) (*models.ClassGroup, error) {
+	group := models.ClassGroup{Name: name, TeacherID: teacherID, JoinCode: joinCode}
+	err := database.QueryRowContext(ctx,
+		`INSERT INTO class_groups (name, teacher_id, join_code) VALUES ($1, $2, $3)
+		RETURNING id, created_at`,
+		name, teacherID, joinCode).Scan(&group.ID, &group.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error creating class group: %w", err)
+	}
+	return &group, nil
+}
+
+// GetUserClassGroups lists the groups a user teaches or belongs to. Join codes are only
+// returned to the group's teacher.
+func GetUserClassGroups(ctx context.Context, userID string) ([]models.ClassGroup, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT g.id, g.name, g.teacher_id, CASE WHEN g.teacher_id = $1 THEN g.join_code ELSE '' END,
+			(SELECT COUNT(*) FROM class_group_members m WHERE m.group_id = g.id), g.created_at
+		FROM class_groups g
+		WHERE g.teacher_id = $1
+			OR EXISTS (SELECT 1 FROM class_group_members m WHERE m.group_id = g.id AND m.user_id = $1)
+		ORDER BY g.name`,
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting class groups: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []models.ClassGroup{}
+	for rows.Next() {
+		var g models.ClassGroup
+		if err := rows.Scan(&g.ID, &g.Name, &g.TeacherID, &g.JoinCode, &g.MemberCount, &g.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning class group: %w", err)
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// JoinClassGroup adds the user to the group with the join code. Joining twice is harmless.
+func JoinClassGroup(ctx context.Context, joinCode, userID string) (*models.ClassGroup, error) {
+	var group models.ClassGroup
+	err := database.QueryRowContext(ctx,
+		"SELECT id, name, teacher_id, created_at FROM class_groups WHERE join_code = $1",
+		joinCode).Scan(&group.ID, &group.Name, &group.TeacherID, &group.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting class group: %w", err)
+	}
+
+	_, err = database.ExecContext(ctx,
+		`INSERT INTO class_group_members (group_id, user_id) VALUES ($1, $2)
+		ON CONFLICT (group_id, user_id) DO NOTHING`,
+		group.ID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error joining class group: %w", err)
+	}
+	return &group, nil
+}
+
+func LeaveClassGroup(ctx context.Context, groupID, userID string) error {
+	_, err := database.ExecContext(ctx,
+		"DELETE FROM class_group_members WHERE group_id = $1 AND user_id = $2", groupID, userID)
+	if err != nil {
+		return fmt.Errorf("error leaving class group: %w", err)
+	}
+	return nil
+}
+
+// GetClassGroupAccess returns the group's teacher and whether the user is a member.
+func GetClassGroupAccess(ctx context.Context, groupID, userID string) (string, bool, error) {
+	var teacherID string
+	var member bool
+	err := database.QueryRowContext(ctx,
+		`SELECT teacher_id,
+			EXISTS (SELECT 1 FROM class_group_members WHERE group_id = $1 AND user_id = $2)
+		FROM class_groups WHERE id = $1`,
+		groupID, userID).Scan(&teacherID, &member)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", false, models.ErrNotFound
+		}
+		return "", false, fmt.Errorf("error getting class group: %w", err)
+	}
+	return teacherID, member, nil
+}
@@ -223,3 +223,16 @@ func SearchCourses(ctx context.Context, term string) ([]*models.Course, error) {
 
 	return courses, nil
 }
+
+// GetCourseTeacherID returns the owner of a course, or models.ErrNotFound.
+func GetCourseTeacherID(ctx context.Context, courseID string) (string, error) {
+	var teacherID sql.NullString
+	err := database.QueryRowContext(ctx, "SELECT teacher_id FROM courses WHERE id = $1", courseID).Scan(&teacherID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", models.ErrNotFound
+		}
+		return "", fmt.Errorf("error getting course teacher: %w", err)
+	}
+	return teacherID.String, nil
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+// GetDashboardCounters reads the totals of a student's dashboard in a single round trip.
+// The average score is over completed attempts.
+func GetDashboardCounters(ctx context.Context, userID string) (*models.DashboardCounters, error) {
+	var counters models.DashboardCounters
+	err := database.QueryRowContext(ctx,
+		`SELECT
+			(SELECT COUNT(*) FROM enrollments WHERE user_id = $1),
+			(SELECT COUNT(*) FROM enrollments WHERE user_id = $1 AND completed_at IS NOT NULL),
+			(SELECT COUNT(*) FROM lesson_progress WHERE user_id = $1 AND status = 'completed'),
+			ta.tests, ta.average
+		FROM (SELECT COUNT(DISTINCT test_id) AS tests, COALESCE(AVG(score), 0) AS average
+			FROM test_attempts WHERE user_id = $1 AND status = 'completed') ta`,
+		userID).Scan(&counters.CoursesEnrolled, &counters.CoursesCompleted, &counters.LessonsCompleted,
+		&counters.TestsCompleted, &counters.AverageScore)
+	if err != nil {
+		return nil, fmt.Errorf("error getting dashboard counters: %w", err)
+	}
+	return &counters, nil
+}
+
+// AddStudyTime adds seconds to the student's study time on day. A zero-second call still
+// marks the day as active for the streak.
+func AddStudyTime(ctx context.Context, userID string, day time.Time, seconds int) error {
+	_, err := database.ExecContext(ctx,
+		`INSERT INTO study_activity (user_id, activity_date, seconds) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, activity_date) DO UPDATE SET seconds = study_activity.seconds + $3`,
+		userID, day.Format("2006-01-02"), seconds)
+	if err != nil {
+		return fmt.Errorf("error recording study time: %w", err)
+	}
+	return nil
+}
+
+// GetStudyDays returns the student's active days since the given day, newest first.
+func GetStudyDays(ctx context.Context, userID string, since time.Time) ([]models.DailyStudy, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT to_char(activity_date, 'YYYY-MM-DD'), seconds FROM study_activity
+		WHERE user_id = $1 AND activity_date >= $2
+		ORDER BY activity_date DESC`,
+		userID, since.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("error getting study days: %w", err)
+	}
+	defer rows.Close()
+
+	var days []models.DailyStudy
+	for rows.Next() {
+		var d models.DailyStudy
+		if err := rows.Scan(&d.Date, &d.Seconds); err != nil {
+			return nil, fmt.Errorf("error scanning study day: %w", err)
+		}
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
+
+// GetRecentActivity lists the student's latest completed lessons and finished tests.
+func GetRecentActivity(ctx context.Context, userID string, limit int) ([]models.ActivityItem, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT kind, course_id, course_title, title, score, at FROM (
+			SELECT 'lesson_completed' AS kind, c.id AS course_id, c.title AS course_title, l.title,
+				NULL::int AS score, lp.completion_date AS at
+			FROM lesson_progress lp
+			JOIN lessons l ON l.id = lp.lesson_id
+			JOIN courses c ON c.id = l.course_id
+			WHERE lp.user_id = $1 AND lp.status = 'completed' AND lp.completion_date IS NOT NULL
+			UNION ALL
+			SELECT 'test_completed', c.id, c.title, t.title, ta.score, ta.completed_at
+			FROM test_attempts ta
+			JOIN tests t ON t.id = ta.test_id
+			JOIN courses c ON c.id = t.course_id
+			WHERE ta.user_id = $1 AND ta.status = 'completed' AND ta.completed_at IS NOT NULL
+		) activity
+		ORDER BY at DESC
+		LIMIT $2`,
+		userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error getting recent activity: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.ActivityItem{}
+	for rows.Next() {
+		var item models.ActivityItem
+		if err := rows.Scan(&item.Type, &item.CourseID, &item.CourseTitle, &item.Title, &item.Score, &item.At); err != nil {
+			return nil, fmt.Errorf("error scanning activity: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetUpcomingDeadlines lists published tests of the student's courses that are due in the
+// future and that the student has not passed yet, soonest first.
+func GetUpcomingDeadlines(ctx context.Context, userID string, limit int) ([]models.Deadline, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT t.id, c.id, c.title, t.title, t.due_at
+		FROM enrollments e
+		JOIN tests t ON t.course_id = e.course_id
+		JOIN courses c ON c.id = t.course_id
+		WHERE e.user_id = $1 AND COALESCE(t.is_published, TRUE) AND t.due_at > NOW()
+			AND NOT EXISTS (SELECT 1 FROM test_attempts ta WHERE ta.test_id = t.id AND ta.user_id = $1
+				AND ta.status = 'completed' AND ta.score >= t.passing_score)
+		ORDER BY t.due_at
+		LIMIT $2`,
+		userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error getting upcoming deadlines: %w", err)
+	}
+	defer rows.Close()
+
+	deadlines := []models.Deadline{}
+	for rows.Next() {
+		var d models.Deadline
+		if err := rows.Scan(&d.TestID, &d.CourseID, &d.CourseTitle, &d.Title, &d.DueAt); err != nil {
+			return nil, fmt.Errorf("error scanning deadline: %w", err)
+		}
+		deadlines = append(deadlines, d)
+	}
+	return deadlines, rows.Err()
+}
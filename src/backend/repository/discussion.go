@@ -0,0 +1,454 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+const discussionColumns = `d.id, COALESCE(d.course_id::text, ''), COALESCE(d.category_id::text, ''), COALESCE(d.user_id::text, ''), COALESCE(u.first_name || ' ' || u.last_name, ''),
+	d.title, d.content, COALESCE(d.is_pinned, FALSE), COALESCE(d.is_closed, FALSE), COALESCE(d.view_count, 0),
+	COALESCE(d.helpful_count, 0), (SELECT COUNT(*) FROM discussion_replies r WHERE r.discussion_id = d.id),
+	EXISTS (SELECT 1 FROM discussion_replies r WHERE r.discussion_id = d.id AND r.is_solution), d.moderation_status,
+	COALESCE((SELECT MAX(r.created_at) FROM discussion_replies r WHERE r.discussion_id = d.id), d.created_at),
+	d.edited_at, d.created_at, d.updated_at`
+
+const discussionReplyColumns = `r.id, r.discussion_id, r.parent_id, r.depth, COALESCE(r.user_id::text, ''),
+	COALESCE(u.first_name || ' ' || u.last_name, ''), r.content, COALESCE(r.is_solution, FALSE), COALESCE(r.helpful_count, 0),
+	r.moderation_status, r.deleted_at, r.edited_at, r.created_at, r.updated_at`
+
+func scanDiscussion(row rowScanner, d *models.Discussion, extra ...interface{}) error {
+	dest := []interface{}{
+		&d.ID, &d.CourseID, &d.CategoryID, &d.UserID, &d.AuthorName, &d.Title, &d.Content, &d.IsPinned, &d.IsClosed,
+		&d.ViewCount, &d.HelpfulCount, &d.ReplyCount, &d.HasSolution, &d.ModerationStatus, &d.LastActivityAt, &d.EditedAt, &d.CreatedAt, &d.UpdatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+func scanDiscussionReply(row rowScanner) (*models.DiscussionReply, error) {
+	var r models.DiscussionReply
+	err := row.Scan(&r.ID, &r.DiscussionID, &r.ParentID, &r.Depth, &r.UserID, &r.AuthorName, &r.Content,
+		&r.IsSolution, &r.HelpfulCount, &r.ModerationStatus, &r.DeletedAt, &r.EditedAt, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	r.IsDeleted = r.DeletedAt != nil
+	return &r, nil
+}
+
+// courseScope is the condition matching column to the course in parameter $n or, when the
+// parameter is empty, to no course: the platform forum.
+func courseScope(column string, n int) string {
+	return fmt.Sprintf("(%[1]s = NULLIF($%[2]d, '')::uuid OR ($%[2]d = '' AND %[1]s IS NULL))", column, n)
+}
+
+// discussionOrder maps a list order to its ORDER BY clause. Pinned threads always come first.
+func discussionOrder(sort string) string {
+	switch sort {
+	case models.DiscussionSortHelpful:
+		return "ORDER BY d.is_pinned DESC, d.helpful_count DESC, d.created_at DESC, d.id"
+	default:
+		return "ORDER BY d.is_pinned DESC, d.created_at DESC, d.id"
+	}
+}
+
+// GetDiscussionPage returns one page of the course's discussions in the given order, with the
+// total number of matching discussions; a non-empty categoryID keeps the topics of one forum
+// category. The unanswered order keeps threads without replies other than deleted ones.
+// Unless moderator is set, held and hidden discussions are left out, except the viewer's own.
+func GetDiscussionPage(ctx context.Context, courseID, categoryID, sort, viewerID string, moderator bool, limit, offset int) ([]models.Discussion, int, error) {
+	filter := ""
+	var args []interface{}
+	if !moderator {
+		args = append(args, viewerID)
+		filter = fmt.Sprintf(" AND (d.moderation_status = 'visible' OR d.user_id::text = $%d)", len(args)+1)
+	}
+	if categoryID != "" {
+		args = append(args, categoryID)
+		filter += fmt.Sprintf(" AND d.category_id = $%d", len(args)+1)
+	}
+	if sort == models.DiscussionSortUnanswered {
+		filter += " AND NOT EXISTS (SELECT 1 FROM discussion_replies r WHERE r.discussion_id = d.id AND r.deleted_at IS NULL)"
+	}
+	return discussionPage(ctx, courseID, filter, discussionOrder(sort), limit, offset, args...)
+}
+
+// GetUnansweredDiscussionPage returns the course's open discussions that have neither a
+// solution nor a reply from the course teacher, the longest waiting first.
+func GetUnansweredDiscussionPage(ctx context.Context, courseID string, limit, offset int) ([]models.Discussion, int, error) {
+	filter := ` AND d.moderation_status = 'visible' AND NOT COALESCE(d.is_closed, FALSE) AND NOT EXISTS (
+			SELECT 1 FROM discussion_replies r JOIN courses c ON c.id = d.course_id
+			WHERE r.discussion_id = d.id AND r.deleted_at IS NULL AND (r.is_solution OR r.user_id = c.teacher_id))`
+	return discussionPage(ctx, courseID, filter, "ORDER BY d.created_at, d.id", limit, offset)
+}
+
+// discussionPage lists the course's discussions matching filter, a condition appended to the
+// WHERE clause, with the total number of them. The filter's arguments start at $2.
+func discussionPage(ctx context.Context, courseID, filter, order string, limit, offset int, filterArgs ...interface{}) ([]models.Discussion, int, error) {
+	args := append([]interface{}{courseID}, filterArgs...)
+	rows, err := database.QueryContext(ctx,
+		`SELECT `+discussionColumns+`, COUNT(*) OVER ()
+		FROM discussions d
+		LEFT JOIN users u ON u.id = d.user_id
+		WHERE `+courseScope("d.course_id", 1)+filter+`
+		`+order+fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2),
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting discussions: %w", err)
+	}
+	defer rows.Close()
+
+	discussions := []models.Discussion{}
+	total := 0
+	for rows.Next() {
+		var d models.Discussion
+		if err := scanDiscussion(rows, &d, &total); err != nil {
+			return nil, 0, fmt.Errorf("error scanning discussion: %w", err)
+		}
+		discussions = append(discussions, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	total, err = pageTotal(ctx, len(discussions), offset, total, "discussions", "SELECT COUNT(*) FROM discussions d WHERE "+courseScope("d.course_id", 1)+filter, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return discussions, total, nil
+}
+
+// CreateDiscussion starts a discussion in the given moderation state; reason explains a hold.
+// An empty courseID starts a topic of the platform forum in req.CategoryID.
+func CreateDiscussion(ctx context.Context, courseID, userID string, req models.DiscussionRequest, status, reason string) (*models.Discussion, error) {
+	var d models.Discussion
+	err := scanDiscussion(database.QueryRowContext(ctx,
+		`WITH d AS (
+			INSERT INTO discussions (course_id, category_id, user_id, title, content, moderation_status, moderation_reason, announced)
+			VALUES (NULLIF($1, '')::uuid, NULLIF($7, '')::uuid, $2, $3, $4, $5, NULLIF($6, ''), $5 = 'visible')
+			RETURNING *
+		)
+		SELECT `+discussionColumns+` FROM d LEFT JOIN users u ON u.id = d.user_id`,
+		courseID, userID, req.Title, req.Content, status, reason, req.CategoryID), &d)
+	if err != nil {
+		return nil, fmt.Errorf("error creating discussion: %w", err)
+	}
+	return &d, nil
+}
+
+func GetDiscussion(ctx context.Context, courseID, discussionID string) (*models.Discussion, error) {
+	var d models.Discussion
+	err := scanDiscussion(database.QueryRowContext(ctx,
+		`SELECT `+discussionColumns+`
+		FROM discussions d
+		LEFT JOIN users u ON u.id = d.user_id
+		WHERE d.id = $1 AND `+courseScope("d.course_id", 2),
+		discussionID, courseID), &d)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting discussion: %w", err)
+	}
+	return &d, nil
+}
+
+// ViewDiscussion counts a view of the discussion and returns it with the new count.
+func ViewDiscussion(ctx context.Context, courseID, discussionID string) (*models.Discussion, error) {
+	var d models.Discussion
+	err := scanDiscussion(database.QueryRowContext(ctx,
+		`WITH d AS (
+			UPDATE discussions SET view_count = COALESCE(view_count, 0) + 1
+			WHERE id = $1 AND `+courseScope("course_id", 2)+`
+			RETURNING *
+		)
+		SELECT `+discussionColumns+` FROM d LEFT JOIN users u ON u.id = d.user_id`,
+		discussionID, courseID), &d)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error viewing discussion: %w", err)
+	}
+	return &d, nil
+}
+
+func GetDiscussionReplies(ctx context.Context, discussionID string) ([]models.DiscussionReply, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT `+discussionReplyColumns+`
+		FROM discussion_replies r
+		LEFT JOIN users u ON u.id = r.user_id
+		WHERE r.discussion_id = $1
+		ORDER BY r.created_at, r.id`,
+		discussionID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting discussion replies: %w", err)
+	}
+	defer rows.Close()
+
+	replies := []models.DiscussionReply{}
+	for rows.Next() {
+		reply, err := scanDiscussionReply(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning discussion reply: %w", err)
+		}
+		replies = append(replies, *reply)
+	}
+	return replies, rows.Err()
+}
+
+// CreateDiscussionReply adds a reply to an open discussion, under parentID when set, in the
+// given moderation state. ErrNotFound means the discussion does not exist or has been closed.
+func CreateDiscussionReply(ctx context.Context, discussionID string, parentID *string, depth int, userID, content, status, reason string) (*models.DiscussionReply, error) {
+	reply, err := scanDiscussionReply(database.QueryRowContext(ctx,
+		`WITH r AS (
+			INSERT INTO discussion_replies (discussion_id, parent_id, depth, user_id, content, moderation_status, moderation_reason, announced)
+			SELECT id, $2, $3, $4, $5, $6, NULLIF($7, ''), $6 = 'visible' FROM discussions WHERE id = $1 AND NOT COALESCE(is_closed, FALSE)
+			RETURNING *
+		)
+		SELECT `+discussionReplyColumns+` FROM r LEFT JOIN users u ON u.id = r.user_id`,
+		discussionID, parentID, depth, userID, content, status, reason))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error creating discussion reply: %w", err)
+	}
+	return reply, nil
+}
+
+func GetDiscussionReply(ctx context.Context, discussionID, replyID string) (*models.DiscussionReply, error) {
+	reply, err := scanDiscussionReply(database.QueryRowContext(ctx,
+		`SELECT `+discussionReplyColumns+`
+		FROM discussion_replies r
+		LEFT JOIN users u ON u.id = r.user_id
+		WHERE r.id = $1 AND r.discussion_id = $2`,
+		replyID, discussionID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting discussion reply: %w", err)
+	}
+	return reply, nil
+}
+
+// SetDiscussionSolution marks a reply as the discussion's solution, unmarking any previous
+// one. An empty replyID only unmarks. The discussion row is locked so concurrent changes
+// cannot leave two solutions.
+func SetDiscussionSolution(ctx context.Context, discussionID, replyID string) error {
+	tx, err := database.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id string
+	if err := tx.QueryRowContext(ctx, "SELECT id FROM discussions WHERE id = $1 FOR UPDATE", discussionID).Scan(&id); err != nil {
+		if err == sql.ErrNoRows {
+			return models.ErrNotFound
+		}
+		return fmt.Errorf("error locking discussion: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE discussion_replies SET is_solution = (id::text = $2 AND deleted_at IS NULL)
+		WHERE discussion_id = $1 AND (is_solution OR id::text = $2)`,
+		discussionID, replyID)
+	if err != nil {
+		return fmt.Errorf("error setting discussion solution: %w", err)
+	}
+	return tx.Commit()
+}
+
+// SetHelpfulVote records or withdraws the user's helpful vote on a discussion or reply and
+// returns the item's helpful count. The counter moves only when the vote actually changed, in
+// the same transaction, so it always matches the votes.
+func SetHelpfulVote(ctx context.Context, userID, targetType, targetID string, voted bool) (int, error) {
+	table := "discussions"
+	if targetType == models.VoteTargetReply {
+		table = "discussion_replies"
+	}
+
+	tx, err := database.BeginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var result sql.Result
+	if voted {
+		result, err = tx.ExecContext(ctx,
+			`INSERT INTO discussion_votes (user_id, target_type, target_id) VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING`,
+			userID, targetType, targetID)
+	} else {
+		result, err = tx.ExecContext(ctx,
+			"DELETE FROM discussion_votes WHERE user_id = $1 AND target_type = $2 AND target_id = $3",
+			userID, targetType, targetID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error saving helpful vote: %w", err)
+	}
+	delta, _ := result.RowsAffected()
+	if !voted {
+		delta = -delta
+	}
+
+	var count int
+	err = tx.QueryRowContext(ctx,
+		"UPDATE "+table+" SET helpful_count = COALESCE(helpful_count, 0) + $2 WHERE id = $1 RETURNING helpful_count",
+		targetID, delta).Scan(&count)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, models.ErrNotFound
+		}
+		return 0, fmt.Errorf("error updating helpful count: %w", err)
+	}
+	return count, tx.Commit()
+}
+
+// GetHelpfulVotes returns the IDs of the discussion and its replies the user voted helpful.
+func GetHelpfulVotes(ctx context.Context, userID, discussionID string) ([]string, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT v.target_id FROM discussion_votes v
+		WHERE v.user_id = $1 AND (
+			(v.target_type = 'discussion' AND v.target_id = $2)
+			OR (v.target_type = 'reply' AND v.target_id IN (SELECT id FROM discussion_replies WHERE discussion_id = $2)))`,
+		userID, discussionID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting helpful votes: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error scanning helpful vote: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// UpdateDiscussion saves a new title and content for a discussion, keeping the previous
+// version in its edit history. A non-empty holdReason holds a visible discussion for review.
+func UpdateDiscussion(ctx context.Context, courseID, discussionID, editorID string, req models.DiscussionRequest, holdReason string) (*models.Discussion, error) {
+	tx, err := database.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO discussion_edits (target_type, target_id, title, content, edited_by)
+		SELECT $3, id, title, content, $4 FROM discussions WHERE id = $1 AND `+courseScope("course_id", 2),
+		discussionID, courseID, models.VoteTargetDiscussion, editorID)
+	if err != nil {
+		return nil, fmt.Errorf("error saving discussion edit: %w", err)
+	}
+
+	var d models.Discussion
+	err = scanDiscussion(tx.QueryRowContext(ctx,
+		`WITH d AS (
+			UPDATE discussions SET title = $3, content = $4, edited_at = NOW(), updated_at = NOW(),
+				moderation_status = CASE WHEN $5 <> '' AND moderation_status = 'visible' THEN 'held' ELSE moderation_status END,
+				moderation_reason = CASE WHEN $5 <> '' AND moderation_status = 'visible' THEN $5 ELSE moderation_reason END
+			WHERE id = $1 AND `+courseScope("course_id", 2)+`
+			RETURNING *
+		)
+		SELECT `+discussionColumns+` FROM d LEFT JOIN users u ON u.id = d.user_id`,
+		discussionID, courseID, req.Title, req.Content, holdReason), &d)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error updating discussion: %w", err)
+	}
+	return &d, tx.Commit()
+}
+
+// UpdateDiscussionReply saves new content for a reply that has not been deleted, keeping the
+// previous version in its edit history. A non-empty holdReason holds a visible reply for
+// review.
+func UpdateDiscussionReply(ctx context.Context, discussionID, replyID, editorID, content, holdReason string) (*models.DiscussionReply, error) {
+	tx, err := database.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO discussion_edits (target_type, target_id, content, edited_by)
+		SELECT $3, id, content, $4 FROM discussion_replies
+		WHERE id = $1 AND discussion_id = $2 AND deleted_at IS NULL`,
+		replyID, discussionID, models.VoteTargetReply, editorID)
+	if err != nil {
+		return nil, fmt.Errorf("error saving reply edit: %w", err)
+	}
+
+	reply, err := scanDiscussionReply(tx.QueryRowContext(ctx,
+		`WITH r AS (
+			UPDATE discussion_replies SET content = $3, edited_at = NOW(), updated_at = NOW(),
+				moderation_status = CASE WHEN $4 <> '' AND moderation_status = 'visible' THEN 'held' ELSE moderation_status END,
+				moderation_reason = CASE WHEN $4 <> '' AND moderation_status = 'visible' THEN $4 ELSE moderation_reason END
+			WHERE id = $1 AND discussion_id = $2 AND deleted_at IS NULL
+			RETURNING *
+		)
+		SELECT `+discussionReplyColumns+` FROM r LEFT JOIN users u ON u.id = r.user_id`,
+		replyID, discussionID, content, holdReason))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error updating discussion reply: %w", err)
+	}
+	return reply, tx.Commit()
+}
+
+// DeleteDiscussionReply soft-deletes a reply: it stays in the thread, with its content kept
+// for moderators, and stops being the solution.
+func DeleteDiscussionReply(ctx context.Context, discussionID, replyID, deletedBy string) error {
+	result, err := database.ExecContext(ctx,
+		`UPDATE discussion_replies SET deleted_at = NOW(), deleted_by = $3, is_solution = FALSE
+		WHERE id = $1 AND discussion_id = $2 AND deleted_at IS NULL`,
+		replyID, discussionID, deletedBy)
+	if err != nil {
+		return fmt.Errorf("error deleting discussion reply: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// GetDiscussionEdits returns the previous versions of a discussion or reply, newest first.
+func GetDiscussionEdits(ctx context.Context, targetType, targetID string) ([]models.DiscussionEdit, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT e.id, e.target_type, e.target_id, e.title, e.content, COALESCE(e.edited_by::text, ''),
+			COALESCE(u.first_name || ' ' || u.last_name, ''), e.edited_at
+		FROM discussion_edits e
+		LEFT JOIN users u ON u.id = e.edited_by
+		WHERE e.target_type = $1 AND e.target_id = $2
+		ORDER BY e.edited_at DESC`,
+		targetType, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting discussion edits: %w", err)
+	}
+	defer rows.Close()
+
+	edits := []models.DiscussionEdit{}
+	for rows.Next() {
+		var e models.DiscussionEdit
+		err := rows.Scan(&e.ID, &e.TargetType, &e.TargetID, &e.Title, &e.Content, &e.EditedBy, &e.EditorName, &e.EditedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning discussion edit: %w", err)
+		}
+		edits = append(edits, e)
+	}
+	return edits, rows.Err()
+}
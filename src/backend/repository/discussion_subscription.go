@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+func SubscribeDiscussion(ctx context.Context, discussionID, userID string) error {
+	_, err := database.ExecContext(ctx,
+		"INSERT INTO discussion_subscriptions (discussion_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		discussionID, userID)
+	if err != nil {
+		return fmt.Errorf("error subscribing to discussion: %w", err)
+	}
+	return nil
+}
+
+func UnsubscribeDiscussion(ctx context.Context, discussionID, userID string) error {
+	_, err := database.ExecContext(ctx,
+		"DELETE FROM discussion_subscriptions WHERE discussion_id = $1 AND user_id = $2", discussionID, userID)
+	if err != nil {
+		return fmt.Errorf("error unsubscribing from discussion: %w", err)
+	}
+	return nil
+}
+
+func IsSubscribedToDiscussion(ctx context.Context, discussionID, userID string) (bool, error) {
+	var subscribed bool
+	err := database.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM discussion_subscriptions WHERE discussion_id = $1 AND user_id = $2)",
+		discussionID, userID).Scan(&subscribed)
+	if err != nil {
+		return false, fmt.Errorf("error checking discussion subscription: %w", err)
+	}
+	return subscribed, nil
+}
+
+func GetDiscussionSubscribers(ctx context.Context, discussionID string) ([]string, error) {
+	rows, err := database.QueryContext(ctx,
+		"SELECT user_id FROM discussion_subscriptions WHERE discussion_id = $1", discussionID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting discussion subscribers: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error scanning discussion subscriber: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// AddDiscussionEvents queues events for the next notification batch.
+func AddDiscussionEvents(ctx context.Context, events []models.DiscussionEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	users := make([]string, len(events))
+	discussions := make([]string, len(events))
+	replies := make([]sql.NullString, len(events))
+	actors := make([]string, len(events))
+	kinds := make([]string, len(events))
+	for i, e := range events {
+		users[i], discussions[i], actors[i], kinds[i] = e.UserID, e.DiscussionID, e.ActorID, e.Kind
+		if e.ReplyID != nil {
+			replies[i] = sql.NullString{String: *e.ReplyID, Valid: true}
+		}
+	}
+
+	_, err := database.ExecContext(ctx,
+		`INSERT INTO discussion_events (user_id, discussion_id, reply_id, actor_id, kind)
+		SELECT * FROM unnest($1::uuid[], $2::uuid[], $3::uuid[], $4::uuid[], $5::text[])`,
+		pq.Array(users), pq.Array(discussions), pq.Array(replies), pq.Array(actors), pq.Array(kinds))
+	if err != nil {
+		return fmt.Errorf("error adding discussion events: %w", err)
+	}
+	return nil
+}
+
+// ClaimDiscussionDigests marks all pending discussion events as notified and returns them
+// summed up per user and discussion. Claiming in one statement keeps concurrent batches from
+// notifying twice.
+func ClaimDiscussionDigests(ctx context.Context) ([]models.DiscussionDigest, error) {
+	rows, err := database.QueryContext(ctx,
+		`WITH claimed AS (
+			UPDATE discussion_events SET notified_at = NOW()
+			WHERE notified_at IS NULL
+			RETURNING user_id, discussion_id, actor_id, kind
+		)
+		SELECT c.user_id, c.discussion_id, COALESCE(d.course_id::text, ''), d.title,
+			COUNT(*) FILTER (WHERE c.kind = 'reply'), COUNT(*) FILTER (WHERE c.kind = 'mention'),
+			array_agg(DISTINCT a.first_name || ' ' || a.last_name)
+		FROM claimed c
+		JOIN discussions d ON d.id = c.discussion_id
+		JOIN users a ON a.id = c.actor_id
+		GROUP BY c.user_id, c.discussion_id, d.course_id, d.title`)
+	if err != nil {
+		return nil, fmt.Errorf("error claiming discussion events: %w", err)
+	}
+	defer rows.Close()
+
+	digests := []models.DiscussionDigest{}
+	for rows.Next() {
+		var a models.DiscussionDigest
+		err := rows.Scan(&a.UserID, &a.DiscussionID, &a.CourseID, &a.Title, &a.Replies, &a.Mentions, pq.Array(&a.Actors))
+		if err != nil {
+			return nil, fmt.Errorf("error scanning discussion digest: %w", err)
+		}
+		digests = append(digests, a)
+	}
+	return digests, rows.Err()
+}
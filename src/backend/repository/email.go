@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+const emailColumns = `e.id, e.user_id, e.to_address, e.template, e.subject, e.html_body, e.text_body, e.status,
+	e.attempts, COALESCE(e.last_error, ''), e.next_attempt_at, e.sent_at, e.created_at`
+
+func scanEmail(row rowScanner) (*models.OutboxEmail, error) {
+	var e models.OutboxEmail
+	err := row.Scan(&e.ID, &e.UserID, &e.ToAddress, &e.Template, &e.Subject, &e.HTMLBody, &e.TextBody, &e.Status,
+		&e.Attempts, &e.LastError, &e.NextAttemptAt, &e.SentAt, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// EnqueueEmail puts a rendered e-mail in the outbox, to be sent right away.
+func EnqueueEmail(ctx context.Context, e models.OutboxEmail) error {
+	_, err := database.ExecContext(ctx,
+		`INSERT INTO email_outbox (user_id, to_address, template, subject, html_body, text_body)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		e.UserID, e.ToAddress, e.Template, e.Subject, e.HTMLBody, e.TextBody)
+	if err != nil {
+		return fmt.Errorf("error queueing email: %w", err)
+	}
+	return nil
+}
+
+// ClaimEmails takes up to limit pending e-mails that are due and counts the attempt. They are
+// leased for the given time: if the sender dies before reporting back they become due again.
+func ClaimEmails(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEmail, error) {
+	rows, err := database.QueryContext(ctx,
+		`WITH e AS (
+			UPDATE email_outbox SET attempts = attempts + 1, next_attempt_at = NOW() + $2::float8 * INTERVAL '1 second'
+			WHERE id IN (
+				SELECT id FROM email_outbox
+				WHERE status = 'pending' AND next_attempt_at <= NOW()
+				ORDER BY next_attempt_at
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING *
+		)
+		SELECT `+emailColumns+` FROM e ORDER BY e.created_at`,
+		limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("error claiming emails: %w", err)
+	}
+	defer rows.Close()
+
+	emails := []models.OutboxEmail{}
+	for rows.Next() {
+		e, err := scanEmail(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning email: %w", err)
+		}
+		emails = append(emails, *e)
+	}
+	return emails, rows.Err()
+}
+
+func MarkEmailSent(ctx context.Context, id string) error {
+	_, err := database.ExecContext(ctx,
+		"UPDATE email_outbox SET status = 'sent', sent_at = NOW(), last_error = NULL WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("error marking email sent: %w", err)
+	}
+	return nil
+}
+
+// MarkEmailFailed records a failed attempt. The e-mail is retried at retryAt, or given up on
+// when retryAt is nil.
+func MarkEmailFailed(ctx context.Context, id, lastError string, retryAt *time.Time) error {
+	_, err := database.ExecContext(ctx,
+		`UPDATE email_outbox SET last_error = $2,
+			status = CASE WHEN $3::timestamp IS NULL THEN 'failed' ELSE 'pending' END,
+			next_attempt_at = COALESCE($3, next_attempt_at)
+		WHERE id = $1`,
+		id, lastError, retryAt)
+	if err != nil {
+		return fmt.Errorf("error marking email failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteEmailsBefore drops sent and failed e-mails created before the given time and returns
+// how many there were.
+func DeleteEmailsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := database.ExecContext(ctx,
+		"DELETE FROM email_outbox WHERE status <> 'pending' AND created_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting old emails: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+const enrollmentColumns = `e.id, e.user_id, e.course_id, e.enrolled_at, e.completed_at, COALESCE(e.progress, 0), e.last_accessed_at`
+
+func scanEnrollment(row rowScanner, e *models.Enrollment, extra ...interface{}) error {
+	dest := []interface{}{&e.ID, &e.UserID, &e.CourseID, &e.EnrolledAt, &e.CompletedAt, &e.Progress, &e.LastAccessedAt}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// Enroll enrolls the user in the course. Enrolling twice is not an error: the existing
+// enrollment is returned and created is false.
+func Enroll(ctx context.Context, userID, courseID string) (*models.Enrollment, bool, error) {
+	var enrollment models.Enrollment
+	err := scanEnrollment(database.QueryRowContext(ctx,
+		`INSERT INTO enrollments AS e (user_id, course_id, last_accessed_at) VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id, course_id) DO NOTHING
+		RETURNING `+enrollmentColumns,
+		userID, courseID), &enrollment)
+	if err == nil {
+		return &enrollment, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, fmt.Errorf("error creating enrollment: %w", err)
+	}
+
+	existing, err := GetEnrollment(ctx, userID, courseID)
+	if err != nil {
+		return nil, false, err
+	}
+	return existing, false, nil
+}
+
+// Unenroll removes the enrollment; removed is false when there was none. Lesson progress is
+// kept so that enrolling again picks up where the student left off.
+func Unenroll(ctx context.Context, userID, courseID string) (bool, error) {
+	result, err := database.ExecContext(ctx,
+		"DELETE FROM enrollments WHERE user_id = $1 AND course_id = $2", userID, courseID)
+	if err != nil {
+		return false, fmt.Errorf("error deleting enrollment: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error deleting enrollment: %w", err)
+	}
+	return n > 0, nil
+}
+
+func GetEnrollment(ctx context.Context, userID, courseID string) (*models.Enrollment, error) {
+	var enrollment models.Enrollment
+	err := scanEnrollment(database.QueryRowContext(ctx,
+		"SELECT "+enrollmentColumns+" FROM enrollments e WHERE e.user_id = $1 AND e.course_id = $2",
+		userID, courseID), &enrollment)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting enrollment: %w", err)
+	}
+	return &enrollment, nil
+}
+
+// GetUserEnrollments lists the user's courses, most recently used first.
+func GetUserEnrollments(ctx context.Context, userID string) ([]models.Enrollment, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT `+enrollmentColumns+`, c.title, c.category, COALESCE(c.image, '')
+		FROM enrollments e
+		JOIN courses c ON c.id = e.course_id
+		WHERE e.user_id = $1
+		ORDER BY e.last_accessed_at DESC NULLS LAST, e.enrolled_at DESC`,
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting enrollments: %w", err)
+	}
+	defer rows.Close()
+
+	enrollments := []models.Enrollment{}
+	for rows.Next() {
+		var e models.Enrollment
+		if err := scanEnrollment(rows, &e, &e.CourseTitle, &e.CourseCategory, &e.CourseImage); err != nil {
+			return nil, fmt.Errorf("error scanning enrollment: %w", err)
+		}
+		enrollments = append(enrollments, e)
+	}
+	return enrollments, rows.Err()
+}
+
+func GetCourseStudents(ctx context.Context, courseID string) ([]models.EnrolledStudent, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT u.id, u.first_name, u.last_name, u.email, e.enrolled_at, e.completed_at, COALESCE(e.progress, 0), e.last_accessed_at
+		FROM enrollments e
+		JOIN users u ON u.id = e.user_id
+		WHERE e.course_id = $1
+		ORDER BY u.last_name, u.first_name`,
+		courseID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting course students: %w", err)
+	}
+	defer rows.Close()
+
+	students := []models.EnrolledStudent{}
+	for rows.Next() {
+		var s models.EnrolledStudent
+		err := rows.Scan(&s.UserID, &s.FirstName, &s.LastName, &s.Email, &s.EnrolledAt, &s.CompletedAt, &s.Progress, &s.LastAccessedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning course student: %w", err)
+		}
+		students = append(students, s)
+	}
+	return students, rows.Err()
+}
+
+func TouchEnrollment(ctx context.Context, userID, courseID string) error {
+	_, err := database.ExecContext(ctx,
+		"UPDATE enrollments SET last_accessed_at = NOW() WHERE user_id = $1 AND course_id = $2", userID, courseID)
+	if err != nil {
+		return fmt.Errorf("error updating enrollment access time: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,383 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+const deckColumns = `d.id, d.course_id, d.title, COALESCE(d.title_kk, ''), COALESCE(d.description, ''),
+	(SELECT COUNT(*) FROM flashcards f WHERE f.deck_id = d.id), d.created_at, d.updated_at`
+
+const flashcardColumns = `f.id, f.deck_id, f.front, COALESCE(f.front_kk, ''), f.back, COALESCE(f.back_kk, ''),
+	f.source_type, f.source_id, f.created_at, f.updated_at`
+
+func scanDeck(row rowScanner) (*models.FlashcardDeck, error) {
+	var d models.FlashcardDeck
+	err := row.Scan(&d.ID, &d.CourseID, &d.Title, &d.TitleKK, &d.Description, &d.CardCount, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func scanFlashcard(row rowScanner, card *models.Flashcard, extra ...interface{}) error {
+	dest := []interface{}{
+		&card.ID, &card.DeckID, &card.Front, &card.FrontKK, &card.Back, &card.BackKK,
+		&card.SourceType, &card.SourceID, &card.CreatedAt, &card.UpdatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+func CreateDeck(ctx context.Context, courseID, createdBy string, req models.FlashcardDeckRequest) (*models.FlashcardDeck, error) {
+	deck, err := scanDeck(database.QueryRowContext(ctx,
+		`WITH d AS (
+			INSERT INTO flashcard_decks (course_id, created_by, title, title_kk, description)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING *
+		)
+		SELECT `+deckColumns+` FROM d`,
+		courseID, createdBy, req.Title, req.TitleKK, req.Description))
+	if err != nil {
+		return nil, fmt.Errorf("error creating deck: %w", err)
+	}
+	return deck, nil
+}
+
+func GetCourseDecks(ctx context.Context, courseID string) ([]models.FlashcardDeck, error) {
+	rows, err := database.QueryContext(ctx,
+		"SELECT "+deckColumns+" FROM flashcard_decks d WHERE d.course_id = $1 ORDER BY d.created_at", courseID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting decks: %w", err)
+	}
+	defer rows.Close()
+
+	decks := []models.FlashcardDeck{}
+	for rows.Next() {
+		deck, err := scanDeck(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning deck: %w", err)
+		}
+		decks = append(decks, *deck)
+	}
+	return decks, rows.Err()
+}
+
+func GetDeck(ctx context.Context, courseID, deckID string) (*models.FlashcardDeck, error) {
+	deck, err := scanDeck(database.QueryRowContext(ctx,
+		"SELECT "+deckColumns+" FROM flashcard_decks d WHERE d.id = $1 AND d.course_id = $2", deckID, courseID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting deck: %w", err)
+	}
+	return deck, nil
+}
+
+func UpdateDeck(ctx context.Context, courseID, deckID string, req models.FlashcardDeckRequest) (*models.FlashcardDeck, error) {
+	deck, err := scanDeck(database.QueryRowContext(ctx,
+		`WITH d AS (
+			UPDATE flashcard_decks SET title = $3, title_kk = $4, description = $5, updated_at = NOW()
+			WHERE id = $1 AND course_id = $2
+			RETURNING *
+		)
+		SELECT `+deckColumns+` FROM d`,
+		deckID, courseID, req.Title, req.TitleKK, req.Description))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error updating deck: %w", err)
+	}
+	return deck, nil
+}
+
+func DeleteDeck(ctx context.Context, courseID, deckID string) error {
+	result, err := database.ExecContext(ctx,
+		"DELETE FROM flashcard_decks WHERE id = $1 AND course_id = $2", deckID, courseID)
+	if err != nil {
+		return fmt.Errorf("error deleting deck: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+func GetDeckCards(ctx context.Context, deckID string) ([]models.Flashcard, error) {
+	rows, err := database.QueryContext(ctx,
+		"SELECT "+flashcardColumns+" FROM flashcards f WHERE f.deck_id = $1 ORDER BY f.created_at", deckID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting flashcards: %w", err)
+	}
+	defer rows.Close()
+
+	cards := []models.Flashcard{}
+	for rows.Next() {
+		var card models.Flashcard
+		if err := scanFlashcard(rows, &card); err != nil {
+			return nil, fmt.Errorf("error scanning flashcard: %w", err)
+		}
+		cards = append(cards, card)
+	}
+	return cards, rows.Err()
+}
+
+func GetFlashcard(ctx context.Context, deckID, cardID string) (*models.Flashcard, error) {
+	var card models.Flashcard
+	err := scanFlashcard(database.QueryRowContext(ctx,
+		"SELECT "+flashcardColumns+" FROM flashcards f WHERE f.id = $1 AND f.deck_id = $2", cardID, deckID), &card)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting flashcard: %w", err)
+	}
+	return &card, nil
+}
+
+func CreateFlashcard(ctx context.Context, deckID string, req models.FlashcardRequest) (*models.Flashcard, error) {
+	var card models.Flashcard
+	err := scanFlashcard(database.QueryRowContext(ctx,
+		`WITH f AS (
+			INSERT INTO flashcards (deck_id, front, front_kk, back, back_kk, source_type)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING *
+		)
+		SELECT `+flashcardColumns+` FROM f`,
+		deckID, req.Front, req.FrontKK, req.Back, req.BackKK, models.FlashcardSourceManual), &card)
+	if err != nil {
+		return nil, fmt.Errorf("error creating flashcard: %w", err)
+	}
+	return &card, nil
+}
+
+// CreateFlashcards adds cards to a deck and returns the number created. Cards derived from a
+// source the deck already has a card for are skipped.
+func CreateFlashcards(ctx context.Context, deckID string, cards []models.Flashcard) (int, error) {
+	tx, err := database.BeginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	created := 0
+	for _, card := range cards {
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO flashcards (deck_id, front, front_kk, back, back_kk, source_type, source_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (deck_id, source_type, source_id) DO NOTHING`,
+			deckID, card.Front, card.FrontKK, card.Back, card.BackKK, card.SourceType, card.SourceID)
+		if err != nil {
+			return 0, fmt.Errorf("error creating flashcard: %w", err)
+		}
+		n, _ := result.RowsAffected()
+		created += int(n)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE flashcard_decks SET updated_at = NOW() WHERE id = $1", deckID); err != nil {
+		return 0, fmt.Errorf("error updating deck: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return created, nil
+}
+
+func UpdateFlashcard(ctx context.Context, deckID, cardID string, req models.FlashcardRequest) (*models.Flashcard, error) {
+	var card models.Flashcard
+	err := scanFlashcard(database.QueryRowContext(ctx,
+		`WITH f AS (
+			UPDATE flashcards SET front = $3, front_kk = $4, back = $5, back_kk = $6, updated_at = NOW()
+			WHERE id = $1 AND deck_id = $2
+			RETURNING *
+		)
+		SELECT `+flashcardColumns+` FROM f`,
+		cardID, deckID, req.Front, req.FrontKK, req.Back, req.BackKK), &card)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error updating flashcard: %w", err)
+	}
+	return &card, nil
+}
+
+func DeleteFlashcard(ctx context.Context, deckID, cardID string) error {
+	result, err := database.ExecContext(ctx,
+		"DELETE FROM flashcards WHERE id = $1 AND deck_id = $2", cardID, deckID)
+	if err != nil {
+		return fmt.Errorf("error deleting flashcard: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// GetFlashcardSchedule returns the student's schedule of a card; ErrNotFound means the card
+// is new to them.
+func GetFlashcardSchedule(ctx context.Context, userID, cardID string) (*models.FlashcardSchedule, error) {
+	var s models.FlashcardSchedule
+	err := database.QueryRowContext(ctx,
+		`SELECT ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at
+		FROM flashcard_progress WHERE user_id = $1 AND card_id = $2`,
+		userID, cardID).Scan(&s.EaseFactor, &s.IntervalDays, &s.Repetitions, &s.Lapses, &s.DueAt, &s.LastReviewedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting flashcard schedule: %w", err)
+	}
+	return &s, nil
+}
+
+// SaveFlashcardReview stores the schedule after a review and logs the review.
+func SaveFlashcardReview(ctx context.Context, userID, cardID string, grade, previousInterval int, s models.FlashcardSchedule) error {
+	tx, err := database.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO flashcard_progress (user_id, card_id, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (user_id, card_id) DO UPDATE SET ease_factor = $3, interval_days = $4, repetitions = $5,
+			lapses = $6, due_at = $7, last_reviewed_at = $8`,
+		userID, cardID, s.EaseFactor, s.IntervalDays, s.Repetitions, s.Lapses, s.DueAt, s.LastReviewedAt)
+	if err != nil {
+		return fmt.Errorf("error saving flashcard schedule: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO flashcard_reviews (user_id, card_id, grade, previous_interval_days, interval_days, ease_factor, reviewed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		userID, cardID, grade, previousInterval, s.IntervalDays, s.EaseFactor, s.LastReviewedAt)
+	if err != nil {
+		return fmt.Errorf("error logging flashcard review: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// deckScope limits card queries to one deck when $2 is set, otherwise to decks of the
+// student's enrolled courses.
+const deckScope = `(d.id = NULLIF($2, '')::uuid
+	OR ($2 = '' AND EXISTS (SELECT 1 FROM enrollments e WHERE e.course_id = d.course_id AND e.user_id = $1)))`
+
+// GetDueFlashcards returns the student's reviewed cards due before the given time, most
+// overdue first.
+func GetDueFlashcards(ctx context.Context, userID, deckID string, before time.Time, limit int) ([]models.DueFlashcard, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT `+flashcardColumns+`, d.title, COALESCE(d.title_kk, ''),
+			p.ease_factor, p.interval_days, p.repetitions, p.lapses, p.due_at, p.last_reviewed_at
+		FROM flashcard_progress p
+		JOIN flashcards f ON f.id = p.card_id
+		JOIN flashcard_decks d ON d.id = f.deck_id
+		WHERE p.user_id = $1 AND p.due_at < $3 AND `+deckScope+`
+		ORDER BY p.due_at
+		LIMIT $4`,
+		userID, deckID, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error getting due flashcards: %w", err)
+	}
+	defer rows.Close()
+
+	var cards []models.DueFlashcard
+	for rows.Next() {
+		var card models.DueFlashcard
+		var s models.FlashcardSchedule
+		err := scanFlashcard(rows, &card.Flashcard, &card.DeckTitle, &card.DeckTitleKK,
+			&s.EaseFactor, &s.IntervalDays, &s.Repetitions, &s.Lapses, &s.DueAt, &s.LastReviewedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning flashcard: %w", err)
+		}
+		card.Schedule = &s
+		cards = append(cards, card)
+	}
+	return cards, rows.Err()
+}
+
+// GetNewFlashcards returns cards the student has never reviewed, in deck and card order.
+func GetNewFlashcards(ctx context.Context, userID, deckID string, limit int) ([]models.DueFlashcard, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT `+flashcardColumns+`, d.title, COALESCE(d.title_kk, '')
+		FROM flashcards f
+		JOIN flashcard_decks d ON d.id = f.deck_id
+		WHERE `+deckScope+`
+			AND NOT EXISTS (SELECT 1 FROM flashcard_progress p WHERE p.card_id = f.id AND p.user_id = $1)
+		ORDER BY d.created_at, f.created_at
+		LIMIT $3`,
+		userID, deckID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error getting new flashcards: %w", err)
+	}
+	defer rows.Close()
+
+	var cards []models.DueFlashcard
+	for rows.Next() {
+		var card models.DueFlashcard
+		if err := scanFlashcard(rows, &card.Flashcard, &card.DeckTitle, &card.DeckTitleKK); err != nil {
+			return nil, fmt.Errorf("error scanning flashcard: %w", err)
+		}
+		cards = append(cards, card)
+	}
+	return cards, rows.Err()
+}
+
+// CountNewFlashcardsSince counts the cards the student first reviewed since the given time.
+func CountNewFlashcardsSince(ctx context.Context, userID, deckID string, since time.Time) (int, error) {
+	var n int
+	err := database.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM flashcard_progress p
+		JOIN flashcards f ON f.id = p.card_id
+		JOIN flashcard_decks d ON d.id = f.deck_id
+		WHERE p.user_id = $1 AND p.created_at >= $3 AND `+deckScope,
+		userID, deckID, since).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("error counting new flashcards: %w", err)
+	}
+	return n, nil
+}
+
+// GetDeckStats counts the deck's cards by the student's learning state and their reviews since
+// the given time. Cards with an interval of matureDays or more are mature.
+func GetDeckStats(ctx context.Context, userID, deckID string, dueBefore, since time.Time, matureDays int) (*models.DeckStats, error) {
+	stats := models.DeckStats{DeckID: deckID}
+	var recalled, learnedReviews int
+	err := database.QueryRowContext(ctx,
+		`SELECT COUNT(*),
+			COUNT(*) FILTER (WHERE p.card_id IS NULL),
+			COUNT(*) FILTER (WHERE p.card_id IS NOT NULL AND p.interval_days < $4),
+			COUNT(*) FILTER (WHERE p.interval_days >= $4),
+			COUNT(*) FILTER (WHERE p.due_at < $3),
+			(SELECT COUNT(*) FROM flashcard_reviews r JOIN flashcards rf ON rf.id = r.card_id
+				WHERE rf.deck_id = $2 AND r.user_id = $1 AND r.reviewed_at >= $5),
+			(SELECT COUNT(*) FROM flashcard_reviews r JOIN flashcards rf ON rf.id = r.card_id
+				WHERE rf.deck_id = $2 AND r.user_id = $1 AND r.reviewed_at >= $5 AND r.previous_interval_days > 0),
+			(SELECT COUNT(*) FROM flashcard_reviews r JOIN flashcards rf ON rf.id = r.card_id
+				WHERE rf.deck_id = $2 AND r.user_id = $1 AND r.reviewed_at >= $5 AND r.previous_interval_days > 0
+				AND r.grade >= 3)
+		FROM flashcards f
+		LEFT JOIN flashcard_progress p ON p.card_id = f.id AND p.user_id = $1
+		WHERE f.deck_id = $2`,
+		userID, deckID, dueBefore, matureDays, since).Scan(&stats.Cards, &stats.New, &stats.Learning, &stats.Mature,
+		&stats.DueToday, &stats.Reviews, &learnedReviews, &recalled)
+	if err != nil {
+		return nil, fmt.Errorf("error getting deck stats: %w", err)
+	}
+	if learnedReviews > 0 {
+		retention := float64(recalled) * 100 / float64(learnedReviews)
+		stats.Retention = &retention
+	}
+	return &stats, nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+// foreignKeyViolation is the Postgres error code for a row still referenced elsewhere.
+const foreignKeyViolation = "23503"
+
+const forumCategoryColumns = `c.id, c.slug, COALESCE(c.subject, ''), c.name, COALESCE(c.name_kk, ''),
+	COALESCE(c.description, ''), COALESCE(c.description_kk, ''), c.order_index, c.is_archived,
+	(SELECT COUNT(*) FROM discussions d WHERE d.category_id = c.id AND d.moderation_status = 'visible'),
+	(SELECT MAX(d.created_at) FROM discussions d WHERE d.category_id = c.id AND d.moderation_status = 'visible'),
+	c.created_at`
+
+func scanForumCategory(row rowScanner) (*models.ForumCategory, error) {
+	var c models.ForumCategory
+	err := row.Scan(&c.ID, &c.Slug, &c.Subject, &c.Name, &c.NameKK, &c.Description, &c.DescriptionKK,
+		&c.OrderIndex, &c.IsArchived, &c.TopicCount, &c.LastTopicAt, &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// forumCategoryError maps a broken unique slug to ErrConflict.
+func forumCategoryError(err error, action string) error {
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+		return models.ErrConflict
+	}
+	return fmt.Errorf("error %s forum category: %w", action, err)
+}
+
+// GetForumCategories returns the forum categories in display order, archived ones only when
+// includeArchived is set.
+func GetForumCategories(ctx context.Context, includeArchived bool) ([]models.ForumCategory, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT `+forumCategoryColumns+` FROM forum_categories c
+		WHERE $1 OR NOT c.is_archived
+		ORDER BY c.order_index, c.name`,
+		includeArchived)
+	if err != nil {
+		return nil, fmt.Errorf("error getting forum categories: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []models.ForumCategory{}
+	for rows.Next() {
+		c, err := scanForumCategory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning forum category: %w", err)
+		}
+		categories = append(categories, *c)
+	}
+	return categories, rows.Err()
+}
+
+func GetForumCategory(ctx context.Context, categoryID string) (*models.ForumCategory, error) {
+	c, err := scanForumCategory(database.QueryRowContext(ctx,
+		"SELECT "+forumCategoryColumns+" FROM forum_categories c WHERE c.id = $1", categoryID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting forum category: %w", err)
+	}
+	return c, nil
+}
+
+// CreateForumCategory adds a category. ErrConflict means the slug is taken.
+func CreateForumCategory(ctx context.Context, req models.ForumCategoryRequest) (*models.ForumCategory, error) {
+	c, err := scanForumCategory(database.QueryRowContext(ctx,
+		`WITH c AS (
+			INSERT INTO forum_categories (slug, subject, name, name_kk, description, description_kk, order_index, is_archived)
+			VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7, $8)
+			RETURNING *
+		)
+		SELECT `+forumCategoryColumns+` FROM c`,
+		req.Slug, req.Subject, req.Name, req.NameKK, req.Description, req.DescriptionKK, req.OrderIndex, req.IsArchived))
+	if err != nil {
+		return nil, forumCategoryError(err, "creating")
+	}
+	return c, nil
+}
+
+// UpdateForumCategory replaces a category's details. ErrConflict means the slug is taken.
+func UpdateForumCategory(ctx context.Context, categoryID string, req models.ForumCategoryRequest) (*models.ForumCategory, error) {
+	c, err := scanForumCategory(database.QueryRowContext(ctx,
+		`WITH c AS (
+			UPDATE forum_categories SET slug = $2, subject = NULLIF($3, ''), name = $4, name_kk = NULLIF($5, ''),
+				description = NULLIF($6, ''), description_kk = NULLIF($7, ''), order_index = $8, is_archived = $9
+			WHERE id = $1
+			RETURNING *
+		)
+		SELECT `+forumCategoryColumns+` FROM c`,
+		categoryID, req.Slug, req.Subject, req.Name, req.NameKK, req.Description, req.DescriptionKK, req.OrderIndex, req.IsArchived))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, forumCategoryError(err, "updating")
+	}
+	return c, nil
+}
+
+// DeleteForumCategory removes a category without topics. ErrConflict means it still has
+// some; such categories can be archived instead.
+func DeleteForumCategory(ctx context.Context, categoryID string) error {
+	result, err := database.ExecContext(ctx, "DELETE FROM forum_categories WHERE id = $1", categoryID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == foreignKeyViolation {
+			return models.ErrConflict
+		}
+		return fmt.Errorf("error deleting forum category: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+// AwardXP records an XP event and reports whether it is new. An event already recorded for
+// the same user, kind and source is left as it was.
+func AwardXP(ctx context.Context, event models.XPEvent) (bool, error) {
+	var id string
+	err := database.QueryRowContext(ctx,
+		`INSERT INTO xp_events (user_id, kind, source_id, course_id, xp) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, kind, source_id) DO NOTHING
+		RETURNING id`,
+		event.UserID, event.Kind, event.SourceID, event.CourseID, event.XP).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("error awarding xp: %w", err)
+	}
+	return true, nil
+}
+
+// RevokeXP deletes the events of a kind and source, except the one of keepUserID if set, and
+// returns what they had awarded.
+func RevokeXP(ctx context.Context, kind, sourceID, keepUserID string) ([]models.XPEvent, error) {
+	rows, err := database.QueryContext(ctx,
+		`DELETE FROM xp_events WHERE kind = $1 AND source_id = $2 AND user_id::text <> $3
+		RETURNING id, user_id, kind, source_id, course_id, xp, created_at`,
+		kind, sourceID, keepUserID)
+	if err != nil {
+		return nil, fmt.Errorf("error revoking xp: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.XPEvent
+	for rows.Next() {
+		var e models.XPEvent
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Kind, &e.SourceID, &e.CourseID, &e.XP, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning xp event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func GetRecentXPEvents(ctx context.Context, userID string, limit int) ([]models.XPEvent, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT id, user_id, kind, source_id, course_id, xp, created_at FROM xp_events
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2`,
+		userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error getting xp events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.XPEvent{}
+	for rows.Next() {
+		var e models.XPEvent
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Kind, &e.SourceID, &e.CourseID, &e.XP, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning xp event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// GetGamificationStats reads the totals badge rules are evaluated against, except the streak.
+// Attempts flagged by the integrity check do not count, as for XP and the leaderboards.
+func GetGamificationStats(ctx context.Context, userID string) (*models.GamificationStats, error) {
+	var stats models.GamificationStats
+	var scores []int64
+	err := database.QueryRowContext(ctx,
+		`SELECT
+			(SELECT COALESCE(SUM(xp), 0) FROM xp_events WHERE user_id = $1),
+			(SELECT COUNT(*) FROM lesson_progress WHERE user_id = $1 AND status = 'completed'),
+			(SELECT COALESCE(array_agg(best), '{}') FROM (
+				SELECT MAX(ta.score) AS best FROM test_attempts ta
+				JOIN tests t ON t.id = ta.test_id
+				WHERE ta.user_id = $1 AND ta.status = 'completed' AND NOT ta.flagged
+				GROUP BY ta.test_id, t.passing_score
+				HAVING MAX(ta.score) >= t.passing_score) passed),
+			(SELECT COUNT(*) FROM xp_events WHERE user_id = $1 AND kind = $2)`,
+		userID, models.XPSolutionAccepted).Scan(&stats.XP, &stats.LessonsCompleted, pq.Array(&scores), &stats.SolutionsAccepted)
+	if err != nil {
+		return nil, fmt.Errorf("error getting gamification stats: %w", err)
+	}
+	for _, s := range scores {
+		stats.TestScores = append(stats.TestScores, int(s))
+	}
+	return &stats, nil
+}
+
+// AwardBadge records a badge and reports whether the user did not have it yet.
+func AwardBadge(ctx context.Context, userID, badgeID string) (bool, error) {
+	result, err := database.ExecContext(ctx,
+		`INSERT INTO user_badges (user_id, badge_id) VALUES ($1, $2)
+		ON CONFLICT (user_id, badge_id) DO NOTHING`,
+		userID, badgeID)
+	if err != nil {
+		return false, fmt.Errorf("error awarding badge: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error awarding badge: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetUserBadges returns when the user earned each of their badges, keyed by badge ID.
+func GetUserBadges(ctx context.Context, userID string) (map[string]time.Time, error) {
+	rows, err := database.QueryContext(ctx,
+		"SELECT badge_id, awarded_at FROM user_badges WHERE user_id = $1", userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting badges: %w", err)
+	}
+	defer rows.Close()
+
+	badges := map[string]time.Time{}
+	for rows.Next() {
+		var id string
+		var awardedAt time.Time
+		if err := rows.Scan(&id, &awardedAt); err != nil {
+			return nil, fmt.Errorf("error scanning badge: %w", err)
+		}
+		badges[id] = awardedAt
+	}
+	return badges, rows.Err()
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+func CreateIntegrityEvents(ctx context.Context, events []models.IntegrityEvent) error {
+	tx, err := database.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, event := range events {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO integrity_events (attempt_id, event_type, details, occurred_at) VALUES ($1, $2, $3, $4)`,
+			event.AttemptID, event.EventType, event.Details, event.OccurredAt)
+		if err != nil {
+			return fmt.Errorf("error storing integrity event: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func GetIntegrityEvents(ctx context.Context, attemptID string) ([]models.IntegrityEvent, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT id, attempt_id, event_type, COALESCE(details, ''), occurred_at
+		FROM integrity_events WHERE attempt_id = $1 ORDER BY occurred_at, created_at`,
+		attemptID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting integrity events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.IntegrityEvent
+	for rows.Next() {
+		var e models.IntegrityEvent
+		if err := rows.Scan(&e.ID, &e.AttemptID, &e.EventType, &e.Details, &e.OccurredAt); err != nil {
+			return nil, fmt.Errorf("error scanning integrity event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func UpdateAttemptIntegrity(ctx context.Context, attemptID string, score int, flagged bool) (*models.TestAttempt, error) {
+	var attempt models.TestAttempt
+	err := scanAttempt(database.QueryRowContext(ctx,
+		`UPDATE test_attempts SET suspicion_score = $2, flagged = flagged OR $3
+		WHERE id = $1
+		RETURNING `+attemptColumns,
+		attemptID, score, flagged), &attempt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error updating attempt integrity: %w", err)
+	}
+	return &attempt, nil
+}
+
+// TerminateAttempt ends an in-progress attempt with a zero score. ErrNotFound means the
+// attempt was no longer in progress.
+func TerminateAttempt(ctx context.Context, attemptID string, score int) (*models.TestAttempt, error) {
+	var attempt models.TestAttempt
+	err := scanAttempt(database.QueryRowContext(ctx,
+		`UPDATE test_attempts
+		SET status = 'terminated', completed_at = NOW(), score = 0, suspicion_score = $2, flagged = TRUE,
+			time_spent_seconds = EXTRACT(EPOCH FROM (NOW() - started_at))::int
+		WHERE id = $1 AND status = 'in_progress'
+		RETURNING `+attemptColumns,
+		attemptID, score), &attempt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error terminating attempt: %w", err)
+	}
+	return &attempt, nil
+}
+
+func GetIntegrityPolicy(ctx context.Context, testID string) (string, int, error) {
+	var policy string
+	var threshold int
+	err := database.QueryRowContext(ctx,
+		"SELECT integrity_policy, integrity_threshold FROM tests WHERE id = $1", testID).Scan(&policy, &threshold)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", 0, models.ErrNotFound
+		}
+		return "", 0, fmt.Errorf("error getting integrity policy: %w", err)
+	}
+	return policy, threshold, nil
+}
+
+func UpdateIntegrityPolicy(ctx context.Context, testID string, req models.IntegrityPolicyRequest) error {
+	_, err := database.ExecContext(ctx,
+		"UPDATE tests SET integrity_policy = $2, integrity_threshold = $3, updated_at = NOW() WHERE id = $1",
+		testID, req.Policy, req.Threshold)
+	if err != nil {
+		return fmt.Errorf("error updating integrity policy: %w", err)
+	}
+	return nil
+}
+
+// GetAttemptsBySuspicion lists every attempt of a test, most suspicious first.
+func GetAttemptsBySuspicion(ctx context.Context, testID string) ([]models.TestAttempt, error) {
+	rows, err := database.QueryContext(ctx,
+		"SELECT "+attemptColumns+" FROM test_attempts WHERE test_id = $1 ORDER BY suspicion_score DESC, started_at DESC",
+		testID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting test attempts: %w", err)
+	}
+	defer rows.Close()
+
+	attempts := []models.TestAttempt{}
+	for rows.Next() {
+		var attempt models.TestAttempt
+		if err := scanAttempt(rows, &attempt); err != nil {
+			return nil, fmt.Errorf("error scanning test attempt: %w", err)
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, rows.Err()
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+func GetCompletedAttemptSummaries(ctx context.Context, testID string) ([]models.AttemptSummary, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT ta.id, COALESCE(ta.score, 0), COALESCE(ta.time_spent_seconds, 0),
+		(SELECT COUNT(*) FROM attempt_questions aq WHERE aq.attempt_id = ta.id)
+		FROM test_attempts ta
+		WHERE ta.test_id = $1 AND ta.status = 'completed'`,
+		testID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting completed attempts: %w", err)
+	}
+	defer rows.Close()
+
+	var attempts []models.AttemptSummary
+	for rows.Next() {
+		var a models.AttemptSummary
+		if err := rows.Scan(&a.ID, &a.Score, &a.TimeSpentSeconds, &a.QuestionCount); err != nil {
+			return nil, fmt.Errorf("error scanning completed attempt: %w", err)
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
+
+// GetItemResponses returns every drawn question of every completed attempt of a test,
+// joined with the answer if one was given.
+func GetItemResponses(ctx context.Context, testID string) ([]models.ItemResponse, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT aq.attempt_id, aq.question_id, q.points, ua.id IS NOT NULL, COALESCE(ua.answer, ''), ua.is_correct,
+		COALESCE(ua.points_earned, 0), COALESCE(ua.time_spent_seconds, 0)
+		FROM attempt_questions aq
+		JOIN test_attempts ta ON ta.id = aq.attempt_id
+		JOIN questions q ON q.id = aq.question_id
+		LEFT JOIN user_answers ua ON ua.attempt_id = aq.attempt_id AND ua.question_id = aq.question_id
+		WHERE ta.test_id = $1 AND ta.status = 'completed'`,
+		testID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting item responses: %w", err)
+	}
+	defer rows.Close()
+
+	var responses []models.ItemResponse
+	for rows.Next() {
+		var r models.ItemResponse
+		err := rows.Scan(&r.AttemptID, &r.QuestionID, &r.Points, &r.Answered, &r.Answer, &r.IsCorrect, &r.PointsEarned, &r.TimeSpentSeconds)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning item response: %w", err)
+		}
+		responses = append(responses, r)
+	}
+	return responses, rows.Err()
+}
+
+func SaveItemAnalysis(ctx context.Context, analysis *models.ItemAnalysis) error {
+	items, err := json.Marshal(analysis.Items)
+	if err != nil {
+		return fmt.Errorf("error encoding item statistics: %w", err)
+	}
+
+	_, err = database.ExecContext(ctx,
+		`INSERT INTO test_item_analysis (test_id, attempts_count, cronbach_alpha, items, computed_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (test_id) DO UPDATE
+		SET attempts_count = EXCLUDED.attempts_count, cronbach_alpha = EXCLUDED.cronbach_alpha,
+			items = EXCLUDED.items, computed_at = EXCLUDED.computed_at`,
+		analysis.TestID, analysis.Attempts, analysis.CronbachAlpha, items, analysis.ComputedAt)
+	if err != nil {
+		return fmt.Errorf("error saving item analysis: %w", err)
+	}
+	return nil
+}
+
+func GetItemAnalysis(ctx context.Context, testID string) (*models.ItemAnalysis, error) {
+	analysis := models.ItemAnalysis{TestID: testID}
+	var items []byte
+	err := database.QueryRowContext(ctx,
+		"SELECT attempts_count, cronbach_alpha, items, computed_at FROM test_item_analysis WHERE test_id = $1",
+		testID).Scan(&analysis.Attempts, &analysis.CronbachAlpha, &items, &analysis.ComputedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting item analysis: %w", err)
+	}
+
+	if err := json.Unmarshal(items, &analysis.Items); err != nil {
+		return nil, fmt.Errorf("error decoding item statistics: %w", err)
+	}
+	return &analysis, nil
+}
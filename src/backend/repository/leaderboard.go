@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+// AddLeaderboardPoints adds XP and test scores to each of the user's aggregate rows in keys,
+// creating the rows as needed.
+func AddLeaderboardPoints(ctx context.Context, userID string, keys []models.LeaderboardKey, xp, scoreSum, scoreCount int) error {
+	scopes := make([]string, len(keys))
+	periods := make([]string, len(keys))
+	starts := make([]string, len(keys))
+	for i, k := range keys {
+		scopes[i], periods[i], starts[i] = k.Scope, k.Period, k.PeriodStart.Format("2006-01-02")
+	}
+
+	_, err := database.ExecContext(ctx,
+		`INSERT INTO leaderboard_scores (user_id, scope, period, period_start, xp, score_sum, score_count)
+		SELECT $1, k.scope, k.period, k.period_start::date, $5, $6, $7
+		FROM unnest($2::text[], $3::text[], $4::text[]) AS k(scope, period, period_start)
+		ON CONFLICT (user_id, scope, period, period_start) DO UPDATE SET
+			xp = leaderboard_scores.xp + EXCLUDED.xp,
+			score_sum = leaderboard_scores.score_sum + EXCLUDED.score_sum,
+			score_count = leaderboard_scores.score_count + EXCLUDED.score_count`,
+		userID, pq.Array(scopes), pq.Array(periods), pq.Array(starts), xp, scoreSum, scoreCount)
+	if err != nil {
+		return fmt.Errorf("error updating leaderboard: %w", err)
+	}
+	return nil
+}
+
+// GetLeaderboard ranks the users of a board, leaving out those who opted out, and returns the
+// top q.Limit entries, the requesting user's entry and the number of ranked users. Ties share
+// a rank.
+func GetLeaderboard(ctx context.Context, q models.LeaderboardQuery) ([]models.LeaderboardEntry, *models.LeaderboardEntry, int, error) {
+	value := "ls.xp::float8"
+	scored := "ls.xp > 0"
+	if q.Metric == models.LeaderboardMetricScore {
+		value = "ROUND(ls.score_sum::numeric / ls.score_count, 1)::float8"
+		scored = "ls.score_count > 0"
+	}
+
+	scope, filter := "", ""
+	args := []interface{}{q.Period, q.PeriodStart.Format("2006-01-02"), q.Limit, q.UserID}
+	switch q.Scope {
+	case models.LeaderboardScopeCourse:
+		scope = q.ScopeID
+	case models.LeaderboardScopeClass:
+		args = append(args, q.ScopeID)
+		filter = "AND EXISTS (SELECT 1 FROM class_group_members m WHERE m.group_id = $6 AND m.user_id = ls.user_id)"
+	case models.LeaderboardScopeRegion:
+		args = append(args, q.ScopeID)
+		filter = "AND us.region = $6"
+	}
+	args = append([]interface{}{scope}, args...)
+
+	rows, err := database.QueryContext(ctx,
+		`WITH ranked AS (
+			SELECT ls.user_id, `+value+` AS value,
+				RANK() OVER (ORDER BY `+value+` DESC) AS rank,
+				COUNT(*) OVER () AS participants
+			FROM leaderboard_scores ls
+			LEFT JOIN user_settings us ON us.user_id = ls.user_id
+			WHERE ls.scope = $1 AND ls.period = $2 AND ls.period_start = $3 AND `+scored+`
+				AND NOT COALESCE(us.leaderboard_opt_out, FALSE) `+filter+`
+		)
+		SELECT r.rank, r.user_id, u.first_name, u.last_name, r.value, r.participants
+		FROM ranked r
+		JOIN users u ON u.id = r.user_id
+		WHERE r.rank <= $4 OR r.user_id = $5
+		ORDER BY r.rank, u.last_name, u.first_name`,
+		args...)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("error getting leaderboard: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.LeaderboardEntry{}
+	var me *models.LeaderboardEntry
+	participants := 0
+	for rows.Next() {
+		var e models.LeaderboardEntry
+		if err := rows.Scan(&e.Rank, &e.UserID, &e.FirstName, &e.LastName, &e.Value, &participants); err != nil {
+			return nil, nil, 0, fmt.Errorf("error scanning leaderboard entry: %w", err)
+		}
+		if e.UserID == q.UserID {
+			mine := e
+			me = &mine
+		}
+		// Users tied at the cut-off can put more than q.Limit users within the top q.Limit ranks.
+		if e.Rank <= q.Limit && len(entries) < q.Limit {
+			entries = append(entries, e)
+		}
+	}
+	return entries, me, participants, rows.Err()
+}
+
+func GetUserSettings(ctx context.Context, userID string) (*models.UserSettings, error) {
+	var settings models.UserSettings
+	err := database.QueryRowContext(ctx,
+		"SELECT leaderboard_opt_out, region FROM user_settings WHERE user_id = $1",
+		userID).Scan(&settings.LeaderboardOptOut, &settings.Region)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("error getting user settings: %w", err)
+	}
+	return &settings, nil
+}
+
+func SaveUserSettings(ctx context.Context, userID string, settings models.UserSettings) error {
+	_, err := database.ExecContext(ctx,
+		`INSERT INTO user_settings (user_id, leaderboard_opt_out, region) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET leaderboard_opt_out = $2, region = $3, updated_at = NOW()`,
+		userID, settings.LeaderboardOptOut, settings.Region)
+	if err != nil {
+		return fmt.Errorf("error saving user settings: %w", err)
+	}
+	return nil
+}
@@ -11,13 +11,13 @@ func CreateLesson(c context.Context, courseId string, request models.LessonReque
 	var lesson models.Lesson
 	err := database.QueryRowContext(
 		c,
-		`INSERT INTO lessons (course_id, title, description, content, video_url, order_index, created_at, updated_at) 
-		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW()) 
-		RETURNING id, course_id, title, description, content, video_url, order_index, created_at, updated_at`,
-		courseId, request.Title, request.Description, request.Content, request.VideoURL, request.OrderIndex,
+		`INSERT INTO lessons (course_id, title, description, content, video_url, order_index, is_public, created_at, updated_at) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW()) 
+		RETURNING id, course_id, title, description, content, video_url, order_index, COALESCE(is_public, FALSE), created_at, updated_at`,
+		courseId, request.Title, request.Description, request.Content, request.VideoURL, request.OrderIndex, request.IsPublic,
 	).Scan(
 		&lesson.ID, &lesson.CourseID, &lesson.Title, &lesson.Description,
-		&lesson.Content, &lesson.VideoURL, &lesson.OrderIndex, &lesson.CreatedAt, &lesson.UpdatedAt,
+		&lesson.Content, &lesson.VideoURL, &lesson.OrderIndex, &lesson.IsPublic, &lesson.CreatedAt, &lesson.UpdatedAt,
 	)
 
 	if err != nil {
@@ -42,13 +42,13 @@ func UpdateLesson(c context.Context, lessonId int, courseId string, req models.L
 	err := database.QueryRowContext(
 		c,
 		`UPDATE lessons 
-		SET title = $1, description = $2, content = $3, video_url = $4, order_index = $5, updated_at = NOW() 
+		SET title = $1, description = $2, content = $3, video_url = $4, order_index = $5, is_public = $8, updated_at = NOW() 
 		WHERE id = $6 AND course_id = $7 
-		RETURNING id, course_id, title, description, content, video_url, order_index, created_at, updated_at`,
-		req.Title, req.Description, req.Content, req.VideoURL, req.OrderIndex, lessonId, courseId,
+		RETURNING id, course_id, title, description, content, video_url, order_index, COALESCE(is_public, FALSE), created_at, updated_at`,
+		req.Title, req.Description, req.Content, req.VideoURL, req.OrderIndex, lessonId, courseId, req.IsPublic,
 	).Scan(
 		&lesson.ID, &lesson.CourseID, &lesson.Title, &lesson.Description,
-		&lesson.Content, &lesson.VideoURL, &lesson.OrderIndex, &lesson.CreatedAt, &lesson.UpdatedAt,
+		&lesson.Content, &lesson.VideoURL, &lesson.OrderIndex, &lesson.IsPublic, &lesson.CreatedAt, &lesson.UpdatedAt,
 	)
 
 	if err != nil {
@@ -73,7 +73,7 @@ func DeleteLesson(c context.Context, courseId string, lessonId int) error {
 func GetLessonsByCourseId(c context.Context, courseId string) ([]models.Lesson, error) {
 	rows, err := database.QueryContext(
 		c,
-		`SELECT id, course_id, title, description, content, video_url, order_index, created_at, updated_at 
+		`SELECT id, course_id, title, description, content, video_url, order_index, COALESCE(is_public, FALSE), created_at, updated_at 
 		FROM lessons 
 		WHERE course_id = $1 
 		ORDER BY order_index ASC`,
@@ -90,7 +90,7 @@ func GetLessonsByCourseId(c context.Context, courseId string) ([]models.Lesson,
 		var lesson models.Lesson
 		err := rows.Scan(
 			&lesson.ID, &lesson.CourseID, &lesson.Title, &lesson.Description,
-			&lesson.Content, &lesson.VideoURL, &lesson.OrderIndex, &lesson.CreatedAt, &lesson.UpdatedAt,
+			&lesson.Content, &lesson.VideoURL, &lesson.OrderIndex, &lesson.IsPublic, &lesson.CreatedAt, &lesson.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -104,13 +104,13 @@ func GetLesson(c context.Context, courseId string, lessonId int) (models.Lesson,
 	var lesson models.Lesson
 	err := database.QueryRowContext(
 		c,
-		`SELECT id, course_id, title, description, content, video_url, order_index, created_at, updated_at 
+		`SELECT id, course_id, title, description, content, video_url, order_index, COALESCE(is_public, FALSE), created_at, updated_at 
 		FROM lessons 
 		WHERE id = $1 AND course_id = $2`,
 		lessonId, courseId,
 	).Scan(
 		&lesson.ID, &lesson.CourseID, &lesson.Title, &lesson.Description,
-		&lesson.Content, &lesson.VideoURL, &lesson.OrderIndex, &lesson.CreatedAt, &lesson.UpdatedAt,
+		&lesson.Content, &lesson.VideoURL, &lesson.OrderIndex, &lesson.IsPublic, &lesson.CreatedAt, &lesson.UpdatedAt,
 	)
 
 	if err != nil {
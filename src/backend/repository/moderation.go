@@ -0,0 +1,377 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+// postTable is the table holding posts of the target type.
+func postTable(targetType string) string {
+	if targetType == models.VoteTargetReply {
+		return "discussion_replies"
+	}
+	return "discussions"
+}
+
+// GetPostOwner returns the course and author of a discussion or reply that has not been
+// deleted. The course is empty for posts of the platform forum.
+func GetPostOwner(ctx context.Context, targetType, targetID string) (string, string, error) {
+	query := `SELECT COALESCE(d.course_id::text, ''), COALESCE(d.user_id::text, '') FROM discussions d WHERE d.id = $1`
+	if targetType == models.VoteTargetReply {
+		query = `SELECT COALESCE(d.course_id::text, ''), COALESCE(r.user_id::text, '')
+			FROM discussion_replies r JOIN discussions d ON d.id = r.discussion_id
+			WHERE r.id = $1 AND r.deleted_at IS NULL`
+	}
+
+	var courseID, authorID string
+	if err := database.QueryRowContext(ctx, query, targetID).Scan(&courseID, &authorID); err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", models.ErrNotFound
+		}
+		return "", "", fmt.Errorf("error getting post: %w", err)
+	}
+	return courseID, authorID, nil
+}
+
+// SetPostModerationStatus moves a discussion or reply to a moderation state; reason explains
+// a hold.
+func SetPostModerationStatus(ctx context.Context, targetType, targetID, status, reason string) error {
+	result, err := database.ExecContext(ctx,
+		"UPDATE "+postTable(targetType)+" SET moderation_status = $2, moderation_reason = NULLIF($3, '') WHERE id = $1",
+		targetID, status, reason)
+	if err != nil {
+		return fmt.Errorf("error setting moderation status: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// RestorePost makes a discussion or reply visible and returns the discussion it belongs to.
+// It reports whether the post is shown for the first time, having been held when written.
+func RestorePost(ctx context.Context, targetType, targetID string) (bool, string, error) {
+	discussionColumn := "p.id"
+	if targetType == models.VoteTargetReply {
+		discussionColumn = "p.discussion_id"
+	}
+	table := postTable(targetType)
+
+	var first bool
+	var discussionID string
+	err := database.QueryRowContext(ctx,
+		"UPDATE "+table+` p SET moderation_status = $2, moderation_reason = NULL, announced = TRUE
+		FROM (SELECT id, announced FROM `+table+` WHERE id = $1 FOR UPDATE) old
+		WHERE p.id = old.id
+		RETURNING NOT old.announced, `+discussionColumn,
+		targetID, models.PostVisible).Scan(&first, &discussionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, "", models.ErrNotFound
+		}
+		return false, "", fmt.Errorf("error restoring post: %w", err)
+	}
+	return first, discussionID, nil
+}
+
+func SetDiscussionPinned(ctx context.Context, discussionID string, pinned bool) error {
+	_, err := database.ExecContext(ctx, "UPDATE discussions SET is_pinned = $2 WHERE id = $1", discussionID, pinned)
+	if err != nil {
+		return fmt.Errorf("error pinning discussion: %w", err)
+	}
+	return nil
+}
+
+func SetDiscussionClosed(ctx context.Context, discussionID string, closed bool) error {
+	_, err := database.ExecContext(ctx, "UPDATE discussions SET is_closed = $2 WHERE id = $1", discussionID, closed)
+	if err != nil {
+		return fmt.Errorf("error locking discussion: %w", err)
+	}
+	return nil
+}
+
+// CreateReport records a user's report of a post and returns the number of pending reports
+// of it. Reporting the same post again changes nothing.
+func CreateReport(ctx context.Context, courseID, targetType, targetID, reporterID string, req models.ReportRequest) (int, error) {
+	_, err := database.ExecContext(ctx,
+		`INSERT INTO discussion_reports (course_id, target_type, target_id, reporter_id, reason, details)
+		VALUES (NULLIF($1, '')::uuid, $2, $3, $4, $5, NULLIF($6, ''))
+		ON CONFLICT (reporter_id, target_type, target_id) DO NOTHING`,
+		courseID, targetType, targetID, reporterID, req.Reason, req.Details)
+	if err != nil {
+		return 0, fmt.Errorf("error creating report: %w", err)
+	}
+
+	var pending int
+	err = database.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM discussion_reports WHERE target_type = $1 AND target_id = $2 AND status = 'pending'",
+		targetType, targetID).Scan(&pending)
+	if err != nil {
+		return 0, fmt.Errorf("error counting reports: %w", err)
+	}
+	return pending, nil
+}
+
+// ResolveReports closes the pending reports of a post with the given status.
+func ResolveReports(ctx context.Context, targetType, targetID, status, resolvedBy string) error {
+	_, err := database.ExecContext(ctx,
+		`UPDATE discussion_reports SET status = $3, resolved_by = $4, resolved_at = NOW()
+		WHERE target_type = $1 AND target_id = $2 AND status = 'pending'`,
+		targetType, targetID, status, resolvedBy)
+	if err != nil {
+		return fmt.Errorf("error resolving reports: %w", err)
+	}
+	return nil
+}
+
+// noCourse stands in for the missing course of platform forum bans in their unique index.
+const noCourse = "00000000-0000-0000-0000-000000000000"
+
+// BanForumUser bans a user from the course forum until expiresAt, or for good when nil,
+// replacing an earlier ban.
+func BanForumUser(ctx context.Context, courseID, userID, bannedBy, reason string, expiresAt *time.Time) error {
+	_, err := database.ExecContext(ctx,
+		`INSERT INTO forum_bans (course_id, user_id, banned_by, reason, expires_at)
+		VALUES (NULLIF($1, '')::uuid, $2, $3, NULLIF($4, ''), $5)
+		ON CONFLICT ((COALESCE(course_id, '`+noCourse+`')), user_id) DO UPDATE SET banned_by = $3, reason = NULLIF($4, ''),
+			expires_at = $5, created_at = NOW()`,
+		courseID, userID, bannedBy, reason, expiresAt)
+	if err != nil {
+		return fmt.Errorf("error banning user: %w", err)
+	}
+	return nil
+}
+
+func UnbanForumUser(ctx context.Context, courseID, userID string) error {
+	result, err := database.ExecContext(ctx,
+		"DELETE FROM forum_bans WHERE "+courseScope("course_id", 1)+" AND user_id = $2", courseID, userID)
+	if err != nil {
+		return fmt.Errorf("error unbanning user: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// IsForumBanned reports whether the user is currently banned from the course forum.
+func IsForumBanned(ctx context.Context, courseID, userID string) (bool, error) {
+	var banned bool
+	err := database.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM forum_bans WHERE `+courseScope("course_id", 1)+` AND user_id = $2
+			AND (expires_at IS NULL OR expires_at > NOW()))`,
+		courseID, userID).Scan(&banned)
+	if err != nil {
+		return false, fmt.Errorf("error checking forum ban: %w", err)
+	}
+	return banned, nil
+}
+
+// GetForumBans returns the course's current bans, newest first.
+func GetForumBans(ctx context.Context, courseID string) ([]models.ForumBan, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT COALESCE(b.course_id::text, ''), b.user_id, u.first_name || ' ' || u.last_name, COALESCE(b.banned_by::text, ''),
+			COALESCE(b.reason, ''), b.expires_at, b.created_at
+		FROM forum_bans b
+		JOIN users u ON u.id = b.user_id
+		WHERE `+courseScope("b.course_id", 1)+` AND (b.expires_at IS NULL OR b.expires_at > NOW())
+		ORDER BY b.created_at DESC`,
+		courseID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting forum bans: %w", err)
+	}
+	defer rows.Close()
+
+	bans := []models.ForumBan{}
+	for rows.Next() {
+		var b models.ForumBan
+		if err := rows.Scan(&b.CourseID, &b.UserID, &b.UserName, &b.BannedBy, &b.Reason, &b.ExpiresAt, &b.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning forum ban: %w", err)
+		}
+		bans = append(bans, b)
+	}
+	return bans, rows.Err()
+}
+
+func AddModerationLog(ctx context.Context, entry models.ModerationLogEntry) error {
+	_, err := database.ExecContext(ctx,
+		`INSERT INTO moderation_log (course_id, moderator_id, action, target_type, target_id, target_user_id, reason)
+		VALUES (NULLIF($1, '')::uuid, $2, $3, $4, $5, $6, NULLIF($7, ''))`,
+		entry.CourseID, entry.ModeratorID, entry.Action, entry.TargetType, entry.TargetID, entry.TargetUserID, entry.Reason)
+	if err != nil {
+		return fmt.Errorf("error adding moderation log entry: %w", err)
+	}
+	return nil
+}
+
+// GetModerationLog returns one page of the moderation log of a course, or of all courses when
+// courseID is empty, newest first, with the total number of entries.
+func GetModerationLog(ctx context.Context, courseID string, limit, offset int) ([]models.ModerationLogEntry, int, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT l.id, COALESCE(l.course_id::text, ''), l.moderator_id, COALESCE(u.first_name || ' ' || u.last_name, ''), l.action,
+			COALESCE(l.target_type, ''), l.target_id, l.target_user_id, COALESCE(l.reason, ''), l.created_at,
+			COUNT(*) OVER ()
+		FROM moderation_log l
+		LEFT JOIN users u ON u.id = l.moderator_id
+		WHERE $1 = '' OR l.course_id::text = $1
+		ORDER BY l.created_at DESC, l.id
+		LIMIT $2 OFFSET $3`,
+		courseID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting moderation log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.ModerationLogEntry{}
+	total := 0
+	for rows.Next() {
+		var e models.ModerationLogEntry
+		err := rows.Scan(&e.ID, &e.CourseID, &e.ModeratorID, &e.ModeratorName, &e.Action,
+			&e.TargetType, &e.TargetID, &e.TargetUserID, &e.Reason, &e.CreatedAt, &total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning moderation log entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	total, err = pageTotal(ctx, len(entries), offset, total, "moderation log", "SELECT COUNT(*) FROM moderation_log l WHERE $1 = '' OR l.course_id::text = $1", courseID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// moderationQueue selects the posts of a course ($1, or all courses when empty) that are held
+// or have pending reports.
+const moderationQueue = `WITH pending AS (
+		SELECT target_type, target_id, COUNT(*) AS reports, array_agg(DISTINCT reason) AS reasons,
+			MAX(created_at) AS last_reported_at
+		FROM discussion_reports WHERE status = 'pending'
+		GROUP BY target_type, target_id
+	), posts AS (
+		SELECT 'discussion' AS target_type, d.id, COALESCE(d.course_id::text, '') AS course_id, d.id AS discussion_id, d.title, d.user_id, d.content,
+			d.moderation_status, d.moderation_reason, d.created_at
+		FROM discussions d
+		WHERE $1 = '' OR d.course_id::text = $1
+		UNION ALL
+		SELECT 'reply', r.id, COALESCE(d.course_id::text, ''), d.id, d.title, r.user_id, r.content,
+			r.moderation_status, r.moderation_reason, r.created_at
+		FROM discussion_replies r JOIN discussions d ON d.id = r.discussion_id
+		WHERE r.deleted_at IS NULL AND ($1 = '' OR d.course_id::text = $1)
+	), queue AS (
+		SELECT p.*, COALESCE(pe.reports, 0) AS reports, pe.reasons, pe.last_reported_at
+		FROM posts p
+		LEFT JOIN pending pe ON pe.target_type = p.target_type AND pe.target_id = p.id
+		WHERE p.moderation_status = 'held' OR pe.reports > 0
+	)`
+
+// GetModerationQueue returns one page of the posts waiting for a moderator, the longest
+// waiting first, with the total number of them.
+func GetModerationQueue(ctx context.Context, courseID string, limit, offset int) ([]models.ModerationQueueItem, int, error) {
+	rows, err := database.QueryContext(ctx,
+		moderationQueue+`
+		SELECT q.target_type, q.id, q.course_id, q.discussion_id, q.title, COALESCE(q.user_id::text, ''),
+			COALESCE(u.first_name || ' ' || u.last_name, ''), q.content, q.moderation_status,
+			COALESCE(q.moderation_reason, ''), q.reports, q.reasons, q.last_reported_at, q.created_at,
+			COUNT(*) OVER ()
+		FROM queue q
+		LEFT JOIN users u ON u.id = q.user_id
+		ORDER BY COALESCE(q.last_reported_at, q.created_at), q.id
+		LIMIT $2 OFFSET $3`,
+		courseID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting moderation queue: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.ModerationQueueItem{}
+	total := 0
+	for rows.Next() {
+		var item models.ModerationQueueItem
+		reasons := []string{}
+		err := rows.Scan(&item.TargetType, &item.TargetID, &item.CourseID, &item.DiscussionID, &item.DiscussionTitle,
+			&item.AuthorID, &item.AuthorName, &item.Content, &item.ModerationStatus, &item.ModerationReason,
+			&item.ReportCount, pq.Array(&reasons), &item.LastReportedAt, &item.CreatedAt, &total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning moderation queue item: %w", err)
+		}
+		item.ReportReasons = reasons
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	total, err = pageTotal(ctx, len(items), offset, total, "moderation queue", moderationQueue+" SELECT COUNT(*) FROM queue", courseID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// CountRecentPosts returns how many discussions and replies the user wrote with exactly this
+// content since the given time.
+func CountRecentPosts(ctx context.Context, userID, content string, since time.Time) (int, error) {
+	var n int
+	err := database.QueryRowContext(ctx,
+		`SELECT (SELECT COUNT(*) FROM discussions WHERE user_id = $1 AND content = $2 AND created_at >= $3)
+			+ (SELECT COUNT(*) FROM discussion_replies WHERE user_id = $1 AND content = $2 AND created_at >= $3)`,
+		userID, content, since).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("error counting recent posts: %w", err)
+	}
+	return n, nil
+}
+
+func GetModerationWords(ctx context.Context) ([]models.ModerationWord, error) {
+	rows, err := database.QueryContext(ctx,
+		"SELECT id, language, word, created_at FROM moderation_words ORDER BY language, word")
+	if err != nil {
+		return nil, fmt.Errorf("error getting moderation words: %w", err)
+	}
+	defer rows.Close()
+
+	words := []models.ModerationWord{}
+	for rows.Next() {
+		var w models.ModerationWord
+		if err := rows.Scan(&w.ID, &w.Language, &w.Word, &w.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning moderation word: %w", err)
+		}
+		words = append(words, w)
+	}
+	return words, rows.Err()
+}
+
+// AddModerationWord adds a word to the filter; adding a word twice returns the existing one.
+func AddModerationWord(ctx context.Context, language, word, createdBy string) (*models.ModerationWord, error) {
+	var w models.ModerationWord
+	err := database.QueryRowContext(ctx,
+		`INSERT INTO moderation_words (language, word, created_by) VALUES ($1, $2, $3)
+		ON CONFLICT (language, word) DO UPDATE SET word = EXCLUDED.word
+		RETURNING id, language, word, created_at`,
+		language, word, createdBy).Scan(&w.ID, &w.Language, &w.Word, &w.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error adding moderation word: %w", err)
+	}
+	return &w, nil
+}
+
+func DeleteModerationWord(ctx context.Context, id string) error {
+	result, err := database.ExecContext(ctx, "DELETE FROM moderation_words WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("error deleting moderation word: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+const notificationColumns = `n.id, n.user_id, n.type, n.title, n.body, COALESCE(n.link, ''), n.data,
+	COALESCE(n.group_key, ''), n.read_at, n.created_at`
+
+func scanNotification(row rowScanner) (*models.Notification, error) {
+	var n models.Notification
+	var data []byte
+	err := row.Scan(&n.ID, &n.UserID, &n.Type, &n.Title, &n.Body, &n.Link, &data, &n.GroupKey, &n.ReadAt, &n.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	n.Data = data
+	return &n, nil
+}
+
+// SaveNotification stores a notification. One with a group key replaces the user's unread
+// notification of the same group, which moves to the top.
+func SaveNotification(ctx context.Context, n models.Notification) (*models.Notification, error) {
+	data := []byte(n.Data)
+	if len(data) == 0 {
+		data = []byte("{}")
+	}
+	saved, err := scanNotification(database.QueryRowContext(ctx,
+		`WITH n AS (
+			INSERT INTO notifications (user_id, type, title, body, link, data, group_key)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, NULLIF($7, ''))
+			ON CONFLICT (user_id, group_key) WHERE read_at IS NULL
+			DO UPDATE SET title = EXCLUDED.title, body = EXCLUDED.body, link = EXCLUDED.link,
+				data = EXCLUDED.data, created_at = NOW()
+			RETURNING *
+		)
+		SELECT `+notificationColumns+` FROM n`,
+		n.UserID, n.Type, n.Title, n.Body, n.Link, data, n.GroupKey))
+	if err != nil {
+		return nil, fmt.Errorf("error saving notification: %w", err)
+	}
+	return saved, nil
+}
+
+// GetUnreadGroupNotification returns the user's unread notification of a group.
+func GetUnreadGroupNotification(ctx context.Context, userID, groupKey string) (*models.Notification, error) {
+	n, err := scanNotification(database.QueryRowContext(ctx,
+		"SELECT "+notificationColumns+" FROM notifications n WHERE n.user_id = $1 AND n.group_key = $2 AND n.read_at IS NULL",
+		userID, groupKey))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting notification: %w", err)
+	}
+	return n, nil
+}
+
+// GetNotificationPage returns one page of the user's notifications, newest first, only the
+// unread ones when unreadOnly is set, with the total number of them.
+func GetNotificationPage(ctx context.Context, userID string, unreadOnly bool, limit, offset int) ([]models.Notification, int, error) {
+	filter := "n.user_id = $1 AND (NOT $2 OR n.read_at IS NULL)"
+	rows, err := database.QueryContext(ctx,
+		"SELECT "+notificationColumns+", COUNT(*) OVER () FROM notifications n WHERE "+filter+
+			" ORDER BY n.created_at DESC, n.id LIMIT $3 OFFSET $4",
+		userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	total := 0
+	for rows.Next() {
+		var n models.Notification
+		var data []byte
+		err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.Title, &n.Body, &n.Link, &data, &n.GroupKey, &n.ReadAt, &n.CreatedAt, &total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning notification: %w", err)
+		}
+		n.Data = data
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	total, err = pageTotal(ctx, len(notifications), offset, total, "notifications", "SELECT COUNT(*) FROM notifications n WHERE "+filter, userID, unreadOnly)
+	if err != nil {
+		return nil, 0, err
+	}
+	return notifications, total, nil
+}
+
+func CountUnreadNotifications(ctx context.Context, userID string) (int, error) {
+	var unread int
+	err := database.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL", userID).Scan(&unread)
+	if err != nil {
+		return 0, fmt.Errorf("error counting unread notifications: %w", err)
+	}
+	return unread, nil
+}
+
+func MarkNotificationRead(ctx context.Context, userID, notificationID string) error {
+	result, err := database.ExecContext(ctx,
+		"UPDATE notifications SET read_at = COALESCE(read_at, NOW()) WHERE id = $1 AND user_id = $2",
+		notificationID, userID)
+	if err != nil {
+		return fmt.Errorf("error marking notification read: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// MarkAllNotificationsRead marks the user's unread notifications read and returns how many
+// there were.
+func MarkAllNotificationsRead(ctx context.Context, userID string) (int64, error) {
+	result, err := database.ExecContext(ctx,
+		"UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL", userID)
+	if err != nil {
+		return 0, fmt.Errorf("error marking notifications read: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func DeleteNotification(ctx context.Context, userID, notificationID string) error {
+	result, err := database.ExecContext(ctx,
+		"DELETE FROM notifications WHERE id = $1 AND user_id = $2", notificationID, userID)
+	if err != nil {
+		return fmt.Errorf("error deleting notification: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteNotificationsBefore drops notifications created before the given time and returns
+// how many there were.
+func DeleteNotificationsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := database.ExecContext(ctx, "DELETE FROM notifications WHERE created_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting old notifications: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetNotificationPreferences returns the user's stored opt-ins. Types missing from Types use
+// the defaults.
+func GetNotificationPreferences(ctx context.Context, userID string) (*models.NotificationPreferences, error) {
+	prefs := models.NotificationPreferences{EmailNotifications: true, EmailDigest: models.DigestOff}
+	var types []byte
+	err := database.QueryRowContext(ctx,
+		"SELECT COALESCE(email_notifications, TRUE), email_digest, notification_preferences FROM user_settings WHERE user_id = $1",
+		userID).Scan(&prefs.EmailNotifications, &prefs.EmailDigest, &types)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("error getting notification preferences: %w", err)
+	}
+	if len(types) > 0 {
+		if err := json.Unmarshal(types, &prefs.Types); err != nil {
+			return nil, fmt.Errorf("error decoding notification preferences: %w", err)
+		}
+	}
+	return &prefs, nil
+}
+
+func SaveNotificationPreferences(ctx context.Context, userID string, prefs models.NotificationPreferences) error {
+	types, err := json.Marshal(prefs.Types)
+	if err != nil {
+		return err
+	}
+	_, err = database.ExecContext(ctx,
+		`INSERT INTO user_settings (user_id, email_notifications, email_digest, notification_preferences) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET email_notifications = $2, email_digest = $3, notification_preferences = $4, updated_at = NOW()`,
+		userID, prefs.EmailNotifications, prefs.EmailDigest, types)
+	if err != nil {
+		return fmt.Errorf("error saving notification preferences: %w", err)
+	}
+	return nil
+}
+
+// FilterNotificationRecipients returns the users among userIDs who get notifications of the
+// type on the channel: everyone but those who turned it off, and for e-mail those who turned
+// e-mail off altogether.
+func FilterNotificationRecipients(ctx context.Context, userIDs []string, notificationType, channel string) ([]string, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT r.id FROM unnest($1::uuid[]) AS r (id)
+		LEFT JOIN user_settings s ON s.user_id = r.id
+		WHERE COALESCE((s.notification_preferences -> $2 ->> $3)::boolean, TRUE)
+			AND ($3 <> 'email' OR COALESCE(s.email_notifications, TRUE))`,
+		pq.Array(userIDs), notificationType, channel)
+	if err != nil {
+		return nil, fmt.Errorf("error filtering notification recipients: %w", err)
+	}
+	defer rows.Close()
+
+	recipients := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error scanning notification recipient: %w", err)
+		}
+		recipients = append(recipients, id)
+	}
+	return recipients, rows.Err()
+}
+
+// GetUnreadNotificationsSince returns the user's newest unread notifications created after
+// since, or all unread ones when since is nil, with the total number of them.
+func GetUnreadNotificationsSince(ctx context.Context, userID string, since *time.Time, limit int) ([]models.Notification, int, error) {
+	rows, err := database.QueryContext(ctx,
+		"SELECT "+notificationColumns+`, COUNT(*) OVER () FROM notifications n
+		WHERE n.user_id = $1 AND n.read_at IS NULL AND ($2::timestamp IS NULL OR n.created_at > $2)
+		ORDER BY n.created_at DESC, n.id LIMIT $3`,
+		userID, since, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting unread notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	total := 0
+	for rows.Next() {
+		var n models.Notification
+		var data []byte
+		err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.Title, &n.Body, &n.Link, &data, &n.GroupKey, &n.ReadAt, &n.CreatedAt, &total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning notification: %w", err)
+		}
+		n.Data = data
+		notifications = append(notifications, n)
+	}
+	return notifications, total, rows.Err()
+}
+
+// ClaimDigestRecipients returns the users due for an e-mail digest and marks it sent, so
+// concurrent callers don't both send it. A digest is due a day, or a week, after the previous
+// one, less an hour so an hourly check doesn't drift later every time.
+func ClaimDigestRecipients(ctx context.Context) ([]models.DigestRecipient, error) {
+	rows, err := database.QueryContext(ctx,
+		`WITH due AS (
+			SELECT user_id, email_digest, last_digest_at FROM user_settings
+			WHERE email_digest IN ('daily', 'weekly') AND COALESCE(email_notifications, TRUE)
+				AND (last_digest_at IS NULL OR last_digest_at <= NOW() -
+					CASE email_digest WHEN 'daily' THEN INTERVAL '23 hours' ELSE INTERVAL '167 hours' END)
+			FOR UPDATE SKIP LOCKED
+		), claimed AS (
+			UPDATE user_settings s SET last_digest_at = NOW()
+			FROM due WHERE s.user_id = due.user_id
+			RETURNING due.user_id, due.email_digest, due.last_digest_at
+		)
+		SELECT user_id, email_digest, last_digest_at FROM claimed`)
+	if err != nil {
+		return nil, fmt.Errorf("error claiming digest recipients: %w", err)
+	}
+	defer rows.Close()
+
+	recipients := []models.DigestRecipient{}
+	for rows.Next() {
+		var r models.DigestRecipient
+		if err := rows.Scan(&r.UserID, &r.Frequency, &r.Since); err != nil {
+			return nil, fmt.Errorf("error scanning digest recipient: %w", err)
+		}
+		recipients = append(recipients, r)
+	}
+	return recipients, rows.Err()
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+)
+
+// pageTotal returns the total of a page read with COUNT(*) OVER (). Past the last page no row
+// carries the window count, so the total is counted with countQuery instead.
+func pageTotal(ctx context.Context, rows, offset, total int, what, countQuery string, args ...interface{}) (int, error) {
+	if rows > 0 || offset == 0 {
+		return total, nil
+	}
+	if err := database.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("error counting %s: %w", what, err)
+	}
+	return total, nil
+}
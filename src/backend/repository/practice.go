@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+const practiceSessionColumns = `id, user_id, course_id, subject, topic, status, theta, standard_error,
+	question_count, current_question_id, created_at, completed_at`
+
+func scanPracticeSession(row rowScanner) (*models.PracticeSession, error) {
+	var s models.PracticeSession
+	var completedAt sql.NullTime
+	err := row.Scan(&s.ID, &s.UserID, &s.CourseID, &s.Subject, &s.Topic, &s.Status, &s.Theta, &s.StandardError,
+		&s.QuestionCount, &s.CurrentQuestionID, &s.CreatedAt, &completedAt)
+	if err != nil {
+		return nil, err
+	}
+	if completedAt.Valid {
+		s.CompletedAt = &completedAt.Time
+	}
+	return &s, nil
+}
+
+// GetItemParams returns the calibrated parameters of the given questions, keyed by question id.
+// Questions that have not been calibrated yet are absent from the map.
+func GetItemParams(ctx context.Context, questionIDs []string) (map[string]models.ItemParams, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT question_id, model, discrimination, difficulty, responses, calibrated_at
+		FROM irt_item_params WHERE question_id = ANY($1)`,
+		pq.Array(questionIDs))
+	if err != nil {
+		return nil, fmt.Errorf("error getting item parameters: %w", err)
+	}
+	defer rows.Close()
+
+	params := make(map[string]models.ItemParams)
+	for rows.Next() {
+		var p models.ItemParams
+		if err := rows.Scan(&p.QuestionID, &p.Model, &p.Discrimination, &p.Difficulty, &p.Responses, &p.CalibratedAt); err != nil {
+			return nil, fmt.Errorf("error scanning item parameters: %w", err)
+		}
+		params[p.QuestionID] = p
+	}
+	return params, rows.Err()
+}
+
+func SaveItemParams(ctx context.Context, params []models.ItemParams) error {
+	tx, err := database.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, p := range params {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO irt_item_params (question_id, model, discrimination, difficulty, responses, calibrated_at)
+			VALUES ($1, $2, $3, $4, $5, NOW())
+			ON CONFLICT (question_id) DO UPDATE SET model = $2, discrimination = $3, difficulty = $4,
+			responses = $5, calibrated_at = NOW()`,
+			p.QuestionID, p.Model, p.Discrimination, p.Difficulty, p.Responses)
+		if err != nil {
+			return fmt.Errorf("error saving item parameters: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetCalibrationResponses returns every auto-graded answer from completed test attempts and
+// practice sessions.
+func GetCalibrationResponses(ctx context.Context) ([]models.CalibrationResponse, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT ta.user_id, ua.question_id, ua.is_correct
+		FROM user_answers ua
+		JOIN test_attempts ta ON ta.id = ua.attempt_id
+		WHERE ta.status = 'completed' AND ua.is_correct IS NOT NULL
+		UNION ALL
+		SELECT ps.user_id, pr.question_id, pr.is_correct
+		FROM practice_responses pr
+		JOIN practice_sessions ps ON ps.id = pr.session_id`)
+	if err != nil {
+		return nil, fmt.Errorf("error getting calibration responses: %w", err)
+	}
+	defer rows.Close()
+
+	var responses []models.CalibrationResponse
+	for rows.Next() {
+		var r models.CalibrationResponse
+		if err := rows.Scan(&r.UserID, &r.QuestionID, &r.IsCorrect); err != nil {
+			return nil, fmt.Errorf("error scanning calibration response: %w", err)
+		}
+		responses = append(responses, r)
+	}
+	return responses, rows.Err()
+}
+
+func CreatePracticeSession(ctx context.Context, userID, courseID string, req models.PracticeSessionRequest, questionID string) (*models.PracticeSession, error) {
+	session, err := scanPracticeSession(database.QueryRowContext(ctx,
+		`INSERT INTO practice_sessions (user_id, course_id, subject, topic, current_question_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+practiceSessionColumns,
+		userID, courseID, req.Subject, req.Topic, questionID))
+	if err != nil {
+		return nil, fmt.Errorf("error creating practice session: %w", err)
+	}
+	return session, nil
+}
+
+func GetPracticeSession(ctx context.Context, sessionID string) (*models.PracticeSession, error) {
+	session, err := scanPracticeSession(database.QueryRowContext(ctx,
+		"SELECT "+practiceSessionColumns+" FROM practice_sessions WHERE id = $1", sessionID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting practice session: %w", err)
+	}
+	return session, nil
+}
+
+func GetPracticeResponses(ctx context.Context, sessionID string) ([]models.PracticeResponse, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT session_id, question_id, COALESCE(answer, ''), is_correct, theta_after, standard_error_after, answered_at
+		FROM practice_responses WHERE session_id = $1 ORDER BY answered_at`,
+		sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting practice responses: %w", err)
+	}
+	defer rows.Close()
+
+	var responses []models.PracticeResponse
+	for rows.Next() {
+		var r models.PracticeResponse
+		if err := rows.Scan(&r.SessionID, &r.QuestionID, &r.Answer, &r.IsCorrect, &r.ThetaAfter, &r.StandardErrorAfter, &r.AnsweredAt); err != nil {
+			return nil, fmt.Errorf("error scanning practice response: %w", err)
+		}
+		responses = append(responses, r)
+	}
+	return responses, rows.Err()
+}
+
+// SavePracticeAnswer records the answer to the session's current question and moves the session
+// on to nextQuestionID, or completes it when nextQuestionID is nil. ErrNotFound means the
+// question was no longer current, typically because the same answer was submitted twice.
+func SavePracticeAnswer(ctx context.Context, response models.PracticeResponse, nextQuestionID *string) (*models.PracticeSession, error) {
+	tx, err := database.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	session, err := scanPracticeSession(tx.QueryRowContext(ctx,
+		`UPDATE practice_sessions SET theta = $3, standard_error = $4, question_count = question_count + 1,
+		current_question_id = $5,
+		status = CASE WHEN $5::uuid IS NULL THEN 'completed' ELSE status END,
+		completed_at = CASE WHEN $5::uuid IS NULL THEN NOW() ELSE completed_at END
+		WHERE id = $1 AND status = 'in_progress' AND current_question_id = $2
+		RETURNING `+practiceSessionColumns,
+		response.SessionID, response.QuestionID, response.ThetaAfter, response.StandardErrorAfter, nextQuestionID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error updating practice session: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO practice_responses (session_id, question_id, answer, is_correct, theta_after, standard_error_after)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		response.SessionID, response.QuestionID, response.Answer, response.IsCorrect, response.ThetaAfter, response.StandardErrorAfter)
+	if err != nil {
+		return nil, fmt.Errorf("error saving practice response: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing practice answer: %w", err)
+	}
+	return session, nil
+}
+
+func SaveAbility(ctx context.Context, userID string, ability models.Ability) error {
+	_, err := database.ExecContext(ctx,
+		`INSERT INTO student_abilities (user_id, course_id, subject, topic, theta, standard_error, ent_score, ent_max_score, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		ON CONFLICT (user_id, course_id, subject, topic) DO UPDATE SET theta = $5, standard_error = $6,
+		ent_score = $7, ent_max_score = $8, updated_at = NOW()`,
+		userID, ability.CourseID, ability.Subject, ability.Topic, ability.Theta, ability.StandardError,
+		ability.EntScore, ability.EntMaxScore)
+	if err != nil {
+		return fmt.Errorf("error saving ability: %w", err)
+	}
+	return nil
+}
+
+func GetAbilities(ctx context.Context, userID, courseID string) ([]models.Ability, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT course_id, subject, topic, theta, standard_error, ent_score, ent_max_score, updated_at
+		FROM student_abilities WHERE user_id = $1 AND course_id = $2
+		ORDER BY subject, topic`,
+		userID, courseID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting abilities: %w", err)
+	}
+	defer rows.Close()
+
+	abilities := []models.Ability{}
+	for rows.Next() {
+		var a models.Ability
+		if err := rows.Scan(&a.CourseID, &a.Subject, &a.Topic, &a.Theta, &a.StandardError, &a.EntScore, &a.EntMaxScore, &a.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning ability: %w", err)
+		}
+		abilities = append(abilities, a)
+	}
+	return abilities, rows.Err()
+}
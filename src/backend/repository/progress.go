@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+const lessonProgressColumns = `lesson_id, status, COALESCE(time_spent_seconds, 0), COALESCE(last_position_seconds, 0), completion_date`
+
+func scanLessonProgress(row rowScanner) (*models.LessonProgress, error) {
+	var p models.LessonProgress
+	if err := row.Scan(&p.LessonID, &p.Status, &p.TimeSpentSeconds, &p.LastPositionSeconds, &p.CompletionDate); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func GetLessonProgress(ctx context.Context, userID string, lessonID int) (*models.LessonProgress, error) {
+	progress, err := scanLessonProgress(database.QueryRowContext(ctx,
+		"SELECT "+lessonProgressColumns+" FROM lesson_progress WHERE user_id = $1 AND lesson_id = $2",
+		userID, lessonID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting lesson progress: %w", err)
+	}
+	return progress, nil
+}
+
+// RecordLessonHeartbeat stores the player position and adds the time spent since the last
+// heartbeat, never more than the wall-clock time since the row was last updated. The first
+// heartbeat has nothing to measure against and is taken as reported. It returns the seconds
+// actually added. A completed lesson stays completed.
+func RecordLessonHeartbeat(ctx context.Context, userID string, lessonID, position, spent int) (*models.LessonProgress, int, error) {
+	var p models.LessonProgress
+	var added int
+	err := database.QueryRowContext(ctx,
+		`WITH elapsed AS (
+			SELECT LEAST($4, COALESCE((
+				SELECT GREATEST(FLOOR(EXTRACT(EPOCH FROM NOW() - updated_at)), 0)::int
+				FROM lesson_progress WHERE user_id = $1 AND lesson_id = $2), $4)) AS seconds
+		)
+		INSERT INTO lesson_progress (user_id, lesson_id, status, time_spent_seconds, last_position_seconds)
+		SELECT $1, $2, 'in_progress', seconds, $3 FROM elapsed
+		ON CONFLICT (user_id, lesson_id) DO UPDATE SET
+			time_spent_seconds = COALESCE(lesson_progress.time_spent_seconds, 0) + EXCLUDED.time_spent_seconds,
+			last_position_seconds = $3,
+			status = CASE WHEN lesson_progress.status = 'completed' THEN 'completed' ELSE 'in_progress' END,
+			updated_at = NOW()
+		RETURNING `+lessonProgressColumns+`, (SELECT seconds FROM elapsed)`,
+		userID, lessonID, position, spent).
+		Scan(&p.LessonID, &p.Status, &p.TimeSpentSeconds, &p.LastPositionSeconds, &p.CompletionDate, &added)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error recording lesson heartbeat: %w", err)
+	}
+	return &p, added, nil
+}
+
+func CompleteLesson(ctx context.Context, userID string, lessonID int) (*models.LessonProgress, error) {
+	progress, err := scanLessonProgress(database.QueryRowContext(ctx,
+		`INSERT INTO lesson_progress (user_id, lesson_id, status, completion_date)
+		VALUES ($1, $2, 'completed', NOW())
+		ON CONFLICT (user_id, lesson_id) DO UPDATE SET
+			status = 'completed',
+			completion_date = COALESCE(lesson_progress.completion_date, NOW()),
+			updated_at = NOW()
+		RETURNING `+lessonProgressColumns,
+		userID, lessonID))
+	if err != nil {
+		return nil, fmt.Errorf("error completing lesson: %w", err)
+	}
+	return progress, nil
+}
+
+// GetCompletionCounts counts the published lessons and tests of a course and how many of
+// them the student has completed or passed. A test is passed when any completed attempt
+// reached its passing score.
+func GetCompletionCounts(ctx context.Context, userID, courseID string) (*models.CompletionCounts, error) {
+	var counts models.CompletionCounts
+	err := database.QueryRowContext(ctx,
+		`SELECT COALESCE(cs.completion_rule, 'lessons_and_tests'), COALESCE(cs.completion_threshold, 100),
+			(SELECT COUNT(*) FROM lessons l WHERE l.course_id = c.id AND COALESCE(l.is_published, TRUE)),
+			(SELECT COUNT(*) FROM lessons l
+				JOIN lesson_progress lp ON lp.lesson_id = l.id AND lp.user_id = $1 AND lp.status = 'completed'
+				WHERE l.course_id = c.id AND COALESCE(l.is_published, TRUE)),
+			(SELECT COUNT(*) FROM tests t WHERE t.course_id = c.id AND COALESCE(t.is_published, TRUE)),
+			(SELECT COUNT(*) FROM tests t WHERE t.course_id = c.id AND COALESCE(t.is_published, TRUE)
+				AND EXISTS (SELECT 1 FROM test_attempts ta WHERE ta.test_id = t.id AND ta.user_id = $1
+					AND ta.status = 'completed' AND ta.score >= t.passing_score))
+		FROM courses c
+		LEFT JOIN course_settings cs ON cs.course_id = c.id
+		WHERE c.id = $2`,
+		userID, courseID).Scan(&counts.Rule, &counts.Threshold, &counts.Lessons, &counts.LessonsCompleted,
+		&counts.Tests, &counts.TestsPassed)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error counting course completion: %w", err)
+	}
+	return &counts, nil
+}
+
+// SetEnrollmentProgress stores the progress of an enrollment. Once set, completed_at is kept
+// even if progress later drops because lessons were added. It reports whether this completed
+// the course.
+func SetEnrollmentProgress(ctx context.Context, userID, courseID string, progress int, completed bool) (bool, error) {
+	var justCompleted bool
+	err := database.QueryRowContext(ctx,
+		`WITH old AS (
+			SELECT id, completed_at FROM enrollments WHERE user_id = $1 AND course_id = $2 FOR UPDATE
+		)
+		UPDATE enrollments e SET progress = $3,
+			completed_at = CASE WHEN $4 THEN COALESCE(e.completed_at, NOW()) ELSE e.completed_at END
+		FROM old WHERE e.id = old.id
+		RETURNING old.completed_at IS NULL AND e.completed_at IS NOT NULL`,
+		userID, courseID, progress, completed).Scan(&justCompleted)
+	if err != nil && err != sql.ErrNoRows {
+		return false, fmt.Errorf("error updating enrollment progress: %w", err)
+	}
+	return justCompleted, nil
+}
+
+// RecomputeCourseEnrollments recomputes the progress of every enrollment in a course in one
+// statement, with the same rule as services.ComputeCourseProgress, and returns the students
+// it completed the course for.
+func RecomputeCourseEnrollments(ctx context.Context, courseID string) ([]models.CourseCompletion, error) {
+	rows, err := database.QueryContext(ctx,
+		`WITH course AS (
+			SELECT COALESCE(cs.completion_rule, 'lessons_and_tests') AS rule, COALESCE(cs.completion_threshold, 100) AS threshold,
+				(SELECT COUNT(*) FROM lessons l WHERE l.course_id = c.id AND COALESCE(l.is_published, TRUE)) AS lessons,
+				(SELECT COUNT(*) FROM tests t WHERE t.course_id = c.id AND COALESCE(t.is_published, TRUE)) AS tests
+			FROM courses c
+			LEFT JOIN course_settings cs ON cs.course_id = c.id
+			WHERE c.id = $1
+		),
+		done AS (
+			SELECT e.id, e.completed_at,
+				(SELECT COUNT(*) FROM lessons l
+					JOIN lesson_progress lp ON lp.lesson_id = l.id AND lp.user_id = e.user_id AND lp.status = 'completed'
+					WHERE l.course_id = e.course_id AND COALESCE(l.is_published, TRUE)) AS lessons_completed,
+				(SELECT COUNT(*) FROM tests t WHERE t.course_id = e.course_id AND COALESCE(t.is_published, TRUE)
+					AND EXISTS (SELECT 1 FROM test_attempts ta WHERE ta.test_id = t.id AND ta.user_id = e.user_id
+						AND ta.status = 'completed' AND ta.score >= t.passing_score)) AS tests_passed
+			FROM enrollments e
+			WHERE e.course_id = $1
+			FOR UPDATE
+		),
+		totals AS (
+			SELECT d.id, d.completed_at, c.threshold,
+				CASE c.rule WHEN 'lessons' THEN d.lessons_completed WHEN 'tests' THEN d.tests_passed
+					ELSE d.lessons_completed + d.tests_passed END AS done,
+				CASE c.rule WHEN 'lessons' THEN c.lessons WHEN 'tests' THEN c.tests
+					ELSE c.lessons + c.tests END AS total
+			FROM done d, course c
+		),
+		progress AS (
+			SELECT id, completed_at,
+				CASE WHEN total = 0 THEN 0 ELSE done * 100 / total END AS progress,
+				total > 0 AND done * 100 / total >= threshold AS completed
+			FROM totals
+		)
+		UPDATE enrollments e SET progress = p.progress,
+			completed_at = CASE WHEN p.completed THEN COALESCE(e.completed_at, NOW()) ELSE e.completed_at END
+		FROM progress p
+		WHERE e.id = p.id
+		RETURNING e.user_id, p.progress, p.completed_at IS NULL AND p.completed`,
+		courseID)
+	if err != nil {
+		return nil, fmt.Errorf("error recomputing course progress: %w", err)
+	}
+	defer rows.Close()
+
+	var completions []models.CourseCompletion
+	for rows.Next() {
+		var c models.CourseCompletion
+		var justCompleted bool
+		if err := rows.Scan(&c.UserID, &c.Progress, &justCompleted); err != nil {
+			return nil, fmt.Errorf("error scanning course progress: %w", err)
+		}
+		if justCompleted {
+			completions = append(completions, c)
+		}
+	}
+	return completions, rows.Err()
+}
+
+func SaveCompletionRule(ctx context.Context, courseID string, req models.CompletionRuleRequest) error {
+	_, err := database.ExecContext(ctx,
+		`INSERT INTO course_settings (course_id, completion_rule, completion_threshold) VALUES ($1, $2, $3)
+		ON CONFLICT (course_id) DO UPDATE SET completion_rule = $2, completion_threshold = $3, updated_at = NOW()`,
+		courseID, req.Rule, req.Threshold)
+	if err != nil {
+		return fmt.Errorf("error saving completion rule: %w", err)
+	}
+	return nil
+}
+
+func GetTestCourseID(ctx context.Context, testID string) (string, error) {
+	var courseID string
+	err := database.QueryRowContext(ctx, "SELECT course_id FROM tests WHERE id = $1", testID).Scan(&courseID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", models.ErrNotFound
+		}
+		return "", fmt.Errorf("error getting test course: %w", err)
+	}
+	return courseID, nil
+}
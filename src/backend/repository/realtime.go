@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+// RealtimeChannel is the Postgres NOTIFY channel carrying the IDs of new real-time events.
+const RealtimeChannel = "realtime_events"
+
+const realtimeEventColumns = "id, topic, type, data, created_at"
+
+func scanRealtimeEvent(row rowScanner) (*models.RealtimeEvent, error) {
+	var e models.RealtimeEvent
+	var data []byte
+	if err := row.Scan(&e.ID, &e.Topic, &e.Type, &data, &e.CreatedAt); err != nil {
+		return nil, err
+	}
+	e.Data = data
+	return &e, nil
+}
+
+// SaveRealtimeEvent stores an event and, with announce, sends its ID on RealtimeChannel.
+// Saving is serialized by a transaction lock, so IDs commit in ascending order: once an event
+// is visible, so is every event with a lower ID. That keeps the replay cursor of streams safe,
+// and as notifications go out on commit, listeners get them in ID order too.
+func SaveRealtimeEvent(ctx context.Context, topic, eventType string, data []byte, announce bool) (*models.RealtimeEvent, error) {
+	tx, err := database.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", RealtimeChannel); err != nil {
+		return nil, fmt.Errorf("error locking realtime events: %w", err)
+	}
+	event, err := scanRealtimeEvent(tx.QueryRowContext(ctx,
+		"INSERT INTO realtime_events (topic, type, data) VALUES ($1, $2, $3) RETURNING "+realtimeEventColumns,
+		topic, eventType, data))
+	if err != nil {
+		return nil, fmt.Errorf("error saving realtime event: %w", err)
+	}
+	if announce {
+		if _, err := tx.ExecContext(ctx, "SELECT pg_notify($1, $2)", RealtimeChannel, strconv.FormatInt(event.ID, 10)); err != nil {
+			return nil, fmt.Errorf("error notifying realtime event: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error saving realtime event: %w", err)
+	}
+	return event, nil
+}
+
+func GetRealtimeEvent(ctx context.Context, id int64) (*models.RealtimeEvent, error) {
+	event, err := scanRealtimeEvent(database.QueryRowContext(ctx,
+		"SELECT "+realtimeEventColumns+" FROM realtime_events WHERE id = $1", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting realtime event: %w", err)
+	}
+	return event, nil
+}
+
+// GetRealtimeEventsAfter returns up to limit events newer than afterID, oldest first. A nil
+// topics list means events of every topic. IDs commit in order, so no event after afterID
+// can still appear once afterID is visible.
+func GetRealtimeEventsAfter(ctx context.Context, topics []string, afterID int64, limit int) ([]models.RealtimeEvent, error) {
+	query := "SELECT " + realtimeEventColumns + " FROM realtime_events WHERE id > $1"
+	args := []interface{}{afterID, limit}
+	if topics != nil {
+		query += " AND topic = ANY ($3)"
+		args = append(args, pq.Array(topics))
+	}
+	rows, err := database.QueryContext(ctx, query+" ORDER BY id LIMIT $2", args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting realtime events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.RealtimeEvent{}
+	for rows.Next() {
+		event, err := scanRealtimeEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning realtime event: %w", err)
+		}
+		events = append(events, *event)
+	}
+	return events, rows.Err()
+}
+
+// DeleteRealtimeEventsBefore drops events too old to be replayed and returns how many there were.
+func DeleteRealtimeEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := database.ExecContext(ctx, "DELETE FROM realtime_events WHERE created_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting realtime events: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetDiscussionCourseID returns the course of a discussion, empty for platform forum topics.
+func GetDiscussionCourseID(ctx context.Context, discussionID string) (string, error) {
+	var courseID string
+	err := database.QueryRowContext(ctx,
+		"SELECT COALESCE(course_id::text, '') FROM discussions WHERE id = $1", discussionID).Scan(&courseID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", models.ErrNotFound
+		}
+		return "", fmt.Errorf("error getting discussion course: %w", err)
+	}
+	return courseID, nil
+}
@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+// GetRiskSettings returns the course's early warning thresholds, with defaults for courses
+// that never changed them.
+func GetRiskSettings(ctx context.Context, courseID string) (*models.RiskSettings, error) {
+	var s models.RiskSettings
+	err := database.QueryRowContext(ctx,
+		`SELECT COALESCE(cs.risk_inactive_days, 7), COALESCE(cs.risk_missed_lessons, 3), COALESCE(cs.risk_score_drop, 15),
+			COALESCE(cs.risk_pace_gap, 20), COALESCE(cs.risk_threshold, 50), COALESCE(cs.expected_weeks, 12)
+		FROM courses c
+		LEFT JOIN course_settings cs ON cs.course_id = c.id
+		WHERE c.id = $1`,
+		courseID).Scan(&s.InactiveDays, &s.MissedLessons, &s.ScoreDrop, &s.PaceGap, &s.Threshold, &s.ExpectedWeeks)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting risk settings: %w", err)
+	}
+	return &s, nil
+}
+
+func SaveRiskSettings(ctx context.Context, courseID string, s models.RiskSettings) error {
+	_, err := database.ExecContext(ctx,
+		`INSERT INTO course_settings (course_id, risk_inactive_days, risk_missed_lessons, risk_score_drop, risk_pace_gap,
+			risk_threshold, expected_weeks)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (course_id) DO UPDATE SET risk_inactive_days = $2, risk_missed_lessons = $3, risk_score_drop = $4,
+			risk_pace_gap = $5, risk_threshold = $6, expected_weeks = $7, updated_at = NOW()`,
+		courseID, s.InactiveDays, s.MissedLessons, s.ScoreDrop, s.PaceGap, s.Threshold, s.ExpectedWeeks)
+	if err != nil {
+		return fmt.Errorf("error saving risk settings: %w", err)
+	}
+	return nil
+}
+
+// GetRiskInputs loads what every enrollment is scored on in one query.
+func GetRiskInputs(ctx context.Context) ([]models.RiskInput, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT e.user_id, e.course_id, COALESCE(e.enrolled_at, NOW()), e.last_accessed_at, e.completed_at, COALESCE(e.progress, 0),
+			(SELECT COUNT(*) FROM lessons l WHERE l.course_id = e.course_id AND COALESCE(l.is_published, TRUE)),
+			(SELECT COUNT(*) FROM lessons l
+				JOIN lesson_progress lp ON lp.lesson_id = l.id AND lp.user_id = e.user_id AND lp.status = 'completed'
+				WHERE l.course_id = e.course_id AND COALESCE(l.is_published, TRUE)),
+			COALESCE((SELECT array_agg(ta.score ORDER BY ta.completed_at)
+				FROM test_attempts ta JOIN tests t ON t.id = ta.test_id
+				WHERE ta.user_id = e.user_id AND t.course_id = e.course_id
+					AND ta.status = 'completed' AND ta.score IS NOT NULL), '{}')
+		FROM enrollments e
+		ORDER BY e.course_id, e.user_id`)
+	if err != nil {
+		return nil, fmt.Errorf("error getting risk inputs: %w", err)
+	}
+	defer rows.Close()
+
+	var inputs []models.RiskInput
+	for rows.Next() {
+		var in models.RiskInput
+		var scores []int64
+		err := rows.Scan(&in.UserID, &in.CourseID, &in.EnrolledAt, &in.LastAccessedAt, &in.CompletedAt, &in.Progress,
+			&in.Lessons, &in.LessonsCompleted, pq.Array(&scores))
+		if err != nil {
+			return nil, fmt.Errorf("error scanning risk input: %w", err)
+		}
+		for _, s := range scores {
+			in.Scores = append(in.Scores, int(s))
+		}
+		inputs = append(inputs, in)
+	}
+	return inputs, rows.Err()
+}
+
+func SaveEnrollmentRisks(ctx context.Context, risks []models.EnrollmentRisk) error {
+	tx, err := database.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, r := range risks {
+		reasons, err := json.Marshal(r.Reasons)
+		if err != nil {
+			return fmt.Errorf("error encoding risk reasons: %w", err)
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO enrollment_risk (user_id, course_id, score, flagged, reasons, computed_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (user_id, course_id) DO UPDATE SET score = $3, flagged = $4, reasons = $5, computed_at = $6`,
+			r.UserID, r.CourseID, r.Score, r.Flagged, reasons, r.ComputedAt)
+		if err != nil {
+			return fmt.Errorf("error saving enrollment risk: %w", err)
+		}
+	}
+
+	// Scores of students who left a course go with the enrollment.
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM enrollment_risk er WHERE NOT EXISTS
+			(SELECT 1 FROM enrollments e WHERE e.user_id = er.user_id AND e.course_id = er.course_id)`)
+	if err != nil {
+		return fmt.Errorf("error removing stale risk scores: %w", err)
+	}
+	return tx.Commit()
+}
+
+const riskColumns = `er.user_id, er.course_id, u.first_name, u.last_name, u.email, er.score, er.flagged, er.reasons, er.computed_at`
+
+func scanEnrollmentRisk(row rowScanner, r *models.EnrollmentRisk, extra ...interface{}) error {
+	var reasons []byte
+	dest := []interface{}{&r.UserID, &r.CourseID, &r.FirstName, &r.LastName, &r.Email, &r.Score, &r.Flagged, &reasons, &r.ComputedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	return json.Unmarshal(reasons, &r.Reasons)
+}
+
+// GetAtRiskStudents lists the course's flagged students, highest score first.
+func GetAtRiskStudents(ctx context.Context, courseID string) ([]models.EnrollmentRisk, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT `+riskColumns+`
+		FROM enrollment_risk er
+		JOIN users u ON u.id = er.user_id
+		WHERE er.course_id = $1 AND er.flagged
+		ORDER BY er.score DESC, u.last_name, u.first_name`,
+		courseID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting at-risk students: %w", err)
+	}
+	defer rows.Close()
+
+	risks := []models.EnrollmentRisk{}
+	for rows.Next() {
+		var r models.EnrollmentRisk
+		if err := scanEnrollmentRisk(rows, &r); err != nil {
+			return nil, fmt.Errorf("error scanning at-risk student: %w", err)
+		}
+		risks = append(risks, r)
+	}
+	return risks, rows.Err()
+}
+
+// GetFlaggedEnrollments lists every flagged student with the teacher of the course, ordered
+// by teacher and course for grouping into digests.
+func GetFlaggedEnrollments(ctx context.Context) ([]models.FlaggedEnrollment, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT `+riskColumns+`, c.teacher_id, c.title
+		FROM enrollment_risk er
+		JOIN users u ON u.id = er.user_id
+		JOIN courses c ON c.id = er.course_id
+		WHERE er.flagged AND c.teacher_id IS NOT NULL
+		ORDER BY c.teacher_id, c.title, c.id, er.score DESC, u.last_name, u.first_name`)
+	if err != nil {
+		return nil, fmt.Errorf("error getting flagged enrollments: %w", err)
+	}
+	defer rows.Close()
+
+	var flagged []models.FlaggedEnrollment
+	for rows.Next() {
+		var f models.FlaggedEnrollment
+		if err := scanEnrollmentRisk(rows, &f.Risk, &f.TeacherID, &f.CourseTitle); err != nil {
+			return nil, fmt.Errorf("error scanning flagged enrollment: %w", err)
+		}
+		flagged = append(flagged, f)
+	}
+	return flagged, rows.Err()
+}
+
+// CreateRiskDigest stores a teacher's digest for the week. A teacher gets at most one digest
+// per week; an existing one is left as it is.
+func CreateRiskDigest(ctx context.Context, teacherID string, weekStart time.Time, courses []models.RiskDigestCourse) error {
+	body, err := json.Marshal(courses)
+	if err != nil {
+		return fmt.Errorf("error encoding risk digest: %w", err)
+	}
+
+	_, err = database.ExecContext(ctx,
+		`INSERT INTO risk_digests (teacher_id, week_start, courses) VALUES ($1, $2, $3)
+		ON CONFLICT (teacher_id, week_start) DO NOTHING`,
+		teacherID, weekStart.Format("2006-01-02"), body)
+	if err != nil {
+		return fmt.Errorf("error creating risk digest: %w", err)
+	}
+	return nil
+}
+
+const riskDigestColumns = `id, teacher_id, to_char(week_start, 'YYYY-MM-DD'), courses, delivered_at,
+	COALESCE(delivery_error, ''), created_at`
+
+func GetRiskDigests(ctx context.Context, teacherID string, limit int) ([]models.RiskDigest, error) {
+	return queryRiskDigests(ctx,
+		`SELECT `+riskDigestColumns+` FROM risk_digests WHERE teacher_id = $1
+		ORDER BY week_start DESC
+		LIMIT $2`,
+		teacherID, limit)
+}
+
+// GetUndeliveredRiskDigests returns the week's digests that were not delivered yet.
+func GetUndeliveredRiskDigests(ctx context.Context, weekStart time.Time) ([]models.RiskDigest, error) {
+	return queryRiskDigests(ctx,
+		`SELECT `+riskDigestColumns+` FROM risk_digests
+		WHERE week_start = $1 AND delivered_at IS NULL
+		ORDER BY created_at`,
+		weekStart.Format("2006-01-02"))
+}
+
+func queryRiskDigests(ctx context.Context, query string, args ...any) ([]models.RiskDigest, error) {
+	rows, err := database.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting risk digests: %w", err)
+	}
+	defer rows.Close()
+
+	digests := []models.RiskDigest{}
+	for rows.Next() {
+		var d models.RiskDigest
+		var courses []byte
+		if err := rows.Scan(&d.ID, &d.TeacherID, &d.WeekStart, &courses, &d.DeliveredAt, &d.DeliveryError, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning risk digest: %w", err)
+		}
+		if err := json.Unmarshal(courses, &d.Courses); err != nil {
+			return nil, fmt.Errorf("error decoding risk digest: %w", err)
+		}
+		digests = append(digests, d)
+	}
+	return digests, rows.Err()
+}
+
+// SetRiskDigestDelivery records the outcome of delivering a digest: delivered when deliveryErr
+// is nil, otherwise the error is kept for the next try.
+func SetRiskDigestDelivery(ctx context.Context, id string, deliveryErr error) error {
+	var err error
+	if deliveryErr == nil {
+		_, err = database.ExecContext(ctx,
+			`UPDATE risk_digests SET delivered_at = NOW(), delivery_error = NULL WHERE id = $1`, id)
+	} else {
+		_, err = database.ExecContext(ctx,
+			`UPDATE risk_digests SET delivery_error = $2 WHERE id = $1`, id, deliveryErr.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("error recording risk digest delivery: %w", err)
+	}
+	return nil
+}
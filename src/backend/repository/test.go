@@ -0,0 +1,714 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+const questionColumns = `q.id, q.test_id, q.course_id, q.question_text, COALESCE(q.question_text_kk, ''), q.question_type,
+	COALESCE(q.correct_answer, ''), COALESCE(q.explanation, ''), COALESCE(q.explanation_kk, ''), q.points, q.difficulty,
+	COALESCE(q.subject, ''), COALESCE(q.topic, ''), COALESCE(q.is_shared, FALSE), q.archived_at, q.created_at, q.updated_at`
+
+const testColumns = `id, course_id, title, COALESCE(title_kk, ''), COALESCE(description, ''), COALESCE(description_kk, ''),
+	time_limit_minutes, passing_score, is_published, is_public, order_index, integrity_policy, integrity_threshold, due_at, created_at, updated_at`
+
+const attemptColumns = `id, user_id, test_id, started_at, completed_at, score, time_spent_seconds, status, attempt_number,
+	suspicion_score, flagged`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanQuestion(row rowScanner, question *models.Question, extra ...interface{}) error {
+	dest := []interface{}{
+		&question.ID, &question.TestID, &question.CourseID, &question.QuestionText, &question.QuestionTextKK,
+		&question.QuestionType, &question.CorrectAnswer, &question.Explanation, &question.ExplanationKK,
+		&question.Points, &question.Difficulty, &question.Subject, &question.Topic, &question.IsShared,
+		&question.ArchivedAt, &question.CreatedAt, &question.UpdatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+func scanTest(row rowScanner, test *models.Test) error {
+	return row.Scan(&test.ID, &test.CourseID, &test.Title, &test.TitleKK, &test.Description, &test.DescriptionKK,
+		&test.TimeLimitMinutes, &test.PassingScore, &test.IsPublished, &test.IsPublic, &test.OrderIndex, &test.IntegrityPolicy,
+		&test.IntegrityThreshold, &test.DueAt, &test.CreatedAt, &test.UpdatedAt)
+}
+
+func scanAttempt(row rowScanner, attempt *models.TestAttempt) error {
+	return row.Scan(&attempt.ID, &attempt.UserID, &attempt.TestID, &attempt.StartedAt, &attempt.CompletedAt,
+		&attempt.Score, &attempt.TimeSpentSeconds, &attempt.Status, &attempt.AttemptNumber, &attempt.SuspicionScore,
+		&attempt.Flagged)
+}
+
+func GetTestByID(ctx context.Context, courseID, testID string) (*models.Test, error) {
+	var test models.Test
+	err := scanTest(database.QueryRowContext(ctx,
+		"SELECT "+testColumns+" FROM tests WHERE id = $1 AND course_id = $2",
+		testID, courseID), &test)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting test: %w", err)
+	}
+	return &test, nil
+}
+
+// CreateQuestion stores a question together with its options. testID and courseID may be
+// empty: bank questions have no test, shared bank questions have no course.
+func CreateQuestion(ctx context.Context, testID, courseID, createdBy string, req models.QuestionRequest) (*models.Question, error) {
+	tx, err := database.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	question, err := createQuestionTx(ctx, tx, testID, courseID, createdBy, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return question, nil
+}
+
+// CreateTestWithQuestions creates a test and its fixed questions in one transaction.
+func CreateTestWithQuestions(ctx context.Context, courseID, createdBy string, req models.TestRequest, questions []models.QuestionRequest) (*models.Test, error) {
+	tx, err := database.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var test models.Test
+	err = scanTest(tx.QueryRowContext(ctx,
+		`INSERT INTO tests (course_id, title, title_kk, description, description_kk, time_limit_minutes, passing_score, order_index, is_public, due_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING `+testColumns,
+		courseID, req.Title, req.TitleKK, req.Description, req.DescriptionKK, req.TimeLimitMinutes, req.PassingScore, req.OrderIndex,
+		req.IsPublic, req.DueAt), &test)
+	if err != nil {
+		return nil, fmt.Errorf("error creating test: %w", err)
+	}
+
+	for _, q := range questions {
+		if _, err := createQuestionTx(ctx, tx, test.ID, "", createdBy, q); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return &test, nil
+}
+
+// CreateBankQuestions adds several questions to a course bank in one transaction.
+func CreateBankQuestions(ctx context.Context, courseID, createdBy string, questions []models.QuestionRequest) error {
+	tx, err := database.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, q := range questions {
+		if _, err := createQuestionTx(ctx, tx, "", courseID, createdBy, q); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+func createQuestionTx(ctx context.Context, tx *sql.Tx, testID, courseID, createdBy string, req models.QuestionRequest) (*models.Question, error) {
+	var question models.Question
+	err := scanQuestion(tx.QueryRowContext(ctx,
+		`WITH q AS (
+			INSERT INTO questions (test_id, course_id, created_by, question_text, question_text_kk, question_type, correct_answer,
+				explanation, explanation_kk, points, difficulty, subject, topic, is_shared)
+			VALUES (NULLIF($1, '')::uuid, NULLIF($2, '')::uuid, NULLIF($3, '')::uuid, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+			RETURNING *
+		)
+		SELECT `+questionColumns+` FROM q`,
+		testID, courseID, createdBy, req.QuestionText, req.QuestionTextKK, req.QuestionType, req.CorrectAnswer,
+		req.Explanation, req.ExplanationKK, req.Points, req.Difficulty, req.Subject, req.Topic, req.IsShared), &question)
+	if err != nil {
+		return nil, fmt.Errorf("error creating question: %w", err)
+	}
+
+	question.Options, err = insertOptions(ctx, tx, question.ID, req.Options)
+	if err != nil {
+		return nil, err
+	}
+	return &question, nil
+}
+
+// UpdateQuestion edits a question and replaces its options. Attempts and practice sessions
+// refer to questions and options by id, so once a question has been used it is never changed:
+// it is archived and the edit is saved as a new question, whose id the caller gets back.
+func UpdateQuestion(ctx context.Context, questionID string, req models.QuestionRequest) (*models.Question, error) {
+	tx, err := database.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	used, err := lockQuestion(ctx, tx, questionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var question *models.Question
+	if used {
+		question, err = copyQuestionTx(ctx, tx, questionID, req)
+	} else {
+		question, err = updateQuestionTx(ctx, tx, questionID, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return question, nil
+}
+
+func updateQuestionTx(ctx context.Context, tx *sql.Tx, questionID string, req models.QuestionRequest) (*models.Question, error) {
+	var question models.Question
+	err := scanQuestion(tx.QueryRowContext(ctx,
+		`WITH q AS (
+			UPDATE questions
+			SET question_text = $1, question_text_kk = $2, question_type = $3, correct_answer = $4, explanation = $5,
+				explanation_kk = $6, points = $7, difficulty = $8, subject = $9, topic = $10, is_shared = $11, updated_at = NOW()
+			WHERE id = $12
+			RETURNING *
+		)
+		SELECT `+questionColumns+` FROM q`,
+		req.QuestionText, req.QuestionTextKK, req.QuestionType, req.CorrectAnswer, req.Explanation,
+		req.ExplanationKK, req.Points, req.Difficulty, req.Subject, req.Topic, req.IsShared, questionID), &question)
+	if err != nil {
+		return nil, fmt.Errorf("error updating question: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM question_options WHERE question_id = $1", questionID); err != nil {
+		return nil, fmt.Errorf("error deleting question options: %w", err)
+	}
+	question.Options, err = insertOptions(ctx, tx, question.ID, req.Options)
+	if err != nil {
+		return nil, err
+	}
+	return &question, nil
+}
+
+// copyQuestionTx archives a used question and saves the edit as a new question in the same
+// test or bank.
+func copyQuestionTx(ctx context.Context, tx *sql.Tx, questionID string, req models.QuestionRequest) (*models.Question, error) {
+	var testID, courseID, createdBy string
+	err := tx.QueryRowContext(ctx,
+		`UPDATE questions SET archived_at = NOW()
+		WHERE id = $1
+		RETURNING COALESCE(test_id::text, ''), COALESCE(course_id::text, ''), COALESCE(created_by::text, '')`,
+		questionID).Scan(&testID, &courseID, &createdBy)
+	if err != nil {
+		return nil, fmt.Errorf("error archiving question: %w", err)
+	}
+	return createQuestionTx(ctx, tx, testID, courseID, createdBy, req)
+}
+
+// lockQuestion locks a live question for a change and reports whether any attempt or
+// practice session has used it. Storing an attempt question takes a key-share lock on the
+// question, so the check cannot race with an attempt being started.
+func lockQuestion(ctx context.Context, tx *sql.Tx, questionID string) (bool, error) {
+	var used bool
+	err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM attempt_questions WHERE question_id = q.id)
+			OR EXISTS (SELECT 1 FROM user_answers WHERE question_id = q.id)
+			OR EXISTS (SELECT 1 FROM practice_responses WHERE question_id = q.id)
+		FROM questions q
+		WHERE q.id = $1 AND q.archived_at IS NULL
+		FOR UPDATE`,
+		questionID).Scan(&used)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, models.ErrNotFound
+		}
+		return false, fmt.Errorf("error locking question: %w", err)
+	}
+	return used, nil
+}
+
+func insertOptions(ctx context.Context, tx *sql.Tx, questionID string, options []models.QuestionOptionRequest) ([]models.QuestionOption, error) {
+	result := make([]models.QuestionOption, 0, len(options))
+	for i, opt := range options {
+		option := models.QuestionOption{QuestionID: questionID}
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO question_options (question_id, option_text, option_text_kk, is_correct, order_index)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, option_text, COALESCE(option_text_kk, ''), is_correct, order_index`,
+			questionID, opt.OptionText, opt.OptionTextKK, opt.IsCorrect, i).Scan(
+			&option.ID, &option.OptionText, &option.OptionTextKK, &option.IsCorrect, &option.OrderIndex)
+		if err != nil {
+			return nil, fmt.Errorf("error creating question option: %w", err)
+		}
+		result = append(result, option)
+	}
+	return result, nil
+}
+
+// DeleteQuestion removes a question that was never used. A used question is archived instead:
+// it no longer shows up in its test, the bank or draws, but past attempts keep it.
+func DeleteQuestion(ctx context.Context, questionID string) error {
+	tx, err := database.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	used, err := lockQuestion(ctx, tx, questionID)
+	if err != nil {
+		return err
+	}
+
+	if used {
+		_, err = tx.ExecContext(ctx, "UPDATE questions SET archived_at = NOW() WHERE id = $1", questionID)
+	} else {
+		_, err = tx.ExecContext(ctx, "DELETE FROM questions WHERE id = $1", questionID)
+	}
+	if err != nil {
+		return fmt.Errorf("error deleting question: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+func GetQuestionByID(ctx context.Context, questionID string) (*models.Question, error) {
+	var question models.Question
+	err := scanQuestion(database.QueryRowContext(ctx,
+		"SELECT "+questionColumns+" FROM questions q WHERE q.id = $1", questionID), &question)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting question: %w", err)
+	}
+
+	options, err := GetOptionsByQuestionIDs(ctx, []string{questionID})
+	if err != nil {
+		return nil, err
+	}
+	question.Options = options[questionID]
+	return &question, nil
+}
+
+func GetQuestionsByIDs(ctx context.Context, questionIDs []string) ([]models.Question, error) {
+	rows, err := database.QueryContext(ctx,
+		"SELECT "+questionColumns+" FROM questions q WHERE q.id = ANY($1) ORDER BY q.created_at",
+		pq.Array(questionIDs))
+	if err != nil {
+		return nil, fmt.Errorf("error getting questions: %w", err)
+	}
+	defer rows.Close()
+
+	questions, err := scanQuestionRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	return questions, attachOptions(ctx, questions)
+}
+
+// GetBankQuestions lists bank questions visible from a course: its own bank plus the shared one.
+func GetBankQuestions(ctx context.Context, courseID string, filter models.QuestionBankFilter) ([]models.Question, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT `+questionColumns+` FROM questions q
+		WHERE q.test_id IS NULL AND (q.course_id = $1 OR q.is_shared) AND q.archived_at IS NULL
+		AND ($2 = '' OR q.subject = $2) AND ($3 = '' OR q.topic = $3) AND ($4 = '' OR q.difficulty = $4)
+		ORDER BY q.subject, q.topic, q.created_at DESC`,
+		courseID, filter.Subject, filter.Topic, filter.Difficulty)
+	if err != nil {
+		return nil, fmt.Errorf("error getting bank questions: %w", err)
+	}
+	defer rows.Close()
+
+	questions, err := scanQuestionRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	return questions, attachOptions(ctx, questions)
+}
+
+// GetTestQuestions returns the fixed questions attached directly to a test.
+func GetTestQuestions(ctx context.Context, testID string) ([]models.Question, error) {
+	rows, err := database.QueryContext(ctx,
+		"SELECT "+questionColumns+" FROM questions q WHERE q.test_id = $1 AND q.archived_at IS NULL ORDER BY q.created_at",
+		testID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting test questions: %w", err)
+	}
+	defer rows.Close()
+
+	questions, err := scanQuestionRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	return questions, attachOptions(ctx, questions)
+}
+
+func scanQuestionRows(rows *sql.Rows) ([]models.Question, error) {
+	var questions []models.Question
+	for rows.Next() {
+		var question models.Question
+		if err := scanQuestion(rows, &question); err != nil {
+			return nil, fmt.Errorf("error scanning question row: %w", err)
+		}
+		questions = append(questions, question)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating question rows: %w", err)
+	}
+	return questions, nil
+}
+
+func attachOptions(ctx context.Context, questions []models.Question) error {
+	ids := make([]string, len(questions))
+	for i, q := range questions {
+		ids[i] = q.ID
+	}
+	options, err := GetOptionsByQuestionIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range questions {
+		questions[i].Options = options[questions[i].ID]
+	}
+	return nil
+}
+
+func GetOptionsByQuestionIDs(ctx context.Context, questionIDs []string) (map[string][]models.QuestionOption, error) {
+	result := make(map[string][]models.QuestionOption)
+	if len(questionIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := database.QueryContext(ctx,
+		`SELECT id, question_id, option_text, COALESCE(option_text_kk, ''), is_correct, order_index
+		FROM question_options WHERE question_id = ANY($1)
+		ORDER BY question_id, order_index`,
+		pq.Array(questionIDs))
+	if err != nil {
+		return nil, fmt.Errorf("error getting question options: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var option models.QuestionOption
+		if err := rows.Scan(&option.ID, &option.QuestionID, &option.OptionText, &option.OptionTextKK, &option.IsCorrect, &option.OrderIndex); err != nil {
+			return nil, fmt.Errorf("error scanning question option: %w", err)
+		}
+		result[option.QuestionID] = append(result[option.QuestionID], option)
+	}
+	return result, rows.Err()
+}
+
+func GetQuestionRules(ctx context.Context, testID string) ([]models.QuestionRule, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT id, test_id, subject, topic, difficulty, question_count, order_index
+		FROM test_question_rules WHERE test_id = $1 ORDER BY order_index`,
+		testID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting question rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []models.QuestionRule
+	for rows.Next() {
+		var rule models.QuestionRule
+		if err := rows.Scan(&rule.ID, &rule.TestID, &rule.Subject, &rule.Topic, &rule.Difficulty, &rule.QuestionCount, &rule.OrderIndex); err != nil {
+			return nil, fmt.Errorf("error scanning question rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// ReplaceQuestionRules swaps the whole rule set of a test. Attempts already started keep
+// their drawn questions, so changing rules never affects existing results.
+func ReplaceQuestionRules(ctx context.Context, testID string, rules []models.QuestionRuleRequest) ([]models.QuestionRule, error) {
+	tx, err := database.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM test_question_rules WHERE test_id = $1", testID); err != nil {
+		return nil, fmt.Errorf("error deleting question rules: %w", err)
+	}
+
+	result := make([]models.QuestionRule, 0, len(rules))
+	for i, req := range rules {
+		rule := models.QuestionRule{TestID: testID, Subject: req.Subject, Topic: req.Topic, Difficulty: req.Difficulty, QuestionCount: req.QuestionCount, OrderIndex: i}
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO test_question_rules (test_id, subject, topic, difficulty, question_count, order_index)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+			testID, req.Subject, req.Topic, req.Difficulty, req.QuestionCount, i).Scan(&rule.ID)
+		if err != nil {
+			return nil, fmt.Errorf("error creating question rule: %w", err)
+		}
+		result = append(result, rule)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return result, nil
+}
+
+// GetBankQuestionIDs returns, in random order, the ids of the live bank questions a rule
+// can draw from.
+func GetBankQuestionIDs(ctx context.Context, courseID string, filter models.QuestionBankFilter) ([]string, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT id FROM questions
+		WHERE test_id IS NULL AND (course_id = $1 OR is_shared) AND archived_at IS NULL
+		AND ($2 = '' OR subject = $2) AND ($3 = '' OR topic = $3) AND ($4 = '' OR difficulty = $4)
+		ORDER BY random()`,
+		courseID, filter.Subject, filter.Topic, filter.Difficulty)
+	if err != nil {
+		return nil, fmt.Errorf("error getting bank questions: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error scanning bank question: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// createAttemptRetries bounds how often a start is retried when a concurrent start of the
+// same test took the attempt number first.
+const createAttemptRetries = 3
+
+// CreateAttempt opens a new attempt and stores the questions drawn for it in one transaction.
+// Attempt numbers are unique per student and test; a start that loses the race for a number
+// is retried with the next one.
+func CreateAttempt(ctx context.Context, userID, testID string, drawn []models.AttemptQuestion) (*models.TestAttempt, error) {
+	for try := 1; ; try++ {
+		attempt, err := createAttemptTx(ctx, userID, testID, drawn)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Constraint == "idx_test_attempts_number" && try < createAttemptRetries {
+			continue
+		}
+		return attempt, err
+	}
+}
+
+func createAttemptTx(ctx context.Context, userID, testID string, drawn []models.AttemptQuestion) (*models.TestAttempt, error) {
+	tx, err := database.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var attempt models.TestAttempt
+	err = scanAttempt(tx.QueryRowContext(ctx,
+		`INSERT INTO test_attempts (user_id, test_id, status, attempt_number)
+		VALUES ($1, $2, 'in_progress', (SELECT COALESCE(MAX(attempt_number), 0) + 1 FROM test_attempts WHERE user_id = $1 AND test_id = $2))
+		RETURNING `+attemptColumns,
+		userID, testID), &attempt)
+	if err != nil {
+		return nil, fmt.Errorf("error creating test attempt: %w", err)
+	}
+
+	for _, q := range drawn {
+		optionOrder := make([]string, len(q.Options))
+		for i, option := range q.Options {
+			optionOrder[i] = option.ID
+		}
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO attempt_questions (attempt_id, question_id, order_index, option_order) VALUES ($1, $2, $3, $4)`,
+			attempt.ID, q.ID, q.OrderIndex, pq.Array(optionOrder))
+		if err != nil {
+			return nil, fmt.Errorf("error storing attempt question: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	attempt.Questions = drawn
+	return &attempt, nil
+}
+
+func GetAttempt(ctx context.Context, attemptID string) (*models.TestAttempt, error) {
+	var attempt models.TestAttempt
+	err := scanAttempt(database.QueryRowContext(ctx,
+		"SELECT "+attemptColumns+" FROM test_attempts WHERE id = $1", attemptID), &attempt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting test attempt: %w", err)
+	}
+	return &attempt, nil
+}
+
+func GetUserAttempts(ctx context.Context, userID, testID string) ([]models.TestAttempt, error) {
+	rows, err := database.QueryContext(ctx,
+		"SELECT "+attemptColumns+" FROM test_attempts WHERE user_id = $1 AND test_id = $2 ORDER BY attempt_number DESC",
+		userID, testID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting test attempts: %w", err)
+	}
+	defer rows.Close()
+
+	var attempts []models.TestAttempt
+	for rows.Next() {
+		var attempt models.TestAttempt
+		if err := scanAttempt(rows, &attempt); err != nil {
+			return nil, fmt.Errorf("error scanning test attempt: %w", err)
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, rows.Err()
+}
+
+// GetAttemptQuestions loads the questions exactly as they were drawn for an attempt,
+// with options in the stored shuffled order and any answers already given.
+func GetAttemptQuestions(ctx context.Context, attemptID string) ([]models.AttemptQuestion, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT `+questionColumns+`, aq.order_index, aq.option_order
+		FROM attempt_questions aq
+		JOIN questions q ON q.id = aq.question_id
+		WHERE aq.attempt_id = $1
+		ORDER BY aq.order_index`,
+		attemptID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting attempt questions: %w", err)
+	}
+	defer rows.Close()
+
+	var questions []models.AttemptQuestion
+	var orders [][]string
+	for rows.Next() {
+		var q models.AttemptQuestion
+		var optionOrder []string
+		if err := scanQuestion(rows, &q.Question, &q.OrderIndex, pq.Array(&optionOrder)); err != nil {
+			return nil, fmt.Errorf("error scanning attempt question: %w", err)
+		}
+		questions = append(questions, q)
+		orders = append(orders, optionOrder)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating attempt questions: %w", err)
+	}
+
+	ids := make([]string, len(questions))
+	for i, q := range questions {
+		ids[i] = q.ID
+	}
+	options, err := GetOptionsByQuestionIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	answers, err := GetAttemptAnswers(ctx, attemptID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range questions {
+		byID := make(map[string]models.QuestionOption)
+		for _, option := range options[questions[i].ID] {
+			byID[option.ID] = option
+		}
+		ordered := make([]models.QuestionOption, 0, len(orders[i]))
+		for _, id := range orders[i] {
+			if option, ok := byID[id]; ok {
+				ordered = append(ordered, option)
+			}
+		}
+		questions[i].Options = ordered
+		if answer, ok := answers[questions[i].ID]; ok {
+			questions[i].Answer = &answer
+		}
+	}
+	return questions, nil
+}
+
+func GetAttemptAnswers(ctx context.Context, attemptID string) (map[string]models.UserAnswer, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT id, attempt_id, question_id, COALESCE(answer, ''), is_correct, points_earned, time_spent_seconds
+		FROM user_answers WHERE attempt_id = $1`,
+		attemptID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user answers: %w", err)
+	}
+	defer rows.Close()
+
+	answers := make(map[string]models.UserAnswer)
+	for rows.Next() {
+		var answer models.UserAnswer
+		if err := rows.Scan(&answer.ID, &answer.AttemptID, &answer.QuestionID, &answer.Answer, &answer.IsCorrect, &answer.PointsEarned, &answer.TimeSpentSeconds); err != nil {
+			return nil, fmt.Errorf("error scanning user answer: %w", err)
+		}
+		answers[answer.QuestionID] = answer
+	}
+	return answers, rows.Err()
+}
+
+// CompleteAttempt stores graded answers and closes the attempt. It only succeeds once per
+// attempt: a second submission finds the attempt no longer in progress.
+func CompleteAttempt(ctx context.Context, attemptID string, answers []models.UserAnswer, score int) (*models.TestAttempt, error) {
+	tx, err := database.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var attempt models.TestAttempt
+	err = scanAttempt(tx.QueryRowContext(ctx,
+		`UPDATE test_attempts
+		SET status = 'completed', completed_at = NOW(), score = $1,
+			time_spent_seconds = EXTRACT(EPOCH FROM (NOW() - started_at))::int
+		WHERE id = $2 AND status = 'in_progress'
+		RETURNING `+attemptColumns,
+		score, attemptID), &attempt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error completing test attempt: %w", err)
+	}
+
+	for _, answer := range answers {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO user_answers (attempt_id, question_id, answer, is_correct, points_earned, time_spent_seconds) VALUES ($1, $2, $3, $4, $5, $6)`,
+			attemptID, answer.QuestionID, answer.Answer, answer.IsCorrect, answer.PointsEarned, answer.TimeSpentSeconds)
+		if err != nil {
+			return nil, fmt.Errorf("error storing user answer: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return &attempt, nil
+}
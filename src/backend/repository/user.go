@@ -42,8 +42,8 @@ func GetUserByID(ctx context.Context, id string) (*models.User, error) {
 	var user models.User
 
 	err := database.QueryRowContext(ctx,
-		"SELECT id, first_name, last_name, email, role, created_at, updated_at FROM users WHERE id = $1", id).
-		Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+		"SELECT id, first_name, last_name, email, COALESCE(username, ''), role, COALESCE(language_preference, 'ru'), created_at, updated_at FROM users WHERE id = $1", id).
+		Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Username, &user.Role, &user.LanguagePreference, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		return nil, fmt.Errorf("error getting user by id: %w", err)
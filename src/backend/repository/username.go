@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+// uniqueViolation is the Postgres error code for a broken unique constraint.
+const uniqueViolation = "23505"
+
+// SetUsername gives the user a username. ErrConflict means another user has it already,
+// compared case-insensitively.
+func SetUsername(ctx context.Context, userID, username string) error {
+	_, err := database.ExecContext(ctx,
+		"UPDATE users SET username = $2, updated_at = NOW() WHERE id = $1", userID, username)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+			return models.ErrConflict
+		}
+		return fmt.Errorf("error setting username: %w", err)
+	}
+	return nil
+}
+
+// GetUsernamesWithPrefix returns the usernames, lowercased, that start with prefix.
+func GetUsernamesWithPrefix(ctx context.Context, prefix string) ([]string, error) {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(prefix))
+	rows, err := database.QueryContext(ctx,
+		"SELECT LOWER(username) FROM users WHERE LOWER(username) LIKE $1 || '%'", escaped)
+	if err != nil {
+		return nil, fmt.Errorf("error getting usernames: %w", err)
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("error scanning username: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// GetUsersWithoutUsername returns up to limit users that have no username yet.
+func GetUsersWithoutUsername(ctx context.Context, limit int) ([]models.User, error) {
+	rows, err := database.QueryContext(ctx,
+		"SELECT id, first_name, last_name, email FROM users WHERE username IS NULL ORDER BY created_at LIMIT $1", limit)
+	if err != nil {
+		return nil, fmt.Errorf("error getting users without username: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email); err != nil {
+			return nil, fmt.Errorf("error scanning user: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// GetCourseMembersByUsername resolves usernames among the course teacher and its enrolled
+// students, or among all users when courseID is empty, as in the platform forum. The result
+// maps lowercased usernames to user IDs; unknown names are left out.
+func GetCourseMembersByUsername(ctx context.Context, courseID string, usernames []string) (map[string]string, error) {
+	rows, err := database.QueryContext(ctx,
+		`SELECT LOWER(u.username), u.id FROM users u
+		WHERE LOWER(u.username) = ANY ($2)
+			AND ($1 = '' OR u.id = (SELECT teacher_id FROM courses WHERE id = NULLIF($1, '')::uuid)
+				OR EXISTS (SELECT 1 FROM enrollments e WHERE e.user_id = u.id AND e.course_id = NULLIF($1, '')::uuid))`,
+		courseID, pq.Array(usernames))
+	if err != nil {
+		return nil, fmt.Errorf("error resolving usernames: %w", err)
+	}
+	defer rows.Close()
+
+	members := make(map[string]string)
+	for rows.Next() {
+		var name, id string
+		if err := rows.Scan(&name, &id); err != nil {
+			return nil, fmt.Errorf("error scanning course member: %w", err)
+		}
+		members[name] = id
+	}
+	return members, rows.Err()
+}
@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+const webhookColumns = `w.id, w.url, w.secret, w.event_types, COALESCE(w.course_id::text, ''), w.is_active,
+	COALESCE(w.created_by::text, ''), w.created_at, w.updated_at`
+
+const webhookDeliveryColumns = `d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+	d.response_code, COALESCE(d.response_body, ''), COALESCE(d.last_error, ''), d.next_attempt_at, d.delivered_at, d.created_at`
+
+func scanWebhook(row rowScanner) (*models.Webhook, error) {
+	var w models.Webhook
+	var eventTypes pq.StringArray
+	err := row.Scan(&w.ID, &w.URL, &w.Secret, &eventTypes, &w.CourseID, &w.IsActive, &w.CreatedBy, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	w.EventTypes = eventTypes
+	return &w, nil
+}
+
+func scanWebhookDelivery(row rowScanner, extra ...interface{}) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload []byte
+	dest := []interface{}{&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+		&d.ResponseCode, &d.ResponseBody, &d.LastError, &d.NextAttemptAt, &d.DeliveredAt, &d.CreatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	d.Payload = payload
+	return &d, nil
+}
+
+func CreateWebhook(ctx context.Context, createdBy string, req models.WebhookRequest) (*models.Webhook, error) {
+	w, err := scanWebhook(database.QueryRowContext(ctx,
+		`WITH w AS (
+			INSERT INTO webhooks (url, secret, event_types, course_id, is_active, created_by)
+			VALUES ($1, $2, $3, NULLIF($4, '')::uuid, $5, $6)
+			RETURNING *
+		)
+		SELECT `+webhookColumns+` FROM w`,
+		req.URL, req.Secret, pq.Array(req.EventTypes), req.CourseID, req.IsActive == nil || *req.IsActive, createdBy))
+	if err != nil {
+		return nil, fmt.Errorf("error creating webhook: %w", err)
+	}
+	return w, nil
+}
+
+func GetWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	rows, err := database.QueryContext(ctx, "SELECT "+webhookColumns+" FROM webhooks w ORDER BY w.created_at")
+	if err != nil {
+		return nil, fmt.Errorf("error getting webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning webhook: %w", err)
+		}
+		webhooks = append(webhooks, *w)
+	}
+	return webhooks, rows.Err()
+}
+
+func GetWebhook(ctx context.Context, webhookID string) (*models.Webhook, error) {
+	w, err := scanWebhook(database.QueryRowContext(ctx,
+		"SELECT "+webhookColumns+" FROM webhooks w WHERE w.id = $1", webhookID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting webhook: %w", err)
+	}
+	return w, nil
+}
+
+// UpdateWebhook edits a webhook, keeping its secret when req.Secret is empty and its state
+// when req.IsActive is nil.
+func UpdateWebhook(ctx context.Context, webhookID string, req models.WebhookRequest) (*models.Webhook, error) {
+	w, err := scanWebhook(database.QueryRowContext(ctx,
+		`WITH w AS (
+			UPDATE webhooks SET url = $2, secret = COALESCE(NULLIF($3, ''), secret), event_types = $4,
+				course_id = NULLIF($5, '')::uuid, is_active = COALESCE($6, is_active), updated_at = NOW()
+			WHERE id = $1
+			RETURNING *
+		)
+		SELECT `+webhookColumns+` FROM w`,
+		webhookID, req.URL, req.Secret, pq.Array(req.EventTypes), req.CourseID, req.IsActive))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error updating webhook: %w", err)
+	}
+	return w, nil
+}
+
+func DeleteWebhook(ctx context.Context, webhookID string) error {
+	result, err := database.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1", webhookID)
+	if err != nil {
+		return fmt.Errorf("error deleting webhook: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// EnqueueWebhookEvent queues an event for every active webhook subscribed to its type and,
+// when courseID is set, to its course. It returns how many deliveries were queued.
+func EnqueueWebhookEvent(ctx context.Context, eventID, eventType, courseID string, payload []byte) (int64, error) {
+	result, err := database.ExecContext(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+		SELECT w.id, $1, $2, $3 FROM webhooks w
+		WHERE w.is_active AND $2 = ANY (w.event_types)
+			AND (w.course_id IS NULL OR w.course_id = NULLIF($4, '')::uuid)`,
+		eventID, eventType, payload, courseID)
+	if err != nil {
+		return 0, fmt.Errorf("error queueing webhook event: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ClaimWebhookDeliveries takes up to limit pending deliveries of active webhooks that are due,
+// with each webhook's URL and secret, and counts the attempt. They are leased for the given
+// time: if the sender dies before reporting back they become due again.
+func ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, []models.Webhook, error) {
+	rows, err := database.QueryContext(ctx,
+		`WITH d AS (
+			UPDATE webhook_deliveries SET attempts = attempts + 1, next_attempt_at = NOW() + $2::float8 * INTERVAL '1 second'
+			WHERE id IN (
+				SELECT d.id FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+				WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND w.is_active
+				ORDER BY d.next_attempt_at
+				LIMIT $1
+				FOR UPDATE OF d SKIP LOCKED
+			)
+			RETURNING *
+		)
+		SELECT `+webhookDeliveryColumns+`, `+webhookColumns+`
+		FROM d JOIN webhooks w ON w.id = d.webhook_id
+		ORDER BY d.created_at`,
+		limit, lease.Seconds())
+	if err != nil {
+		return nil, nil, fmt.Errorf("error claiming webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		var w models.Webhook
+		var eventTypes pq.StringArray
+		d, err := scanWebhookDelivery(rows, &w.ID, &w.URL, &w.Secret, &eventTypes, &w.CourseID, &w.IsActive,
+			&w.CreatedBy, &w.CreatedAt, &w.UpdatedAt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error scanning webhook delivery: %w", err)
+		}
+		w.EventTypes = eventTypes
+		deliveries = append(deliveries, *d)
+		webhooks = append(webhooks, w)
+	}
+	return deliveries, webhooks, rows.Err()
+}
+
+// RecordWebhookAttempt stores the outcome of an attempt. A delivered one is done; otherwise it
+// is retried at retryAt, or given up on when retryAt is nil. responseCode is nil when no
+// response came back.
+func RecordWebhookAttempt(ctx context.Context, deliveryID string, delivered bool, responseCode *int, responseBody, lastError string, retryAt *time.Time) error {
+	_, err := database.ExecContext(ctx,
+		`UPDATE webhook_deliveries SET response_code = $3, response_body = NULLIF($4, ''), last_error = NULLIF($5, ''),
+			status = CASE WHEN $2 THEN 'delivered' WHEN $6::timestamp IS NULL THEN 'failed' ELSE 'pending' END,
+			delivered_at = CASE WHEN $2 THEN NOW() ELSE delivered_at END,
+			next_attempt_at = COALESCE($6, next_attempt_at)
+		WHERE id = $1`,
+		deliveryID, delivered, responseCode, responseBody, lastError, retryAt)
+	if err != nil {
+		return fmt.Errorf("error recording webhook attempt: %w", err)
+	}
+	return nil
+}
+
+// GetWebhookDeliveryPage returns one page of a webhook's deliveries, newest first, with the
+// total number of them.
+func GetWebhookDeliveryPage(ctx context.Context, webhookID string, limit, offset int) ([]models.WebhookDelivery, int, error) {
+	rows, err := database.QueryContext(ctx,
+		"SELECT "+webhookDeliveryColumns+`, COUNT(*) OVER () FROM webhook_deliveries d
+		WHERE d.webhook_id = $1
+		ORDER BY d.created_at DESC, d.id LIMIT $2 OFFSET $3`,
+		webhookID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	total := 0
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows, &total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	total, err = pageTotal(ctx, len(deliveries), offset, total, "webhook deliveries", "SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = $1", webhookID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+// RedeliverWebhookDelivery queues a fresh copy of a delivery of the webhook, keeping the
+// original and its history.
+func RedeliverWebhookDelivery(ctx context.Context, webhookID, deliveryID string) (*models.WebhookDelivery, error) {
+	d, err := scanWebhookDelivery(database.QueryRowContext(ctx,
+		`WITH d AS (
+			INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+			SELECT webhook_id, event_id, event_type, payload FROM webhook_deliveries
+			WHERE id = $1 AND webhook_id = $2
+			RETURNING *
+		)
+		SELECT `+webhookDeliveryColumns+` FROM d`,
+		deliveryID, webhookID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("error redelivering webhook delivery: %w", err)
+	}
+	return d, nil
+}
+
+// DeleteWebhookDeliveriesBefore drops finished deliveries created before the given time and
+// returns how many there were.
+func DeleteWebhookDeliveriesBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := database.ExecContext(ctx,
+		"DELETE FROM webhook_deliveries WHERE status <> 'pending' AND created_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting old webhook deliveries: %w", err)
+	}
+	return result.RowsAffected()
+}
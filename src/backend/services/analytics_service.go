@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+const (
+	scoreBucketWidth = 10
+	// dropOffPoints is how many lessons with the largest drop-off the report highlights.
+	dropOffPoints = 3
+)
+
+type analyticsEntry struct {
+	analytics *models.CourseAnalytics
+	expires   time.Time
+}
+
+var (
+	analyticsCacheMu sync.Mutex
+	analyticsCache   = map[string]analyticsEntry{}
+)
+
+// InvalidateCourseAnalytics drops the cached report of a course, so the next request
+// recomputes it.
+func InvalidateCourseAnalytics(courseID string) {
+	analyticsCacheMu.Lock()
+	delete(analyticsCache, courseID)
+	analyticsCacheMu.Unlock()
+}
+
+// GetCourseAnalytics returns the course-wide report, from the cache when it is younger than
+// ANALYTICS_CACHE_TTL (10m by default) unless refresh is set. The returned value is shared
+// with the cache and must not be modified.
+func GetCourseAnalytics(ctx context.Context, courseID string, refresh bool) (*models.CourseAnalytics, error) {
+	if !refresh {
+		analyticsCacheMu.Lock()
+		entry, ok := analyticsCache[courseID]
+		analyticsCacheMu.Unlock()
+		if ok && time.Now().Before(entry.expires) {
+			return entry.analytics, nil
+		}
+	}
+
+	analytics, err := computeCourseAnalytics(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	analyticsCacheMu.Lock()
+	analyticsCache[courseID] = analyticsEntry{
+		analytics: analytics,
+		expires:   time.Now().Add(envDuration("ANALYTICS_CACHE_TTL", 10*time.Minute)),
+	}
+	analyticsCacheMu.Unlock()
+	return analytics, nil
+}
+
+func computeCourseAnalytics(ctx context.Context, courseID string) (*models.CourseAnalytics, error) {
+	total, completed, err := repository.GetEnrollmentCounts(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	enrollments, err := repository.GetWeeklyEnrollments(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	funnel, err := repository.GetLessonFunnel(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	scores, err := repository.GetCourseTestScores(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	discussions, err := repository.GetDiscussionActivity(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	analytics := &models.CourseAnalytics{
+		CourseID:          courseID,
+		TotalStudents:     total,
+		CompletedStudents: completed,
+		Enrollments:       enrollments,
+		LessonFunnel:      funnel,
+		Discussions:       *discussions,
+		GeneratedAt:       time.Now(),
+	}
+	if total > 0 {
+		analytics.CompletionRate = round2(float64(completed) / float64(total))
+	}
+
+	running := 0
+	for i := range analytics.Enrollments {
+		running += analytics.Enrollments[i].New
+		analytics.Enrollments[i].Total = running
+	}
+
+	analytics.DropOffPoints = buildLessonFunnel(analytics.LessonFunnel, total)
+
+	all := make([]int, len(scores))
+	byTest := map[string][]int{}
+	var tests []models.TestScoreSummary
+	for i, s := range scores {
+		all[i] = s.Score
+		if _, ok := byTest[s.TestID]; !ok {
+			tests = append(tests, models.TestScoreSummary{TestID: s.TestID, Title: s.Title})
+		}
+		byTest[s.TestID] = append(byTest[s.TestID], s.Score)
+	}
+	analytics.AverageScore, analytics.MedianScore = scoreSummary(all)
+	analytics.ScoreDistribution = scoreHistogram(all)
+	for i := range tests {
+		values := byTest[tests[i].TestID]
+		tests[i].Attempts = len(values)
+		tests[i].AverageScore, tests[i].MedianScore = scoreSummary(values)
+	}
+	analytics.Tests = tests
+	if analytics.Tests == nil {
+		analytics.Tests = []models.TestScoreSummary{}
+	}
+
+	return analytics, nil
+}
+
+// buildLessonFunnel fills in completion and drop-off rates along the lesson order and returns
+// the lessons where most students dropped off. The first lesson is measured against all
+// enrolled students.
+func buildLessonFunnel(steps []models.LessonFunnelStep, students int) []models.LessonFunnelStep {
+	previous := students
+	for i := range steps {
+		if students > 0 {
+			steps[i].CompletionRate = round2(float64(steps[i].Completed) / float64(students))
+		}
+		if previous > 0 && steps[i].Completed < previous {
+			steps[i].DropOff = round2(float64(previous-steps[i].Completed) / float64(previous))
+		}
+		previous = steps[i].Completed
+	}
+
+	var worst []models.LessonFunnelStep
+	for _, s := range steps {
+		if s.DropOff > 0 {
+			worst = append(worst, s)
+		}
+	}
+	sort.SliceStable(worst, func(i, j int) bool { return worst[i].DropOff > worst[j].DropOff })
+	if len(worst) > dropOffPoints {
+		worst = worst[:dropOffPoints]
+	}
+	if worst == nil {
+		worst = []models.LessonFunnelStep{}
+	}
+	return worst
+}
+
+// scoreSummary returns the mean and median of scores, 0 when there are none.
+func scoreSummary(scores []int) (float64, float64) {
+	if len(scores) == 0 {
+		return 0, 0
+	}
+	sorted := append([]int(nil), scores...)
+	sort.Ints(sorted)
+
+	sum := 0
+	for _, s := range sorted {
+		sum += s
+	}
+	mid := len(sorted) / 2
+	median := float64(sorted[mid])
+	if len(sorted)%2 == 0 {
+		median = float64(sorted[mid-1]+sorted[mid]) / 2
+	}
+	return round2(float64(sum) / float64(len(sorted))), median
+}
+
+// scoreHistogram counts scores in buckets of ten points, 0-9 up to 90-100.
+func scoreHistogram(scores []int) []models.ScoreBucket {
+	buckets := make([]models.ScoreBucket, 100/scoreBucketWidth)
+	for i := range buckets {
+		buckets[i] = models.ScoreBucket{From: i * scoreBucketWidth, To: (i+1)*scoreBucketWidth - 1}
+	}
+	buckets[len(buckets)-1].To = 100
+
+	for _, s := range scores {
+		i := int(math.Max(0, float64(s))) / scoreBucketWidth
+		if i >= len(buckets) {
+			i = len(buckets) - 1
+		}
+		buckets[i].Count++
+	}
+	return buckets
+}
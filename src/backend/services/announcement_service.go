@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+var ErrInvalidAnnouncement = errors.New("invalid announcement")
+
+// announcementPreviewLength caps the announcement text carried by its notification.
+const announcementPreviewLength = 300
+
+// checkAnnouncement validates a request. Times are stored in server time, like the database's
+// NOW().
+func checkAnnouncement(req *models.AnnouncementRequest) error {
+	req.Title = strings.TrimSpace(req.Title)
+	req.Body = strings.TrimSpace(req.Body)
+	if req.Title == "" || req.Body == "" {
+		return fmt.Errorf("%w: title and body are required", ErrInvalidAnnouncement)
+	}
+	if utf8.RuneCountInString(req.Title) > 255 {
+		return fmt.Errorf("%w: title is too long", ErrInvalidAnnouncement)
+	}
+
+	publishAt := time.Now()
+	if req.PublishAt != nil {
+		publishAt = req.PublishAt.Local()
+		req.PublishAt = &publishAt
+	}
+	if req.ExpiresAt != nil {
+		expiresAt := req.ExpiresAt.Local()
+		if !expiresAt.After(publishAt) {
+			return fmt.Errorf("%w: expires_at must be after publish_at", ErrInvalidAnnouncement)
+		}
+		req.ExpiresAt = &expiresAt
+	}
+	return nil
+}
+
+// CreateAnnouncement posts an announcement to a course. One published right away is delivered
+// to the students in the background; a scheduled one by the scheduler.
+func CreateAnnouncement(ctx context.Context, courseID, authorID string, req models.AnnouncementRequest) (*models.Announcement, error) {
+	if err := checkAnnouncement(&req); err != nil {
+		return nil, err
+	}
+	if req.PublishAt == nil {
+		now := time.Now()
+		req.PublishAt = &now
+	}
+	a, err := repository.CreateAnnouncement(ctx, courseID, authorID, req)
+	if err != nil {
+		return nil, err
+	}
+	if !a.PublishAt.After(time.Now()) {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			if _, err := DeliverDueAnnouncements(ctx); err != nil {
+				log.Printf("Announcement delivery error: %v", err)
+			}
+		}()
+	}
+	return a, nil
+}
+
+// UpdateAnnouncement edits an announcement, keeping its publish time unless a new one is given.
+// Students already notified are not notified again.
+func UpdateAnnouncement(ctx context.Context, courseID, announcementID, editorID string, req models.AnnouncementRequest) (*models.Announcement, error) {
+	if err := checkAnnouncement(&req); err != nil {
+		return nil, err
+	}
+	return repository.UpdateAnnouncement(ctx, courseID, announcementID, editorID, req)
+}
+
+// GetAnnouncements lists a course's announcements. Staff see scheduled and expired ones too,
+// and how many students have read each; students see the current ones.
+func GetAnnouncements(ctx context.Context, courseID, viewerID string, staff bool) ([]models.Announcement, error) {
+	announcements, err := repository.GetAnnouncements(ctx, courseID, viewerID, staff)
+	if err != nil {
+		return nil, err
+	}
+	if !staff {
+		for i := range announcements {
+			announcements[i].Stats = nil
+		}
+	}
+	return announcements, nil
+}
+
+// ViewAnnouncement returns an announcement, marking it read when a student views it.
+func ViewAnnouncement(ctx context.Context, courseID, announcementID, viewerID string, staff bool) (*models.Announcement, error) {
+	a, err := repository.GetAnnouncement(ctx, courseID, announcementID, viewerID, staff)
+	if err != nil {
+		return nil, err
+	}
+	if staff {
+		return a, nil
+	}
+	a.Stats = nil
+	if !a.Read {
+		if err := repository.MarkAnnouncementRead(ctx, a.ID, viewerID); err != nil {
+			return nil, err
+		}
+		a.Read = true
+	}
+	return a, nil
+}
+
+// MarkAnnouncementRead records that a student has seen a current announcement of the course.
+func MarkAnnouncementRead(ctx context.Context, courseID, announcementID, userID string) error {
+	a, err := repository.GetAnnouncement(ctx, courseID, announcementID, userID, false)
+	if err != nil {
+		return err
+	}
+	return repository.MarkAnnouncementRead(ctx, a.ID, userID)
+}
+
+func announcementPreview(body string) string {
+	if utf8.RuneCountInString(body) <= announcementPreviewLength {
+		return body
+	}
+	runes := []rune(body)
+	return strings.TrimSpace(string(runes[:announcementPreviewLength])) + "…"
+}
+
+// deliverAnnouncement notifies the students enrolled in the course, in the app and by e-mail
+// as each of them prefers.
+func deliverAnnouncement(ctx context.Context, a models.Announcement) error {
+	students, err := repository.GetCourseStudents(ctx, a.CourseID)
+	if err != nil {
+		return err
+	}
+	userIDs := make([]string, len(students))
+	for i, s := range students {
+		userIDs[i] = s.UserID
+	}
+
+	title := a.Title
+	if course, err := repository.GetCourseByID(ctx, a.CourseID); err == nil {
+		title = fmt.Sprintf("%s: %s", course.Title, a.Title)
+	}
+	data, err := json.Marshal(map[string]string{"course_id": a.CourseID, "announcement_id": a.ID})
+	if err != nil {
+		return err
+	}
+	Notify(ctx, models.Notification{
+		Type:  models.NotificationAnnouncement,
+		Title: title,
+		Body:  announcementPreview(a.Body),
+		Link:  fmt.Sprintf("/courses/%s/announcements/%s", a.CourseID, a.ID),
+		Data:  data,
+	}, userIDs...)
+	return nil
+}
+
+// DeliverDueAnnouncements delivers the announcements whose time has come and returns how many
+// there were.
+func DeliverDueAnnouncements(ctx context.Context) (int, error) {
+	announcements, err := repository.ClaimDueAnnouncements(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, a := range announcements {
+		if err := deliverAnnouncement(ctx, a); err != nil {
+			log.Printf("Announcement delivery error for %s: %v", a.ID, err)
+		}
+	}
+	return len(announcements), nil
+}
+
+// StartAnnouncementScheduler delivers scheduled announcements, checking every
+// ANNOUNCEMENT_SCHEDULE_INTERVAL (a minute by default).
+func StartAnnouncementScheduler() {
+	interval := envDuration("ANNOUNCEMENT_SCHEDULE_INTERVAL", time.Minute)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			if _, err := DeliverDueAnnouncements(ctx); err != nil {
+				log.Printf("Announcement scheduler error: %v", err)
+			}
+			cancel()
+		}
+	}()
+}
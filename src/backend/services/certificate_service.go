@@ -0,0 +1,296 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+var (
+	ErrCourseNotCompleted         = errors.New("course is not completed")
+	ErrCertificateRevoked         = errors.New("certificate is revoked")
+	ErrCertificateFontMissing     = errors.New("certificate font is missing")
+	ErrInvalidCertificateTemplate = errors.New("invalid certificate template")
+)
+
+const defaultCertificateColor = "#1F4E79"
+
+// Serials use Crockford's base 32 alphabet, which leaves out letters easily misread.
+const serialAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var hexColor = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// certificateWording is the built-in text of each certificate language.
+var certificateWording = map[string]struct {
+	models.CertificateTexts
+	teacher, date, serial, verify string
+	months                        [12]string
+	dateFormat                    func(t time.Time, months [12]string) string
+}{
+	"en": {
+		CertificateTexts: models.CertificateTexts{
+			Heading:    "Certificate of Completion",
+			Intro:      "This is to certify that",
+			Completion: "has successfully completed the course",
+		},
+		teacher: "Teacher", date: "Date", serial: "Serial No.", verify: "Verify at",
+		months: [12]string{"January", "February", "March", "April", "May", "June", "July", "August",
+			"September", "October", "November", "December"},
+		dateFormat: func(t time.Time, m [12]string) string {
+			return fmt.Sprintf("%s %d, %d", m[t.Month()-1], t.Day(), t.Year())
+		},
+	},
+	"ru": {
+		CertificateTexts: models.CertificateTexts{
+			Heading:    "Сертификат об окончании курса",
+			Intro:      "Настоящим подтверждается, что",
+			Completion: "успешно завершил(а) курс",
+		},
+		teacher: "Преподаватель", date: "Дата", serial: "Серийный номер", verify: "Проверка подлинности",
+		months: [12]string{"января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа",
+			"сентября", "октября", "ноября", "декабря"},
+		dateFormat: func(t time.Time, m [12]string) string {
+			return fmt.Sprintf("%d %s %d г.", t.Day(), m[t.Month()-1], t.Year())
+		},
+	},
+	"kk": {
+		CertificateTexts: models.CertificateTexts{
+			Heading:    "Курсты аяқтағаны туралы сертификат",
+			Intro:      "Осы сертификат",
+			Completion: "төмендегі курсты сәтті аяқтағанын растайды",
+		},
+		teacher: "Оқытушы", date: "Күні", serial: "Сериялық нөмірі", verify: "Түпнұсқалығын тексеру",
+		months: [12]string{"қаңтар", "ақпан", "наурыз", "сәуір", "мамыр", "маусым", "шілде", "тамыз",
+			"қыркүйек", "қазан", "қараша", "желтоқсан"},
+		dateFormat: func(t time.Time, m [12]string) string {
+			return fmt.Sprintf("%d жылғы %d %s", t.Year(), t.Day(), m[t.Month()-1])
+		},
+	},
+}
+
+// CertificateLanguage picks the certificate language: the requested one when supported, then
+// the user's preference, then Russian.
+func CertificateLanguage(requested, preferred string) string {
+	for _, lang := range []string{requested, preferred} {
+		if _, ok := certificateWording[lang]; ok {
+			return lang
+		}
+	}
+	return "ru"
+}
+
+// Default certificate fonts, from the Debian and Ubuntu fonts-dejavu-core package.
+const (
+	defaultCertificateFont     = "/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf"
+	defaultCertificateFontBold = "/usr/share/fonts/truetype/dejavu/DejaVuSans-Bold.ttf"
+)
+
+var certificateFonts = struct {
+	once          sync.Once
+	regular, bold *ttfFont
+	err           error
+}{}
+
+// LoadCertificateFonts loads the fonts certificates are set in. It is called at startup so a
+// missing font shows up in the log right away; the rest of the server keeps working and only
+// certificate downloads fail, with ErrCertificateFontMissing.
+//
+// CERTIFICATE_FONT is the path of a TrueType font that covers Kazakh Cyrillic, DejaVu Sans by
+// default. CERTIFICATE_FONT_BOLD is its bold face for names and headings; if it is not set and
+// the default bold face is missing, the regular face is used instead.
+func LoadCertificateFonts() error {
+	_, _, err := loadCertificateFonts()
+	return err
+}
+
+func loadCertificateFonts() (*ttfFont, *ttfFont, error) {
+	f := &certificateFonts
+	f.once.Do(func() {
+		f.regular, f.err = loadTrueType(envString("CERTIFICATE_FONT", defaultCertificateFont))
+		if f.err != nil {
+			f.err = fmt.Errorf("%w: loading CERTIFICATE_FONT: %v", ErrCertificateFontMissing, f.err)
+			return
+		}
+		boldPath := os.Getenv("CERTIFICATE_FONT_BOLD")
+		bold, err := loadTrueType(envString("CERTIFICATE_FONT_BOLD", defaultCertificateFontBold))
+		switch {
+		case err == nil:
+			f.bold = bold
+		case boldPath != "":
+			f.err = fmt.Errorf("%w: loading CERTIFICATE_FONT_BOLD: %v", ErrCertificateFontMissing, err)
+		default:
+			f.bold = f.regular
+		}
+	})
+	return f.regular, f.bold, f.err
+}
+
+// CertificateVerifyURL is the public page a certificate's QR code points to,
+// CERTIFICATE_VERIFY_URL followed by the serial.
+func CertificateVerifyURL(serial string) string {
+	return strings.TrimRight(envString("CERTIFICATE_VERIFY_URL", "http://localhost:8080/verify"), "/") + "/" + serial
+}
+
+func newCertificateSerial() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	var serial strings.Builder
+	serial.WriteString("SHB")
+	for i, c := range b {
+		if i%4 == 0 {
+			serial.WriteByte('-')
+		}
+		serial.WriteByte(serialAlphabet[int(c)%len(serialAlphabet)])
+	}
+	return serial.String(), nil
+}
+
+// EnsureCertificate returns the student's certificate for a course, issuing it on first use
+// once the enrollment is completed.
+func EnsureCertificate(ctx context.Context, userID, courseID string) (*models.Certificate, error) {
+	cert, err := repository.GetCertificate(ctx, userID, courseID)
+	if err == nil {
+		return cert, nil
+	}
+	if !errors.Is(err, models.ErrNotFound) {
+		return nil, err
+	}
+
+	serial, err := newCertificateSerial()
+	if err != nil {
+		return nil, err
+	}
+	cert, err = repository.IssueCertificate(ctx, userID, courseID, serial)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, ErrCourseNotCompleted
+	}
+	return cert, err
+}
+
+// VerifyCertificate looks a serial up for the public verification page.
+func VerifyCertificate(ctx context.Context, serial string) (*models.CertificateVerification, error) {
+	cert, err := repository.GetCertificateBySerial(ctx, strings.ToUpper(strings.TrimSpace(serial)))
+	if err != nil {
+		return nil, err
+	}
+	return &models.CertificateVerification{
+		Serial:           cert.Serial,
+		Valid:            cert.RevokedAt == nil,
+		StudentName:      cert.StudentName,
+		CourseTitle:      cert.CourseTitle,
+		TeacherName:      cert.TeacherName,
+		IssuedAt:         cert.IssuedAt,
+		RevokedAt:        cert.RevokedAt,
+		RevocationReason: cert.RevocationReason,
+	}, nil
+}
+
+// GetCertificateTemplate returns the course template, or the defaults when none was saved.
+func GetCertificateTemplate(ctx context.Context, courseID string) (*models.CertificateTemplate, error) {
+	tmpl, err := repository.GetCertificateTemplate(ctx, courseID)
+	if errors.Is(err, models.ErrNotFound) {
+		return &models.CertificateTemplate{
+			CourseID:    courseID,
+			AccentColor: defaultCertificateColor,
+			Texts:       map[string]models.CertificateTexts{},
+		}, nil
+	}
+	return tmpl, err
+}
+
+func SaveCertificateTemplate(ctx context.Context, tmpl models.CertificateTemplate) error {
+	if tmpl.AccentColor == "" {
+		tmpl.AccentColor = defaultCertificateColor
+	}
+	if !hexColor.MatchString(tmpl.AccentColor) {
+		return fmt.Errorf("%w: accent_color must look like #1F4E79", ErrInvalidCertificateTemplate)
+	}
+	for lang := range tmpl.Texts {
+		if _, ok := certificateWording[lang]; !ok {
+			return fmt.Errorf("%w: unsupported language %q", ErrInvalidCertificateTemplate, lang)
+		}
+	}
+	if tmpl.Texts == nil {
+		tmpl.Texts = map[string]models.CertificateTexts{}
+	}
+	return repository.SaveCertificateTemplate(ctx, tmpl)
+}
+
+// RenderCertificatePDF draws an A4 landscape certificate in the given language. Revoked
+// certificates are not rendered.
+func RenderCertificatePDF(cert *models.Certificate, tmpl *models.CertificateTemplate, lang string) ([]byte, error) {
+	if cert.RevokedAt != nil {
+		return nil, ErrCertificateRevoked
+	}
+	regular, bold, err := loadCertificateFonts()
+	if err != nil {
+		return nil, err
+	}
+
+	wording := certificateWording[lang]
+	texts := wording.CertificateTexts
+	if custom, ok := tmpl.Texts[lang]; ok {
+		if custom.Heading != "" {
+			texts.Heading = custom.Heading
+		}
+		if custom.Intro != "" {
+			texts.Intro = custom.Intro
+		}
+		if custom.Completion != "" {
+			texts.Completion = custom.Completion
+		}
+	}
+
+	verifyURL := CertificateVerifyURL(cert.Serial)
+	qr, err := qrEncode([]byte(verifyURL))
+	if err != nil {
+		return nil, err
+	}
+
+	const width, height = 842.0, 595.0
+	const center, textWidth = width / 2, 640.0
+	doc := newPDFDocument(width, height)
+	normal, strong := doc.addFont(regular), doc.addFont(bold)
+
+	doc.setColor(tmpl.AccentColor)
+	doc.strokeRect(24, 24, width-48, height-48, 4)
+	doc.strokeRect(34, 34, width-68, height-68, 1)
+	doc.centeredText(strong, fitText(strong, 30, textWidth, strings.ToUpper(texts.Heading)), center, 480, strings.ToUpper(texts.Heading))
+	doc.line(center-120, 462, center+120, 462, 1.5)
+
+	doc.setColor("#333333")
+	doc.centeredText(normal, 15, center, 415, texts.Intro)
+	doc.setColor("#000000")
+	doc.centeredText(strong, fitText(strong, 32, textWidth, cert.StudentName), center, 370, cert.StudentName)
+	doc.setColor("#333333")
+	doc.centeredText(normal, 15, center, 330, texts.Completion)
+	doc.setColor(tmpl.AccentColor)
+	doc.centeredText(strong, fitText(strong, 24, textWidth, cert.CourseTitle), center, 290, cert.CourseTitle)
+
+	doc.setColor("#000000")
+	doc.line(90, 170, 290, 170, 0.75)
+	doc.text(normal, 13, 90, 178, cert.TeacherName)
+	doc.text(normal, 10, 90, 155, wording.teacher)
+
+	doc.line(330, 170, 530, 170, 0.75)
+	doc.text(normal, 13, 330, 178, wording.dateFormat(cert.IssuedAt, wording.months))
+	doc.text(normal, 10, 330, 155, wording.date)
+
+	doc.text(normal, 10, 90, 90, wording.serial+": "+cert.Serial)
+	doc.text(normal, 8, 90, 75, wording.verify+": "+verifyURL)
+
+	doc.qr(qr, width-90-110, 70, 110)
+
+	return doc.bytes()
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// envInt, envFloat, envDuration and envString read optional settings, falling back to the default when
+// the variable is unset or malformed.
+func envInt(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid %s=%q, using %d", name, value, def)
+		return def
+	}
+	return n
+}
+
+func envFloat(name string, def float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Invalid %s=%q, using %v", name, value, def)
+		return def
+	}
+	return f
+}
+
+func envDuration(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid %s=%q, using %s", name, value, def)
+		return def
+	}
+	return d
+}
+
+func envString(name, def string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return def
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+// CSV layout, one question per row, header required (columns may come in any order):
+//
+//	type            multiple_choice, true_false, short_answer or essay
+//	subject, topic  bank tags, optional
+//	difficulty      easy, medium or hard (default medium)
+//	points          positive integer (default 1)
+//	question_ru     question text, required
+//	question_kk     Kazakh question text, optional
+//	correct         choice questions: 1-based numbers of the correct options separated by ";"
+//	                true_false: "true" or "false"; short_answer: the expected answer
+//	explanation_ru, explanation_kk
+//	option1_ru, option1_kk, option2_ru, option2_kk, ...   answer options, as many as needed
+var csvBaseColumns = []string{
+	"type", "subject", "topic", "difficulty", "points",
+	"question_ru", "question_kk", "correct", "explanation_ru", "explanation_kk",
+}
+
+func parseCSV(data []byte) (*importResult, error) {
+	result := &importResult{}
+
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		columns[name] = i
+		if !isKnownCSVColumn(name) {
+			result.warn(1, "unknown column %q is ignored", name)
+		}
+	}
+	for _, required := range []string{"type", "question_ru"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header is missing the %q column", required)
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				line = parseErr.Line
+			}
+			result.found++
+			result.skip(line, "malformed CSV row: %v", err)
+			continue
+		}
+
+		get := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		if strings.Join(record, "") == "" {
+			continue
+		}
+		result.found++
+
+		q := models.QuestionRequest{
+			QuestionType:   get("type"),
+			Subject:        get("subject"),
+			Topic:          get("topic"),
+			Difficulty:     get("difficulty"),
+			QuestionText:   get("question_ru"),
+			QuestionTextKK: get("question_kk"),
+			Explanation:    get("explanation_ru"),
+			ExplanationKK:  get("explanation_kk"),
+		}
+		if points := get("points"); points != "" {
+			q.Points, err = strconv.Atoi(points)
+			if err != nil {
+				result.warn(line, "points %q is not a number, using 1", points)
+			}
+		}
+
+		correct := get("correct")
+		switch q.QuestionType {
+		case "short_answer":
+			q.CorrectAnswer = correct
+		case "true_false":
+			isTrue := strings.EqualFold(correct, "true")
+			if !isTrue && !strings.EqualFold(correct, "false") {
+				result.skip(line, "true_false questions need correct set to true or false")
+				continue
+			}
+			t, f := trueOption, falseOption
+			t.IsCorrect, f.IsCorrect = isTrue, !isTrue
+			q.Options = []models.QuestionOptionRequest{t, f}
+		case "multiple_choice":
+			for n := 1; ; n++ {
+				text := get(fmt.Sprintf("option%d_ru", n))
+				if text == "" {
+					break
+				}
+				q.Options = append(q.Options, models.QuestionOptionRequest{OptionText: text, OptionTextKK: get(fmt.Sprintf("option%d_kk", n))})
+			}
+			ok := true
+			for _, part := range strings.Split(correct, ";") {
+				part = strings.TrimSpace(part)
+				if part == "" {
+					continue
+				}
+				n, err := strconv.Atoi(part)
+				if err != nil || n < 1 || n > len(q.Options) {
+					result.skip(line, "correct option %q does not match any option column", part)
+					ok = false
+					break
+				}
+				q.Options[n-1].IsCorrect = true
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		result.questions = append(result.questions, importedQuestion{line: line, question: q})
+	}
+
+	return result, nil
+}
+
+func isKnownCSVColumn(name string) bool {
+	for _, column := range csvBaseColumns {
+		if column == name {
+			return true
+		}
+	}
+	if strings.HasPrefix(name, "option") {
+		rest := strings.TrimSuffix(strings.TrimSuffix(name[len("option"):], "_ru"), "_kk")
+		_, err := strconv.Atoi(rest)
+		return err == nil && rest != name[len("option"):]
+	}
+	return false
+}
+
+func writeCSV(questions []models.Question) ([]byte, error) {
+	maxOptions := 0
+	for _, q := range questions {
+		if q.QuestionType == "multiple_choice" && len(q.Options) > maxOptions {
+			maxOptions = len(q.Options)
+		}
+	}
+
+	header := append([]string{}, csvBaseColumns...)
+	for n := 1; n <= maxOptions; n++ {
+		header = append(header, fmt.Sprintf("option%d_ru", n), fmt.Sprintf("option%d_kk", n))
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(header); err != nil {
+		return nil, fmt.Errorf("error writing CSV: %w", err)
+	}
+
+	for _, q := range questions {
+		correct := q.CorrectAnswer
+		var options []string
+		switch q.QuestionType {
+		case "true_false":
+			correct = strconv.FormatBool(trueFalseAnswer(q))
+		case "multiple_choice":
+			var numbers []string
+			for i, option := range q.Options {
+				if option.IsCorrect {
+					numbers = append(numbers, strconv.Itoa(i+1))
+				}
+				options = append(options, option.OptionText, option.OptionTextKK)
+			}
+			correct = strings.Join(numbers, ";")
+		}
+
+		record := []string{
+			q.QuestionType, q.Subject, q.Topic, q.Difficulty, strconv.Itoa(q.Points),
+			q.QuestionText, q.QuestionTextKK, correct, q.Explanation, q.ExplanationKK,
+		}
+		record = append(record, options...)
+		for len(record) < len(header) {
+			record = append(record, "")
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, fmt.Errorf("error writing CSV: %w", err)
+		}
+	}
+
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
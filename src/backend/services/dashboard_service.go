@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+const (
+	dashboardListLimit = 10
+	// Streaks are counted over at most this many days of study history.
+	streakLookbackDays = 366
+	// An attempt left open counts for at most the length of a full ENT sitting.
+	maxAttemptStudySeconds = 4 * 60 * 60
+)
+
+// BuildStudentDashboard assembles the dashboard with a fixed number of queries however many
+// courses the student is enrolled in.
+func BuildStudentDashboard(ctx context.Context, userID string) (*models.StudentDashboard, error) {
+	counters, err := repository.GetDashboardCounters(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	today := truncateDay(time.Now())
+	days, err := repository.GetStudyDays(ctx, userID, today.AddDate(0, 0, -streakLookbackDays))
+	if err != nil {
+		return nil, err
+	}
+
+	recent, err := repository.GetRecentActivity(ctx, userID, dashboardListLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	deadlines, err := repository.GetUpcomingDeadlines(ctx, userID, dashboardListLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	dashboard := &models.StudentDashboard{
+		CoursesEnrolled:   counters.CoursesEnrolled,
+		CoursesCompleted:  counters.CoursesCompleted,
+		LessonsCompleted:  counters.LessonsCompleted,
+		TestsCompleted:    counters.TestsCompleted,
+		AverageScore:      int(math.Round(counters.AverageScore)),
+		CurrentStreak:     studyStreak(days, today),
+		RecentActivity:    recent,
+		UpcomingDeadlines: deadlines,
+	}
+	dashboard.WeeklyActivity, dashboard.WeeklyStudySeconds = weeklyActivity(days, today)
+	return dashboard, nil
+}
+
+// weeklyActivity returns the last seven days, oldest first and including days without study,
+// and their total.
+func weeklyActivity(days []models.DailyStudy, today time.Time) ([]models.DailyStudy, int) {
+	seconds := make(map[string]int, len(days))
+	for _, d := range days {
+		seconds[d.Date] = d.Seconds
+	}
+
+	week := make([]models.DailyStudy, 7)
+	total := 0
+	for i := range week {
+		date := today.AddDate(0, 0, i-6).Format("2006-01-02")
+		week[i] = models.DailyStudy{Date: date, Seconds: seconds[date]}
+		total += seconds[date]
+	}
+	return week, total
+}
+
+// studyStreak counts consecutive active days up to today. A streak is not broken until a
+// whole day passes without study, so it may end yesterday.
+func studyStreak(days []models.DailyStudy, today time.Time) int {
+	active := make(map[string]bool, len(days))
+	for _, d := range days {
+		active[d.Date] = true
+	}
+
+	day := today
+	if !active[day.Format("2006-01-02")] {
+		day = day.AddDate(0, 0, -1)
+	}
+	streak := 0
+	for active[day.Format("2006-01-02")] {
+		streak++
+		day = day.AddDate(0, 0, -1)
+	}
+	return streak
+}
+
+// recordStudyTime adds to today's study time and awards the day's streak XP. Failures are
+// logged: the dashboard rollup must not fail the action being recorded.
+func recordStudyTime(ctx context.Context, userID string, seconds int) {
+	now := time.Now()
+	if err := repository.AddStudyTime(ctx, userID, now, seconds); err != nil {
+		log.Printf("Study time error for user %s: %v", userID, err)
+		return
+	}
+	awardStreakXP(ctx, userID, now)
+}
+
+func attemptStudySeconds(attempt *models.TestAttempt) int {
+	if attempt.TimeSpentSeconds == nil || *attempt.TimeSpentSeconds < 0 {
+		return 0
+	}
+	if *attempt.TimeSpentSeconds > maxAttemptStudySeconds {
+		return maxAttemptStudySeconds
+	}
+	return *attempt.TimeSpentSeconds
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
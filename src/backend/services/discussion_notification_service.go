@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+// mentionPattern matches @username. The lookbehind Go lacks is replaced by requiring a
+// non-word character, or the start of the text, before the @ so e-mail addresses don't match.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9_]{3,30})\b`)
+
+// maxMentions caps how many users one post can notify by mentioning them.
+const maxMentions = 10
+
+// parseMentions returns the distinct lowercased usernames mentioned in text, in order.
+func parseMentions(text string) []string {
+	seen := make(map[string]bool)
+	names := []string{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		name := strings.ToLower(m[1])
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+		if len(names) == maxMentions {
+			break
+		}
+	}
+	return names
+}
+
+// resolveMentions maps the usernames mentioned in text to the course members they belong to.
+// Names that are not members of the course are ignored, as is the author mentioning themselves.
+func resolveMentions(ctx context.Context, courseID, authorID, text string) ([]string, error) {
+	names := parseMentions(text)
+	if len(names) == 0 {
+		return nil, nil
+	}
+	members, err := repository.GetCourseMembersByUsername(ctx, courseID, names)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, name := range names {
+		if id, ok := members[name]; ok && id != authorID {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// queueDiscussionEvents records a new post for the next notification batch: a mention for
+// every course member it mentions and a reply event for every other subscriber. replyID is
+// nil for the opening post. Posts held for review notify nobody. Errors are logged, the post
+// itself has already been saved.
+func queueDiscussionEvents(ctx context.Context, courseID, discussionID string, replyID *string, authorID, text string) {
+	mentioned, err := resolveMentions(ctx, courseID, authorID, text)
+	if err != nil {
+		log.Printf("Discussion mentions error: %v", err)
+	}
+	var subscribers []string
+	if replyID != nil {
+		if subscribers, err = repository.GetDiscussionSubscribers(ctx, discussionID); err != nil {
+			log.Printf("Discussion subscribers error: %v", err)
+		}
+	}
+
+	notified := map[string]bool{authorID: true}
+	events := []models.DiscussionEvent{}
+	for _, id := range mentioned {
+		notified[id] = true
+		events = append(events, models.DiscussionEvent{
+			UserID: id, DiscussionID: discussionID, ReplyID: replyID, ActorID: authorID, Kind: models.DiscussionEventMention,
+		})
+	}
+	for _, id := range subscribers {
+		if notified[id] {
+			continue
+		}
+		events = append(events, models.DiscussionEvent{
+			UserID: id, DiscussionID: discussionID, ReplyID: replyID, ActorID: authorID, Kind: models.DiscussionEventReply,
+		})
+	}
+	if err := repository.AddDiscussionEvents(ctx, events); err != nil {
+		log.Printf("Discussion events error: %v", err)
+	}
+}
+
+// SubscribeDiscussion makes the user follow a discussion they can see.
+func SubscribeDiscussion(ctx context.Context, courseID, discussionID, userID string, moderator bool) error {
+	discussion, err := repository.GetDiscussion(ctx, courseID, discussionID)
+	if err != nil {
+		return err
+	}
+	if !visibleTo(discussion.ModerationStatus, discussion.UserID, userID, moderator) {
+		return models.ErrNotFound
+	}
+	return repository.SubscribeDiscussion(ctx, discussionID, userID)
+}
+
+func UnsubscribeDiscussion(ctx context.Context, courseID, discussionID, userID string) error {
+	if _, err := repository.GetDiscussion(ctx, courseID, discussionID); err != nil {
+		return err
+	}
+	return repository.UnsubscribeDiscussion(ctx, discussionID, userID)
+}
+
+// mergeDigest adds the counts and actors of a new digest to the one an unread notification
+// already shows, so a user sees one notification per discussion until they read it.
+func mergeDigest(old, digest models.DiscussionDigest) models.DiscussionDigest {
+	digest.Replies += old.Replies
+	digest.Mentions += old.Mentions
+	actors := append([]string{}, old.Actors...)
+	for _, a := range digest.Actors {
+		found := false
+		for _, o := range old.Actors {
+			if o == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			actors = append(actors, a)
+		}
+	}
+	digest.Actors = actors
+	return digest
+}
+
+// digestText renders the title and body of a discussion_activity notification.
+func digestText(d models.DiscussionDigest) (string, string) {
+	who := "Someone"
+	switch len(d.Actors) {
+	case 0:
+	case 1:
+		who = d.Actors[0]
+	case 2:
+		who = d.Actors[0] + " and " + d.Actors[1]
+	default:
+		who = fmt.Sprintf("%s and %d others", d.Actors[0], len(d.Actors)-1)
+	}
+
+	var parts []string
+	if d.Mentions == 1 {
+		parts = append(parts, "mentioned you")
+	} else if d.Mentions > 1 {
+		parts = append(parts, fmt.Sprintf("mentioned you %d times", d.Mentions))
+	}
+	if d.Replies == 1 {
+		parts = append(parts, "posted a new reply")
+	} else if d.Replies > 1 {
+		parts = append(parts, fmt.Sprintf("posted %d new replies", d.Replies))
+	}
+	return d.Title, fmt.Sprintf("%s %s", who, strings.Join(parts, " and "))
+}
+
+func discussionLink(courseID, discussionID string) string {
+	if courseID == "" {
+		return "/forum/topics/" + discussionID
+	}
+	return fmt.Sprintf("/courses/%s/discussions/%s", courseID, discussionID)
+}
+
+// notifyDiscussionDigest turns a digest into the user's notification for the discussion,
+// folding it into the unread one if there is any.
+func notifyDiscussionDigest(ctx context.Context, digest models.DiscussionDigest) error {
+	groupKey := "discussion:" + digest.DiscussionID
+	existing, err := repository.GetUnreadGroupNotification(ctx, digest.UserID, groupKey)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		return err
+	}
+	if existing != nil {
+		var old models.DiscussionDigest
+		if err := json.Unmarshal(existing.Data, &old); err == nil {
+			digest = mergeDigest(old, digest)
+		}
+	}
+
+	data, err := json.Marshal(digest)
+	if err != nil {
+		return err
+	}
+	title, body := digestText(digest)
+	Notify(ctx, models.Notification{
+		Type:     models.NotificationDiscussionActivity,
+		Title:    title,
+		Body:     body,
+		Link:     discussionLink(digest.CourseID, digest.DiscussionID),
+		Data:     data,
+		GroupKey: groupKey,
+	}, digest.UserID)
+	return nil
+}
+
+// SendDiscussionNotifications batches all pending discussion events into notifications and
+// returns how many users were notified.
+func SendDiscussionNotifications(ctx context.Context) (int, error) {
+	digests, err := repository.ClaimDiscussionDigests(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, d := range digests {
+		if err := notifyDiscussionDigest(ctx, d); err != nil {
+			log.Printf("Discussion notification error: %v", err)
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// StartDiscussionNotifier sends discussion notifications in batches, every
+// DISCUSSION_NOTIFY_INTERVAL, so a busy thread doesn't notify once per reply.
+func StartDiscussionNotifier() {
+	interval := envDuration("DISCUSSION_NOTIFY_INTERVAL", 10*time.Minute)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			if _, err := SendDiscussionNotifications(ctx); err != nil {
+				log.Printf("Discussion notifier error: %v", err)
+			}
+			cancel()
+		}
+	}()
+}
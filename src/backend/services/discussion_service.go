@@ -0,0 +1,435 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+var (
+	ErrInvalidDiscussion = errors.New("invalid discussion")
+	ErrDiscussionClosed  = errors.New("discussion is closed")
+	ErrOwnPostVote       = errors.New("cannot vote for your own post")
+	ErrNotPostAuthor     = errors.New("not the author of this post")
+	ErrEditWindowClosed  = errors.New("the edit window for this post has passed")
+)
+
+// maxReplyDepth is the deepest nesting of replies; answers to a reply at that depth join its
+// siblings instead.
+const maxReplyDepth = 2
+
+// GetDiscussionPage lists a course's discussions, or with no courseID the platform forum's
+// topics, of one category when categoryID is set, newest first unless sort asks for the most
+// helpful or the unanswered ones. Held and hidden discussions are listed for moderators and
+// their authors only.
+func GetDiscussionPage(ctx context.Context, courseID, categoryID, sort, viewerID string, moderator bool, page, limit int) (*models.DiscussionPage, error) {
+	switch sort {
+	case "":
+		sort = models.DiscussionSortNewest
+	case models.DiscussionSortNewest, models.DiscussionSortHelpful, models.DiscussionSortUnanswered:
+	default:
+		return nil, fmt.Errorf("%w: unknown sort %q", ErrInvalidDiscussion, sort)
+	}
+
+	items, total, err := repository.GetDiscussionPage(ctx, courseID, categoryID, sort, viewerID, moderator, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	return &models.DiscussionPage{Items: items, Page: page, Limit: limit, Total: total}, nil
+}
+
+func validateDiscussion(req *models.DiscussionRequest) error {
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" || strings.TrimSpace(req.Content) == "" {
+		return fmt.Errorf("%w: title and content are required", ErrInvalidDiscussion)
+	}
+	if utf8.RuneCountInString(req.Title) > 255 {
+		return fmt.Errorf("%w: title is too long", ErrInvalidDiscussion)
+	}
+	return nil
+}
+
+// CreateDiscussion starts a discussion, held for review when the automatic filter flags it.
+// Without a courseID it starts a topic of the platform forum in an open category.
+func CreateDiscussion(ctx context.Context, courseID, userID string, req models.DiscussionRequest) (*models.Discussion, error) {
+	if err := validateDiscussion(&req); err != nil {
+		return nil, err
+	}
+	if courseID != "" {
+		req.CategoryID = ""
+	} else if err := checkForumCategory(ctx, req.CategoryID); err != nil {
+		return nil, err
+	}
+	if err := checkForumBan(ctx, courseID, userID); err != nil {
+		return nil, err
+	}
+
+	status, reason := models.PostVisible, screenPost(ctx, userID, req.Title+"\n"+req.Content, true)
+	if reason != "" {
+		status = models.PostHeld
+	}
+	discussion, err := repository.CreateDiscussion(ctx, courseID, userID, req, status, reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := repository.SubscribeDiscussion(ctx, discussion.ID, userID); err != nil {
+		log.Printf("Discussion subscription error: %v", err)
+	}
+	if reason != "" {
+		recordAutoHold(ctx, courseID, models.VoteTargetDiscussion, discussion.ID, userID, reason)
+	} else {
+		queueDiscussionEvents(ctx, courseID, discussion.ID, nil, userID, req.Title+"\n"+req.Content)
+	}
+	return discussion, nil
+}
+
+// visibleTo reports whether a post in the given moderation state is shown to the viewer.
+func visibleTo(status, authorID, viewerID string, moderator bool) bool {
+	return status == models.PostVisible || moderator || authorID == viewerID
+}
+
+// ViewDiscussion returns a discussion with its replies as a tree, the viewer's helpful votes
+// and whether they follow it, and counts the view. Deleted, held and hidden replies keep their
+// place in the tree; only moderators see what they said, and authors see their own held or
+// hidden replies.
+func ViewDiscussion(ctx context.Context, courseID, discussionID, userID string, moderator bool) (*models.DiscussionThread, error) {
+	discussion, err := repository.GetDiscussion(ctx, courseID, discussionID)
+	if err != nil {
+		return nil, err
+	}
+	if !visibleTo(discussion.ModerationStatus, discussion.UserID, userID, moderator) {
+		return nil, models.ErrNotFound
+	}
+
+	discussion, err = repository.ViewDiscussion(ctx, courseID, discussionID)
+	if err != nil {
+		return nil, err
+	}
+	replies, err := repository.GetDiscussionReplies(ctx, discussionID)
+	if err != nil {
+		return nil, err
+	}
+	votes, err := repository.GetHelpfulVotes(ctx, userID, discussionID)
+	if err != nil {
+		return nil, err
+	}
+	subscribed, err := repository.IsSubscribedToDiscussion(ctx, discussionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !moderator {
+		for i := range replies {
+			r := &replies[i]
+			switch {
+			case r.IsDeleted:
+				r.UserID, r.AuthorName, r.Content = "", "", models.DeletedPostContent
+			case !visibleTo(r.ModerationStatus, r.UserID, userID, false):
+				r.UserID, r.AuthorName, r.Content = "", "", models.HiddenPostContent
+			}
+		}
+	}
+	return &models.DiscussionThread{Discussion: *discussion, Replies: nestReplies(replies), HelpfulVotes: votes, Subscribed: subscribed}, nil
+}
+
+// nestReplies arranges replies, oldest first, into a tree under their parents. A reply whose
+// parent is missing stays at the top level.
+func nestReplies(replies []models.DiscussionReply) []models.DiscussionReply {
+	children := make(map[string][]models.DiscussionReply)
+	known := make(map[string]bool, len(replies))
+	for _, r := range replies {
+		known[r.ID] = true
+	}
+	var roots []models.DiscussionReply
+	for _, r := range replies {
+		if r.ParentID != nil && known[*r.ParentID] {
+			children[*r.ParentID] = append(children[*r.ParentID], r)
+		} else {
+			roots = append(roots, r)
+		}
+	}
+
+	var attach func([]models.DiscussionReply) []models.DiscussionReply
+	attach = func(level []models.DiscussionReply) []models.DiscussionReply {
+		for i := range level {
+			level[i].Replies = attach(children[level[i].ID])
+		}
+		return level
+	}
+	if roots == nil {
+		return []models.DiscussionReply{}
+	}
+	return attach(roots)
+}
+
+// ReplyToDiscussion adds a reply to a discussion of the course, or to one of its replies when
+// req.ParentID is set. Closed discussions take no more replies. The reply is held for review
+// when the automatic filter flags it.
+func ReplyToDiscussion(ctx context.Context, courseID, discussionID, userID string, req models.DiscussionReplyRequest) (*models.DiscussionReply, error) {
+	if strings.TrimSpace(req.Content) == "" {
+		return nil, fmt.Errorf("%w: content is required", ErrInvalidDiscussion)
+	}
+	if err := checkForumBan(ctx, courseID, userID); err != nil {
+		return nil, err
+	}
+
+	discussion, err := repository.GetDiscussion(ctx, courseID, discussionID)
+	if err != nil {
+		return nil, err
+	}
+	if !visibleTo(discussion.ModerationStatus, discussion.UserID, userID, false) {
+		return nil, models.ErrNotFound
+	}
+	if discussion.IsClosed {
+		return nil, ErrDiscussionClosed
+	}
+
+	var parentID *string
+	depth := 0
+	if req.ParentID != "" {
+		parent, err := repository.GetDiscussionReply(ctx, discussionID, req.ParentID)
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, fmt.Errorf("%w: parent reply not found", ErrInvalidDiscussion)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if parent.IsDeleted || parent.ModerationStatus != models.PostVisible {
+			return nil, fmt.Errorf("%w: cannot reply to a deleted or hidden reply", ErrInvalidDiscussion)
+		}
+		parentID, depth = &parent.ID, parent.Depth+1
+		if parent.Depth >= maxReplyDepth {
+			parentID, depth = parent.ParentID, parent.Depth
+		}
+	}
+
+	status, reason := models.PostVisible, screenPost(ctx, userID, req.Content, true)
+	if reason != "" {
+		status = models.PostHeld
+	}
+	reply, err := repository.CreateDiscussionReply(ctx, discussionID, parentID, depth, userID, req.Content, status, reason)
+	if errors.Is(err, models.ErrNotFound) {
+		// Closed between the check and the insert.
+		return nil, ErrDiscussionClosed
+	}
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		recordAutoHold(ctx, courseID, models.VoteTargetReply, reply.ID, userID, reason)
+	} else {
+		queueDiscussionEvents(ctx, courseID, discussionID, &reply.ID, userID, req.Content)
+		PublishRealtime(ctx, DiscussionTopic(discussionID), models.RealtimeDiscussionReply, reply)
+	}
+	return reply, nil
+}
+
+// MarkDiscussionSolution accepts a reply as the discussion's solution, replacing any earlier
+// one. The reply's author earns XP for it unless they started the discussion themselves; the
+// author of a replaced solution loses theirs.
+func MarkDiscussionSolution(ctx context.Context, discussion *models.Discussion, replyID string) (*models.DiscussionReply, error) {
+	reply, err := repository.GetDiscussionReply(ctx, discussion.ID, replyID)
+	if err != nil {
+		return nil, err
+	}
+	if reply.IsDeleted || reply.ModerationStatus != models.PostVisible {
+		return nil, models.ErrNotFound
+	}
+	if err := repository.SetDiscussionSolution(ctx, discussion.ID, reply.ID); err != nil {
+		return nil, err
+	}
+	reply.IsSolution = true
+
+	earner := ""
+	if reply.UserID != "" && reply.UserID != discussion.UserID {
+		earner = reply.UserID
+	}
+	RevokeSolutionXP(ctx, discussion.ID, earner)
+	if earner != "" {
+		AwardSolutionXP(ctx, earner, discussion.CourseID, discussion.ID)
+	}
+	return reply, nil
+}
+
+// UnmarkDiscussionSolution leaves the discussion without a solution and takes back the XP its
+// author earned for it.
+func UnmarkDiscussionSolution(ctx context.Context, discussionID string) error {
+	if err := repository.SetDiscussionSolution(ctx, discussionID, ""); err != nil {
+		return err
+	}
+	RevokeSolutionXP(ctx, discussionID, "")
+	return nil
+}
+
+// VoteHelpful records (voted true) or withdraws the user's helpful vote on a discussion of the
+// course, or on one of its replies when replyID is set. Authors cannot vote for their own posts.
+func VoteHelpful(ctx context.Context, courseID, discussionID, replyID, userID string, voted bool) (*models.HelpfulVote, error) {
+	discussion, err := repository.GetDiscussion(ctx, courseID, discussionID)
+	if err != nil {
+		return nil, err
+	}
+
+	vote := models.HelpfulVote{TargetType: models.VoteTargetDiscussion, TargetID: discussion.ID, Voted: voted}
+	authorID, status := discussion.UserID, discussion.ModerationStatus
+	if replyID != "" {
+		reply, err := repository.GetDiscussionReply(ctx, discussionID, replyID)
+		if err != nil {
+			return nil, err
+		}
+		if reply.IsDeleted {
+			return nil, models.ErrNotFound
+		}
+		vote.TargetType, vote.TargetID = models.VoteTargetReply, reply.ID
+		authorID, status = reply.UserID, reply.ModerationStatus
+	}
+	if status != models.PostVisible {
+		return nil, models.ErrNotFound
+	}
+	if authorID == userID {
+		return nil, ErrOwnPostVote
+	}
+
+	vote.HelpfulCount, err = repository.SetHelpfulVote(ctx, userID, vote.TargetType, vote.TargetID, voted)
+	if err != nil {
+		return nil, err
+	}
+	return &vote, nil
+}
+
+// GetUnansweredDiscussionPage lists the open discussions of the course still waiting for the
+// teacher: no solution and no reply from them yet, the longest waiting first.
+func GetUnansweredDiscussionPage(ctx context.Context, courseID string, page, limit int) (*models.DiscussionPage, error) {
+	items, total, err := repository.GetUnansweredDiscussionPage(ctx, courseID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	return &models.DiscussionPage{Items: items, Page: page, Limit: limit, Total: total}, nil
+}
+
+// checkEditable allows the author to edit a post within DISCUSSION_EDIT_WINDOW (30 minutes
+// by default) of writing it.
+func checkEditable(authorID, userID string, createdAt time.Time) error {
+	if authorID != userID {
+		return ErrNotPostAuthor
+	}
+	if time.Since(createdAt) > envDuration("DISCUSSION_EDIT_WINDOW", 30*time.Minute) {
+		return ErrEditWindowClosed
+	}
+	return nil
+}
+
+// EditDiscussion lets the author change the title and content of their discussion, keeping
+// the previous version. Edits go through the automatic filter like new posts.
+func EditDiscussion(ctx context.Context, courseID, discussionID, userID string, req models.DiscussionRequest) (*models.Discussion, error) {
+	if err := validateDiscussion(&req); err != nil {
+		return nil, err
+	}
+	if err := checkForumBan(ctx, courseID, userID); err != nil {
+		return nil, err
+	}
+	discussion, err := repository.GetDiscussion(ctx, courseID, discussionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkEditable(discussion.UserID, userID, discussion.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	reason := screenPost(ctx, userID, req.Title+"\n"+req.Content, false)
+	updated, err := repository.UpdateDiscussion(ctx, courseID, discussionID, userID, req, reason)
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" && discussion.ModerationStatus == models.PostVisible {
+		recordAutoHold(ctx, courseID, models.VoteTargetDiscussion, discussion.ID, userID, reason)
+	}
+	return updated, nil
+}
+
+// EditDiscussionReply lets the author change the content of their reply, keeping the previous
+// version. Deleted replies cannot be edited; edits go through the automatic filter.
+func EditDiscussionReply(ctx context.Context, courseID, discussionID, replyID, userID, content string) (*models.DiscussionReply, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, fmt.Errorf("%w: content is required", ErrInvalidDiscussion)
+	}
+	if err := checkForumBan(ctx, courseID, userID); err != nil {
+		return nil, err
+	}
+	reply, err := discussionReply(ctx, courseID, discussionID, replyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkEditable(reply.UserID, userID, reply.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	reason := screenPost(ctx, userID, content, false)
+	updated, err := repository.UpdateDiscussionReply(ctx, discussionID, replyID, userID, content, reason)
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" && reply.ModerationStatus == models.PostVisible {
+		recordAutoHold(ctx, courseID, models.VoteTargetReply, reply.ID, userID, reason)
+	}
+	return updated, nil
+}
+
+// DeleteDiscussionReply soft-deletes a reply. Authors may delete their own replies at any
+// time, moderators any reply. A deleted solution stops being one and loses its XP.
+func DeleteDiscussionReply(ctx context.Context, courseID, discussionID, replyID, userID string, moderator bool) error {
+	reply, err := discussionReply(ctx, courseID, discussionID, replyID)
+	if err != nil {
+		return err
+	}
+	if reply.UserID != userID && !moderator {
+		return ErrNotPostAuthor
+	}
+	if err := repository.DeleteDiscussionReply(ctx, discussionID, replyID, userID); err != nil {
+		return err
+	}
+	if reply.IsSolution {
+		RevokeSolutionXP(ctx, discussionID, "")
+	}
+	return nil
+}
+
+// GetDiscussionReplyEdits returns the previous versions of a reply for its author and
+// moderators.
+func GetDiscussionReplyEdits(ctx context.Context, courseID, discussionID, replyID, userID string, moderator bool) ([]models.DiscussionEdit, error) {
+	reply, err := discussionReply(ctx, courseID, discussionID, replyID)
+	if err != nil {
+		return nil, err
+	}
+	if reply.UserID != userID && !moderator {
+		return nil, ErrNotPostAuthor
+	}
+	return repository.GetDiscussionEdits(ctx, models.VoteTargetReply, reply.ID)
+}
+
+// GetDiscussionEdits returns the previous versions of a discussion for its author and
+// moderators.
+func GetDiscussionEdits(ctx context.Context, courseID, discussionID, userID string, moderator bool) ([]models.DiscussionEdit, error) {
+	discussion, err := repository.GetDiscussion(ctx, courseID, discussionID)
+	if err != nil {
+		return nil, err
+	}
+	if discussion.UserID != userID && !moderator {
+		return nil, ErrNotPostAuthor
+	}
+	return repository.GetDiscussionEdits(ctx, models.VoteTargetDiscussion, discussion.ID)
+}
+
+// discussionReply loads a reply making sure its discussion belongs to the course.
+func discussionReply(ctx context.Context, courseID, discussionID, replyID string) (*models.DiscussionReply, error) {
+	if _, err := repository.GetDiscussion(ctx, courseID, discussionID); err != nil {
+		return nil, err
+	}
+	return repository.GetDiscussionReply(ctx, discussionID, replyID)
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/mail"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+const (
+	emailBatchSize    = 20
+	emailLease        = 5 * time.Minute
+	digestItemLimit   = 10
+	emailRetryBase    = time.Minute
+	emailRetryMaximum = 6 * time.Hour
+)
+
+var (
+	mailTransport = mail.NewFileTransport("mailbox")
+	mailFrom      = "Shabyt <no-reply@shabyt.kz>"
+)
+
+// StartMail sets up outgoing e-mail and starts the outbox worker and the digest sender.
+// MAIL_TRANSPORT=smtp sends through SMTP_HOST; the default, file, saves messages in MAIL_DIR
+// for local development.
+func StartMail() {
+	mailFrom = envString("MAIL_FROM", mailFrom)
+	switch envString("MAIL_TRANSPORT", "file") {
+	case "smtp":
+		mailTransport = mail.NewSMTPTransport(mail.SMTPConfig{
+			Host:     envString("SMTP_HOST", "localhost"),
+			Port:     envInt("SMTP_PORT", 587),
+			Username: envString("SMTP_USERNAME", ""),
+			Password: envString("SMTP_PASSWORD", ""),
+		})
+	default:
+		mailTransport = mail.NewFileTransport(envString("MAIL_DIR", "mailbox"))
+	}
+
+	interval := envDuration("MAIL_SEND_INTERVAL", 30*time.Second)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*emailLease)
+			if _, err := SendQueuedEmails(ctx); err != nil {
+				log.Printf("Email worker error: %v", err)
+			}
+			cancel()
+		}
+	}()
+
+	retention := envDuration("MAIL_RETENTION", 30*24*time.Hour)
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			if _, err := SendEmailDigests(ctx); err != nil {
+				log.Printf("Email digest error: %v", err)
+			}
+			if _, err := repository.DeleteEmailsBefore(ctx, time.Now().Add(-retention)); err != nil {
+				log.Printf("Email pruning error: %v", err)
+			}
+			cancel()
+		}
+	}()
+}
+
+// appLink turns a path of the web app into an absolute link for e-mails.
+func appLink(path string) string {
+	if path == "" || strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	return strings.TrimRight(envString("APP_URL", "http://localhost:8080"), "/") + path
+}
+
+// QueueEmail renders an e-mail in the user's language and puts it in the outbox.
+func QueueEmail(ctx context.Context, user *models.User, template string, data any) error {
+	msg, err := mail.Render(template, user.LanguagePreference, data)
+	if err != nil {
+		return err
+	}
+	return repository.EnqueueEmail(ctx, models.OutboxEmail{
+		UserID:    &user.ID,
+		ToAddress: user.Email,
+		Template:  template,
+		Subject:   msg.Subject,
+		HTMLBody:  msg.HTML,
+		TextBody:  msg.Text,
+	})
+}
+
+// emailNotification e-mails a notification to those of the users who opted in to its type on
+// the e-mail channel. Failures are logged.
+func emailNotification(ctx context.Context, n models.Notification, userIDs []string) {
+	recipients, err := repository.FilterNotificationRecipients(ctx, userIDs, n.Type, models.ChannelEmail)
+	if err != nil {
+		log.Printf("Notification email error for %s: %v", n.Type, err)
+		return
+	}
+	for _, userID := range recipients {
+		user, err := repository.GetUserByID(ctx, userID)
+		if err != nil {
+			log.Printf("Notification email error for %s to user %s: %v", n.Type, userID, err)
+			continue
+		}
+		err = QueueEmail(ctx, user, mail.TemplateNotification, mail.NotificationEmail{
+			FirstName:   user.FirstName,
+			Title:       n.Title,
+			Body:        n.Body,
+			Link:        appLink(n.Link),
+			SettingsURL: appLink("/settings/notifications"),
+		})
+		if err != nil {
+			log.Printf("Notification email error for %s to user %s: %v", n.Type, userID, err)
+		}
+	}
+}
+
+// retryDelay is the wait before the next attempt after the given number of failed ones: the
+// base delay, doubling up to the maximum.
+func retryDelay(attempts int, base, maximum time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < maximum; i++ {
+		delay *= 2
+	}
+	if delay > maximum {
+		delay = maximum
+	}
+	return delay
+}
+
+// SendQueuedEmails sends a batch of due e-mails from the outbox and returns how many were
+// sent. Failed ones are retried with backoff until MAIL_MAX_ATTEMPTS (6 by default).
+func SendQueuedEmails(ctx context.Context) (int, error) {
+	emails, err := repository.ClaimEmails(ctx, emailBatchSize, emailLease)
+	if err != nil {
+		return 0, err
+	}
+	maxAttempts := envInt("MAIL_MAX_ATTEMPTS", 6)
+
+	sent := 0
+	for _, e := range emails {
+		sendCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		err := mailTransport.Send(sendCtx, mail.Message{
+			From:    mailFrom,
+			To:      e.ToAddress,
+			Subject: e.Subject,
+			HTML:    e.HTMLBody,
+			Text:    e.TextBody,
+		})
+		cancel()
+
+		if err != nil {
+			var retryAt *time.Time
+			if e.Attempts < maxAttempts {
+				t := time.Now().Add(retryDelay(e.Attempts, emailRetryBase, emailRetryMaximum))
+				retryAt = &t
+			}
+			log.Printf("Email %s to %s failed (attempt %d): %v", e.ID, e.ToAddress, e.Attempts, err)
+			if err := repository.MarkEmailFailed(ctx, e.ID, err.Error(), retryAt); err != nil {
+				log.Printf("Email worker error: %v", err)
+			}
+			continue
+		}
+		if err := repository.MarkEmailSent(ctx, e.ID); err != nil {
+			log.Printf("Email worker error: %v", err)
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// SendEmailDigests queues the digests of unread notifications for the users due for one and
+// returns how many were queued. Users with nothing new since their last digest get none.
+func SendEmailDigests(ctx context.Context) (int, error) {
+	recipients, err := repository.ClaimDigestRecipients(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for _, r := range recipients {
+		notifications, total, err := repository.GetUnreadNotificationsSince(ctx, r.UserID, r.Since, digestItemLimit)
+		if err != nil {
+			log.Printf("Email digest error for user %s: %v", r.UserID, err)
+			continue
+		}
+		if total == 0 {
+			continue
+		}
+		user, err := repository.GetUserByID(ctx, r.UserID)
+		if err != nil {
+			log.Printf("Email digest error for user %s: %v", r.UserID, err)
+			continue
+		}
+
+		data := mail.DigestEmail{
+			FirstName:        user.FirstName,
+			Weekly:           r.Frequency == models.DigestWeekly,
+			Count:            total,
+			More:             total - len(notifications),
+			NotificationsURL: appLink("/notifications"),
+			SettingsURL:      appLink("/settings/notifications"),
+		}
+		for _, n := range notifications {
+			data.Items = append(data.Items, mail.DigestItem{Title: n.Title, Body: n.Body, Link: appLink(n.Link)})
+		}
+		if err := QueueEmail(ctx, user, mail.TemplateDigest, data); err != nil {
+			log.Printf("Email digest error for user %s: %v", r.UserID, err)
+			continue
+		}
+		queued++
+	}
+	return queued, nil
+}
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+const (
+	defaultEaseFactor = 2.5
+	minEaseFactor     = 1.3
+	// Grades run from 0 (blackout) to 5 (perfect recall); 3 and above count as recalled.
+	maxFlashcardGrade     = 5
+	passingFlashcardGrade = 3
+
+	matureIntervalDays   = 21
+	retentionWindowDays  = 30
+	defaultDueCardsLimit = 50
+	maxDueCardsLimit     = 200
+)
+
+var (
+	ErrInvalidFlashcard = errors.New("invalid flashcard")
+	ErrInvalidGrade     = errors.New("grade must be between 0 and 5")
+)
+
+// ScheduleFlashcard applies the SM-2 algorithm to a review graded 0 to 5 at time now. A nil
+// schedule is a card reviewed for the first time. Cards fall due at the start of a day.
+func ScheduleFlashcard(s *models.FlashcardSchedule, grade int, now time.Time) models.FlashcardSchedule {
+	next := models.FlashcardSchedule{EaseFactor: defaultEaseFactor}
+	if s != nil {
+		next = *s
+	}
+
+	if grade >= passingFlashcardGrade {
+		switch next.Repetitions {
+		case 0:
+			next.IntervalDays = 1
+		case 1:
+			next.IntervalDays = 6
+		default:
+			next.IntervalDays = int(math.Round(float64(next.IntervalDays) * next.EaseFactor))
+		}
+		next.Repetitions++
+	} else {
+		if next.Repetitions > 0 {
+			next.Lapses++
+		}
+		next.Repetitions = 0
+		next.IntervalDays = 1
+	}
+
+	q := float64(maxFlashcardGrade - grade)
+	next.EaseFactor = math.Max(minEaseFactor, next.EaseFactor+0.1-q*(0.08+q*0.02))
+
+	next.DueAt = truncateDay(now).AddDate(0, 0, next.IntervalDays)
+	next.LastReviewedAt = &now
+	return next
+}
+
+func validateFlashcard(req models.FlashcardRequest) error {
+	if strings.TrimSpace(req.Front) == "" || strings.TrimSpace(req.Back) == "" {
+		return fmt.Errorf("%w: front and back are required", ErrInvalidFlashcard)
+	}
+	return nil
+}
+
+func ValidateFlashcardDeck(req models.FlashcardDeckRequest) error {
+	if strings.TrimSpace(req.Title) == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidFlashcard)
+	}
+	return nil
+}
+
+func CreateFlashcard(ctx context.Context, deckID string, req models.FlashcardRequest) (*models.Flashcard, error) {
+	if err := validateFlashcard(req); err != nil {
+		return nil, err
+	}
+	return repository.CreateFlashcard(ctx, deckID, req)
+}
+
+func UpdateFlashcard(ctx context.Context, deckID, cardID string, req models.FlashcardRequest) (*models.Flashcard, error) {
+	if err := validateFlashcard(req); err != nil {
+		return nil, err
+	}
+	return repository.UpdateFlashcard(ctx, deckID, cardID, req)
+}
+
+// flashcardFromQuestion turns a question into a card: the question on the front, the correct
+// answer and explanation on the back. Questions without a single right answer, such as
+// essays, give no card. The Kazakh sides are filled in when the question was translated.
+func flashcardFromQuestion(q models.Question) (models.Flashcard, bool) {
+	var answers, answersKK []string
+	for _, o := range q.Options {
+		if !o.IsCorrect {
+			continue
+		}
+		answers = append(answers, o.OptionText)
+		answersKK = append(answersKK, firstNonEmpty(o.OptionTextKK, o.OptionText))
+	}
+	if len(answers) == 0 && q.CorrectAnswer != "" {
+		answers, answersKK = []string{q.CorrectAnswer}, []string{q.CorrectAnswer}
+	}
+	if len(answers) == 0 {
+		return models.Flashcard{}, false
+	}
+
+	id := q.ID
+	card := models.Flashcard{
+		Front:      q.QuestionText,
+		Back:       withExplanation(strings.Join(answers, "; "), q.Explanation),
+		SourceType: models.FlashcardSourceQuestion,
+		SourceID:   &id,
+	}
+	if q.QuestionTextKK != "" {
+		card.FrontKK = q.QuestionTextKK
+		card.BackKK = withExplanation(strings.Join(answersKK, "; "), firstNonEmpty(q.ExplanationKK, q.Explanation))
+	}
+	return card, true
+}
+
+func withExplanation(answer, explanation string) string {
+	if explanation == "" {
+		return answer
+	}
+	return answer + "\n\n" + explanation
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// GenerateFlashcards derives cards from questions of the course (its own or the shared bank)
+// and from its lessons: the lesson title on the front, its description on the back. It
+// returns how many cards were created; sources the deck already has cards for are skipped.
+func GenerateFlashcards(ctx context.Context, courseID, deckID string, req models.GenerateFlashcardsRequest) (int, error) {
+	var questions []models.Question
+	if len(req.QuestionIDs) > 0 {
+		found, err := repository.GetQuestionsByIDs(ctx, req.QuestionIDs)
+		if err != nil {
+			return 0, err
+		}
+		for _, q := range found {
+			if !q.IsShared && (q.CourseID == nil || *q.CourseID != courseID) {
+				return 0, fmt.Errorf("%w: question %s does not belong to this course", ErrInvalidFlashcard, q.ID)
+			}
+		}
+		questions = append(questions, found...)
+	}
+	if req.TestID != "" {
+		if _, err := repository.GetTestByID(ctx, courseID, req.TestID); err != nil {
+			if errors.Is(err, models.ErrNotFound) {
+				return 0, fmt.Errorf("%w: test %s does not belong to this course", ErrInvalidFlashcard, req.TestID)
+			}
+			return 0, err
+		}
+		found, err := repository.GetTestQuestions(ctx, req.TestID)
+		if err != nil {
+			return 0, err
+		}
+		questions = append(questions, found...)
+	}
+
+	var cards []models.Flashcard
+	for _, q := range questions {
+		if card, ok := flashcardFromQuestion(q); ok {
+			cards = append(cards, card)
+		}
+	}
+
+	if len(req.LessonIDs) > 0 {
+		lessons, err := repository.GetLessonsByCourseId(ctx, courseID)
+		if err != nil {
+			return 0, err
+		}
+		byID := make(map[int]models.Lesson, len(lessons))
+		for _, l := range lessons {
+			byID[l.ID] = l
+		}
+		for _, id := range req.LessonIDs {
+			lesson, ok := byID[id]
+			if !ok {
+				return 0, fmt.Errorf("%w: lesson %d does not belong to this course", ErrInvalidFlashcard, id)
+			}
+			if strings.TrimSpace(lesson.Description) == "" {
+				continue
+			}
+			sourceID := fmt.Sprint(lesson.ID)
+			cards = append(cards, models.Flashcard{
+				Front:      lesson.Title,
+				Back:       lesson.Description,
+				SourceType: models.FlashcardSourceLesson,
+				SourceID:   &sourceID,
+			})
+		}
+	}
+
+	if len(cards) == 0 {
+		return 0, nil
+	}
+	return repository.CreateFlashcards(ctx, deckID, cards)
+}
+
+// studyCard shows a card in the student's language, falling back to the Russian sides where
+// a Kazakh one is missing.
+func studyCard(card models.DueFlashcard, lang string) models.StudyCard {
+	view := models.StudyCard{
+		ID:        card.ID,
+		DeckID:    card.DeckID,
+		DeckTitle: card.DeckTitle,
+		Front:     card.Front,
+		Back:      card.Back,
+		Schedule:  card.Schedule,
+	}
+	if lang == "kk" {
+		view.DeckTitle = firstNonEmpty(card.DeckTitleKK, card.DeckTitle)
+		view.Front = firstNonEmpty(card.FrontKK, card.Front)
+		view.Back = firstNonEmpty(card.BackKK, card.Back)
+	}
+	return view
+}
+
+// GetDueFlashcards returns the cards to study today: reviews due by the end of the day, then
+// new cards up to FLASHCARD_NEW_PER_DAY (20 by default) a day. Without a deck it covers the
+// decks of all courses the student is enrolled in.
+func GetDueFlashcards(ctx context.Context, user *models.User, deckID string, limit int) ([]models.StudyCard, error) {
+	if limit <= 0 {
+		limit = defaultDueCardsLimit
+	}
+	if limit > maxDueCardsLimit {
+		limit = maxDueCardsLimit
+	}
+
+	today := truncateDay(time.Now())
+	due, err := repository.GetDueFlashcards(ctx, user.ID, deckID, today.AddDate(0, 0, 1), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if remaining := limit - len(due); remaining > 0 {
+		introduced, err := repository.CountNewFlashcardsSince(ctx, user.ID, deckID, today)
+		if err != nil {
+			return nil, err
+		}
+		newLimit := envInt("FLASHCARD_NEW_PER_DAY", 20) - introduced
+		if newLimit > remaining {
+			newLimit = remaining
+		}
+		if newLimit > 0 {
+			fresh, err := repository.GetNewFlashcards(ctx, user.ID, deckID, newLimit)
+			if err != nil {
+				return nil, err
+			}
+			due = append(due, fresh...)
+		}
+	}
+
+	cards := make([]models.StudyCard, len(due))
+	for i, card := range due {
+		cards[i] = studyCard(card, user.LanguagePreference)
+	}
+	return cards, nil
+}
+
+// ReviewFlashcard records a review of a card and returns its next schedule. A review counts
+// as study time for the day.
+func ReviewFlashcard(ctx context.Context, userID, cardID string, grade int) (*models.FlashcardSchedule, error) {
+	if grade < 0 || grade > maxFlashcardGrade {
+		return nil, ErrInvalidGrade
+	}
+
+	current, err := repository.GetFlashcardSchedule(ctx, userID, cardID)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		return nil, err
+	}
+	previousInterval := 0
+	if current != nil {
+		previousInterval = current.IntervalDays
+	}
+
+	next := ScheduleFlashcard(current, grade, time.Now())
+	if err := repository.SaveFlashcardReview(ctx, userID, cardID, grade, previousInterval, next); err != nil {
+		return nil, err
+	}
+	recordStudyTime(ctx, userID, 0)
+	return &next, nil
+}
+
+// GetDeckStats returns the student's progress through a deck and their retention over the
+// last 30 days.
+func GetDeckStats(ctx context.Context, userID, deckID string) (*models.DeckStats, error) {
+	today := truncateDay(time.Now())
+	stats, err := repository.GetDeckStats(ctx, userID, deckID, today.AddDate(0, 0, 1),
+		today.AddDate(0, 0, -retentionWindowDays), matureIntervalDays)
+	if err != nil {
+		return nil, err
+	}
+	stats.RetentionDays = retentionWindowDays
+	if stats.Retention != nil {
+		retention := round2(*stats.Retention)
+		stats.Retention = &retention
+	}
+	return stats, nil
+}
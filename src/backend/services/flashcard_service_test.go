@@ -0,0 +1,66 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+var reviewedAt = time.Date(2026, 3, 2, 18, 30, 0, 0, time.UTC)
+
+func TestScheduleFlashcard(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule *models.FlashcardSchedule
+		grade    int
+		want     models.FlashcardSchedule
+	}{
+		{"first review", nil, 4,
+			models.FlashcardSchedule{EaseFactor: 2.5, IntervalDays: 1, Repetitions: 1}},
+		{"first review forgotten", nil, 1,
+			models.FlashcardSchedule{EaseFactor: 1.96, IntervalDays: 1}},
+		{"second review", &models.FlashcardSchedule{EaseFactor: 2.5, IntervalDays: 1, Repetitions: 1}, 4,
+			models.FlashcardSchedule{EaseFactor: 2.5, IntervalDays: 6, Repetitions: 2}},
+		{"third review grows by the ease", &models.FlashcardSchedule{EaseFactor: 2.5, IntervalDays: 6, Repetitions: 2}, 5,
+			models.FlashcardSchedule{EaseFactor: 2.6, IntervalDays: 15, Repetitions: 3}},
+		{"nth review rounds the interval", &models.FlashcardSchedule{EaseFactor: 2.36, IntervalDays: 15, Repetitions: 3}, 3,
+			models.FlashcardSchedule{EaseFactor: 2.22, IntervalDays: 35, Repetitions: 4}},
+		{"lapse starts over", &models.FlashcardSchedule{EaseFactor: 2.22, IntervalDays: 35, Repetitions: 4, Lapses: 1}, 2,
+			models.FlashcardSchedule{EaseFactor: 1.9, IntervalDays: 1, Lapses: 2}},
+		{"relearning after a lapse", &models.FlashcardSchedule{EaseFactor: 1.9, IntervalDays: 1, Lapses: 2}, 4,
+			models.FlashcardSchedule{EaseFactor: 1.9, IntervalDays: 1, Repetitions: 1, Lapses: 2}},
+		{"ease stops at the floor", &models.FlashcardSchedule{EaseFactor: 1.4, IntervalDays: 6, Repetitions: 2}, 3,
+			models.FlashcardSchedule{EaseFactor: 1.3, IntervalDays: 8, Repetitions: 3}},
+		{"blackout at the floor", &models.FlashcardSchedule{EaseFactor: 1.3, IntervalDays: 20, Repetitions: 5}, 0,
+			models.FlashcardSchedule{EaseFactor: 1.3, IntervalDays: 1, Lapses: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var before models.FlashcardSchedule
+			if tt.schedule != nil {
+				before = *tt.schedule
+			}
+			got := ScheduleFlashcard(tt.schedule, tt.grade, reviewedAt)
+
+			if got.IntervalDays != tt.want.IntervalDays || got.Repetitions != tt.want.Repetitions || got.Lapses != tt.want.Lapses {
+				t.Errorf("interval, repetitions, lapses = %d, %d, %d; want %d, %d, %d",
+					got.IntervalDays, got.Repetitions, got.Lapses, tt.want.IntervalDays, tt.want.Repetitions, tt.want.Lapses)
+			}
+			if math.Abs(got.EaseFactor-tt.want.EaseFactor) > 1e-9 {
+				t.Errorf("ease factor = %v, want %v", got.EaseFactor, tt.want.EaseFactor)
+			}
+			if due := time.Date(2026, 3, 2+tt.want.IntervalDays, 0, 0, 0, 0, time.UTC); !got.DueAt.Equal(due) {
+				t.Errorf("due at %v, want %v", got.DueAt, due)
+			}
+			if got.LastReviewedAt == nil || !got.LastReviewedAt.Equal(reviewedAt) {
+				t.Errorf("last reviewed at %v, want %v", got.LastReviewedAt, reviewedAt)
+			}
+			if tt.schedule != nil && *tt.schedule != before {
+				t.Errorf("schedule changed to %+v", *tt.schedule)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+var ErrInvalidForumCategory = errors.New("invalid forum category")
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// checkForumCategory fails with ErrInvalidDiscussion unless new topics can be started in the
+// category.
+func checkForumCategory(ctx context.Context, categoryID string) error {
+	if categoryID == "" {
+		return fmt.Errorf("%w: category_id is required", ErrInvalidDiscussion)
+	}
+	category, err := repository.GetForumCategory(ctx, categoryID)
+	if errors.Is(err, models.ErrNotFound) {
+		return fmt.Errorf("%w: category not found", ErrInvalidDiscussion)
+	}
+	if err != nil {
+		return err
+	}
+	if category.IsArchived {
+		return fmt.Errorf("%w: category is archived", ErrInvalidDiscussion)
+	}
+	return nil
+}
+
+// localizeCategory shows a category in the user's language, falling back to Russian where a
+// Kazakh name or description is missing.
+func localizeCategory(c models.ForumCategory, lang string) models.ForumCategoryView {
+	view := models.ForumCategoryView{
+		ID:          c.ID,
+		Slug:        c.Slug,
+		Subject:     c.Subject,
+		Name:        c.Name,
+		Description: c.Description,
+		TopicCount:  c.TopicCount,
+		LastTopicAt: c.LastTopicAt,
+	}
+	if lang == "kk" {
+		view.Name = firstNonEmpty(c.NameKK, c.Name)
+		view.Description = firstNonEmpty(c.DescriptionKK, c.Description)
+	}
+	return view
+}
+
+// GetForumCategories lists the open forum categories in lang.
+func GetForumCategories(ctx context.Context, lang string) ([]models.ForumCategoryView, error) {
+	categories, err := repository.GetForumCategories(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]models.ForumCategoryView, len(categories))
+	for i, c := range categories {
+		views[i] = localizeCategory(c, lang)
+	}
+	return views, nil
+}
+
+func validateForumCategory(req *models.ForumCategoryRequest) error {
+	req.Slug = strings.ToLower(strings.TrimSpace(req.Slug))
+	req.Subject = strings.TrimSpace(req.Subject)
+	req.Name = strings.TrimSpace(req.Name)
+	req.NameKK = strings.TrimSpace(req.NameKK)
+	if !slugPattern.MatchString(req.Slug) || len(req.Slug) > 50 {
+		return fmt.Errorf("%w: slug must be lowercase latin letters and digits separated by hyphens", ErrInvalidForumCategory)
+	}
+	if req.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidForumCategory)
+	}
+	if utf8.RuneCountInString(req.Name) > 100 || utf8.RuneCountInString(req.NameKK) > 100 {
+		return fmt.Errorf("%w: name is too long", ErrInvalidForumCategory)
+	}
+	if utf8.RuneCountInString(req.Subject) > 50 {
+		return fmt.Errorf("%w: subject is too long", ErrInvalidForumCategory)
+	}
+	return nil
+}
+
+func CreateForumCategory(ctx context.Context, req models.ForumCategoryRequest) (*models.ForumCategory, error) {
+	if err := validateForumCategory(&req); err != nil {
+		return nil, err
+	}
+	category, err := repository.CreateForumCategory(ctx, req)
+	if errors.Is(err, models.ErrConflict) {
+		return nil, fmt.Errorf("%w: slug %q is already used", err, req.Slug)
+	}
+	return category, err
+}
+
+// UpdateForumCategory replaces a category's details. Archiving it keeps its topics readable
+// but takes no new ones.
+func UpdateForumCategory(ctx context.Context, categoryID string, req models.ForumCategoryRequest) (*models.ForumCategory, error) {
+	if err := validateForumCategory(&req); err != nil {
+		return nil, err
+	}
+	category, err := repository.UpdateForumCategory(ctx, categoryID, req)
+	if errors.Is(err, models.ErrConflict) {
+		return nil, fmt.Errorf("%w: slug %q is already used", err, req.Slug)
+	}
+	return category, err
+}
+
+// DeleteForumCategory removes a category that has no topics.
+func DeleteForumCategory(ctx context.Context, categoryID string) error {
+	err := repository.DeleteForumCategory(ctx, categoryID)
+	if errors.Is(err, models.ErrConflict) {
+		return fmt.Errorf("%w: category has topics, archive it instead", err)
+	}
+	return err
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+// XP awarded per event kind. A streak day is every day of study that continues a streak.
+var xpRewards = map[string]int{
+	models.XPLessonCompleted:  10,
+	models.XPTestPassed:       25,
+	models.XPSolutionAccepted: 30,
+	models.XPStreakDay:        5,
+}
+
+// levelThresholds is the total XP each level starts at; level 1 starts at 0.
+var levelThresholds = []int{0, 100, 250, 500, 1000, 2000, 3500, 5500, 8000, 12000}
+
+const recentXPEventsLimit = 20
+
+// Metrics a badge rule can count.
+const (
+	BadgeMetricLessonsCompleted  = "lessons_completed"
+	BadgeMetricTestsPassed       = "tests_passed"
+	BadgeMetricSolutionsAccepted = "solutions_accepted"
+	BadgeMetricStreakDays        = "streak_days"
+	BadgeMetricLevel             = "level"
+)
+
+// badgeCatalog lists every badge in display order. Badges are never taken back, so changing
+// a rule only affects users who have not earned the badge yet.
+var badgeCatalog = []models.Badge{
+	{ID: "first_lesson", Name: "First Steps", Description: "Complete your first lesson",
+		Rule: models.BadgeRule{Metric: BadgeMetricLessonsCompleted, Count: 1}},
+	{ID: "lessons_25", Name: "Bookworm", Description: "Complete 25 lessons",
+		Rule: models.BadgeRule{Metric: BadgeMetricLessonsCompleted, Count: 25}},
+	{ID: "lessons_100", Name: "Scholar", Description: "Complete 100 lessons",
+		Rule: models.BadgeRule{Metric: BadgeMetricLessonsCompleted, Count: 100}},
+	{ID: "first_test", Name: "Test Taker", Description: "Pass your first test",
+		Rule: models.BadgeRule{Metric: BadgeMetricTestsPassed, Count: 1}},
+	{ID: "perfect_score", Name: "Flawless", Description: "Score 100% on a test",
+		Rule: models.BadgeRule{Metric: BadgeMetricTestsPassed, Count: 1, MinScore: 100}},
+	{ID: "tests_10_excellent", Name: "Top of the Class", Description: "Score at least 90% on 10 tests",
+		Rule: models.BadgeRule{Metric: BadgeMetricTestsPassed, Count: 10, MinScore: 90}},
+	{ID: "first_solution", Name: "Helping Hand", Description: "Have an answer accepted as the solution",
+		Rule: models.BadgeRule{Metric: BadgeMetricSolutionsAccepted, Count: 1}},
+	{ID: "solutions_10", Name: "Mentor", Description: "Have 10 answers accepted as solutions",
+		Rule: models.BadgeRule{Metric: BadgeMetricSolutionsAccepted, Count: 10}},
+	{ID: "streak_7", Name: "On a Roll", Description: "Study 7 days in a row",
+		Rule: models.BadgeRule{Metric: BadgeMetricStreakDays, Count: 7}},
+	{ID: "streak_30", Name: "Unstoppable", Description: "Study 30 days in a row",
+		Rule: models.BadgeRule{Metric: BadgeMetricStreakDays, Count: 30}},
+	{ID: "level_5", Name: "Rising Star", Description: "Reach level 5",
+		Rule: models.BadgeRule{Metric: BadgeMetricLevel, Count: 5}},
+}
+
+// LevelForXP returns the level reached with the given XP, the XP it started at and the XP
+// the next level starts at, nil at the top level.
+func LevelForXP(xp int) (int, int, *int) {
+	level := 1
+	for level < len(levelThresholds) && xp >= levelThresholds[level] {
+		level++
+	}
+	if level == len(levelThresholds) {
+		return level, levelThresholds[level-1], nil
+	}
+	next := levelThresholds[level]
+	return level, levelThresholds[level-1], &next
+}
+
+// badgeProgress returns how far the stats are towards a badge rule, in the rule's units.
+func badgeProgress(rule models.BadgeRule, stats models.GamificationStats) int {
+	switch rule.Metric {
+	case BadgeMetricLessonsCompleted:
+		return stats.LessonsCompleted
+	case BadgeMetricTestsPassed:
+		n := 0
+		for _, score := range stats.TestScores {
+			if score >= rule.MinScore {
+				n++
+			}
+		}
+		return n
+	case BadgeMetricSolutionsAccepted:
+		return stats.SolutionsAccepted
+	case BadgeMetricStreakDays:
+		return stats.CurrentStreak
+	case BadgeMetricLevel:
+		level, _, _ := LevelForXP(stats.XP)
+		return level
+	}
+	return 0
+}
+
+// awardXP records a gamification event and, when it is new, adds it to the leaderboards. It
+// reports whether the event was new; replayed events award nothing. Failures are logged:
+// gamification must not fail the action that earned the XP.
+func awardXP(ctx context.Context, userID, kind, sourceID string, courseID *string) bool {
+	xp := xpRewards[kind]
+	created, err := repository.AwardXP(ctx, models.XPEvent{
+		UserID:   userID,
+		Kind:     kind,
+		SourceID: sourceID,
+		CourseID: courseID,
+		XP:       xp,
+	})
+	if err != nil {
+		log.Printf("Gamification error for user %s: %v", userID, err)
+		return false
+	}
+	if !created {
+		return false
+	}
+	recordLeaderboardXP(ctx, userID, courseID, xp)
+	return true
+}
+
+// checkBadges awards the badges the user's stats unlock, logging failures.
+func checkBadges(ctx context.Context, userID string) {
+	if err := evaluateBadges(ctx, userID); err != nil {
+		log.Printf("Badge evaluation error for user %s: %v", userID, err)
+	}
+}
+
+func loadGamificationStats(ctx context.Context, userID string) (*models.GamificationStats, error) {
+	stats, err := repository.GetGamificationStats(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	today := truncateDay(time.Now())
+	days, err := repository.GetStudyDays(ctx, userID, today.AddDate(0, 0, -streakLookbackDays))
+	if err != nil {
+		return nil, err
+	}
+	stats.CurrentStreak = studyStreak(days, today)
+	return stats, nil
+}
+
+func evaluateBadges(ctx context.Context, userID string) error {
+	stats, err := loadGamificationStats(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, badge := range badgeCatalog {
+		if badgeProgress(badge.Rule, *stats) < badge.Rule.Count {
+			continue
+		}
+		awarded, err := repository.AwardBadge(ctx, userID, badge.ID)
+		if err != nil {
+			return err
+		}
+		if awarded {
+			log.Printf("Badge %s awarded to user %s", badge.ID, userID)
+		}
+	}
+	return nil
+}
+
+// AwardLessonXP awards XP for a completed lesson, once per lesson, and checks the badges.
+func AwardLessonXP(ctx context.Context, userID, courseID string, lessonID int) {
+	awardXP(ctx, userID, models.XPLessonCompleted, strconv.Itoa(lessonID), &courseID)
+	checkBadges(ctx, userID)
+}
+
+// AwardTestXP awards XP for a passed attempt, once per test. Badges are checked after every
+// attempt, since stats like perfect scores grow without new XP. Attempts flagged by the
+// integrity check earn nothing.
+func AwardTestXP(ctx context.Context, attempt *models.TestAttempt, courseID string) {
+	if attempt.Score == nil || attempt.Flagged {
+		return
+	}
+	test, err := repository.GetTestByID(ctx, courseID, attempt.TestID)
+	if err != nil {
+		log.Printf("Gamification error for user %s: %v", attempt.UserID, err)
+		return
+	}
+	if *attempt.Score >= test.PassingScore {
+		awardXP(ctx, attempt.UserID, models.XPTestPassed, attempt.TestID, &courseID)
+	}
+	checkBadges(ctx, attempt.UserID)
+}
+
+// AwardSolutionXP awards XP to the author of a discussion's accepted solution, once per
+// discussion. Solutions in the platform forum (no courseID) count towards no course.
+func AwardSolutionXP(ctx context.Context, authorID, courseID, discussionID string) {
+	var course *string
+	if courseID != "" {
+		course = &courseID
+	}
+	if awardXP(ctx, authorID, models.XPSolutionAccepted, discussionID, course) {
+		checkBadges(ctx, authorID)
+	}
+}
+
+// RevokeSolutionXP takes back the solution XP of a discussion whose solution was unmarked or
+// replaced, except from keepAuthorID, the author of the new solution. Badges stay earned.
+// Failures are logged.
+func RevokeSolutionXP(ctx context.Context, discussionID, keepAuthorID string) {
+	events, err := repository.RevokeXP(ctx, models.XPSolutionAccepted, discussionID, keepAuthorID)
+	if err != nil {
+		log.Printf("Gamification error for discussion %s: %v", discussionID, err)
+		return
+	}
+	for _, e := range events {
+		removeLeaderboardXP(ctx, e)
+	}
+}
+
+// awardStreakXP awards the daily streak XP when the user studied yesterday as well as on day.
+func awardStreakXP(ctx context.Context, userID string, day time.Time) {
+	day = truncateDay(day)
+	days, err := repository.GetStudyDays(ctx, userID, day.AddDate(0, 0, -1))
+	if err != nil {
+		log.Printf("Gamification error for user %s: %v", userID, err)
+		return
+	}
+	if studyStreak(days, day) >= 2 {
+		if awardXP(ctx, userID, models.XPStreakDay, day.Format("2006-01-02"), nil) {
+			checkBadges(ctx, userID)
+		}
+	}
+}
+
+func GetGamificationProgress(ctx context.Context, userID string) (*models.GamificationProgress, error) {
+	stats, err := loadGamificationStats(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	badges, err := repository.GetUserBadges(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := repository.GetRecentXPEvents(ctx, userID, recentXPEventsLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &models.GamificationProgress{
+		XP:             stats.XP,
+		CurrentStreak:  stats.CurrentStreak,
+		BadgesEarned:   len(badges),
+		RecentXPEvents: events,
+	}
+	progress.Level, progress.LevelXP, progress.NextLevelXP = LevelForXP(stats.XP)
+	return progress, nil
+}
+
+// GetUserBadges returns the whole badge catalog with the user's progress on each badge.
+func GetUserBadges(ctx context.Context, userID string) ([]models.UserBadge, error) {
+	stats, err := loadGamificationStats(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	awarded, err := repository.GetUserBadges(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	badges := make([]models.UserBadge, len(badgeCatalog))
+	for i, badge := range badgeCatalog {
+		badges[i] = models.UserBadge{Badge: badge, Progress: badgeProgress(badge.Rule, *stats)}
+		if at, ok := awarded[badge.ID]; ok {
+			badges[i].Earned = true
+			badges[i].AwardedAt = &at
+			badges[i].Progress = badge.Rule.Count
+		} else if badges[i].Progress > badge.Rule.Count {
+			badges[i].Progress = badge.Rule.Count
+		}
+	}
+	return badges, nil
+}
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+// GIFT support covers multiple choice, true/false, short answer and essay questions,
+// $CATEGORY lines and general feedback. Matching, numerical and partial-credit answers have
+// no equivalent in our model and are reported instead of being guessed at.
+
+var giftWeight = regexp.MustCompile(`^%(-?[0-9.]+)%`)
+
+// giftMeta is the comment writeGIFT puts above each question, so difficulty and points
+// survive a round trip even though GIFT has no syntax for them.
+var giftMeta = regexp.MustCompile(`^//\s*difficulty:\s*(\w*),\s*points:\s*(\d+)`)
+
+const giftSpecial = `~=#{}:`
+
+type giftAnswer struct {
+	correct  bool
+	weight   float64
+	text     string
+	feedback string
+}
+
+func parseGIFT(data string) *importResult {
+	result := &importResult{}
+	subject, topic := "", ""
+
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	var block []string
+	start := 0
+	difficulty, points := "", 0
+
+	flush := func() {
+		if len(block) == 0 {
+			return
+		}
+		text := strings.TrimSpace(strings.Join(block, "\n"))
+		block = nil
+		if strings.HasPrefix(text, "$CATEGORY:") {
+			subject, topic = categoryPath(strings.TrimPrefix(text, "$CATEGORY:"))
+			return
+		}
+		result.found++
+		q, ok := parseGIFTQuestion(result, start, text)
+		q.Difficulty, q.Points = difficulty, points
+		difficulty, points = "", 0
+		if ok {
+			q.Subject, q.Topic = subject, topic
+			result.questions = append(result.questions, importedQuestion{line: start, question: q})
+		}
+	}
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "//") {
+			if m := giftMeta.FindStringSubmatch(trimmed); m != nil && len(block) == 0 {
+				difficulty = m[1]
+				points, _ = strconv.Atoi(m[2])
+			}
+			continue
+		}
+		if trimmed == "" {
+			flush()
+			continue
+		}
+		if len(block) == 0 {
+			start = i + 1
+		}
+		block = append(block, line)
+	}
+	flush()
+
+	return result
+}
+
+func parseGIFTQuestion(result *importResult, line int, text string) (models.QuestionRequest, bool) {
+	var q models.QuestionRequest
+
+	if strings.HasPrefix(text, "::") {
+		end := indexUnescaped(text, "::", 2)
+		if end < 0 {
+			result.skip(line, "unterminated question title")
+			return q, false
+		}
+		text = strings.TrimSpace(text[end+2:])
+	}
+
+	if strings.HasPrefix(text, "[") {
+		if end := strings.Index(text, "]"); end > 0 {
+			switch markup := text[1:end]; markup {
+			case "html", "moodle", "plain":
+			case "markdown":
+				result.warn(line, "markdown text format is imported as plain text")
+			default:
+				result.warn(line, "unknown text format [%s] is imported as plain text", markup)
+			}
+			text = text[end+1:]
+		}
+	}
+
+	open := indexUnescaped(text, "{", 0)
+	if open < 0 {
+		result.skip(line, "description items without an answer block are not supported")
+		return q, false
+	}
+	closeIdx := indexUnescaped(text, "}", open)
+	if closeIdx < 0 {
+		result.skip(line, "unterminated answer block")
+		return q, false
+	}
+
+	stem := strings.TrimSpace(text[:open])
+	if after := strings.TrimSpace(text[closeIdx+1:]); after != "" {
+		result.warn(line, "missing-word question converted to a blank in the question text")
+		stem += " _____ " + after
+	}
+	q.QuestionText, q.QuestionTextKK = result.localized(line, giftUnescape(stem))
+
+	body := strings.TrimSpace(text[open+1 : closeIdx])
+	if fb := indexUnescaped(body, "####", 0); fb >= 0 {
+		q.Explanation, q.ExplanationKK = result.localized(line, giftUnescape(body[fb+4:]))
+		body = strings.TrimSpace(body[:fb])
+	}
+
+	switch {
+	case body == "":
+		q.QuestionType = "essay"
+		return q, true
+	case strings.HasPrefix(body, "#"):
+		result.skip(line, "numerical questions are not supported")
+		return q, false
+	}
+
+	if value, feedback := splitGIFTFeedback(body); isGIFTBool(value) {
+		if feedback != "" {
+			result.warn(line, "answer feedback is not supported and was dropped")
+		}
+		isTrue := strings.HasPrefix(strings.ToUpper(value), "T")
+		t, f := trueOption, falseOption
+		t.IsCorrect, f.IsCorrect = isTrue, !isTrue
+		q.QuestionType = "true_false"
+		q.Options = []models.QuestionOptionRequest{t, f}
+		return q, true
+	}
+
+	answers, err := splitGIFTAnswers(body)
+	if err != nil {
+		result.skip(line, "%v", err)
+		return q, false
+	}
+
+	hasWrong := false
+	for _, a := range answers {
+		if strings.Contains(a.text, "->") {
+			result.skip(line, "matching questions are not supported")
+			return q, false
+		}
+		hasWrong = hasWrong || !a.correct
+		if a.feedback != "" {
+			result.warn(line, "answer feedback is not supported and was dropped")
+		}
+	}
+
+	if !hasWrong {
+		q.QuestionType = "short_answer"
+		q.CorrectAnswer = giftUnescape(answers[0].text)
+		if len(answers) > 1 {
+			result.warn(line, "only the first of %d accepted answers was kept", len(answers))
+		}
+		return q, true
+	}
+
+	q.QuestionType = "multiple_choice"
+	for _, a := range answers {
+		correct := a.correct
+		if a.weight != 0 && a.weight != 100 {
+			result.warn(line, "partial credit %g%% is not supported; the option is imported as %s", a.weight, map[bool]string{true: "correct", false: "wrong"}[a.weight > 0])
+			correct = a.weight > 0
+		} else if a.weight == 100 {
+			correct = true
+		}
+		option := models.QuestionOptionRequest{IsCorrect: correct}
+		option.OptionText, option.OptionTextKK = result.localized(line, giftUnescape(a.text))
+		q.Options = append(q.Options, option)
+	}
+	return q, true
+}
+
+func splitGIFTAnswers(body string) ([]giftAnswer, error) {
+	var answers []giftAnswer
+	var current *giftAnswer
+	var buf strings.Builder
+
+	finish := func() error {
+		if current == nil {
+			return nil
+		}
+		text := strings.TrimSpace(buf.String())
+		buf.Reset()
+		if m := giftWeight.FindStringSubmatch(text); m != nil {
+			w, err := strconv.ParseFloat(m[1], 64)
+			if err != nil {
+				return fmt.Errorf("invalid answer weight %q", m[0])
+			}
+			current.weight = w
+			text = strings.TrimSpace(text[len(m[0]):])
+		}
+		current.text, current.feedback = splitGIFTFeedback(text)
+		answers = append(answers, *current)
+		return nil
+	}
+
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c == '\\' && i+1 < len(body) {
+			buf.WriteByte(c)
+			buf.WriteByte(body[i+1])
+			i++
+			continue
+		}
+		if c == '=' || c == '~' {
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			current = &giftAnswer{correct: c == '='}
+			continue
+		}
+		if current == nil && strings.TrimSpace(string(c)) != "" {
+			return nil, fmt.Errorf("answer block must start with = or ~")
+		}
+		buf.WriteByte(c)
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	if len(answers) == 0 {
+		return nil, fmt.Errorf("answer block has no answers")
+	}
+	return answers, nil
+}
+
+func splitGIFTFeedback(text string) (string, string) {
+	if idx := indexUnescaped(text, "#", 0); idx >= 0 {
+		return strings.TrimSpace(text[:idx]), strings.TrimSpace(text[idx+1:])
+	}
+	return strings.TrimSpace(text), ""
+}
+
+func isGIFTBool(value string) bool {
+	switch strings.ToUpper(value) {
+	case "T", "TRUE", "F", "FALSE":
+		return true
+	}
+	return false
+}
+
+// indexUnescaped finds sep in s at or after from, skipping backslash-escaped characters.
+func indexUnescaped(s, sep string, from int) int {
+	for i := from; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], sep) {
+			return i
+		}
+	}
+	return -1
+}
+
+func giftUnescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			if s[i+1] == 'n' {
+				b.WriteByte('\n')
+				i++
+				continue
+			}
+			if strings.IndexByte(giftSpecial, s[i+1]) >= 0 || s[i+1] == '\\' {
+				b.WriteByte(s[i+1])
+				i++
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func giftEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r == '\\' || strings.ContainsRune(giftSpecial, r) {
+			b.WriteByte('\\')
+		}
+		if r == '\n' {
+			b.WriteString(`\n`)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func writeGIFT(questions []models.Question) string {
+	var b strings.Builder
+	category := ""
+
+	for i, q := range questions {
+		if c := categoryFor(q); c != category {
+			category = c
+			fmt.Fprintf(&b, "$CATEGORY: %s\n\n", category)
+		}
+
+		text := giftEscape(joinMultilang(q.QuestionText, q.QuestionTextKK))
+		if q.QuestionTextKK != "" {
+			text = "[html]" + text
+		}
+		fmt.Fprintf(&b, "// difficulty: %s, points: %d\n", q.Difficulty, q.Points)
+		fmt.Fprintf(&b, "::Q%d:: %s {", i+1, text)
+
+		switch q.QuestionType {
+		case "true_false":
+			if trueFalseAnswer(q) {
+				b.WriteString("TRUE")
+			} else {
+				b.WriteString("FALSE")
+			}
+		case "short_answer":
+			b.WriteString("=" + giftEscape(q.CorrectAnswer))
+		case "multiple_choice":
+			for _, option := range q.Options {
+				marker := "~"
+				if option.IsCorrect {
+					marker = "="
+				}
+				b.WriteString("\n\t" + marker + giftEscape(joinMultilang(option.OptionText, option.OptionTextKK)))
+			}
+			b.WriteString("\n")
+		}
+
+		if q.Explanation != "" {
+			b.WriteString("####" + giftEscape(joinMultilang(q.Explanation, q.ExplanationKK)))
+		}
+		b.WriteString("}\n\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"math/rand"
+	"strings"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+// GradeAnswer checks a single answer against the question. For choice questions the answer
+// is the id of the selected option. Essays are left ungraded (nil) for teacher review.
+func GradeAnswer(question models.Question, answer string) (*bool, int) {
+	var correct bool
+
+	switch question.QuestionType {
+	case "multiple_choice", "true_false":
+		for _, option := range question.Options {
+			if option.ID == answer {
+				correct = option.IsCorrect
+				break
+			}
+		}
+	case "short_answer":
+		expected := strings.TrimSpace(strings.ToLower(question.CorrectAnswer))
+		correct = expected != "" && strings.TrimSpace(strings.ToLower(answer)) == expected
+	default:
+		return nil, 0
+	}
+
+	if correct {
+		return &correct, question.Points
+	}
+	return &correct, 0
+}
+
+// ScorePercent converts earned points into the integer percentage stored on test_attempts.
+func ScorePercent(earned, total int) int {
+	if total <= 0 {
+		return 0
+	}
+	return earned * 100 / total
+}
+
+// ShuffleOptionIDs returns the option ids of a question in a random order.
+func ShuffleOptionIDs(options []models.QuestionOption) []string {
+	ids := make([]string, len(options))
+	for i, option := range options {
+		ids[i] = option.ID
+	}
+	rand.Shuffle(len(ids), func(i, j int) {
+		ids[i], ids[j] = ids[j], ids[i]
+	})
+	return ids
+}
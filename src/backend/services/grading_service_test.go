@@ -0,0 +1,80 @@
+package services
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+func TestGradeAnswer(t *testing.T) {
+	choice := models.Question{
+		QuestionType: "multiple_choice",
+		Points:       3,
+		Options: []models.QuestionOption{
+			{ID: "o1", IsCorrect: false},
+			{ID: "o2", IsCorrect: true},
+		},
+	}
+	short := models.Question{QuestionType: "short_answer", Points: 2, CorrectAnswer: " Astana "}
+
+	tests := []struct {
+		name     string
+		question models.Question
+		answer   string
+		graded   bool
+		correct  bool
+		points   int
+	}{
+		{"correct option", choice, "o2", true, true, 3},
+		{"wrong option", choice, "o1", true, false, 0},
+		{"unknown option", choice, "o9", true, false, 0},
+		{"short answer ignores case and spaces", short, "  astana", true, true, 2},
+		{"short answer mismatch", short, "Almaty", true, false, 0},
+		{"short answer without a key", models.Question{QuestionType: "short_answer", Points: 1}, "", true, false, 0},
+		{"essay is left for review", models.Question{QuestionType: "essay", Points: 5}, "text", false, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			correct, points := GradeAnswer(tt.question, tt.answer)
+			if (correct != nil) != tt.graded {
+				t.Fatalf("graded = %v, want %v", correct != nil, tt.graded)
+			}
+			if correct != nil && *correct != tt.correct {
+				t.Errorf("correct = %v, want %v", *correct, tt.correct)
+			}
+			if points != tt.points {
+				t.Errorf("points = %d, want %d", points, tt.points)
+			}
+		})
+	}
+}
+
+func TestScorePercent(t *testing.T) {
+	tests := []struct {
+		earned, total, want int
+	}{
+		{0, 0, 0},
+		{0, 10, 0},
+		{5, 10, 50},
+		{2, 3, 66},
+		{10, 10, 100},
+	}
+	for _, tt := range tests {
+		if got := ScorePercent(tt.earned, tt.total); got != tt.want {
+			t.Errorf("ScorePercent(%d, %d) = %d, want %d", tt.earned, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestShuffleOptionIDsKeepsEveryOption(t *testing.T) {
+	options := []models.QuestionOption{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}}
+	ids := ShuffleOptionIDs(options)
+	sort.Strings(ids)
+	for i, option := range options {
+		if ids[i] != option.ID {
+			t.Fatalf("shuffled ids = %v, want a permutation of a, b, c, d", ids)
+		}
+	}
+}
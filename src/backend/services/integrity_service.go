@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+// Integrity policies of a test, applied once an attempt's suspicion score reaches the
+// test's threshold.
+const (
+	IntegrityPolicyNone      = "none"
+	IntegrityPolicyFlag      = "flag"
+	IntegrityPolicyTerminate = "terminate"
+)
+
+const (
+	maxIntegrityEvents = 100
+	// Correct answers given faster than this are counted as suspiciously fast.
+	fastAnswerSeconds = 5
+	// A few quick answers are normal for easy questions; only a run of them counts.
+	minFastAnswers = 3
+	// A submitted attempt averaging less than this per answered question is suspiciously
+	// fast. Unlike the per-answer times it is measured by the server, so it can't be faked.
+	fastAttemptSeconds = 8
+	fastAttemptPoints  = 30
+	maxSuspicion       = 100
+)
+
+var ErrInvalidIntegrityEvent = errors.New("invalid integrity event")
+
+// integrityWeights are the points per event and the cap per event type.
+var integrityWeights = map[string]struct {
+	points, max int
+	message     string
+}{
+	"tab_switch":      {8, 40, "Switched to another tab"},
+	"focus_lost":      {4, 20, "Test window lost focus"},
+	"fullscreen_exit": {6, 30, "Left fullscreen mode"},
+	"copy":            {3, 15, "Copied text"},
+	"paste":           {10, 30, "Pasted text"},
+}
+
+// Events that are recorded for the timeline but carry no weight.
+var neutralIntegrityEvents = map[string]bool{
+	"focus_gained":     true,
+	"fullscreen_enter": true,
+}
+
+func IsValidIntegrityPolicy(policy string) bool {
+	return policy == IntegrityPolicyNone || policy == IntegrityPolicyFlag || policy == IntegrityPolicyTerminate
+}
+
+// ComputeSuspicion scores an attempt from 0 to 100 from its integrity events and answer
+// timing, returning the signals the score is made of.
+func ComputeSuspicion(attempt *models.TestAttempt, events []models.IntegrityEvent, answers []models.UserAnswer) (int, []models.IntegritySignal) {
+	counts := map[string]int{}
+	for _, e := range events {
+		counts[e.EventType]++
+	}
+
+	signals := []models.IntegritySignal{}
+	score := 0
+	for _, kind := range []string{"tab_switch", "focus_lost", "fullscreen_exit", "copy", "paste"} {
+		if counts[kind] == 0 {
+			continue
+		}
+		weight := integrityWeights[kind]
+		points := counts[kind] * weight.points
+		if points > weight.max {
+			points = weight.max
+		}
+		score += points
+		signals = append(signals, models.IntegritySignal{Kind: kind, Count: counts[kind], Points: points, Message: weight.message})
+	}
+
+	fast := 0
+	for _, a := range answers {
+		if a.IsCorrect != nil && *a.IsCorrect && a.TimeSpentSeconds != nil && *a.TimeSpentSeconds < fastAnswerSeconds {
+			fast++
+		}
+	}
+	if fast >= minFastAnswers {
+		points := fast * 5
+		if points > 40 {
+			points = 40
+		}
+		score += points
+		signals = append(signals, models.IntegritySignal{
+			Kind:    "fast_correct_answers",
+			Count:   fast,
+			Points:  points,
+			Message: fmt.Sprintf("Correct answers given in under %d seconds", fastAnswerSeconds),
+		})
+	}
+
+	if signal, ok := attemptPaceSignal(attempt, answers); ok {
+		score += signal.Points
+		signals = append(signals, signal)
+	}
+
+	if score > maxSuspicion {
+		score = maxSuspicion
+	}
+	return score, signals
+}
+
+// attemptPaceSignal checks the server's own clock: the time from start to submission spread
+// over the answered questions. Quickly clicking through wrong answers isn't cheating, so the
+// signal needs a few correct answers too.
+func attemptPaceSignal(attempt *models.TestAttempt, answers []models.UserAnswer) (models.IntegritySignal, bool) {
+	if attempt == nil || attempt.CompletedAt == nil {
+		return models.IntegritySignal{}, false
+	}
+	answered, correct := 0, 0
+	for _, a := range answers {
+		if strings.TrimSpace(a.Answer) == "" {
+			continue
+		}
+		answered++
+		if a.IsCorrect != nil && *a.IsCorrect {
+			correct++
+		}
+	}
+	if correct < minFastAnswers {
+		return models.IntegritySignal{}, false
+	}
+	mean := attempt.CompletedAt.Sub(attempt.StartedAt).Seconds() / float64(answered)
+	if mean >= fastAttemptSeconds {
+		return models.IntegritySignal{}, false
+	}
+	return models.IntegritySignal{
+		Kind:    "fast_attempt",
+		Count:   answered,
+		Points:  fastAttemptPoints,
+		Message: fmt.Sprintf("Averaged %.1f seconds per answered question", mean),
+	}, true
+}
+
+// RecordIntegrityEvents stores events reported during an attempt, rescores it and applies the
+// test's policy. Under the terminate policy the returned attempt may already be closed.
+func RecordIntegrityEvents(ctx context.Context, attempt *models.TestAttempt, reqs []models.IntegrityEventRequest) (*models.TestAttempt, error) {
+	if attempt.Status != "in_progress" {
+		return nil, ErrAttemptClosed
+	}
+	if len(reqs) == 0 || len(reqs) > maxIntegrityEvents {
+		return nil, fmt.Errorf("%w: between 1 and %d events are accepted", ErrInvalidIntegrityEvent, maxIntegrityEvents)
+	}
+
+	now := time.Now()
+	events := make([]models.IntegrityEvent, len(reqs))
+	for i, req := range reqs {
+		if _, ok := integrityWeights[req.Type]; !ok && !neutralIntegrityEvents[req.Type] {
+			return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidIntegrityEvent, req.Type)
+		}
+		occurredAt := now
+		if req.OccurredAt != nil && !req.OccurredAt.Before(attempt.StartedAt) && req.OccurredAt.Before(now.Add(time.Minute)) {
+			occurredAt = *req.OccurredAt
+		}
+		events[i] = models.IntegrityEvent{AttemptID: attempt.ID, EventType: req.Type, Details: req.Details, OccurredAt: occurredAt}
+	}
+
+	if err := repository.CreateIntegrityEvents(ctx, events); err != nil {
+		return nil, err
+	}
+	return applyIntegrityPolicy(ctx, attempt, nil, true)
+}
+
+// RescoreAttemptIntegrity recomputes the suspicion score of a submitted attempt now that its
+// answer timing is known. A finished attempt can no longer be terminated, so it is flagged.
+func RescoreAttemptIntegrity(ctx context.Context, attempt *models.TestAttempt) (*models.TestAttempt, error) {
+	answers, err := attemptAnswerList(ctx, attempt.ID)
+	if err != nil {
+		return nil, err
+	}
+	return applyIntegrityPolicy(ctx, attempt, answers, false)
+}
+
+func applyIntegrityPolicy(ctx context.Context, attempt *models.TestAttempt, answers []models.UserAnswer, inProgress bool) (*models.TestAttempt, error) {
+	events, err := repository.GetIntegrityEvents(ctx, attempt.ID)
+	if err != nil {
+		return nil, err
+	}
+	score, _ := ComputeSuspicion(attempt, events, answers)
+
+	policy, threshold, err := repository.GetIntegrityPolicy(ctx, attempt.TestID)
+	if err != nil {
+		return nil, err
+	}
+	triggered := policy != IntegrityPolicyNone && score >= threshold
+
+	if triggered && inProgress && policy == IntegrityPolicyTerminate {
+		terminated, err := repository.TerminateAttempt(ctx, attempt.ID, score)
+		if err == nil {
+			return terminated, nil
+		}
+		if !errors.Is(err, models.ErrNotFound) {
+			return nil, err
+		}
+		// Submitted in the meantime, flag it instead.
+	}
+	return repository.UpdateAttemptIntegrity(ctx, attempt.ID, score, triggered)
+}
+
+// BuildIntegrityReport assembles the teacher's view of an attempt, with its start and end
+// placed on the timeline next to the reported events.
+func BuildIntegrityReport(ctx context.Context, attempt *models.TestAttempt) (*models.IntegrityReport, error) {
+	events, err := repository.GetIntegrityEvents(ctx, attempt.ID)
+	if err != nil {
+		return nil, err
+	}
+	answers, err := attemptAnswerList(ctx, attempt.ID)
+	if err != nil {
+		return nil, err
+	}
+	_, signals := ComputeSuspicion(attempt, events, answers)
+
+	timeline := []models.IntegrityEvent{{AttemptID: attempt.ID, EventType: "attempt_started", OccurredAt: attempt.StartedAt}}
+	timeline = append(timeline, events...)
+	if attempt.CompletedAt != nil {
+		timeline = append(timeline, models.IntegrityEvent{AttemptID: attempt.ID, EventType: "attempt_" + attempt.Status, OccurredAt: *attempt.CompletedAt})
+	}
+
+	return &models.IntegrityReport{Attempt: attempt, Signals: signals, Timeline: timeline}, nil
+}
+
+func attemptAnswerList(ctx context.Context, attemptID string) ([]models.UserAnswer, error) {
+	answers, err := repository.GetAttemptAnswers(ctx, attemptID)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]models.UserAnswer, 0, len(answers))
+	for _, a := range answers {
+		list = append(list, a)
+	}
+	return list, nil
+}
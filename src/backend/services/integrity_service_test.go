@@ -0,0 +1,89 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+var integrityStart = time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+func submittedAfter(seconds int) *models.TestAttempt {
+	completed := integrityStart.Add(time.Duration(seconds) * time.Second)
+	return &models.TestAttempt{ID: "a", StartedAt: integrityStart, CompletedAt: &completed, Status: "completed"}
+}
+
+func integrityEvents(kinds ...string) []models.IntegrityEvent {
+	events := make([]models.IntegrityEvent, len(kinds))
+	for i, kind := range kinds {
+		events[i] = models.IntegrityEvent{AttemptID: "a", EventType: kind, OccurredAt: integrityStart}
+	}
+	return events
+}
+
+// answered builds answers, correct ones first, each reported as taking the given seconds.
+func answered(correct, wrong, seconds int) []models.UserAnswer {
+	answers := []models.UserAnswer{}
+	for i := 0; i < correct+wrong; i++ {
+		isCorrect := i < correct
+		spent := seconds
+		answers = append(answers, models.UserAnswer{Answer: "x", IsCorrect: &isCorrect, TimeSpentSeconds: &spent})
+	}
+	return answers
+}
+
+func TestComputeSuspicionSignals(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt *models.TestAttempt
+		events  []models.IntegrityEvent
+		answers []models.UserAnswer
+		signals []string
+		score   int
+	}{
+		{"clean", submittedAfter(600), nil, answered(8, 2, 60), nil, 0},
+		{"tab switches", submittedAfter(600), integrityEvents("tab_switch", "tab_switch"), answered(8, 2, 60), []string{"tab_switch"}, 16},
+		{"tab switches are capped", submittedAfter(600), integrityEvents("tab_switch", "tab_switch", "tab_switch", "tab_switch", "tab_switch", "tab_switch"), nil, []string{"tab_switch"}, 40},
+		{"neutral events", submittedAfter(600), integrityEvents("focus_gained", "fullscreen_enter"), nil, nil, 0},
+		{"two fast answers are normal", submittedAfter(600), nil, append(answered(2, 0, 2), answered(6, 2, 60)...), nil, 0},
+		{"run of fast answers", submittedAfter(600), nil, append(answered(4, 0, 2), answered(4, 2, 60)...), []string{"fast_correct_answers"}, 20},
+		{"fast wrong answers don't count", submittedAfter(600), nil, answered(0, 10, 2), nil, 0},
+		{"whole attempt too fast", submittedAfter(50), nil, answered(8, 2, 60), []string{"fast_attempt"}, 30},
+		{"pace at the limit", submittedAfter(80), nil, answered(8, 2, 60), nil, 0},
+		{"skipped questions don't count", submittedAfter(30), nil, append(answered(3, 0, 60), models.UserAnswer{}, models.UserAnswer{}, models.UserAnswer{}), nil, 0},
+		{"clicking through wrong answers", submittedAfter(20), nil, answered(2, 8, 60), nil, 0},
+		{"in progress", &models.TestAttempt{ID: "a", StartedAt: integrityStart, Status: "in_progress"}, nil, nil, nil, 0},
+		{"client and server timing agree", submittedAfter(30), nil, answered(10, 0, 2), []string{"fast_correct_answers", "fast_attempt"}, 70},
+		{"score is capped", submittedAfter(30), integrityEvents("tab_switch", "tab_switch", "tab_switch", "tab_switch", "tab_switch", "paste", "paste", "paste"), answered(10, 0, 2), []string{"tab_switch", "paste", "fast_correct_answers", "fast_attempt"}, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, signals := ComputeSuspicion(tt.attempt, tt.events, tt.answers)
+
+			var kinds []string
+			for _, s := range signals {
+				kinds = append(kinds, s.Kind)
+			}
+			if !reflect.DeepEqual(kinds, tt.signals) {
+				t.Errorf("signals = %v, want %v", kinds, tt.signals)
+			}
+			if score != tt.score {
+				t.Errorf("score = %d, want %d", score, tt.score)
+			}
+		})
+	}
+}
+
+func TestComputeSuspicionPaceSignal(t *testing.T) {
+	_, signals := ComputeSuspicion(submittedAfter(40), nil, answered(8, 2, 60))
+	if len(signals) != 1 {
+		t.Fatalf("signals = %+v, want the pace signal only", signals)
+	}
+	want := models.IntegritySignal{Kind: "fast_attempt", Count: 10, Points: fastAttemptPoints, Message: "Averaged 4.0 seconds per answered question"}
+	if signals[0] != want {
+		t.Errorf("signal = %+v, want %+v", signals[0], want)
+	}
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+const (
+	IRTModel1PL = "1pl"
+	IRTModel2PL = "2pl"
+
+	thetaMin = -4.0
+	thetaMax = 4.0
+
+	calibrationRounds = 20
+)
+
+// irtModel is the model used by calibration, IRT_MODEL=1pl or 2pl.
+func irtModel() string {
+	if strings.ToLower(envString("IRT_MODEL", IRTModel2PL)) == IRTModel1PL {
+		return IRTModel1PL
+	}
+	return IRTModel2PL
+}
+
+// defaultItemParams places an uncalibrated question on the ability scale by its difficulty label.
+func defaultItemParams(q models.Question) models.ItemParams {
+	b := 0.0
+	switch q.Difficulty {
+	case "easy":
+		b = -1
+	case "hard":
+		b = 1
+	}
+	return models.ItemParams{QuestionID: q.ID, Model: IRTModel1PL, Discrimination: 1, Difficulty: b}
+}
+
+// irtProbability is the probability of a correct answer at ability theta (2PL logistic; 1PL with a = 1).
+func irtProbability(theta float64, item models.ItemParams) float64 {
+	return 1 / (1 + math.Exp(-item.Discrimination*(theta-item.Difficulty)))
+}
+
+// itemInformation is the Fisher information an item gives about ability theta.
+func itemInformation(theta float64, item models.ItemParams) float64 {
+	p := irtProbability(theta, item)
+	return item.Discrimination * item.Discrimination * p * (1 - p)
+}
+
+// EstimateAbility returns the expected a posteriori ability and its posterior standard
+// deviation under a standard normal prior. Unlike maximum likelihood it stays finite when
+// every answer so far is right or every answer is wrong.
+func EstimateAbility(items []models.ItemParams, correct []bool) (float64, float64) {
+	const step = 0.1
+
+	var nodes, logPosterior []float64
+	maxLog := math.Inf(-1)
+	for x := thetaMin; x <= thetaMax+step/2; x += step {
+		lp := -x * x / 2
+		for i, item := range items {
+			p := irtProbability(x, item)
+			if correct[i] {
+				lp += math.Log(p)
+			} else {
+				lp += math.Log(1 - p)
+			}
+		}
+		nodes = append(nodes, x)
+		logPosterior = append(logPosterior, lp)
+		maxLog = math.Max(maxLog, lp)
+	}
+
+	var sum, mean float64
+	weights := make([]float64, len(nodes))
+	for i, lp := range logPosterior {
+		weights[i] = math.Exp(lp - maxLog)
+		sum += weights[i]
+		mean += nodes[i] * weights[i]
+	}
+	mean /= sum
+
+	var variance float64
+	for i, x := range nodes {
+		variance += (x - mean) * (x - mean) * weights[i]
+	}
+	return mean, math.Sqrt(variance / sum)
+}
+
+// CalibrateItems fits item parameters by joint maximum a posteriori estimation: item and
+// person parameters are updated in turn with Newton steps, with weak priors keeping
+// estimates finite for items everyone answers correctly. Abilities are rescaled to mean 0
+// and standard deviation 1 after every round to fix the scale. Only items with at least
+// minResponses answers are returned.
+func CalibrateItems(responses []models.CalibrationResponse, model string, minResponses int) []models.ItemParams {
+	counts := make(map[string]int)
+	for _, r := range responses {
+		counts[r.QuestionID]++
+	}
+
+	itemIndex := map[string]int{}
+	userIndex := map[string]int{}
+	type observation struct {
+		user, item int
+		y          float64
+	}
+	var obs []observation
+	var itemIDs []string
+	for _, r := range responses {
+		if counts[r.QuestionID] < minResponses {
+			continue
+		}
+		i, ok := itemIndex[r.QuestionID]
+		if !ok {
+			i = len(itemIDs)
+			itemIndex[r.QuestionID] = i
+			itemIDs = append(itemIDs, r.QuestionID)
+		}
+		u, ok := userIndex[r.UserID]
+		if !ok {
+			u = len(userIndex)
+			userIndex[r.UserID] = u
+		}
+		y := 0.0
+		if r.IsCorrect {
+			y = 1
+		}
+		obs = append(obs, observation{user: u, item: i, y: y})
+	}
+	if len(itemIDs) == 0 {
+		return nil
+	}
+
+	// Start abilities from each student's share of correct answers.
+	theta := make([]float64, len(userIndex))
+	right := make([]float64, len(userIndex))
+	total := make([]float64, len(userIndex))
+	for _, o := range obs {
+		right[o.user] += o.y
+		total[o.user]++
+	}
+	for u := range theta {
+		p := (right[u] + 0.5) / (total[u] + 1)
+		theta[u] = clamp(math.Log(p/(1-p)), thetaMin, thetaMax)
+	}
+
+	a := make([]float64, len(itemIDs))
+	b := make([]float64, len(itemIDs))
+	for i := range a {
+		a[i] = 1
+	}
+
+	for round := 0; round < calibrationRounds; round++ {
+		gb := make([]float64, len(itemIDs))
+		hb := make([]float64, len(itemIDs))
+		ga := make([]float64, len(itemIDs))
+		ha := make([]float64, len(itemIDs))
+		for _, o := range obs {
+			p := 1 / (1 + math.Exp(-a[o.item]*(theta[o.user]-b[o.item])))
+			d := theta[o.user] - b[o.item]
+			gb[o.item] += -a[o.item] * (o.y - p)
+			hb[o.item] += a[o.item] * a[o.item] * p * (1 - p)
+			ga[o.item] += d * (o.y - p)
+			ha[o.item] += d * d * p * (1 - p)
+		}
+		for i := range itemIDs {
+			// b ~ N(0, 2), a ~ N(1, 0.5)
+			b[i] = clamp(b[i]+(gb[i]-b[i]/4)/(hb[i]+0.25), thetaMin, thetaMax)
+			if model == IRTModel2PL {
+				a[i] = clamp(a[i]+(ga[i]-(a[i]-1)*4)/(ha[i]+4), 0.25, 3)
+			}
+		}
+
+		gt := make([]float64, len(theta))
+		ht := make([]float64, len(theta))
+		for _, o := range obs {
+			p := 1 / (1 + math.Exp(-a[o.item]*(theta[o.user]-b[o.item])))
+			gt[o.user] += a[o.item] * (o.y - p)
+			ht[o.user] += a[o.item] * a[o.item] * p * (1 - p)
+		}
+		for u := range theta {
+			theta[u] = clamp(theta[u]+(gt[u]-theta[u])/(ht[u]+1), thetaMin, thetaMax)
+		}
+		standardize(theta)
+	}
+
+	params := make([]models.ItemParams, len(itemIDs))
+	for i, id := range itemIDs {
+		params[i] = models.ItemParams{
+			QuestionID:     id,
+			Model:          model,
+			Discrimination: round2(a[i]),
+			Difficulty:     round2(b[i]),
+			Responses:      counts[id],
+		}
+	}
+	return params
+}
+
+// RunIRTCalibration recalibrates every question with enough answers.
+func RunIRTCalibration(ctx context.Context) (int, error) {
+	responses, err := repository.GetCalibrationResponses(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	params := CalibrateItems(responses, irtModel(), envInt("IRT_MIN_RESPONSES", 30))
+	if err := repository.SaveItemParams(ctx, params); err != nil {
+		return 0, err
+	}
+	return len(params), nil
+}
+
+// StartIRTCalibration runs the calibration batch job every IRT_CALIBRATION_INTERVAL (24h by default).
+func StartIRTCalibration() {
+	interval := envDuration("IRT_CALIBRATION_INTERVAL", 24*time.Hour)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+			count, err := RunIRTCalibration(ctx)
+			cancel()
+			if err != nil {
+				log.Printf("IRT calibration error: %v", err)
+			} else {
+				log.Printf("IRT calibration finished, %d items calibrated", count)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// standardize rescales values in place to mean 0 and standard deviation 1.
+func standardize(values []float64) {
+	if len(values) < 2 {
+		return
+	}
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	sd := math.Sqrt(variance(values))
+	if sd == 0 {
+		return
+	}
+	for i, v := range values {
+		values[i] = (v - mean) / sd
+	}
+}
+
+func clamp(v, min, max float64) float64 {
+	return math.Max(min, math.Min(max, v))
+}
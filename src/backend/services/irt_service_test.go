@@ -0,0 +1,139 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+func TestIRTProbabilityAndInformation(t *testing.T) {
+	item := models.ItemParams{Discrimination: 2, Difficulty: 0.5}
+	if p := irtProbability(0.5, item); math.Abs(p-0.5) > 1e-12 {
+		t.Errorf("probability at theta = b is %v, want 0.5", p)
+	}
+	if irtProbability(2, item) <= irtProbability(1, item) {
+		t.Error("probability should grow with ability")
+	}
+	// Information peaks at theta = b with a²/4.
+	if info := itemInformation(0.5, item); math.Abs(info-1) > 1e-12 {
+		t.Errorf("information at theta = b is %v, want 1", info)
+	}
+	if itemInformation(2, item) >= itemInformation(0.5, item) {
+		t.Error("information should fall away from the item difficulty")
+	}
+}
+
+func TestDefaultItemParams(t *testing.T) {
+	for difficulty, want := range map[string]float64{"easy": -1, "medium": 0, "hard": 1, "": 0} {
+		p := defaultItemParams(models.Question{ID: "q", Difficulty: difficulty})
+		if p.Difficulty != want || p.Discrimination != 1 || p.Model != IRTModel1PL {
+			t.Errorf("defaultItemParams(%q) = %+v", difficulty, p)
+		}
+	}
+}
+
+func TestEstimateAbility(t *testing.T) {
+	theta, se := EstimateAbility(nil, nil)
+	if math.Abs(theta) > 1e-9 || math.Abs(se-1) > 0.01 {
+		t.Errorf("prior only: theta = %v, se = %v; want 0 and about 1", theta, se)
+	}
+
+	items := []models.ItemParams{{Discrimination: 1, Difficulty: -1}, {Discrimination: 1}, {Discrimination: 1, Difficulty: 1}}
+	right, seRight := EstimateAbility(items, []bool{true, true, true})
+	wrong, _ := EstimateAbility(items, []bool{false, false, false})
+	mixed, _ := EstimateAbility(items, []bool{true, false, false})
+
+	if right <= mixed || mixed <= wrong {
+		t.Errorf("abilities not ordered: all wrong %v, mixed %v, all right %v", wrong, mixed, right)
+	}
+	if right >= thetaMax || math.IsNaN(right) {
+		t.Errorf("all right gave %v, want a finite estimate inside the scale", right)
+	}
+	if math.Abs(right+wrong) > 1e-9 {
+		t.Errorf("symmetric items gave %v and %v, want opposite estimates", right, wrong)
+	}
+	if seRight >= 1 {
+		t.Errorf("se after three answers = %v, want below the prior's 1", seRight)
+	}
+}
+
+// simulateResponses answers items with the given parameters for students spread evenly over
+// the ability scale, with a fixed seed so the test is repeatable.
+func simulateResponses(items []models.ItemParams, students int) []models.CalibrationResponse {
+	rng := rand.New(rand.NewSource(1))
+	var responses []models.CalibrationResponse
+	for u := 0; u < students; u++ {
+		theta := -2.5 + 5*float64(u)/float64(students-1)
+		for _, item := range items {
+			responses = append(responses, models.CalibrationResponse{
+				UserID:     fmt.Sprintf("u%d", u),
+				QuestionID: item.QuestionID,
+				IsCorrect:  rng.Float64() < irtProbability(theta, item),
+			})
+		}
+	}
+	return responses
+}
+
+// fillerItems gives the simulated test enough other questions for abilities to be estimated
+// independently of any single item.
+func fillerItems(n int) []models.ItemParams {
+	items := make([]models.ItemParams, n)
+	for i := range items {
+		items[i] = models.ItemParams{QuestionID: fmt.Sprintf("filler%d", i), Discrimination: 1, Difficulty: -2 + 4*float64(i)/float64(n-1)}
+	}
+	return items
+}
+
+func TestCalibrateItemsRecoversDifficultyOrder(t *testing.T) {
+	responses := simulateResponses([]models.ItemParams{
+		{QuestionID: "easy", Discrimination: 1, Difficulty: -1.5},
+		{QuestionID: "medium", Discrimination: 1, Difficulty: 0},
+		{QuestionID: "hard", Discrimination: 1, Difficulty: 1.5},
+	}, 400)
+	responses = append(responses, models.CalibrationResponse{UserID: "u0", QuestionID: "rare", IsCorrect: true})
+
+	params := CalibrateItems(responses, IRTModel1PL, 30)
+	b := map[string]float64{}
+	for _, p := range params {
+		if p.Discrimination != 1 || p.Model != IRTModel1PL {
+			t.Errorf("1PL item %s has %+v", p.QuestionID, p)
+		}
+		b[p.QuestionID] = p.Difficulty
+	}
+	if _, ok := b["rare"]; ok || len(b) != 3 {
+		t.Fatalf("calibrated %v, want the three items with enough responses", b)
+	}
+	if !(b["easy"] < b["medium"] && b["medium"] < b["hard"]) {
+		t.Errorf("difficulties not ordered: %v", b)
+	}
+	if b["easy"] >= 0 || b["hard"] <= 0 {
+		t.Errorf("difficulties on the wrong side of average ability: %v", b)
+	}
+}
+
+func TestCalibrateItemsSeparatesDiscrimination(t *testing.T) {
+	responses := simulateResponses(append(fillerItems(20),
+		models.ItemParams{QuestionID: "medium", Discrimination: 1, Difficulty: 0},
+		models.ItemParams{QuestionID: "sharp", Discrimination: 2.5, Difficulty: 0},
+		models.ItemParams{QuestionID: "flat", Discrimination: 0.4, Difficulty: 0},
+	), 400)
+
+	a := map[string]float64{}
+	for _, p := range CalibrateItems(responses, IRTModel2PL, 30) {
+		a[p.QuestionID] = p.Discrimination
+	}
+	if !(a["flat"] < a["medium"] && a["medium"] < a["sharp"]) {
+		t.Errorf("discriminations not ordered: flat %v, medium %v, sharp %v", a["flat"], a["medium"], a["sharp"])
+	}
+}
+
+func TestCalibrateItemsWithoutEnoughData(t *testing.T) {
+	responses := []models.CalibrationResponse{{UserID: "u", QuestionID: "q", IsCorrect: true}}
+	if params := CalibrateItems(responses, IRTModel2PL, 2); params != nil {
+		t.Errorf("params = %+v, want nil", params)
+	}
+}
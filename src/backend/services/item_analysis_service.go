@@ -0,0 +1,393 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+const (
+	// discriminationGroup is the share of attempts in the upper and lower groups (Kelley's 27%).
+	discriminationGroup = 0.27
+	// Questions with fewer responses are reported but not flagged, the numbers are too noisy.
+	minResponsesForFlags = 10
+)
+
+var (
+	analysisMu      sync.Mutex
+	analysisRunning = map[string]bool{}
+	analysisPending = map[string]bool{}
+)
+
+// ScheduleItemAnalysis recomputes a test's statistics in the background. Calls that arrive
+// while a refresh for the same test is running are coalesced into one follow-up run, so a
+// burst of submissions at the end of an exam costs at most two passes.
+func ScheduleItemAnalysis(testID string) {
+	analysisMu.Lock()
+	if analysisRunning[testID] {
+		analysisPending[testID] = true
+		analysisMu.Unlock()
+		return
+	}
+	analysisRunning[testID] = true
+	analysisMu.Unlock()
+
+	go func() {
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			if _, err := RefreshItemAnalysis(ctx, testID); err != nil {
+				log.Printf("Item analysis refresh error for test %s: %v", testID, err)
+			}
+			cancel()
+
+			analysisMu.Lock()
+			if !analysisPending[testID] {
+				delete(analysisRunning, testID)
+				analysisMu.Unlock()
+				return
+			}
+			delete(analysisPending, testID)
+			analysisMu.Unlock()
+		}
+	}()
+}
+
+// RefreshItemAnalysis computes and stores the statistics of a test from its completed attempts.
+func RefreshItemAnalysis(ctx context.Context, testID string) (*models.ItemAnalysis, error) {
+	attempts, err := repository.GetCompletedAttemptSummaries(ctx, testID)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := repository.GetItemResponses(ctx, testID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var ids []string
+	for _, r := range responses {
+		if !seen[r.QuestionID] {
+			seen[r.QuestionID] = true
+			ids = append(ids, r.QuestionID)
+		}
+	}
+	questions, err := repository.GetQuestionsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := ComputeItemAnalysis(testID, attempts, responses, questions)
+	if err := repository.SaveItemAnalysis(ctx, analysis); err != nil {
+		return nil, err
+	}
+	return analysis, nil
+}
+
+// ComputeItemAnalysis derives classical test theory statistics for every question that
+// appeared in at least one completed attempt.
+func ComputeItemAnalysis(testID string, attempts []models.AttemptSummary, responses []models.ItemResponse, questions []models.Question) *models.ItemAnalysis {
+	analysis := &models.ItemAnalysis{
+		TestID:     testID,
+		Attempts:   len(attempts),
+		Items:      []models.ItemStatistics{},
+		ComputedAt: time.Now(),
+	}
+
+	attemptByID := make(map[string]models.AttemptSummary, len(attempts))
+	for _, a := range attempts {
+		attemptByID[a.ID] = a
+	}
+	top, bottom := scoreGroups(attempts)
+
+	byQuestion := make(map[string][]models.ItemResponse)
+	for _, r := range responses {
+		byQuestion[r.QuestionID] = append(byQuestion[r.QuestionID], r)
+	}
+
+	for _, q := range questions {
+		rs := byQuestion[q.ID]
+		if len(rs) == 0 {
+			continue
+		}
+		item := models.ItemStatistics{
+			QuestionID:   q.ID,
+			QuestionText: q.QuestionText,
+			QuestionType: q.QuestionType,
+			Responses:    len(rs),
+			Flags:        []string{},
+		}
+
+		graded, correct := 0, 0
+		var topGraded, topCorrect, bottomGraded, bottomCorrect int
+		totalTime := 0.0
+		optionCounts := map[string]int{}
+
+		for _, r := range rs {
+			if !r.Answered {
+				item.Unanswered++
+			} else {
+				optionCounts[r.Answer]++
+			}
+			if r.TimeSpentSeconds > 0 {
+				totalTime += float64(r.TimeSpentSeconds)
+			} else if a, ok := attemptByID[r.AttemptID]; ok && a.QuestionCount > 0 {
+				totalTime += float64(a.TimeSpentSeconds) / float64(a.QuestionCount)
+			}
+
+			// Unanswered questions count as wrong, ungraded essays are left out.
+			if r.Answered && r.IsCorrect == nil {
+				continue
+			}
+			isCorrect := r.IsCorrect != nil && *r.IsCorrect
+			graded++
+			if isCorrect {
+				correct++
+			}
+			if top[r.AttemptID] {
+				topGraded++
+				if isCorrect {
+					topCorrect++
+				}
+			}
+			if bottom[r.AttemptID] {
+				bottomGraded++
+				if isCorrect {
+					bottomCorrect++
+				}
+			}
+		}
+
+		item.AvgTimeSeconds = round2(totalTime / float64(len(rs)))
+		if graded > 0 {
+			p := round2(float64(correct) * 100 / float64(graded))
+			item.DifficultyIndex = &p
+		}
+		if topGraded > 0 && bottomGraded > 0 {
+			d := round2(float64(topCorrect)/float64(topGraded) - float64(bottomCorrect)/float64(bottomGraded))
+			item.DiscriminationIndex = &d
+		}
+
+		if q.QuestionType == "multiple_choice" || q.QuestionType == "true_false" {
+			for _, option := range q.Options {
+				count := optionCounts[option.ID]
+				item.Distractors = append(item.Distractors, models.DistractorStatistics{
+					OptionID:   option.ID,
+					OptionText: option.OptionText,
+					IsCorrect:  option.IsCorrect,
+					Count:      count,
+					Frequency:  round2(float64(count) / float64(len(rs))),
+				})
+			}
+		}
+
+		item.Flags = itemFlags(item)
+		analysis.Items = append(analysis.Items, item)
+	}
+
+	analysis.CronbachAlpha = cronbachAlpha(attempts, responses, questions)
+	return analysis
+}
+
+// scoreGroups splits attempts into the upper and lower 27% by score. Ties are broken by
+// attempt id so the same data always gives the same groups.
+func scoreGroups(attempts []models.AttemptSummary) (map[string]bool, map[string]bool) {
+	top, bottom := map[string]bool{}, map[string]bool{}
+	if len(attempts) < 2 {
+		return top, bottom
+	}
+
+	sorted := append([]models.AttemptSummary{}, attempts...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Score != sorted[j].Score {
+			return sorted[i].Score > sorted[j].Score
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	size := int(math.Ceil(discriminationGroup * float64(len(sorted))))
+	if size*2 > len(sorted) {
+		size = len(sorted) / 2
+	}
+	for i := 0; i < size; i++ {
+		top[sorted[i].ID] = true
+		bottom[sorted[len(sorted)-1-i].ID] = true
+	}
+	return top, bottom
+}
+
+// cronbachAlpha estimates internal consistency over the auto-graded questions that every
+// completed attempt received. Questions drawn at random from the bank differ between
+// attempts and cannot enter the formula; nil means there is not enough common data.
+func cronbachAlpha(attempts []models.AttemptSummary, responses []models.ItemResponse, questions []models.Question) *float64 {
+	if len(attempts) < 2 {
+		return nil
+	}
+
+	autoGraded := map[string]bool{}
+	for _, q := range questions {
+		autoGraded[q.ID] = q.QuestionType != "essay"
+	}
+
+	scores := map[string]map[string]float64{}
+	for _, r := range responses {
+		if !autoGraded[r.QuestionID] || r.Points <= 0 {
+			continue
+		}
+		if scores[r.QuestionID] == nil {
+			scores[r.QuestionID] = map[string]float64{}
+		}
+		scores[r.QuestionID][r.AttemptID] = float64(r.PointsEarned) / float64(r.Points)
+	}
+
+	var items []string
+	for id, byAttempt := range scores {
+		if len(byAttempt) == len(attempts) {
+			items = append(items, id)
+		}
+	}
+	k := len(items)
+	if k < 2 {
+		return nil
+	}
+
+	totals := make([]float64, len(attempts))
+	sumItemVariance := 0.0
+	for _, id := range items {
+		values := make([]float64, len(attempts))
+		for i, a := range attempts {
+			values[i] = scores[id][a.ID]
+			totals[i] += values[i]
+		}
+		sumItemVariance += variance(values)
+	}
+
+	totalVariance := variance(totals)
+	if totalVariance == 0 {
+		return nil
+	}
+	alpha := round2(float64(k) / float64(k-1) * (1 - sumItemVariance/totalVariance))
+	return &alpha
+}
+
+func itemFlags(item models.ItemStatistics) []string {
+	flags := []string{}
+	if item.Responses < minResponsesForFlags {
+		return flags
+	}
+
+	if p := item.DifficultyIndex; p != nil {
+		if *p < 20 {
+			flags = append(flags, "too_hard")
+		} else if *p > 95 {
+			flags = append(flags, "too_easy")
+		}
+	}
+	if d := item.DiscriminationIndex; d != nil {
+		if *d < 0 {
+			flags = append(flags, "negative_discrimination")
+		} else if *d < 0.2 {
+			flags = append(flags, "low_discrimination")
+		}
+	}
+
+	keyCount := 0
+	for _, option := range item.Distractors {
+		if option.IsCorrect && option.Count > keyCount {
+			keyCount = option.Count
+		}
+	}
+	for _, option := range item.Distractors {
+		if !option.IsCorrect && option.Count > keyCount {
+			flags = append(flags, "distractor_beats_key")
+			break
+		}
+	}
+	for _, option := range item.Distractors {
+		if !option.IsCorrect && option.Count == 0 {
+			flags = append(flags, "unused_distractor")
+			break
+		}
+	}
+	return flags
+}
+
+// ItemAnalysisCSV writes one row per question option (or one row per question without
+// options), repeating the question-level columns.
+func ItemAnalysisCSV(analysis *models.ItemAnalysis) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	header := []string{
+		"question_id", "question_text", "question_type", "responses", "unanswered", "difficulty_index",
+		"discrimination_index", "avg_time_seconds", "flags", "option_text", "option_correct", "option_count", "option_frequency",
+	}
+	if err := writer.Write(header); err != nil {
+		return nil, fmt.Errorf("error writing CSV: %w", err)
+	}
+
+	for _, item := range analysis.Items {
+		base := []string{
+			item.QuestionID, item.QuestionText, item.QuestionType, strconv.Itoa(item.Responses), strconv.Itoa(item.Unanswered),
+			formatOptional(item.DifficultyIndex), formatOptional(item.DiscriminationIndex),
+			strconv.FormatFloat(item.AvgTimeSeconds, 'f', 2, 64), fmt.Sprint(item.Flags),
+		}
+		if len(item.Distractors) == 0 {
+			if err := writer.Write(append(base, "", "", "", "")); err != nil {
+				return nil, fmt.Errorf("error writing CSV: %w", err)
+			}
+			continue
+		}
+		for _, option := range item.Distractors {
+			row := append(append([]string{}, base...),
+				option.OptionText, strconv.FormatBool(option.IsCorrect), strconv.Itoa(option.Count),
+				strconv.FormatFloat(option.Frequency, 'f', 2, 64))
+			if err := writer.Write(row); err != nil {
+				return nil, fmt.Errorf("error writing CSV: %w", err)
+			}
+		}
+	}
+
+	if err := writer.Write([]string{"cronbach_alpha", formatOptional(analysis.CronbachAlpha)}); err != nil {
+		return nil, fmt.Errorf("error writing CSV: %w", err)
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+func variance(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return sum / float64(len(values))
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func formatOptional(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', 2, 64)
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+func boolPtr(v bool) *bool { return &v }
+
+func TestComputeItemAnalysis(t *testing.T) {
+	attempts := []models.AttemptSummary{
+		{ID: "a", Score: 100, TimeSpentSeconds: 20, QuestionCount: 2},
+		{ID: "b", Score: 75, TimeSpentSeconds: 20, QuestionCount: 2},
+		{ID: "c", Score: 50, TimeSpentSeconds: 20, QuestionCount: 2},
+		{ID: "d", Score: 25, TimeSpentSeconds: 60, QuestionCount: 2},
+	}
+	questions := []models.Question{
+		{ID: "q1", QuestionType: "multiple_choice", Options: []models.QuestionOption{
+			{ID: "o1", IsCorrect: true}, {ID: "o2"}, {ID: "o3"},
+		}},
+		{ID: "q2", QuestionType: "essay"},
+		{ID: "q3", QuestionType: "short_answer"},
+	}
+	responses := []models.ItemResponse{
+		{AttemptID: "a", QuestionID: "q1", Points: 1, Answered: true, Answer: "o1", IsCorrect: boolPtr(true), PointsEarned: 1, TimeSpentSeconds: 10},
+		{AttemptID: "b", QuestionID: "q1", Points: 1, Answered: true, Answer: "o1", IsCorrect: boolPtr(true), PointsEarned: 1, TimeSpentSeconds: 10},
+		{AttemptID: "c", QuestionID: "q1", Points: 1, Answered: true, Answer: "o2", IsCorrect: boolPtr(false), TimeSpentSeconds: 10},
+		{AttemptID: "d", QuestionID: "q1", Points: 1},
+		{AttemptID: "a", QuestionID: "q2", Points: 5, Answered: true, Answer: "text"},
+	}
+
+	analysis := ComputeItemAnalysis("t1", attempts, responses, questions)
+	if analysis.Attempts != 4 || len(analysis.Items) != 2 {
+		t.Fatalf("attempts = %d, items = %d; want 4 and 2 (q3 was never drawn)", analysis.Attempts, len(analysis.Items))
+	}
+
+	mc := analysis.Items[0]
+	if mc.Responses != 4 || mc.Unanswered != 1 {
+		t.Errorf("responses/unanswered = %d/%d, want 4/1", mc.Responses, mc.Unanswered)
+	}
+	if mc.DifficultyIndex == nil || *mc.DifficultyIndex != 50 {
+		t.Errorf("difficulty index = %v, want 50 (a blank counts as wrong)", mc.DifficultyIndex)
+	}
+	if mc.DiscriminationIndex == nil || *mc.DiscriminationIndex != 1 {
+		t.Errorf("discrimination index = %v, want 1", mc.DiscriminationIndex)
+	}
+	// d left it blank and has no per-answer timing, so it is charged 60s / 2 questions.
+	if mc.AvgTimeSeconds != 15 {
+		t.Errorf("average time = %v, want 15", mc.AvgTimeSeconds)
+	}
+	counts := []int{}
+	for _, d := range mc.Distractors {
+		counts = append(counts, d.Count)
+	}
+	if !reflect.DeepEqual(counts, []int{2, 1, 0}) {
+		t.Errorf("option counts = %v, want [2 1 0]", counts)
+	}
+	if len(mc.Flags) != 0 {
+		t.Errorf("flags = %v, want none below %d responses", mc.Flags, minResponsesForFlags)
+	}
+
+	essay := analysis.Items[1]
+	if essay.DifficultyIndex != nil || essay.Distractors != nil {
+		t.Errorf("ungraded essay = %+v, want no difficulty or distractors", essay)
+	}
+}
+
+func TestScoreGroups(t *testing.T) {
+	var attempts []models.AttemptSummary
+	for _, a := range []struct {
+		id    string
+		score int
+	}{{"a", 90}, {"b", 80}, {"c", 80}, {"d", 70}, {"e", 60}, {"f", 50}, {"g", 40}, {"h", 30}, {"i", 20}, {"j", 10}} {
+		attempts = append(attempts, models.AttemptSummary{ID: a.id, Score: a.score})
+	}
+
+	top, bottom := scoreGroups(attempts)
+	// ceil(27% of 10) = 3; the tie at 80 is broken by id.
+	if !reflect.DeepEqual(top, map[string]bool{"a": true, "b": true, "c": true}) {
+		t.Errorf("top = %v", top)
+	}
+	if !reflect.DeepEqual(bottom, map[string]bool{"h": true, "i": true, "j": true}) {
+		t.Errorf("bottom = %v", bottom)
+	}
+
+	top, bottom = scoreGroups(attempts[:1])
+	if len(top) != 0 || len(bottom) != 0 {
+		t.Errorf("one attempt gave groups %v / %v, want none", top, bottom)
+	}
+}
+
+func TestCronbachAlpha(t *testing.T) {
+	attempts := []models.AttemptSummary{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}}
+	questions := []models.Question{{ID: "q1"}, {ID: "q2"}, {ID: "q3"}, {ID: "q4", QuestionType: "essay"}}
+	earned := map[string][]int{
+		"q1": {1, 1, 1, 0},
+		"q2": {1, 1, 0, 0},
+		"q3": {1, 0, 0, 0},
+		"q4": {5, 0, 3, 1},
+	}
+	var responses []models.ItemResponse
+	for _, q := range questions {
+		for i, a := range attempts {
+			responses = append(responses, models.ItemResponse{AttemptID: a.ID, QuestionID: q.ID, Points: 1, PointsEarned: earned[q.ID][i]})
+		}
+	}
+
+	// k/(k-1) * (1 - sum of item variances / total variance) = 3/2 * (1 - 0.625/1.25)
+	alpha := cronbachAlpha(attempts, responses, questions)
+	if alpha == nil || math.Abs(*alpha-0.75) > 1e-9 {
+		t.Fatalf("alpha = %v, want 0.75", alpha)
+	}
+
+	// Only one question common to every attempt: nothing to correlate.
+	if alpha := cronbachAlpha(attempts, responses[:4], questions); alpha != nil {
+		t.Errorf("alpha with one item = %v, want nil", *alpha)
+	}
+}
+
+func TestItemFlags(t *testing.T) {
+	p := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name string
+		item models.ItemStatistics
+		want []string
+	}{
+		{"too few responses", models.ItemStatistics{Responses: 5, DifficultyIndex: p(5)}, []string{}},
+		{"too hard", models.ItemStatistics{Responses: 20, DifficultyIndex: p(10), DiscriminationIndex: p(0.5)}, []string{"too_hard"}},
+		{"too easy and flat", models.ItemStatistics{Responses: 20, DifficultyIndex: p(98), DiscriminationIndex: p(0.1)}, []string{"too_easy", "low_discrimination"}},
+		{"negative discrimination", models.ItemStatistics{Responses: 20, DifficultyIndex: p(50), DiscriminationIndex: p(-0.3)}, []string{"negative_discrimination"}},
+		{"distractors", models.ItemStatistics{Responses: 20, Distractors: []models.DistractorStatistics{
+			{IsCorrect: true, Count: 5}, {Count: 15}, {Count: 0},
+		}}, []string{"distractor_beats_key", "unused_distractor"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := itemFlags(tt.item); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("itemFlags = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+const (
+	defaultLeaderboardLimit = 10
+	maxLeaderboardLimit     = 100
+	joinCodeLength          = 8
+)
+
+var (
+	ErrInvalidLeaderboard = errors.New("invalid leaderboard")
+	ErrInvalidSettings    = errors.New("invalid settings")
+	ErrInvalidClassGroup  = errors.New("invalid class group")
+)
+
+// allTimeStart is the period start of the all-time aggregates.
+var allTimeStart = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// kazakhstanRegions are the regions and cities of republican significance users can pick
+// for the regional leaderboard.
+var kazakhstanRegions = map[string]bool{
+	"abai": true, "akmola": true, "aktobe": true, "almaty_region": true, "atyrau": true,
+	"east_kazakhstan": true, "zhambyl": true, "zhetisu": true, "west_kazakhstan": true,
+	"karaganda": true, "kostanay": true, "kyzylorda": true, "mangystau": true, "pavlodar": true,
+	"north_kazakhstan": true, "turkestan": true, "ulytau": true,
+	"astana": true, "almaty": true, "shymkent": true,
+}
+
+// leaderboardPeriodStart returns the start of the period containing t: Monday for weeks, the
+// first of the month for months.
+func leaderboardPeriodStart(period string, t time.Time) time.Time {
+	switch period {
+	case models.LeaderboardPeriodWeek:
+		return startOfWeek(t)
+	case models.LeaderboardPeriodMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	}
+	return allTimeStart
+}
+
+// leaderboardKeys lists the aggregate rows a change at time t counts towards: every period of
+// the platform totals and, for course activity, of the course.
+func leaderboardKeys(courseID *string, t time.Time) []models.LeaderboardKey {
+	scopes := []string{""}
+	if courseID != nil {
+		scopes = append(scopes, *courseID)
+	}
+
+	var keys []models.LeaderboardKey
+	for _, scope := range scopes {
+		for _, period := range []string{models.LeaderboardPeriodWeek, models.LeaderboardPeriodMonth, models.LeaderboardPeriodAll} {
+			keys = append(keys, models.LeaderboardKey{Scope: scope, Period: period, PeriodStart: leaderboardPeriodStart(period, t)})
+		}
+	}
+	return keys
+}
+
+// recordLeaderboardXP adds newly awarded XP to the leaderboards. Failures are logged.
+func recordLeaderboardXP(ctx context.Context, userID string, courseID *string, xp int) {
+	if err := repository.AddLeaderboardPoints(ctx, userID, leaderboardKeys(courseID, time.Now()), xp, 0, 0); err != nil {
+		log.Printf("Leaderboard error for user %s: %v", userID, err)
+	}
+}
+
+// removeLeaderboardXP takes a revoked event's XP off the leaderboards of the periods it was
+// awarded in. Failures are logged.
+func removeLeaderboardXP(ctx context.Context, event models.XPEvent) {
+	if err := repository.AddLeaderboardPoints(ctx, event.UserID, leaderboardKeys(event.CourseID, event.CreatedAt), -event.XP, 0, 0); err != nil {
+		log.Printf("Leaderboard error for user %s: %v", event.UserID, err)
+	}
+}
+
+// recordLeaderboardScore adds a completed attempt's score to the average score leaderboards.
+// Attempts flagged by the integrity check are left out.
+func recordLeaderboardScore(ctx context.Context, attempt *models.TestAttempt, courseID string) {
+	if attempt.Score == nil || attempt.Flagged {
+		return
+	}
+	keys := leaderboardKeys(&courseID, time.Now())
+	if err := repository.AddLeaderboardPoints(ctx, attempt.UserID, keys, 0, *attempt.Score, 1); err != nil {
+		log.Printf("Leaderboard error for user %s: %v", attempt.UserID, err)
+	}
+}
+
+// GetLeaderboard validates the query and returns the board with the user's own entry. A
+// region board without a region is the user's own region.
+func GetLeaderboard(ctx context.Context, q models.LeaderboardQuery) (*models.Leaderboard, error) {
+	if q.Metric == "" {
+		q.Metric = models.LeaderboardMetricXP
+	}
+	if q.Period == "" {
+		q.Period = models.LeaderboardPeriodWeek
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultLeaderboardLimit
+	}
+	if q.Limit > maxLeaderboardLimit {
+		q.Limit = maxLeaderboardLimit
+	}
+
+	switch q.Metric {
+	case models.LeaderboardMetricXP, models.LeaderboardMetricScore:
+	default:
+		return nil, fmt.Errorf("%w: metric must be xp or score", ErrInvalidLeaderboard)
+	}
+	switch q.Period {
+	case models.LeaderboardPeriodWeek, models.LeaderboardPeriodMonth, models.LeaderboardPeriodAll:
+	default:
+		return nil, fmt.Errorf("%w: period must be week, month or all", ErrInvalidLeaderboard)
+	}
+
+	settings, err := repository.GetUserSettings(ctx, q.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch q.Scope {
+	case models.LeaderboardScopePlatform:
+		q.ScopeID = ""
+	case models.LeaderboardScopeCourse, models.LeaderboardScopeClass:
+		if q.ScopeID == "" {
+			return nil, fmt.Errorf("%w: id is required for %s leaderboards", ErrInvalidLeaderboard, q.Scope)
+		}
+	case models.LeaderboardScopeRegion:
+		if q.ScopeID == "" && settings.Region != nil {
+			q.ScopeID = *settings.Region
+		}
+		if !kazakhstanRegions[q.ScopeID] {
+			return nil, fmt.Errorf("%w: choose a region or set yours in settings", ErrInvalidLeaderboard)
+		}
+	default:
+		return nil, fmt.Errorf("%w: scope must be platform, course, class or region", ErrInvalidLeaderboard)
+	}
+
+	q.PeriodStart = leaderboardPeriodStart(q.Period, time.Now())
+	entries, me, participants, err := repository.GetLeaderboard(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	board := &models.Leaderboard{
+		Scope:        q.Scope,
+		ScopeID:      q.ScopeID,
+		Metric:       q.Metric,
+		Period:       q.Period,
+		Participants: participants,
+		Entries:      entries,
+		Me:           me,
+		OptedOut:     settings.LeaderboardOptOut,
+	}
+	if q.Period != models.LeaderboardPeriodAll {
+		board.PeriodStart = q.PeriodStart.Format("2006-01-02")
+	}
+	return board, nil
+}
+
+func GetUserSettings(ctx context.Context, userID string) (*models.UserSettings, error) {
+	return repository.GetUserSettings(ctx, userID)
+}
+
+func SaveUserSettings(ctx context.Context, userID string, settings models.UserSettings) error {
+	if settings.Region != nil {
+		region := strings.ToLower(strings.TrimSpace(*settings.Region))
+		if region == "" {
+			settings.Region = nil
+		} else if !kazakhstanRegions[region] {
+			return fmt.Errorf("%w: unknown region %q", ErrInvalidSettings, *settings.Region)
+		} else {
+			settings.Region = &region
+		}
+	}
+	return repository.SaveUserSettings(ctx, userID, settings)
+}
+
+// CreateClassGroup creates a teacher's class group with a fresh join code for students.
+func CreateClassGroup(ctx context.Context, teacherID, name string) (*models.ClassGroup, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidClassGroup)
+	}
+
+	b := make([]byte, joinCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	code := make([]byte, joinCodeLength)
+	for i, c := range b {
+		code[i] = serialAlphabet[int(c)%len(serialAlphabet)]
+	}
+	return repository.CreateClassGroup(ctx, name, teacherID, string(code))
+}
+
+// JoinClassGroup adds a student to the class group with the code; codes are case-insensitive.
+func JoinClassGroup(ctx context.Context, code, userID string) (*models.ClassGroup, error) {
+	return repository.JoinClassGroup(ctx, strings.ToUpper(strings.TrimSpace(code)), userID)
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+const (
+	moderationWordsTTL = 5 * time.Minute
+	// A run of this many identical characters, or a long post mostly in capitals, looks like
+	// spam.
+	maxRepeatedChars   = 10
+	minLettersForCaps  = 20
+	maxCapitalsShare   = 0.7
+	duplicatePostsSpan = time.Hour
+)
+
+var linkPattern = regexp.MustCompile(`(?i)(https?://|www\.)\S+`)
+
+// cyrillicLookalikes maps Latin letters and digits that look like Cyrillic ones, so that
+// "мaт" written with a Latin "a" still matches the word list.
+var cyrillicLookalikes = map[rune]rune{
+	'a': 'а', 'b': 'в', 'c': 'с', 'e': 'е', 'h': 'н', 'k': 'к', 'm': 'м',
+	'o': 'о', 'p': 'р', 't': 'т', 'x': 'х', 'y': 'у', '0': 'о', '3': 'з',
+}
+
+var (
+	moderationWordsMu      sync.Mutex
+	moderationWordsCache   []string
+	moderationWordsExpires time.Time
+)
+
+// InvalidateModerationWords drops the cached word list after it changed.
+func InvalidateModerationWords() {
+	moderationWordsMu.Lock()
+	moderationWordsExpires = time.Time{}
+	moderationWordsMu.Unlock()
+}
+
+// moderationWords returns the Russian and Kazakh filter words, normalized, cached for a few
+// minutes so that changes made on another instance are picked up.
+func moderationWords(ctx context.Context) ([]string, error) {
+	moderationWordsMu.Lock()
+	defer moderationWordsMu.Unlock()
+	if time.Now().Before(moderationWordsExpires) {
+		return moderationWordsCache, nil
+	}
+
+	words, err := repository.GetModerationWords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	moderationWordsCache = make([]string, 0, len(words))
+	for _, w := range words {
+		moderationWordsCache = append(moderationWordsCache, normalizeWord(w.Word))
+	}
+	moderationWordsExpires = time.Now().Add(moderationWordsTTL)
+	return moderationWordsCache, nil
+}
+
+// normalizeWord lowercases a word, folds ё into е and, in words with Cyrillic letters,
+// replaces Latin lookalikes with their Cyrillic twins.
+func normalizeWord(word string) string {
+	word = strings.ReplaceAll(strings.ToLower(word), "ё", "е")
+	cyrillic := false
+	for _, r := range word {
+		if unicode.Is(unicode.Cyrillic, r) {
+			cyrillic = true
+			break
+		}
+	}
+	if !cyrillic {
+		return word
+	}
+	return strings.Map(func(r rune) rune {
+		if c, ok := cyrillicLookalikes[r]; ok {
+			return c
+		}
+		return r
+	}, word)
+}
+
+// screenPost runs the automatic filter over a post and returns why it should be held for
+// review, or "" when it can be published. It looks for words of the Russian and Kazakh
+// lists, more than DISCUSSION_MAX_LINKS (2 by default) links, runs of repeated characters,
+// shouting and, when checkDuplicates is set, the same text posted by the author within the
+// last hour. Failures to read the word list or past posts are logged and let the post through.
+func screenPost(ctx context.Context, userID, text string, checkDuplicates bool) string {
+	var reasons []string
+
+	words, err := moderationWords(ctx)
+	if err != nil {
+		log.Printf("Moderation filter error: %v", err)
+	}
+	if containsFilteredWord(text, words) {
+		reasons = append(reasons, "filtered word")
+	}
+	if len(linkPattern.FindAllStringIndex(text, -1)) > envInt("DISCUSSION_MAX_LINKS", 2) {
+		reasons = append(reasons, "too many links")
+	}
+	if longestRun(text) >= maxRepeatedChars {
+		reasons = append(reasons, "repeated characters")
+	}
+	if shouting(text) {
+		reasons = append(reasons, "excessive capitals")
+	}
+	if checkDuplicates {
+		n, err := repository.CountRecentPosts(ctx, userID, text, time.Now().Add(-duplicatePostsSpan))
+		if err != nil {
+			log.Printf("Moderation filter error: %v", err)
+		} else if n > 0 {
+			reasons = append(reasons, "duplicate post")
+		}
+	}
+	return strings.Join(reasons, ", ")
+}
+
+// containsFilteredWord reports whether a word of the text starts with one of the filter
+// words.
+func containsFilteredWord(text string, words []string) bool {
+	if len(words) == 0 {
+		return false
+	}
+	tokens := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, token := range tokens {
+		token = normalizeWord(token)
+		for _, w := range words {
+			if w != "" && strings.HasPrefix(token, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func longestRun(text string) int {
+	longest, run := 0, 0
+	var prev rune
+	for i, r := range text {
+		if i > 0 && r == prev && !unicode.IsSpace(r) {
+			run++
+		} else {
+			run = 1
+		}
+		prev = r
+		longest = maxInt(longest, run)
+	}
+	return longest
+}
+
+func shouting(text string) bool {
+	letters, upper := 0, 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	return letters >= minLettersForCaps && float64(upper)/float64(letters) > maxCapitalsShare
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+var (
+	ErrForumBanned       = errors.New("you are banned from this forum")
+	ErrInvalidReport     = errors.New("invalid report")
+	ErrInvalidModeration = errors.New("invalid moderation action")
+	ErrInvalidFilterWord = errors.New("invalid filter word")
+)
+
+var (
+	reportReasons       = map[string]bool{"spam": true, "offensive": true, "harassment": true, "inappropriate": true, "other": true}
+	moderationWordLangs = map[string]bool{"ru": true, "kk": true}
+)
+
+// checkForumBan fails with ErrForumBanned while the user is banned from the course forum.
+func checkForumBan(ctx context.Context, courseID, userID string) error {
+	banned, err := repository.IsForumBanned(ctx, courseID, userID)
+	if err != nil {
+		return err
+	}
+	if banned {
+		return ErrForumBanned
+	}
+	return nil
+}
+
+// recordAutoHold logs a post held by the filter or by reports. The post is already saved, so
+// a failure is only logged.
+func recordAutoHold(ctx context.Context, courseID, targetType, targetID, authorID, reason string) {
+	err := repository.AddModerationLog(ctx, models.ModerationLogEntry{
+		CourseID:     courseID,
+		Action:       models.ModerationAutoHold,
+		TargetType:   targetType,
+		TargetID:     &targetID,
+		TargetUserID: &authorID,
+		Reason:       reason,
+	})
+	if err != nil {
+		log.Printf("Moderation log error for %s %s: %v", targetType, targetID, err)
+	}
+}
+
+// ReportPost records a user's report of a discussion of the course, or of one of its replies
+// when replyID is set. Once DISCUSSION_REPORT_HOLD (3 by default) users reported a post it is
+// held for review.
+func ReportPost(ctx context.Context, courseID, discussionID, replyID, reporterID string, req models.ReportRequest) error {
+	if !reportReasons[req.Reason] {
+		return fmt.Errorf("%w: reason must be spam, offensive, harassment, inappropriate or other", ErrInvalidReport)
+	}
+
+	discussion, err := repository.GetDiscussion(ctx, courseID, discussionID)
+	if err != nil {
+		return err
+	}
+	targetType, targetID := models.VoteTargetDiscussion, discussion.ID
+	authorID, status := discussion.UserID, discussion.ModerationStatus
+	if replyID != "" {
+		reply, err := repository.GetDiscussionReply(ctx, discussionID, replyID)
+		if err != nil {
+			return err
+		}
+		if reply.IsDeleted {
+			return models.ErrNotFound
+		}
+		targetType, targetID = models.VoteTargetReply, reply.ID
+		authorID, status = reply.UserID, reply.ModerationStatus
+	}
+	if authorID == reporterID {
+		return fmt.Errorf("%w: cannot report your own post", ErrInvalidReport)
+	}
+
+	pending, err := repository.CreateReport(ctx, courseID, targetType, targetID, reporterID, req)
+	if err != nil {
+		return err
+	}
+	if status == models.PostVisible && pending >= envInt("DISCUSSION_REPORT_HOLD", 3) {
+		reason := fmt.Sprintf("reported by %d users", pending)
+		if err := repository.SetPostModerationStatus(ctx, targetType, targetID, models.PostHeld, reason); err != nil {
+			return err
+		}
+		recordAutoHold(ctx, courseID, targetType, targetID, authorID, reason)
+	}
+	return nil
+}
+
+// publishReleasedPost sends out what creating a post sends when it isn't held: mentions and
+// reply notifications, and the live update of a reply. A post held when written goes out
+// when a moderator first releases it.
+func publishReleasedPost(ctx context.Context, courseID, targetType, targetID, discussionID string) {
+	if targetType == models.VoteTargetDiscussion {
+		discussion, err := repository.GetDiscussion(ctx, courseID, discussionID)
+		if err != nil {
+			log.Printf("Released post error: %v", err)
+			return
+		}
+		queueDiscussionEvents(ctx, courseID, discussion.ID, nil, discussion.UserID, discussion.Title+"\n"+discussion.Content)
+		return
+	}
+	reply, err := repository.GetDiscussionReply(ctx, discussionID, targetID)
+	if err != nil {
+		log.Printf("Released post error: %v", err)
+		return
+	}
+	queueDiscussionEvents(ctx, courseID, discussionID, &reply.ID, reply.UserID, reply.Content)
+	PublishRealtime(ctx, DiscussionTopic(discussionID), models.RealtimeDiscussionReply, reply)
+}
+
+// Moderate carries out a moderator's action in a course forum, or with no courseID in the
+// platform forum, and records it in the moderation log. Hiding a post resolves its reports; restoring or dismissing dismisses them.
+func Moderate(ctx context.Context, courseID, moderatorID string, req models.ModerationActionRequest) error {
+	entry := models.ModerationLogEntry{
+		CourseID:    courseID,
+		ModeratorID: &moderatorID,
+		Action:      req.Action,
+		TargetType:  req.TargetType,
+		Reason:      strings.TrimSpace(req.Reason),
+	}
+
+	switch req.Action {
+	case models.ModerationHide, models.ModerationRestore, models.ModerationDismiss:
+		if req.TargetType != models.VoteTargetDiscussion && req.TargetType != models.VoteTargetReply {
+			return fmt.Errorf("%w: target_type must be discussion or reply", ErrInvalidModeration)
+		}
+		postCourseID, authorID, err := repository.GetPostOwner(ctx, req.TargetType, req.TargetID)
+		if err != nil {
+			return err
+		}
+		if postCourseID != courseID {
+			return models.ErrNotFound
+		}
+		entry.TargetID, entry.TargetUserID = &req.TargetID, &authorID
+
+		switch req.Action {
+		case models.ModerationHide:
+			err = repository.SetPostModerationStatus(ctx, req.TargetType, req.TargetID, models.PostHidden, entry.Reason)
+			if err == nil {
+				err = repository.ResolveReports(ctx, req.TargetType, req.TargetID, models.ReportActioned, moderatorID)
+			}
+		case models.ModerationRestore:
+			var first bool
+			var discussionID string
+			first, discussionID, err = repository.RestorePost(ctx, req.TargetType, req.TargetID)
+			if err == nil {
+				err = repository.ResolveReports(ctx, req.TargetType, req.TargetID, models.ReportDismissed, moderatorID)
+			}
+			if err == nil && first {
+				publishReleasedPost(ctx, courseID, req.TargetType, req.TargetID, discussionID)
+			}
+		default:
+			err = repository.ResolveReports(ctx, req.TargetType, req.TargetID, models.ReportDismissed, moderatorID)
+		}
+		if err != nil {
+			return err
+		}
+
+	case models.ModerationLock, models.ModerationUnlock, models.ModerationPin, models.ModerationUnpin:
+		discussion, err := repository.GetDiscussion(ctx, courseID, req.TargetID)
+		if err != nil {
+			return err
+		}
+		entry.TargetType, entry.TargetID, entry.TargetUserID = models.VoteTargetDiscussion, &discussion.ID, &discussion.UserID
+
+		switch req.Action {
+		case models.ModerationLock, models.ModerationUnlock:
+			err = repository.SetDiscussionClosed(ctx, discussion.ID, req.Action == models.ModerationLock)
+		default:
+			err = repository.SetDiscussionPinned(ctx, discussion.ID, req.Action == models.ModerationPin)
+		}
+		if err != nil {
+			return err
+		}
+
+	case models.ModerationBan, models.ModerationUnban:
+		if req.UserID == "" || req.UserID == moderatorID {
+			return fmt.Errorf("%w: user_id must name another user", ErrInvalidModeration)
+		}
+		entry.TargetType, entry.TargetID, entry.TargetUserID = models.ModerationTargetUser, nil, &req.UserID
+
+		var err error
+		if req.Action == models.ModerationBan {
+			if req.DurationDays < 0 {
+				return fmt.Errorf("%w: duration_days cannot be negative", ErrInvalidModeration)
+			}
+			// Only members of the course can be banned from its forum; anyone can be banned from
+			// the platform forum.
+			if courseID != "" {
+				if _, err := repository.GetEnrollment(ctx, req.UserID, courseID); err != nil {
+					return err
+				}
+			} else if _, err := repository.GetUserByID(ctx, req.UserID); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return models.ErrNotFound
+				}
+				return err
+			}
+			var expiresAt *time.Time
+			if req.DurationDays > 0 {
+				t := time.Now().AddDate(0, 0, req.DurationDays)
+				expiresAt = &t
+			}
+			err = repository.BanForumUser(ctx, courseID, req.UserID, moderatorID, entry.Reason, expiresAt)
+		} else {
+			err = repository.UnbanForumUser(ctx, courseID, req.UserID)
+		}
+		if err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidModeration, req.Action)
+	}
+
+	return repository.AddModerationLog(ctx, entry)
+}
+
+// GetModerationQueue lists the held and reported posts of a course, or of every course when
+// courseID is empty.
+func GetModerationQueue(ctx context.Context, courseID string, page, limit int) (*models.ModerationQueuePage, error) {
+	items, total, err := repository.GetModerationQueue(ctx, courseID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ModerationQueuePage{Items: items, Page: page, Limit: limit, Total: total}, nil
+}
+
+// GetModerationLog lists the moderation actions in a course, or in every course when
+// courseID is empty, newest first.
+func GetModerationLog(ctx context.Context, courseID string, page, limit int) (*models.ModerationLogPage, error) {
+	items, total, err := repository.GetModerationLog(ctx, courseID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ModerationLogPage{Items: items, Page: page, Limit: limit, Total: total}, nil
+}
+
+// AddModerationWord adds a single Russian or Kazakh word or stem to the automatic filter.
+func AddModerationWord(ctx context.Context, userID string, req models.ModerationWordRequest) (*models.ModerationWord, error) {
+	if !moderationWordLangs[req.Language] {
+		return nil, fmt.Errorf("%w: language must be ru or kk", ErrInvalidFilterWord)
+	}
+	word := strings.ToLower(strings.TrimSpace(req.Word))
+	if word == "" || strings.IndexFunc(word, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) >= 0 {
+		return nil, fmt.Errorf("%w: a single word is required", ErrInvalidFilterWord)
+	}
+
+	w, err := repository.AddModerationWord(ctx, req.Language, word, userID)
+	if err != nil {
+		return nil, err
+	}
+	InvalidateModerationWords()
+	return w, nil
+}
+
+func DeleteModerationWord(ctx context.Context, id string) error {
+	if err := repository.DeleteModerationWord(ctx, id); err != nil {
+		return err
+	}
+	InvalidateModerationWords()
+	return nil
+}
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+// Moodle XML quiz format: https://docs.moodle.org/en/Moodle_XML_format
+
+type moodleText struct {
+	Format string       `xml:"format,attr,omitempty"`
+	Text   string       `xml:"text"`
+	Files  []moodleFile `xml:"file,omitempty"`
+}
+
+type moodleFile struct {
+	Name string `xml:"name,attr"`
+}
+
+type moodleAnswer struct {
+	Fraction string      `xml:"fraction,attr"`
+	Format   string      `xml:"format,attr,omitempty"`
+	Text     string      `xml:"text"`
+	Feedback *moodleText `xml:"feedback,omitempty"`
+}
+
+type moodleTags struct {
+	Tags []moodleTag `xml:"tag"`
+}
+
+type moodleTag struct {
+	Text string `xml:"text"`
+}
+
+type moodleQuestion struct {
+	XMLName         xml.Name       `xml:"question"`
+	Type            string         `xml:"type,attr"`
+	Category        *moodleText    `xml:"category,omitempty"`
+	Name            *moodleText    `xml:"name,omitempty"`
+	QuestionText    *moodleText    `xml:"questiontext,omitempty"`
+	GeneralFeedback *moodleText    `xml:"generalfeedback,omitempty"`
+	DefaultGrade    string         `xml:"defaultgrade,omitempty"`
+	Single          string         `xml:"single,omitempty"`
+	ShuffleAnswers  string         `xml:"shuffleanswers,omitempty"`
+	Answers         []moodleAnswer `xml:"answer"`
+	Tags            *moodleTags    `xml:"tags,omitempty"`
+}
+
+type moodleQuiz struct {
+	XMLName   xml.Name         `xml:"quiz"`
+	Questions []moodleQuestion `xml:"question"`
+}
+
+func parseMoodleXML(data []byte) (*importResult, error) {
+	result := &importResult{}
+	subject, topic := "", ""
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			line, _ := decoder.InputPos()
+			return nil, fmt.Errorf("invalid XML near line %d: %w", line, err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "question" {
+			continue
+		}
+		line, _ := decoder.InputPos()
+
+		var mq moodleQuestion
+		if err := decoder.DecodeElement(&mq, &start); err != nil {
+			return nil, fmt.Errorf("invalid question near line %d: %w", line, err)
+		}
+
+		if mq.Type == "category" {
+			if mq.Category != nil {
+				subject, topic = categoryPath(mq.Category.Text)
+			}
+			continue
+		}
+
+		result.found++
+		if q, ok := convertMoodleQuestion(result, line, mq); ok {
+			q.Subject, q.Topic = subject, topic
+			result.questions = append(result.questions, importedQuestion{line: line, question: q})
+		}
+	}
+
+	return result, nil
+}
+
+func convertMoodleQuestion(result *importResult, line int, mq moodleQuestion) (models.QuestionRequest, bool) {
+	var q models.QuestionRequest
+
+	switch mq.Type {
+	case "multichoice":
+		q.QuestionType = "multiple_choice"
+	case "truefalse":
+		q.QuestionType = "true_false"
+	case "shortanswer":
+		q.QuestionType = "short_answer"
+	case "essay":
+		q.QuestionType = "essay"
+	default:
+		result.skip(line, "question type %q is not supported", mq.Type)
+		return q, false
+	}
+
+	if mq.QuestionText == nil {
+		result.skip(line, "question has no questiontext")
+		return q, false
+	}
+	if strings.Contains(mq.QuestionText.Text, "@@PLUGINFILE@@") || len(mq.QuestionText.Files) > 0 {
+		result.warn(line, "embedded files are not supported and were dropped")
+	}
+	q.QuestionText, q.QuestionTextKK = result.localized(line, mq.QuestionText.Text)
+	if mq.GeneralFeedback != nil {
+		q.Explanation, q.ExplanationKK = result.localized(line, mq.GeneralFeedback.Text)
+	}
+
+	if grade, err := strconv.ParseFloat(strings.TrimSpace(mq.DefaultGrade), 64); err == nil && grade > 0 {
+		q.Points = int(grade + 0.5)
+		if float64(q.Points) != grade {
+			result.warn(line, "fractional grade %g rounded to %d", grade, q.Points)
+		}
+	}
+
+	if mq.Tags == nil {
+		mq.Tags = &moodleTags{}
+	}
+	for _, tag := range mq.Tags.Tags {
+		value := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag.Text), "difficulty:"))
+		if difficulties[value] {
+			q.Difficulty = value
+		}
+	}
+
+	for _, answer := range mq.Answers {
+		if answer.Feedback != nil && strings.TrimSpace(answer.Feedback.Text) != "" {
+			result.warn(line, "answer feedback is not supported and was dropped")
+			break
+		}
+	}
+
+	switch q.QuestionType {
+	case "true_false":
+		t, f := trueOption, falseOption
+		for _, answer := range mq.Answers {
+			correct := parseFraction(answer.Fraction) > 0
+			if strings.EqualFold(strings.TrimSpace(answer.Text), "true") {
+				t.IsCorrect = correct
+			} else {
+				f.IsCorrect = correct
+			}
+		}
+		q.Options = []models.QuestionOptionRequest{t, f}
+	case "short_answer":
+		var accepted []string
+		for _, answer := range mq.Answers {
+			if parseFraction(answer.Fraction) == 100 {
+				accepted = append(accepted, strings.TrimSpace(answer.Text))
+			} else if parseFraction(answer.Fraction) > 0 {
+				result.warn(line, "partial credit answer %q was dropped", answer.Text)
+			}
+		}
+		if len(accepted) > 0 {
+			q.CorrectAnswer = accepted[0]
+		}
+		if len(accepted) > 1 {
+			result.warn(line, "only the first of %d accepted answers was kept", len(accepted))
+		}
+	case "multiple_choice":
+		for _, answer := range mq.Answers {
+			fraction := parseFraction(answer.Fraction)
+			if fraction != 0 && fraction != 100 && mq.Single != "false" {
+				result.warn(line, "partial credit %g%% is not supported; the option is imported as %s", fraction, map[bool]string{true: "correct", false: "wrong"}[fraction > 0])
+			}
+			option := models.QuestionOptionRequest{IsCorrect: fraction > 0}
+			option.OptionText, option.OptionTextKK = result.localized(line, answer.Text)
+			q.Options = append(q.Options, option)
+		}
+	}
+
+	return q, true
+}
+
+func parseFraction(value string) float64 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+func writeMoodleXML(questions []models.Question) ([]byte, error) {
+	quiz := moodleQuiz{}
+	category := ""
+
+	for _, q := range questions {
+		if c := categoryFor(q); c != category {
+			category = c
+			quiz.Questions = append(quiz.Questions, moodleQuestion{Type: "category", Category: &moodleText{Text: category}})
+		}
+
+		mq := moodleQuestion{
+			Name:         &moodleText{Text: shortName(q.QuestionText)},
+			QuestionText: &moodleText{Format: "html", Text: joinMultilang(q.QuestionText, q.QuestionTextKK)},
+			DefaultGrade: strconv.Itoa(q.Points),
+		}
+		if q.Difficulty != "" {
+			mq.Tags = &moodleTags{Tags: []moodleTag{{Text: "difficulty:" + q.Difficulty}}}
+		}
+		if q.Explanation != "" {
+			mq.GeneralFeedback = &moodleText{Format: "html", Text: joinMultilang(q.Explanation, q.ExplanationKK)}
+		}
+
+		switch q.QuestionType {
+		case "multiple_choice":
+			mq.Type = "multichoice"
+			mq.ShuffleAnswers = "true"
+			correct := 0
+			for _, option := range q.Options {
+				if option.IsCorrect {
+					correct++
+				}
+			}
+			mq.Single = strconv.FormatBool(correct == 1)
+			for _, option := range q.Options {
+				fraction := "0"
+				if option.IsCorrect {
+					fraction = strconv.FormatFloat(100/float64(correct), 'f', -1, 64)
+				}
+				mq.Answers = append(mq.Answers, moodleAnswer{Fraction: fraction, Format: "html", Text: joinMultilang(option.OptionText, option.OptionTextKK)})
+			}
+		case "true_false":
+			mq.Type = "truefalse"
+			isTrue := trueFalseAnswer(q)
+			mq.Answers = []moodleAnswer{
+				{Fraction: map[bool]string{true: "100", false: "0"}[isTrue], Format: "moodle_auto_format", Text: "true"},
+				{Fraction: map[bool]string{true: "0", false: "100"}[isTrue], Format: "moodle_auto_format", Text: "false"},
+			}
+		case "short_answer":
+			mq.Type = "shortanswer"
+			mq.Answers = []moodleAnswer{{Fraction: "100", Format: "moodle_auto_format", Text: q.CorrectAnswer}}
+		default:
+			mq.Type = "essay"
+		}
+		quiz.Questions = append(quiz.Questions, mq)
+	}
+
+	body, err := xml.MarshalIndent(quiz, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encoding Moodle XML: %w", err)
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+func shortName(text string) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) > 50 {
+		return string(runes[:50]) + "…"
+	}
+	return string(runes)
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+var ErrInvalidNotificationPreferences = errors.New("invalid notification preferences")
+
+// Notify delivers a notification to each of the users who haven't turned its type off in
+// the app, pushes it to their live streams and e-mails it to those who opted in to e-mail.
+// Failures are logged; a notification never fails the action that caused it.
+func Notify(ctx context.Context, n models.Notification, userIDs ...string) {
+	if len(userIDs) == 0 {
+		return
+	}
+	recipients, err := repository.FilterNotificationRecipients(ctx, userIDs, n.Type, models.ChannelInApp)
+	if err != nil {
+		log.Printf("Notification error for %s: %v", n.Type, err)
+	}
+	for _, userID := range recipients {
+		n.UserID = userID
+		saved, err := repository.SaveNotification(ctx, n)
+		if err != nil {
+			log.Printf("Notification error for %s to user %s: %v", n.Type, userID, err)
+			continue
+		}
+		PublishRealtime(ctx, UserTopic(userID), models.RealtimeNotification, saved)
+	}
+	emailNotification(ctx, n, userIDs)
+}
+
+// GetNotifications returns one page of the user's notifications with their unread count.
+func GetNotifications(ctx context.Context, userID string, unreadOnly bool, page, limit int) (*models.NotificationPage, error) {
+	items, total, err := repository.GetNotificationPage(ctx, userID, unreadOnly, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := repository.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.NotificationPage{Items: items, Page: page, Limit: limit, Total: total, Unread: unread}, nil
+}
+
+// GetNotificationPreferences returns the user's opt-ins for every notification type, with
+// the defaults (everything on) filled in.
+func GetNotificationPreferences(ctx context.Context, userID string) (*models.NotificationPreferences, error) {
+	prefs, err := repository.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[string]models.ChannelPreferences, len(models.NotificationTypes))
+	for _, t := range models.NotificationTypes {
+		types[t] = models.ChannelPreferences{InApp: true, Email: true}
+	}
+	for t, channels := range prefs.Types {
+		if _, ok := types[t]; ok {
+			types[t] = channels
+		}
+	}
+	prefs.Types = types
+	return prefs, nil
+}
+
+// SaveNotificationPreferences stores the user's opt-ins. Types left out keep the defaults.
+func SaveNotificationPreferences(ctx context.Context, userID string, prefs models.NotificationPreferences) error {
+	known := make(map[string]bool, len(models.NotificationTypes))
+	for _, t := range models.NotificationTypes {
+		known[t] = true
+	}
+	for t := range prefs.Types {
+		if !known[t] {
+			return fmt.Errorf("%w: unknown notification type %q", ErrInvalidNotificationPreferences, t)
+		}
+	}
+	switch prefs.EmailDigest {
+	case "":
+		prefs.EmailDigest = models.DigestOff
+	case models.DigestOff, models.DigestDaily, models.DigestWeekly:
+	default:
+		return fmt.Errorf("%w: email_digest must be off, daily or weekly", ErrInvalidNotificationPreferences)
+	}
+	if prefs.Types == nil {
+		prefs.Types = map[string]models.ChannelPreferences{}
+	}
+	return repository.SaveNotificationPreferences(ctx, userID, prefs)
+}
+
+// StartNotificationPruning deletes notifications older than NOTIFICATION_RETENTION (90 days
+// by default) once a day.
+func StartNotificationPruning() {
+	retention := envDuration("NOTIFICATION_RETENTION", 90*24*time.Hour)
+
+	go func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			if _, err := repository.DeleteNotificationsBefore(ctx, time.Now().Add(-retention)); err != nil {
+				log.Printf("Notification pruning error: %v", err)
+			}
+			cancel()
+		}
+	}()
+}
+
+// notifyTestGraded tells a student their attempt was graded.
+func notifyTestGraded(ctx context.Context, attempt *models.TestAttempt, courseID string) {
+	title := "Your test has been graded"
+	link := ""
+	if courseID != "" {
+		if test, err := repository.GetTestByID(ctx, courseID, attempt.TestID); err == nil {
+			title = fmt.Sprintf("%s has been graded", test.Title)
+		}
+		link = fmt.Sprintf("/courses/%s/tests/%s", courseID, attempt.TestID)
+	}
+	body := "Your results are ready."
+	if attempt.Score != nil {
+		body = fmt.Sprintf("You scored %d%%.", *attempt.Score)
+	}
+	data, _ := json.Marshal(models.GradingResult{
+		AttemptID: attempt.ID,
+		TestID:    attempt.TestID,
+		CourseID:  courseID,
+		Score:     attempt.Score,
+		Status:    attempt.Status,
+	})
+	Notify(ctx, models.Notification{
+		Type:  models.NotificationGradedTest,
+		Title: title,
+		Body:  body,
+		Link:  link,
+		Data:  data,
+	}, attempt.UserID)
+}
+
+// NotifyEnrollment tells the course's teacher that a student enrolled.
+func NotifyEnrollment(ctx context.Context, courseID string, student *models.User) {
+	course, err := repository.GetCourseByID(ctx, courseID)
+	if err != nil {
+		log.Printf("Enrollment notification error for course %s: %v", courseID, err)
+		return
+	}
+	data, _ := json.Marshal(map[string]string{"course_id": courseID, "user_id": student.ID})
+	Notify(ctx, models.Notification{
+		Type:  models.NotificationEnrollment,
+		Title: course.Title,
+		Body:  fmt.Sprintf("%s %s enrolled in your course", student.FirstName, student.LastName),
+		Link:  fmt.Sprintf("/courses/%s/students", courseID),
+		Data:  data,
+	}, course.TeacherID)
+}
+
+// NotifyCourseUpdate tells the students of a course that it changed. A course can have many
+// students, so they are notified in the background.
+func NotifyCourseUpdate(course *models.Course) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		students, err := repository.GetCourseStudents(ctx, course.ID)
+		if err != nil {
+			log.Printf("Course update notification error for course %s: %v", course.ID, err)
+			return
+		}
+		userIDs := make([]string, len(students))
+		for i, s := range students {
+			userIDs[i] = s.UserID
+		}
+		data, _ := json.Marshal(map[string]string{"course_id": course.ID})
+		Notify(ctx, models.Notification{
+			Type:     models.NotificationCourseUpdate,
+			Title:    course.Title,
+			Body:     "The course has been updated",
+			Link:     "/courses/" + course.ID,
+			Data:     data,
+			GroupKey: "course_update:" + course.ID,
+		}, userIDs...)
+	}()
+}
@@ -0,0 +1,393 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A small single-page PDF writer for certificates. Text is set in an embedded TrueType font
+// addressed by glyph ID (Identity-H), so any script the font covers can be used, Kazakh and
+// Russian included. A ToUnicode map keeps the text searchable and copyable.
+
+var errInvalidFont = errors.New("invalid TrueType font")
+
+type ttfFont struct {
+	name       string
+	data       []byte
+	unitsPerEm int
+	ascent     int
+	descent    int
+	capHeight  int
+	bbox       [4]int
+	advances   []int
+	glyphs     map[rune]uint16
+}
+
+func loadTrueType(path string) (*ttfFont, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	font, err := parseTrueType(data)
+	if err != nil {
+		return nil, err
+	}
+	// The file name stands in for the PostScript name; PDF names cannot hold spaces.
+	font.name = strings.NewReplacer(" ", "", "/", "", "#", "").Replace(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	return font, nil
+}
+
+func parseTrueType(data []byte) (*ttfFont, error) {
+	if len(data) < 12 {
+		return nil, errInvalidFont
+	}
+	u16 := func(b []byte, off int) int { return int(binary.BigEndian.Uint16(b[off:])) }
+	i16 := func(b []byte, off int) int { return int(int16(binary.BigEndian.Uint16(b[off:]))) }
+	u32 := func(b []byte, off int) int { return int(binary.BigEndian.Uint32(b[off:])) }
+
+	tables := map[string][]byte{}
+	numTables := u16(data, 4)
+	for i := 0; i < numTables; i++ {
+		rec := 12 + 16*i
+		if rec+16 > len(data) {
+			return nil, errInvalidFont
+		}
+		offset, length := u32(data, rec+8), u32(data, rec+12)
+		if offset+length > len(data) {
+			return nil, errInvalidFont
+		}
+		tables[string(data[rec:rec+4])] = data[offset : offset+length]
+	}
+	for _, tag := range []string{"head", "hhea", "hmtx", "maxp", "cmap"} {
+		if tables[tag] == nil {
+			return nil, fmt.Errorf("%w: missing %s table", errInvalidFont, tag)
+		}
+	}
+
+	head, hhea, hmtx, maxp := tables["head"], tables["hhea"], tables["hmtx"], tables["maxp"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 {
+		return nil, errInvalidFont
+	}
+	f := &ttfFont{
+		data:       data,
+		unitsPerEm: u16(head, 18),
+		bbox:       [4]int{i16(head, 36), i16(head, 38), i16(head, 40), i16(head, 42)},
+		ascent:     i16(hhea, 4),
+		descent:    i16(hhea, 6),
+		glyphs:     map[rune]uint16{},
+	}
+	if f.unitsPerEm == 0 {
+		return nil, errInvalidFont
+	}
+	f.capHeight = f.ascent * 7 / 10
+	if os2 := tables["OS/2"]; len(os2) >= 90 && u16(os2, 0) >= 2 {
+		f.capHeight = i16(os2, 88)
+	}
+
+	numGlyphs, numMetrics := u16(maxp, 4), u16(hhea, 34)
+	if numMetrics == 0 || len(hmtx) < 4*numMetrics {
+		return nil, errInvalidFont
+	}
+	f.advances = make([]int, numGlyphs)
+	for g := range f.advances {
+		if g < numMetrics {
+			f.advances[g] = u16(hmtx, 4*g)
+		} else {
+			f.advances[g] = f.advances[numMetrics-1]
+		}
+	}
+
+	if err := f.parseCmap(tables["cmap"], u16, u32); err != nil {
+		return nil, err
+	}
+	f.name = "CertificateFont"
+	return f, nil
+}
+
+// parseCmap reads the Unicode character map, preferring the full-range format 12 subtable.
+func (f *ttfFont) parseCmap(cmap []byte, u16, u32 func([]byte, int) int) error {
+	var format4, format12 []byte
+	for i := 0; i < u16(cmap, 2); i++ {
+		rec := 4 + 8*i
+		platform, encoding, offset := u16(cmap, rec), u16(cmap, rec+2), u32(cmap, rec+4)
+		if offset >= len(cmap) {
+			continue
+		}
+		sub := cmap[offset:]
+		switch {
+		case u16(sub, 0) == 12 && (platform == 3 && encoding == 10 || platform == 0):
+			format12 = sub
+		case u16(sub, 0) == 4 && (platform == 3 && encoding == 1 || platform == 0):
+			format4 = sub
+		}
+	}
+
+	switch {
+	case format12 != nil:
+		groups := u32(format12, 12)
+		for i := 0; i < groups; i++ {
+			g := 16 + 12*i
+			start, end, glyph := u32(format12, g), u32(format12, g+4), u32(format12, g+8)
+			for c := start; c <= end; c++ {
+				f.glyphs[rune(c)] = uint16(glyph + c - start)
+			}
+		}
+	case format4 != nil:
+		segments := u16(format4, 6) / 2
+		ends := 14
+		starts := ends + 2*segments + 2
+		deltas := starts + 2*segments
+		rangeOffsets := deltas + 2*segments
+		for i := 0; i < segments; i++ {
+			start, end := u16(format4, starts+2*i), u16(format4, ends+2*i)
+			delta, rangeOffset := u16(format4, deltas+2*i), u16(format4, rangeOffsets+2*i)
+			for c := start; c <= end && c != 0xFFFF; c++ {
+				glyph := 0
+				if rangeOffset == 0 {
+					glyph = (c + delta) & 0xFFFF
+				} else {
+					addr := rangeOffsets + 2*i + rangeOffset + 2*(c-start)
+					if addr+2 > len(format4) {
+						continue
+					}
+					if glyph = u16(format4, addr); glyph != 0 {
+						glyph = (glyph + delta) & 0xFFFF
+					}
+				}
+				if glyph != 0 {
+					f.glyphs[rune(c)] = uint16(glyph)
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("%w: no Unicode character map", errInvalidFont)
+	}
+	return nil
+}
+
+// scale converts font units to thousandths of the font size.
+func (f *ttfFont) scale(v int) int {
+	return v * 1000 / f.unitsPerEm
+}
+
+// textWidth is the width of s in points at the given font size.
+func (f *ttfFont) textWidth(s string, size float64) float64 {
+	units := 0
+	for _, r := range s {
+		if g := int(f.glyphs[r]); g < len(f.advances) {
+			units += f.advances[g]
+		}
+	}
+	return float64(units) * size / float64(f.unitsPerEm)
+}
+
+// pdfDocument builds a one-page PDF. Fonts are registered before drawing and written with the
+// widths and Unicode mappings of the glyphs actually used.
+type pdfDocument struct {
+	width, height float64
+	content       bytes.Buffer
+	fonts         []*pdfFont
+}
+
+type pdfFont struct {
+	resource string
+	font     *ttfFont
+	used     map[uint16]rune
+}
+
+func newPDFDocument(width, height float64) *pdfDocument {
+	return &pdfDocument{width: width, height: height}
+}
+
+func (d *pdfDocument) addFont(font *ttfFont) *pdfFont {
+	f := &pdfFont{resource: fmt.Sprintf("F%d", len(d.fonts)+1), font: font, used: map[uint16]rune{}}
+	d.fonts = append(d.fonts, f)
+	return f
+}
+
+// setColor sets the stroke and fill color from a #RRGGBB string; malformed values give black.
+func (d *pdfDocument) setColor(hex string) {
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(strings.TrimPrefix(hex, "#"), "%02x%02x%02x", &r, &g, &b); err != nil {
+		r, g, b = 0, 0, 0
+	}
+	fmt.Fprintf(&d.content, "%.3f %.3f %.3f RG %.3f %.3f %.3f rg\n",
+		float64(r)/255, float64(g)/255, float64(b)/255, float64(r)/255, float64(g)/255, float64(b)/255)
+}
+
+// strokeRect outlines a rectangle; coordinates are from the bottom left corner of the page.
+func (d *pdfDocument) strokeRect(x, y, w, h, lineWidth float64) {
+	fmt.Fprintf(&d.content, "%.2f w %.2f %.2f %.2f %.2f re S\n", lineWidth, x, y, w, h)
+}
+
+func (d *pdfDocument) fillRect(x, y, w, h float64) {
+	fmt.Fprintf(&d.content, "%.2f %.2f %.2f %.2f re f\n", x, y, w, h)
+}
+
+func (d *pdfDocument) line(x1, y1, x2, y2, lineWidth float64) {
+	fmt.Fprintf(&d.content, "%.2f w %.2f %.2f m %.2f %.2f l S\n", lineWidth, x1, y1, x2, y2)
+}
+
+// text draws s with its baseline starting at x, y.
+func (d *pdfDocument) text(f *pdfFont, size, x, y float64, s string) {
+	var hex strings.Builder
+	for _, r := range s {
+		g := f.font.glyphs[r]
+		if _, ok := f.used[g]; !ok {
+			f.used[g] = r
+		}
+		fmt.Fprintf(&hex, "%04X", g)
+	}
+	fmt.Fprintf(&d.content, "BT /%s %.2f Tf %.2f %.2f Td <%s> Tj ET\n", f.resource, size, x, y, hex.String())
+}
+
+// centeredText draws s centered on x.
+func (d *pdfDocument) centeredText(f *pdfFont, size, x, y float64, s string) {
+	d.text(f, size, x-f.font.textWidth(s, size)/2, y, s)
+}
+
+// fitText returns the largest size not above max at which s fits in width.
+func fitText(f *pdfFont, max, width float64, s string) float64 {
+	if w := f.font.textWidth(s, max); w > width {
+		return max * width / w
+	}
+	return max
+}
+
+// qr draws a QR code as dark squares with its bottom left corner at x, y.
+func (d *pdfDocument) qr(modules [][]bool, x, y, size float64) {
+	module := size / float64(len(modules))
+	for row, line := range modules {
+		for col, dark := range line {
+			if dark {
+				d.fillRect(x+float64(col)*module, y+size-float64(row+1)*module, module, module)
+			}
+		}
+	}
+}
+
+// bytes serializes the document.
+func (d *pdfDocument) bytes() ([]byte, error) {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) int {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+		return len(offsets)
+	}
+	stream := func(dict string, data []byte) (int, error) {
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(data); err != nil {
+			return 0, err
+		}
+		if err := zw.Close(); err != nil {
+			return 0, err
+		}
+		return object(fmt.Sprintf("<< %s /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream",
+			dict, compressed.Len(), compressed.Bytes())), nil
+	}
+
+	out.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+
+	var fontRefs []string
+	for _, f := range d.fonts {
+		ref, err := f.write(object, stream)
+		if err != nil {
+			return nil, err
+		}
+		fontRefs = append(fontRefs, fmt.Sprintf("/%s %d 0 R", f.resource, ref))
+	}
+
+	content, err := stream("", d.content.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	// Catalog, page tree and page reference each other; their numbers are known in advance.
+	pages := len(offsets) + 2
+	page := object(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Contents %d 0 R /Resources << /Font << %s >> >> >>",
+		pages, d.width, d.height, content, strings.Join(fontRefs, " ")))
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%d 0 R] /Count 1 >>", page))
+	catalog := object(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, catalog, xref)
+	return out.Bytes(), nil
+}
+
+// write emits the font as a Type 0 font over a CIDFontType2 whose CIDs are glyph IDs.
+func (f *pdfFont) write(object func(string) int, stream func(string, []byte) (int, error)) (int, error) {
+	t := f.font
+	file, err := stream(fmt.Sprintf("/Length1 %d", len(t.data)), t.data)
+	if err != nil {
+		return 0, err
+	}
+	descriptor := object(fmt.Sprintf(
+		"<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		t.name, t.scale(t.bbox[0]), t.scale(t.bbox[1]), t.scale(t.bbox[2]), t.scale(t.bbox[3]),
+		t.scale(t.ascent), t.scale(t.descent), t.scale(t.capHeight), file))
+
+	glyphs := make([]int, 0, len(f.used))
+	for g := range f.used {
+		glyphs = append(glyphs, int(g))
+	}
+	sort.Ints(glyphs)
+
+	var widths, cmap strings.Builder
+	for _, g := range glyphs {
+		if g < len(t.advances) {
+			fmt.Fprintf(&widths, "%d [%d] ", g, t.scale(t.advances[g]))
+		}
+	}
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for i := 0; i < len(glyphs); i += 100 {
+		chunk := glyphs[i:minInt(i+100, len(glyphs))]
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", len(chunk))
+		for _, g := range chunk {
+			fmt.Fprintf(&cmap, "<%04X> <%s>\n", g, utf16Hex(f.used[uint16(g)]))
+		}
+		cmap.WriteString("endbfchar\n")
+	}
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend")
+	toUnicode, err := stream("", []byte(cmap.String()))
+	if err != nil {
+		return 0, err
+	}
+
+	cidFont := object(fmt.Sprintf(
+		"<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /CIDToGIDMap /Identity /W [%s] >>",
+		t.name, descriptor, widths.String()))
+	return object(fmt.Sprintf(
+		"<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		t.name, cidFont, toUnicode)), nil
+}
+
+// utf16Hex encodes a rune as UTF-16BE hex, as ToUnicode maps expect.
+func utf16Hex(r rune) string {
+	if r >= 0x10000 {
+		r -= 0x10000
+		return fmt.Sprintf("%04X%04X", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+	}
+	return fmt.Sprintf("%04X", r)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+var (
+	ErrNoPracticeQuestions = errors.New("no questions available for practice")
+	ErrSessionClosed       = errors.New("practice session is already completed")
+	ErrNotCurrentQuestion  = errors.New("question is not the current practice question")
+)
+
+// The next question is picked at random among this many most informative ones, so students
+// practising the same topic do not all see the same sequence.
+const practiceCandidates = 3
+
+// ENT points of a subject. Profile subjects are worth 50; the compulsory ones are listed
+// under their Russian, Kazakh and English names.
+var entMaxScores = map[string]int{
+	"история казахстана":         20,
+	"қазақстан тарихы":           20,
+	"history of kazakhstan":      20,
+	"математическая грамотность": 10,
+	"математикалық сауаттылық":   10,
+	"mathematical literacy":      10,
+	"грамотность чтения":         10,
+	"оқу сауаттылығы":            10,
+	"reading literacy":           10,
+}
+
+const entProfileMaxScore = 50
+
+// practicePool is the set of auto-graded bank questions a session draws from, with the IRT
+// parameters of each.
+type practicePool struct {
+	questions map[string]models.Question
+	params    map[string]models.ItemParams
+}
+
+func loadPracticePool(ctx context.Context, courseID, subject, topic string) (*practicePool, error) {
+	bank, err := repository.GetBankQuestions(ctx, courseID, models.QuestionBankFilter{Subject: subject, Topic: topic})
+	if err != nil {
+		return nil, err
+	}
+
+	pool := &practicePool{questions: map[string]models.Question{}, params: map[string]models.ItemParams{}}
+	var ids []string
+	for _, q := range bank {
+		if q.QuestionType == "essay" {
+			continue
+		}
+		pool.questions[q.ID] = q
+		pool.params[q.ID] = defaultItemParams(q)
+		ids = append(ids, q.ID)
+	}
+	if len(ids) == 0 {
+		return pool, nil
+	}
+
+	calibrated, err := repository.GetItemParams(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for id, p := range calibrated {
+		pool.params[id] = p
+	}
+	return pool, nil
+}
+
+// next picks an unanswered question that is among the most informative at ability theta.
+func (p *practicePool) next(theta float64, answered map[string]bool) *models.Question {
+	type candidate struct {
+		id          string
+		information float64
+	}
+	var candidates []candidate
+	for id, params := range p.params {
+		if !answered[id] {
+			candidates = append(candidates, candidate{id, itemInformation(theta, params)})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].information != candidates[j].information {
+			return candidates[i].information > candidates[j].information
+		}
+		return candidates[i].id < candidates[j].id
+	})
+	n := practiceCandidates
+	if len(candidates) < n {
+		n = len(candidates)
+	}
+	q := p.questions[candidates[rand.Intn(n)].id]
+	return &q
+}
+
+// entScore converts ability to the ENT score it corresponds to: the expected share of the
+// topic's questions answered correctly, scaled to the subject's ENT points.
+func (p *practicePool) entScore(subject string, theta float64) (int, int) {
+	max, ok := entMaxScores[strings.ToLower(strings.TrimSpace(subject))]
+	if !ok {
+		max = entProfileMaxScore
+	}
+	if len(p.params) == 0 {
+		return 0, max
+	}
+
+	expected := 0.0
+	for _, params := range p.params {
+		expected += irtProbability(theta, params)
+	}
+	return int(math.Round(expected / float64(len(p.params)) * float64(max))), max
+}
+
+func StartPracticeSession(ctx context.Context, userID, courseID string, req models.PracticeSessionRequest) (*models.PracticeSession, error) {
+	pool, err := loadPracticePool(ctx, courseID, req.Subject, req.Topic)
+	if err != nil {
+		return nil, err
+	}
+
+	first := pool.next(0, nil)
+	if first == nil {
+		return nil, ErrNoPracticeQuestions
+	}
+
+	session, err := repository.CreatePracticeSession(ctx, userID, courseID, req, first.ID)
+	if err != nil {
+		return nil, err
+	}
+	hideQuestionKey(first)
+	session.CurrentQuestion = first
+	return session, nil
+}
+
+// AnswerPracticeQuestion grades the answer to the current question, re-estimates ability and
+// either serves the next question or ends the session. The session ends once the standard
+// error drops below IRT_SE_THRESHOLD, after IRT_MAX_QUESTIONS questions or when the pool runs out.
+func AnswerPracticeQuestion(ctx context.Context, session *models.PracticeSession, req models.PracticeAnswerRequest) (*models.PracticeAnswerResult, error) {
+	if session.Status != "in_progress" {
+		return nil, ErrSessionClosed
+	}
+	if session.CurrentQuestionID == nil || *session.CurrentQuestionID != req.QuestionID {
+		return nil, ErrNotCurrentQuestion
+	}
+
+	pool, err := loadPracticePool(ctx, session.CourseID, session.Subject, session.Topic)
+	if err != nil {
+		return nil, err
+	}
+	question, ok := pool.questions[req.QuestionID]
+	if !ok {
+		// The question left the bank during the session.
+		q, err := repository.GetQuestionByID(ctx, req.QuestionID)
+		if err != nil {
+			return nil, err
+		}
+		question = *q
+		pool.params[q.ID] = defaultItemParams(*q)
+	}
+
+	isCorrect, _ := GradeAnswer(question, req.Answer)
+	correct := isCorrect != nil && *isCorrect
+
+	previous, err := repository.GetPracticeResponses(ctx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	answered := map[string]bool{question.ID: true}
+	items := []models.ItemParams{}
+	outcomes := []bool{}
+	for _, r := range previous {
+		answered[r.QuestionID] = true
+		params, ok := pool.params[r.QuestionID]
+		if !ok {
+			continue
+		}
+		items = append(items, params)
+		outcomes = append(outcomes, r.IsCorrect)
+	}
+	items = append(items, pool.params[question.ID])
+	outcomes = append(outcomes, correct)
+
+	theta, se := EstimateAbility(items, outcomes)
+
+	var next *models.Question
+	if se >= envFloat("IRT_SE_THRESHOLD", 0.3) && session.QuestionCount+1 < envInt("IRT_MAX_QUESTIONS", 30) {
+		next = pool.next(theta, answered)
+	}
+	var nextID *string
+	if next != nil {
+		nextID = &next.ID
+	}
+
+	updated, err := repository.SavePracticeAnswer(ctx, models.PracticeResponse{
+		SessionID:          session.ID,
+		QuestionID:         question.ID,
+		Answer:             req.Answer,
+		IsCorrect:          correct,
+		ThetaAfter:         theta,
+		StandardErrorAfter: se,
+	}, nextID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, ErrNotCurrentQuestion
+		}
+		return nil, err
+	}
+
+	if next != nil {
+		hideQuestionKey(next)
+		updated.CurrentQuestion = next
+	} else {
+		ability := models.Ability{
+			CourseID:      updated.CourseID,
+			Subject:       updated.Subject,
+			Topic:         updated.Topic,
+			Theta:         theta,
+			StandardError: se,
+		}
+		ability.EntScore, ability.EntMaxScore = pool.entScore(updated.Subject, theta)
+		if err := repository.SaveAbility(ctx, updated.UserID, ability); err != nil {
+			return nil, err
+		}
+		updated.Ability = &ability
+	}
+
+	return &models.PracticeAnswerResult{
+		IsCorrect:     correct,
+		CorrectAnswer: question.CorrectAnswer,
+		Explanation:   question.Explanation,
+		ExplanationKK: question.ExplanationKK,
+		Session:       updated,
+	}, nil
+}
+
+// LoadPracticeSessionView fills in what a client needs to resume a session: the current
+// question while in progress, the ENT estimate once completed.
+func LoadPracticeSessionView(ctx context.Context, session *models.PracticeSession) error {
+	if session.Status == "in_progress" && session.CurrentQuestionID != nil {
+		q, err := repository.GetQuestionByID(ctx, *session.CurrentQuestionID)
+		if err != nil {
+			return err
+		}
+		hideQuestionKey(q)
+		session.CurrentQuestion = q
+		return nil
+	}
+
+	pool, err := loadPracticePool(ctx, session.CourseID, session.Subject, session.Topic)
+	if err != nil {
+		return err
+	}
+	ability := models.Ability{
+		CourseID:      session.CourseID,
+		Subject:       session.Subject,
+		Topic:         session.Topic,
+		Theta:         session.Theta,
+		StandardError: session.StandardError,
+	}
+	ability.EntScore, ability.EntMaxScore = pool.entScore(session.Subject, session.Theta)
+	session.Ability = &ability
+	return nil
+}
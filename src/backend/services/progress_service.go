@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+// Course completion rules: what counts towards enrollments.progress.
+const (
+	CompletionRuleLessons         = "lessons"
+	CompletionRuleTests           = "tests"
+	CompletionRuleLessonsAndTests = "lessons_and_tests"
+)
+
+// maxHeartbeatSeconds caps the time one heartbeat can add. The repository also limits it to
+// the time since the previous heartbeat, so sending heartbeats faster than real time doesn't
+// inflate time spent either.
+const maxHeartbeatSeconds = 120
+
+func IsValidCompletionRule(rule string) bool {
+	return rule == CompletionRuleLessons || rule == CompletionRuleTests || rule == CompletionRuleLessonsAndTests
+}
+
+// ComputeCourseProgress returns the completion percentage under the course rule and whether it
+// reaches the rule's threshold. A course with nothing to complete is never complete.
+func ComputeCourseProgress(counts models.CompletionCounts) (int, bool) {
+	var done, total int
+	switch counts.Rule {
+	case CompletionRuleLessons:
+		done, total = counts.LessonsCompleted, counts.Lessons
+	case CompletionRuleTests:
+		done, total = counts.TestsPassed, counts.Tests
+	default:
+		done, total = counts.LessonsCompleted+counts.TestsPassed, counts.Lessons+counts.Tests
+	}
+	if total == 0 {
+		return 0, false
+	}
+
+	progress := done * 100 / total
+	return progress, progress >= counts.Threshold
+}
+
+func RecordLessonHeartbeat(ctx context.Context, userID string, lessonID int, req models.LessonHeartbeatRequest) (*models.LessonProgress, error) {
+	position := req.PositionSeconds
+	if position < 0 {
+		position = 0
+	}
+	spent := req.SecondsSpent
+	if spent < 0 {
+		spent = 0
+	}
+	if spent > maxHeartbeatSeconds {
+		spent = maxHeartbeatSeconds
+	}
+	progress, added, err := repository.RecordLessonHeartbeat(ctx, userID, lessonID, position, spent)
+	if err != nil {
+		return nil, err
+	}
+	recordStudyTime(ctx, userID, added)
+	return progress, nil
+}
+
+func CompleteLesson(ctx context.Context, userID, courseID string, lessonID int) (*models.LessonProgress, error) {
+	progress, err := repository.CompleteLesson(ctx, userID, lessonID)
+	if err != nil {
+		return nil, err
+	}
+	recordStudyTime(ctx, userID, 0)
+	// Public lessons can be completed without enrolling, but only enrolled students earn XP.
+	if _, err := repository.GetEnrollment(ctx, userID, courseID); err == nil {
+		AwardLessonXP(ctx, userID, courseID, lessonID)
+	} else if !errors.Is(err, models.ErrNotFound) {
+		log.Printf("Gamification error for user %s: %v", userID, err)
+	}
+	return progress, RecomputeCourseProgress(ctx, userID, courseID)
+}
+
+// RecomputeCourseProgress updates the student's enrollment from completed lessons and passed
+// tests and issues the certificate once the course is completed. It does nothing for students
+// who are not enrolled. The course analytics are invalidated, as every caller records a
+// completed lesson or attempt.
+func RecomputeCourseProgress(ctx context.Context, userID, courseID string) error {
+	InvalidateCourseAnalytics(courseID)
+
+	counts, err := repository.GetCompletionCounts(ctx, userID, courseID)
+	if err != nil {
+		return err
+	}
+	progress, completed := ComputeCourseProgress(*counts)
+	justCompleted, err := repository.SetEnrollmentProgress(ctx, userID, courseID, progress, completed)
+	if err != nil {
+		return err
+	}
+	if justCompleted {
+		emitCourseCompleted(ctx, userID, courseID, progress)
+	}
+	if completed {
+		ensureCompletionCertificate(ctx, userID, courseID)
+	}
+	return nil
+}
+
+// UpdateCompletionRule changes a course's rule and recomputes every enrolled student in one
+// statement. A looser rule can complete the course for many students at once, so their
+// certificates and course.completed events are issued in the background.
+func UpdateCompletionRule(ctx context.Context, courseID string, req models.CompletionRuleRequest) error {
+	if err := repository.SaveCompletionRule(ctx, courseID, req); err != nil {
+		return err
+	}
+
+	completions, err := repository.RecomputeCourseEnrollments(ctx, courseID)
+	if err != nil {
+		return err
+	}
+	InvalidateCourseAnalytics(courseID)
+	if len(completions) == 0 {
+		return nil
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		for _, c := range completions {
+			emitCourseCompleted(ctx, c.UserID, courseID, c.Progress)
+			ensureCompletionCertificate(ctx, c.UserID, courseID)
+		}
+	}()
+	return nil
+}
+
+func emitCourseCompleted(ctx context.Context, userID, courseID string, progress int) {
+	EmitWebhookEvent(ctx, models.WebhookCourseCompleted, courseID, map[string]interface{}{
+		"course_id":    courseID,
+		"progress":     progress,
+		"completed_at": time.Now().UTC(),
+		"user":         webhookUser(ctx, userID),
+	})
+}
+
+func ensureCompletionCertificate(ctx context.Context, userID, courseID string) {
+	if _, err := EnsureCertificate(ctx, userID, courseID); err != nil && !errors.Is(err, ErrCourseNotCompleted) {
+		log.Printf("Certificate error for user %s in course %s: %v", userID, courseID, err)
+	}
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+func TestComputeCourseProgress(t *testing.T) {
+	tests := []struct {
+		name      string
+		counts    models.CompletionCounts
+		progress  int
+		completed bool
+	}{
+		{"lessons only", models.CompletionCounts{Rule: CompletionRuleLessons, Threshold: 100, Lessons: 4, LessonsCompleted: 3, Tests: 2, TestsPassed: 2}, 75, false},
+		{"tests only", models.CompletionCounts{Rule: CompletionRuleTests, Threshold: 50, Lessons: 4, Tests: 2, TestsPassed: 1}, 50, true},
+		{"both", models.CompletionCounts{Rule: CompletionRuleLessonsAndTests, Threshold: 80, Lessons: 3, LessonsCompleted: 3, Tests: 2, TestsPassed: 1}, 80, true},
+		{"rounds down", models.CompletionCounts{Rule: CompletionRuleLessons, Threshold: 67, Lessons: 3, LessonsCompleted: 2}, 66, false},
+		{"nothing to complete", models.CompletionCounts{Rule: CompletionRuleTests, Threshold: 1, Lessons: 5, LessonsCompleted: 5}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			progress, completed := ComputeCourseProgress(tt.counts)
+			if progress != tt.progress || completed != tt.completed {
+				t.Errorf("ComputeCourseProgress = %d, %v; want %d, %v", progress, completed, tt.progress, tt.completed)
+			}
+		})
+	}
+}
@@ -0,0 +1,429 @@
+package services
+
+import (
+	"errors"
+	"math"
+)
+
+// A minimal QR code encoder for certificate verification links: byte mode, error correction
+// level M, versions 1 to 10 (up to 213 bytes). It follows ISO/IEC 18004.
+
+var errQRTooLong = errors.New("data too long for a QR code")
+
+const qrMaxVersion = 10
+
+// qrBlocks is the block structure of each version at level M: error correction codewords
+// per block, and the number and data length of the short and long blocks.
+var qrBlocks = [qrMaxVersion + 1]struct {
+	ecLen, shortBlocks, shortLen, longBlocks int
+}{
+	1:  {10, 1, 16, 0},
+	2:  {16, 1, 28, 0},
+	3:  {26, 1, 44, 0},
+	4:  {18, 2, 32, 0},
+	5:  {24, 2, 43, 0},
+	6:  {16, 4, 27, 0},
+	7:  {18, 4, 31, 0},
+	8:  {22, 2, 38, 2},
+	9:  {22, 3, 36, 2},
+	10: {26, 4, 43, 1},
+}
+
+var qrAlignment = [qrMaxVersion + 1][]int{
+	2:  {6, 18},
+	3:  {6, 22},
+	4:  {6, 26},
+	5:  {6, 30},
+	6:  {6, 34},
+	7:  {6, 22, 38},
+	8:  {6, 24, 42},
+	9:  {6, 26, 46},
+	10: {6, 28, 50},
+}
+
+type qrCode struct {
+	size     int
+	modules  [][]bool
+	function [][]bool
+}
+
+// qrEncode returns the modules of a QR code holding data, true for dark, without the quiet zone.
+func qrEncode(data []byte) ([][]bool, error) {
+	version := 0
+	for v := 1; v <= qrMaxVersion; v++ {
+		b := qrBlocks[v]
+		capacity := (b.shortBlocks*b.shortLen + b.longBlocks*(b.shortLen+1)) * 8
+		if 4+qrCountBits(v)+8*len(data) <= capacity {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, errQRTooLong
+	}
+
+	qr := &qrCode{size: version*4 + 17}
+	qr.modules = make([][]bool, qr.size)
+	qr.function = make([][]bool, qr.size)
+	for i := range qr.modules {
+		qr.modules[i] = make([]bool, qr.size)
+		qr.function[i] = make([]bool, qr.size)
+	}
+
+	qr.drawFunctionPatterns(version)
+	qr.drawCodewords(qrInterleave(version, qrDataCodewords(version, data)))
+
+	best, bestPenalty := 0, math.MaxInt32
+	for mask := 0; mask < 8; mask++ {
+		qr.applyMask(mask)
+		qr.drawFormatBits(mask)
+		if p := qr.penalty(); p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		qr.applyMask(mask) // masking is its own inverse
+	}
+	qr.applyMask(best)
+	qr.drawFormatBits(best)
+	return qr.modules, nil
+}
+
+func qrCountBits(version int) int {
+	if version < 10 {
+		return 8
+	}
+	return 16
+}
+
+// qrDataCodewords encodes data as one byte mode segment padded to the version's capacity.
+func qrDataCodewords(version int, data []byte) []byte {
+	b := qrBlocks[version]
+	capacity := b.shortBlocks*b.shortLen + b.longBlocks*(b.shortLen+1)
+
+	var bits []bool
+	appendBits := func(value, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, value>>uint(i)&1 == 1)
+		}
+	}
+	appendBits(0x4, 4)
+	appendBits(len(data), qrCountBits(version))
+	for _, c := range data {
+		appendBits(int(c), 8)
+	}
+	for i := 0; i < 4 && len(bits) < capacity*8; i++ {
+		bits = append(bits, false)
+	}
+	for len(bits)%8 != 0 {
+		bits = append(bits, false)
+	}
+
+	codewords := make([]byte, 0, capacity)
+	for i := 0; i < len(bits); i += 8 {
+		var c byte
+		for j := 0; j < 8; j++ {
+			if bits[i+j] {
+				c |= 1 << uint(7-j)
+			}
+		}
+		codewords = append(codewords, c)
+	}
+	for pad := byte(0xEC); len(codewords) < capacity; pad ^= 0xEC ^ 0x11 {
+		codewords = append(codewords, pad)
+	}
+	return codewords
+}
+
+// qrInterleave splits the data into blocks, appends each block's error correction codewords
+// and interleaves the result.
+func qrInterleave(version int, data []byte) []byte {
+	b := qrBlocks[version]
+	divisor := reedSolomonDivisor(b.ecLen)
+
+	var blocks, ecc [][]byte
+	for i, offset := 0, 0; i < b.shortBlocks+b.longBlocks; i++ {
+		n := b.shortLen
+		if i >= b.shortBlocks {
+			n++
+		}
+		block := data[offset : offset+n]
+		offset += n
+		blocks = append(blocks, block)
+		ecc = append(ecc, reedSolomonRemainder(block, divisor))
+	}
+
+	var result []byte
+	for i := 0; i <= b.shortLen; i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < b.ecLen; i++ {
+		for _, e := range ecc {
+			result = append(result, e[i])
+		}
+	}
+	return result
+}
+
+// reedSolomonDivisor returns the generator polynomial of the given degree over GF(2^8),
+// highest coefficient (always 1) omitted.
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, d := range divisor {
+			result[i] ^= gfMultiply(d, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int(y>>uint(i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+func (qr *qrCode) setFunction(x, y int, dark bool) {
+	qr.modules[y][x] = dark
+	qr.function[y][x] = true
+}
+
+func (qr *qrCode) drawFunctionPatterns(version int) {
+	for i := 0; i < qr.size; i++ {
+		qr.setFunction(6, i, i%2 == 0)
+		qr.setFunction(i, 6, i%2 == 0)
+	}
+
+	for _, c := range [][2]int{{3, 3}, {qr.size - 4, 3}, {3, qr.size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := c[0]+dx, c[1]+dy
+				if x < 0 || x >= qr.size || y < 0 || y >= qr.size {
+					continue
+				}
+				dist := maxInt(absInt(dx), absInt(dy))
+				qr.setFunction(x, y, dist != 2 && dist != 4)
+			}
+		}
+	}
+
+	positions := qrAlignment[version]
+	last := len(positions) - 1
+	for i, cx := range positions {
+		for j, cy := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue // overlaps a finder pattern
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					qr.setFunction(cx+dx, cy+dy, maxInt(absInt(dx), absInt(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	qr.drawFormatBits(0) // reserves the area, the real bits are drawn after masking
+
+	if version >= 7 {
+		rem := version
+		for i := 0; i < 12; i++ {
+			rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+		}
+		bits := version<<12 | rem
+		for i := 0; i < 18; i++ {
+			dark := bits>>uint(i)&1 == 1
+			a, b := qr.size-11+i%3, i/3
+			qr.setFunction(a, b, dark)
+			qr.setFunction(b, a, dark)
+		}
+	}
+}
+
+// drawFormatBits draws both copies of the format information for level M and the mask.
+func (qr *qrCode) drawFormatBits(mask int) {
+	const levelM = 0 // format bits 00
+	data := levelM<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>uint(i)&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		qr.setFunction(8, i, bit(i))
+	}
+	qr.setFunction(8, 7, bit(6))
+	qr.setFunction(8, 8, bit(7))
+	qr.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		qr.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		qr.setFunction(qr.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		qr.setFunction(8, qr.size-15+i, bit(i))
+	}
+	qr.setFunction(8, qr.size-8, true) // the dark module
+}
+
+// drawCodewords places the codewords in the zigzag order, two columns at a time from the
+// bottom right, skipping function modules.
+func (qr *qrCode) drawCodewords(data []byte) {
+	i := 0
+	for right := qr.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < qr.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = qr.size - 1 - vert
+				}
+				if !qr.function[y][x] && i < len(data)*8 {
+					qr.modules[y][x] = data[i>>3]>>uint(7-i&7)&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+func (qr *qrCode) applyMask(mask int) {
+	for y := 0; y < qr.size; y++ {
+		for x := 0; x < qr.size; x++ {
+			if qr.function[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				qr.modules[y][x] = !qr.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores a masked symbol by the four rules of the standard; lower is better.
+func (qr *qrCode) penalty() int {
+	n := qr.size
+	at := func(x, y int, vertical bool) bool {
+		if vertical {
+			return qr.modules[x][y]
+		}
+		return qr.modules[y][x]
+	}
+
+	result := 0
+	finderLike := [][]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+	for _, vertical := range []bool{false, true} {
+		for y := 0; y < n; y++ {
+			run := 1
+			for x := 1; x <= n; x++ {
+				if x < n && at(x, y, vertical) == at(x-1, y, vertical) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					result += 3 + run - 5
+				}
+				run = 1
+			}
+			for x := 0; x+11 <= n; x++ {
+				for _, pattern := range finderLike {
+					match := true
+					for k, dark := range pattern {
+						if at(x+k, y, vertical) != dark {
+							match = false
+							break
+						}
+					}
+					if match {
+						result += 40
+					}
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if qr.modules[y][x] {
+				dark++
+			}
+			if x+1 < n && y+1 < n {
+				c := qr.modules[y][x]
+				if c == qr.modules[y][x+1] && c == qr.modules[y+1][x] && c == qr.modules[y+1][x+1] {
+					result += 3
+				}
+			}
+		}
+	}
+	deviation := absInt(dark*100/(n*n) - 50)
+	result += deviation / 5 * 10
+	return result
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
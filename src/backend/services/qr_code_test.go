@@ -0,0 +1,236 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// qrDecode reads a symbol made by qrEncode back into its data: it reads the format bits,
+// removes the mask, collects the codewords in placement order, undoes the interleaving,
+// checks every block's error correction and parses the byte mode segment.
+func qrDecode(t *testing.T, modules [][]bool) []byte {
+	t.Helper()
+	size := len(modules)
+	version := (size - 17) / 4
+	if version < 1 || version > qrMaxVersion || size != version*4+17 {
+		t.Fatalf("size %d is not a QR code version 1 to %d", size, qrMaxVersion)
+	}
+
+	format := 0
+	for i, p := range [][2]int{{8, 0}, {8, 1}, {8, 2}, {8, 3}, {8, 4}, {8, 5}, {8, 7}, {8, 8}, {7, 8}, {5, 8}, {4, 8}, {3, 8}, {2, 8}, {1, 8}, {0, 8}} {
+		if modules[p[1]][p[0]] {
+			format |= 1 << uint(i)
+		}
+	}
+	format ^= 0x5412
+	if level := format >> 13; level != 0 {
+		t.Fatalf("error correction level bits %02b, want 00 (M)", level)
+	}
+	mask := format >> 10 & 7
+
+	qr := &qrCode{size: size, modules: make([][]bool, size), function: make([][]bool, size)}
+	for i := range qr.modules {
+		qr.modules[i] = make([]bool, size)
+		qr.function[i] = make([]bool, size)
+	}
+	qr.drawFunctionPatterns(version)
+	for y := range modules {
+		copy(qr.modules[y], modules[y])
+	}
+	qr.applyMask(mask)
+
+	var bits []bool
+	upward := true
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for i := 0; i < size; i++ {
+			y := i
+			if upward {
+				y = size - 1 - i
+			}
+			for _, x := range []int{right, right - 1} {
+				if !qr.function[y][x] {
+					bits = append(bits, qr.modules[y][x])
+				}
+			}
+		}
+		upward = !upward
+	}
+	codewords := make([]byte, len(bits)/8)
+	for i := range codewords {
+		for j := 0; j < 8; j++ {
+			if bits[i*8+j] {
+				codewords[i] |= 1 << uint(7-j)
+			}
+		}
+	}
+
+	b := qrBlocks[version]
+	count := b.shortBlocks + b.longBlocks
+	blocks := make([][]byte, count)
+	next := 0
+	for i := 0; i <= b.shortLen; i++ {
+		for k := range blocks {
+			if i < b.shortLen || k >= b.shortBlocks {
+				blocks[k] = append(blocks[k], codewords[next])
+				next++
+			}
+		}
+	}
+	for i := 0; i < b.ecLen; i++ {
+		for k := range blocks {
+			blocks[k] = append(blocks[k], codewords[next])
+			next++
+		}
+	}
+
+	var data []byte
+	for k, block := range blocks {
+		// A valid block is a multiple of the generator, so it vanishes at its roots 2^0 .. 2^(ecLen-1).
+		root := byte(1)
+		for i := 0; i < b.ecLen; i++ {
+			var value byte
+			for _, c := range block {
+				value = gfMultiply(value, root) ^ c
+			}
+			if value != 0 {
+				t.Fatalf("block %d fails error correction check %d", k, i)
+			}
+			root = gfMultiply(root, 2)
+		}
+		data = append(data, block[:len(block)-b.ecLen]...)
+	}
+
+	read := func(pos, n int) int {
+		v := 0
+		for i := 0; i < n; i++ {
+			v = v<<1 | int(data[(pos+i)/8]>>uint(7-(pos+i)%8)&1)
+		}
+		return v
+	}
+	if mode := read(0, 4); mode != 0x4 {
+		t.Fatalf("mode %04b, want byte mode", mode)
+	}
+	n := read(4, qrCountBits(version))
+	result := make([]byte, n)
+	for i := range result {
+		result[i] = byte(read(4+qrCountBits(version)+8*i, 8))
+	}
+	return result
+}
+
+func TestQREncodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		version int
+	}{
+		{"fills version 1", strings.Repeat("a", 14), 1},
+		{"one byte more", strings.Repeat("a", 15), 2},
+		{"verification link", "https://shabyt.kz/verify/SHB-0ABC-DEFG-HJKM-NPQR-STVW", 4},
+		{"Cyrillic", "Сертификат Шабыт — тексеру", 4},
+		{"version information", strings.Repeat("0123456789", 12), 7},
+		{"long and short blocks", strings.Repeat("x", 150), 8},
+		{"largest", strings.Repeat("z", 213), 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modules, err := qrEncode([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := tt.version*4 + 17; len(modules) != want {
+				t.Fatalf("size = %d, want %d (version %d)", len(modules), want, tt.version)
+			}
+			if got := qrDecode(t, modules); !bytes.Equal(got, []byte(tt.data)) {
+				t.Errorf("decoded %q, want %q", got, tt.data)
+			}
+		})
+	}
+}
+
+func TestQREncodeFunctionPatterns(t *testing.T) {
+	modules, err := qrEncode(bytes.Repeat([]byte("q"), 120))
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := len(modules)
+
+	for _, corner := range [][2]int{{0, 0}, {size - 7, 0}, {0, size - 7}} {
+		for dy := 0; dy < 7; dy++ {
+			for dx := 0; dx < 7; dx++ {
+				ring := maxInt(absInt(dx-3), absInt(dy-3))
+				if want := ring != 2; modules[corner[1]+dy][corner[0]+dx] != want {
+					t.Fatalf("finder pattern at %v is wrong at (%d, %d)", corner, dx, dy)
+				}
+			}
+		}
+	}
+	for i := 8; i < size-8; i++ {
+		if modules[6][i] != (i%2 == 0) || modules[i][6] != (i%2 == 0) {
+			t.Fatalf("timing pattern is wrong at %d", i)
+		}
+	}
+	if !modules[size-8][8] {
+		t.Error("dark module is missing")
+	}
+
+	// Version 7 information, 000111110010010100, least significant bit first in the block
+	// above the bottom left finder.
+	version := 0
+	for i := 0; i < 18; i++ {
+		if modules[size-11+i%3][i/3] {
+			version |= 1 << uint(i)
+		}
+	}
+	if version != 0x07C94 {
+		t.Errorf("version information = %018b, want %018b", version, 0x07C94)
+	}
+}
+
+func TestQRFormatBits(t *testing.T) {
+	// Level M format information for masks 0 to 7, from the standard's table.
+	want := []int{0x5412, 0x5125, 0x5E7C, 0x5B4B, 0x45F9, 0x40CE, 0x4F97, 0x4AA0}
+	for mask, bits := range want {
+		qr := &qrCode{size: 21, modules: make([][]bool, 21), function: make([][]bool, 21)}
+		for i := range qr.modules {
+			qr.modules[i] = make([]bool, 21)
+			qr.function[i] = make([]bool, 21)
+		}
+		qr.drawFormatBits(mask)
+
+		got := 0
+		for i := 0; i < 8; i++ {
+			if qr.modules[8][20-i] {
+				got |= 1 << uint(i)
+			}
+		}
+		for i := 8; i < 15; i++ {
+			if qr.modules[6+i][8] {
+				got |= 1 << uint(i)
+			}
+		}
+		if got != bits {
+			t.Errorf("mask %d: format bits %015b, want %015b", mask, got, bits)
+		}
+	}
+}
+
+func TestReedSolomonRemainder(t *testing.T) {
+	// The version 1-M example of the standard: "01234567" in numeric mode.
+	data := []byte{0x10, 0x20, 0x0C, 0x56, 0x61, 0x80, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11}
+	want := []byte{0xA5, 0x24, 0xD4, 0xC1, 0xED, 0x36, 0xC7, 0x87, 0x2C, 0x55}
+	if got := reedSolomonRemainder(data, reedSolomonDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("error correction = % X, want % X", got, want)
+	}
+}
+
+func TestQREncodeTooLong(t *testing.T) {
+	if _, err := qrEncode(make([]byte, 214)); !errors.Is(err, errQRTooLong) {
+		t.Errorf("err = %v, want errQRTooLong", err)
+	}
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/database"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+// streamBuffer is how many events a subscription holds for a slow client. A client that
+// falls further behind is disconnected and catches up by replaying on reconnect.
+const streamBuffer = 64
+
+// Broker fans real-time events out to the subscriptions of their topic. Publish is called
+// once the event has been saved, so any instance can load it by ID.
+type Broker interface {
+	Publish(ctx context.Context, event models.RealtimeEvent) error
+	Subscribe(topics []string) *Subscription
+	Unsubscribe(sub *Subscription)
+}
+
+// Subscription receives the events of its topics. Events is closed when the subscription
+// ends, including when the client falls too far behind.
+type Subscription struct {
+	Events <-chan models.RealtimeEvent
+	events chan models.RealtimeEvent
+	topics []string
+	closed bool
+}
+
+// memoryBroker delivers events to the subscriptions of this server instance only.
+type memoryBroker struct {
+	mu     sync.Mutex
+	topics map[string]map[*Subscription]struct{}
+}
+
+func newMemoryBroker() *memoryBroker {
+	return &memoryBroker{topics: make(map[string]map[*Subscription]struct{})}
+}
+
+func (b *memoryBroker) Publish(ctx context.Context, event models.RealtimeEvent) error {
+	b.deliver(event)
+	return nil
+}
+
+func (b *memoryBroker) deliver(event models.RealtimeEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.topics[event.Topic] {
+		select {
+		case sub.events <- event:
+		default:
+			b.remove(sub)
+		}
+	}
+}
+
+func (b *memoryBroker) Subscribe(topics []string) *Subscription {
+	events := make(chan models.RealtimeEvent, streamBuffer)
+	sub := &Subscription{Events: events, events: events, topics: topics}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, topic := range topics {
+		if b.topics[topic] == nil {
+			b.topics[topic] = make(map[*Subscription]struct{})
+		}
+		b.topics[topic][sub] = struct{}{}
+	}
+	return sub
+}
+
+func (b *memoryBroker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+// remove must be called with b.mu held.
+func (b *memoryBroker) remove(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	for _, topic := range sub.topics {
+		delete(b.topics[topic], sub)
+		if len(b.topics[topic]) == 0 {
+			delete(b.topics, topic)
+		}
+	}
+	close(sub.events)
+}
+
+// postgresBroker publishes through Postgres NOTIFY, so every server instance, this one
+// included, delivers each event to its own subscriptions.
+type postgresBroker struct {
+	*memoryBroker
+	listener *pq.Listener
+	lastID   int64
+}
+
+func newPostgresBroker() (*postgresBroker, error) {
+	b := &postgresBroker{memoryBroker: newMemoryBroker()}
+	b.listener = database.NewListener(func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Realtime listener error: %v", err)
+		}
+	})
+	if err := b.listener.Listen(repository.RealtimeChannel); err != nil {
+		b.listener.Close()
+		return nil, err
+	}
+	go b.listen()
+	return b, nil
+}
+
+// Publish has nothing left to do: the transaction that saved the event announced it.
+func (b *postgresBroker) Publish(ctx context.Context, event models.RealtimeEvent) error {
+	return nil
+}
+
+// listen delivers the events announced on the channel. A nil notification means the
+// connection was re-established, and whatever was announced meanwhile is read back from the
+// table.
+func (b *postgresBroker) listen() {
+	for {
+		select {
+		case n, ok := <-b.listener.Notify:
+			if !ok {
+				return
+			}
+			if n == nil {
+				b.catchUp()
+				continue
+			}
+			id, err := strconv.ParseInt(n.Extra, 10, 64)
+			if err != nil {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			event, err := repository.GetRealtimeEvent(ctx, id)
+			cancel()
+			if err != nil {
+				log.Printf("Realtime event %d error: %v", id, err)
+				continue
+			}
+			b.deliverAndTrack(*event)
+		case <-time.After(90 * time.Second):
+			go b.listener.Ping()
+		}
+	}
+}
+
+func (b *postgresBroker) catchUp() {
+	if b.lastID == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	events, err := repository.GetRealtimeEventsAfter(ctx, nil, b.lastID, 1000)
+	if err != nil {
+		log.Printf("Realtime catch-up error: %v", err)
+		return
+	}
+	for _, event := range events {
+		b.deliverAndTrack(event)
+	}
+}
+
+// deliverAndTrack delivers events in ID order, as they are committed, skipping the ones a
+// catch-up already delivered.
+func (b *postgresBroker) deliverAndTrack(event models.RealtimeEvent) {
+	if event.ID <= b.lastID {
+		return
+	}
+	b.lastID = event.ID
+	b.deliver(event)
+}
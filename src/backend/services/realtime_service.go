@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+var ErrTooManyStreams = errors.New("too many open event streams")
+
+// maxReplayEvents caps how many missed events a reconnecting client is sent. A client that
+// missed more gets a reset and should reload what it shows.
+const maxReplayEvents = 500
+
+var (
+	broker Broker = newMemoryBroker()
+
+	// publishMu keeps this instance's events in ID order from saving to delivery, so a stream
+	// never receives an event after one with a higher ID.
+	publishMu sync.Mutex
+
+	streamsMu sync.Mutex
+	streams   = make(map[string]int)
+)
+
+func UserTopic(userID string) string {
+	return "user:" + userID
+}
+
+func DiscussionTopic(discussionID string) string {
+	return "discussion:" + discussionID
+}
+
+// StartRealtime picks the broker named by REALTIME_BROKER: "memory", the default, for a
+// single server, or "postgres" to fan out through LISTEN/NOTIFY across instances. It also
+// prunes events older than REALTIME_EVENT_RETENTION, past which they can't be replayed.
+func StartRealtime() {
+	if envString("REALTIME_BROKER", "memory") == "postgres" {
+		pg, err := newPostgresBroker()
+		if err != nil {
+			log.Printf("Realtime broker error, falling back to in-process delivery: %v", err)
+		} else {
+			broker = pg
+		}
+	}
+
+	retention := envDuration("REALTIME_EVENT_RETENTION", 24*time.Hour)
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			if _, err := repository.DeleteRealtimeEventsBefore(ctx, time.Now().Add(-retention)); err != nil {
+				log.Printf("Realtime event pruning error: %v", err)
+			}
+			cancel()
+		}
+	}()
+}
+
+// PublishRealtime saves an event and pushes it to the live streams of its topic. Failures are
+// logged; real-time delivery never fails the action that caused it.
+func PublishRealtime(ctx context.Context, topic, eventType string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Realtime event error: %v", err)
+		return
+	}
+	publishMu.Lock()
+	defer publishMu.Unlock()
+	_, announce := broker.(*postgresBroker)
+	event, err := repository.SaveRealtimeEvent(ctx, topic, eventType, payload, announce)
+	if err != nil {
+		log.Printf("Realtime event error: %v", err)
+		return
+	}
+	if err := broker.Publish(ctx, *event); err != nil {
+		log.Printf("Realtime publish error: %v", err)
+	}
+}
+
+// EventStream is one client connection. Backlog holds the events missed since the client's
+// Last-Event-ID; Reset is set when there were too many to replay.
+type EventStream struct {
+	*Subscription
+	Backlog []models.RealtimeEvent
+	Reset   bool
+	userID  string
+}
+
+// OpenEventStream subscribes a connection of the user to topics, allowing at most
+// REALTIME_MAX_STREAMS_PER_USER connections per user on this instance. With lastEventID
+// above zero the events after it are loaded into Backlog; the subscription is made first, so
+// an event may arrive both ways and the caller skips IDs it has already sent. Events are
+// saved and delivered in ID order, so those are exactly the IDs up to the last one sent.
+func OpenEventStream(ctx context.Context, userID string, topics []string, lastEventID int64) (*EventStream, error) {
+	limit := envInt("REALTIME_MAX_STREAMS_PER_USER", 5)
+	streamsMu.Lock()
+	if streams[userID] >= limit {
+		streamsMu.Unlock()
+		return nil, fmt.Errorf("%w: at most %d per user", ErrTooManyStreams, limit)
+	}
+	streams[userID]++
+	streamsMu.Unlock()
+
+	stream := &EventStream{Subscription: broker.Subscribe(topics), userID: userID}
+	if lastEventID > 0 {
+		backlog, err := repository.GetRealtimeEventsAfter(ctx, topics, lastEventID, maxReplayEvents+1)
+		if err != nil {
+			CloseEventStream(stream)
+			return nil, err
+		}
+		if len(backlog) > maxReplayEvents {
+			stream.Reset = true
+		} else {
+			stream.Backlog = backlog
+		}
+	}
+	return stream, nil
+}
+
+func CloseEventStream(stream *EventStream) {
+	broker.Unsubscribe(stream.Subscription)
+
+	streamsMu.Lock()
+	defer streamsMu.Unlock()
+	streams[stream.userID]--
+	if streams[stream.userID] <= 0 {
+		delete(streams, stream.userID)
+	}
+}
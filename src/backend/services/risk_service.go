@@ -0,0 +1,295 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/mail"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+// Points each early warning signal adds to an enrollment's risk score; together they make 100.
+const (
+	riskInactivityPoints = 30
+	riskMissedPoints     = 25
+	riskDecliningPoints  = 25
+	riskPacePoints       = 20
+
+	// Score trends are read from at most this many latest attempts, older half against newer half.
+	riskScoreWindow = 6
+	riskDigestLimit = 12
+)
+
+var ErrInvalidRiskSettings = errors.New("invalid risk settings")
+
+// ValidateRiskSettings checks the thresholds a teacher submits.
+func ValidateRiskSettings(s models.RiskSettings) error {
+	switch {
+	case s.InactiveDays < 1:
+		return fmt.Errorf("%w: inactive_days must be at least 1", ErrInvalidRiskSettings)
+	case s.MissedLessons < 1:
+		return fmt.Errorf("%w: missed_lessons must be at least 1", ErrInvalidRiskSettings)
+	case s.ScoreDrop < 1 || s.ScoreDrop > 100:
+		return fmt.Errorf("%w: score_drop must be between 1 and 100", ErrInvalidRiskSettings)
+	case s.PaceGap < 1 || s.PaceGap > 100:
+		return fmt.Errorf("%w: pace_gap must be between 1 and 100", ErrInvalidRiskSettings)
+	case s.Threshold < 1 || s.Threshold > 100:
+		return fmt.Errorf("%w: threshold must be between 1 and 100", ErrInvalidRiskSettings)
+	case s.ExpectedWeeks < 0:
+		return fmt.Errorf("%w: expected_weeks must not be negative", ErrInvalidRiskSettings)
+	}
+	return nil
+}
+
+// ScoreEnrollmentRisk scores an enrollment from 0 to 100 at time now. The result depends only
+// on its arguments. Students who completed the course are never at risk.
+func ScoreEnrollmentRisk(in models.RiskInput, s models.RiskSettings, now time.Time) models.EnrollmentRisk {
+	risk := models.EnrollmentRisk{
+		UserID:     in.UserID,
+		CourseID:   in.CourseID,
+		Reasons:    []models.RiskReason{},
+		ComputedAt: now,
+	}
+	if in.CompletedAt != nil {
+		return risk
+	}
+
+	add := func(kind string, points int, message string) {
+		risk.Score += points
+		risk.Reasons = append(risk.Reasons, models.RiskReason{Kind: kind, Points: points, Message: message})
+	}
+
+	lastSeen := in.EnrolledAt
+	if in.LastAccessedAt != nil && in.LastAccessedAt.After(lastSeen) {
+		lastSeen = *in.LastAccessedAt
+	}
+	if inactive := int(now.Sub(lastSeen).Hours() / 24); inactive >= s.InactiveDays {
+		add("inactivity", riskInactivityPoints, fmt.Sprintf("No activity for %d days", inactive))
+	}
+
+	if s.ExpectedWeeks > 0 {
+		elapsed := now.Sub(in.EnrolledAt).Hours() / (float64(s.ExpectedWeeks) * 7 * 24)
+		elapsed = clamp(elapsed, 0, 1)
+
+		expectedLessons := int(math.Floor(elapsed * float64(in.Lessons)))
+		if missed := expectedLessons - in.LessonsCompleted; missed >= s.MissedLessons {
+			add("missed_lessons", riskMissedPoints,
+				fmt.Sprintf("%d lessons behind schedule (%d of %d expected by now)", missed, in.LessonsCompleted, expectedLessons))
+		}
+
+		expectedProgress := int(math.Round(elapsed * 100))
+		if gap := expectedProgress - in.Progress; gap >= s.PaceGap {
+			add("pace", riskPacePoints,
+				fmt.Sprintf("Progress %d%% against %d%% expected by now", in.Progress, expectedProgress))
+		}
+	}
+
+	if drop, ok := scoreDecline(in.Scores); ok && drop >= float64(s.ScoreDrop) {
+		add("declining_scores", riskDecliningPoints, fmt.Sprintf("Test scores dropped by %.0f points", drop))
+	}
+
+	risk.Flagged = risk.Score >= s.Threshold
+	return risk
+}
+
+// scoreDecline compares the older and newer half of the latest attempts and returns how many
+// points the average fell. At least three attempts are needed to speak of a trend.
+func scoreDecline(scores []int) (float64, bool) {
+	if len(scores) < 3 {
+		return 0, false
+	}
+	if len(scores) > riskScoreWindow {
+		scores = scores[len(scores)-riskScoreWindow:]
+	}
+	half := len(scores) / 2
+	return mean(scores[:half]) - mean(scores[len(scores)-half:]), true
+}
+
+func mean(values []int) float64 {
+	sum := 0
+	for _, v := range values {
+		sum += v
+	}
+	return float64(sum) / float64(len(values))
+}
+
+// RunRiskScan scores every enrollment, stores the results and sends the weekly digests.
+func RunRiskScan(ctx context.Context, now time.Time) (int, error) {
+	inputs, err := repository.GetRiskInputs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	settings := map[string]*models.RiskSettings{}
+	risks := make([]models.EnrollmentRisk, 0, len(inputs))
+	flagged := 0
+	for _, in := range inputs {
+		s, ok := settings[in.CourseID]
+		if !ok {
+			if s, err = repository.GetRiskSettings(ctx, in.CourseID); err != nil {
+				return 0, err
+			}
+			settings[in.CourseID] = s
+		}
+		risk := ScoreEnrollmentRisk(in, *s, now)
+		if risk.Flagged {
+			flagged++
+		}
+		risks = append(risks, risk)
+	}
+
+	if err := repository.SaveEnrollmentRisks(ctx, risks); err != nil {
+		return 0, err
+	}
+	if err := sendRiskDigests(ctx, now); err != nil {
+		return flagged, err
+	}
+	return flagged, nil
+}
+
+// sendRiskDigests creates this week's digest for every teacher with flagged students and
+// delivers the week's digests that are still undelivered. The first scan of a week decides a
+// digest's content; later scans that week only retry its delivery.
+func sendRiskDigests(ctx context.Context, now time.Time) error {
+	flagged, err := repository.GetFlaggedEnrollments(ctx)
+	if err != nil {
+		return err
+	}
+
+	weekStart := startOfWeek(now)
+	for len(flagged) > 0 {
+		teacherID := flagged[0].TeacherID
+		var courses []models.RiskDigestCourse
+		for len(flagged) > 0 && flagged[0].TeacherID == teacherID {
+			f := flagged[0]
+			flagged = flagged[1:]
+			if n := len(courses); n == 0 || courses[n-1].CourseID != f.Risk.CourseID {
+				courses = append(courses, models.RiskDigestCourse{CourseID: f.Risk.CourseID, CourseTitle: f.CourseTitle})
+			}
+			courses[len(courses)-1].Students = append(courses[len(courses)-1].Students, f.Risk)
+		}
+
+		if err := repository.CreateRiskDigest(ctx, teacherID, weekStart, courses); err != nil {
+			return err
+		}
+	}
+
+	digests, err := repository.GetUndeliveredRiskDigests(ctx, weekStart)
+	if err != nil {
+		return err
+	}
+	for _, d := range digests {
+		deliveryErr := deliverRiskDigest(ctx, d)
+		if err := repository.SetRiskDigestDelivery(ctx, d.ID, deliveryErr); err != nil {
+			return err
+		}
+		if deliveryErr != nil {
+			log.Printf("At-risk digest for week %s to teacher %s not delivered: %v", d.WeekStart, d.TeacherID, deliveryErr)
+			continue
+		}
+		log.Printf("At-risk digest for week %s delivered to teacher %s", d.WeekStart, d.TeacherID)
+	}
+	return nil
+}
+
+// deliverRiskDigest notifies the teacher in the app and queues the digest e-mail, on the
+// channels the teacher has not turned off. Unlike Notify it reports failures, so the digest
+// can be retried.
+func deliverRiskDigest(ctx context.Context, d models.RiskDigest) error {
+	title, body := riskDigestText(d)
+	data, err := json.Marshal(map[string]string{"digest_id": d.ID, "week_start": d.WeekStart})
+	if err != nil {
+		return err
+	}
+	n := models.Notification{
+		UserID: d.TeacherID,
+		Type:   models.NotificationAtRiskStudents,
+		Title:  title,
+		Body:   body,
+		Link:   "/teacher-dashboard",
+		Data:   data,
+	}
+
+	inApp, err := repository.FilterNotificationRecipients(ctx, []string{d.TeacherID}, n.Type, models.ChannelInApp)
+	if err != nil {
+		return err
+	}
+	if len(inApp) > 0 {
+		saved, err := repository.SaveNotification(ctx, n)
+		if err != nil {
+			return err
+		}
+		PublishRealtime(ctx, UserTopic(d.TeacherID), models.RealtimeNotification, saved)
+	}
+
+	email, err := repository.FilterNotificationRecipients(ctx, []string{d.TeacherID}, n.Type, models.ChannelEmail)
+	if err != nil || len(email) == 0 {
+		return err
+	}
+	teacher, err := repository.GetUserByID(ctx, d.TeacherID)
+	if err != nil {
+		return err
+	}
+	return QueueEmail(ctx, teacher, mail.TemplateNotification, mail.NotificationEmail{
+		FirstName:   teacher.FirstName,
+		Title:       title,
+		Body:        body,
+		Link:        appLink(n.Link),
+		SettingsURL: appLink("/settings/notifications"),
+	})
+}
+
+// riskDigestText summarises a digest as a notification: the number of flagged students and
+// where they are.
+func riskDigestText(d models.RiskDigest) (string, string) {
+	students := 0
+	titles := make([]string, len(d.Courses))
+	for i, c := range d.Courses {
+		students += len(c.Students)
+		titles[i] = fmt.Sprintf("%s (%d)", c.CourseTitle, len(c.Students))
+	}
+	title := "1 student needs attention"
+	if students != 1 {
+		title = fmt.Sprintf("%d students need attention", students)
+	}
+	return title, "At risk this week: " + strings.Join(titles, ", ")
+}
+
+func GetRiskDigests(ctx context.Context, teacherID string) ([]models.RiskDigest, error) {
+	return repository.GetRiskDigests(ctx, teacherID, riskDigestLimit)
+}
+
+// StartRiskScan runs the early warning scan every RISK_SCAN_INTERVAL (24h by default).
+func StartRiskScan() {
+	interval := envDuration("RISK_SCAN_INTERVAL", 24*time.Hour)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+			flagged, err := RunRiskScan(ctx, time.Now())
+			cancel()
+			if err != nil {
+				log.Printf("At-risk scan error: %v", err)
+			} else {
+				log.Printf("At-risk scan finished, %d students flagged", flagged)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// startOfWeek returns midnight of the Monday of t's week.
+func startOfWeek(t time.Time) time.Time {
+	day := truncateDay(t)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+var riskNow = time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+
+func daysAgo(days float64) *time.Time {
+	t := riskNow.Add(-time.Duration(days * 24 * float64(time.Hour)))
+	return &t
+}
+
+func riskSettings() models.RiskSettings {
+	return models.RiskSettings{InactiveDays: 7, MissedLessons: 3, ScoreDrop: 15, PaceGap: 20, Threshold: 50, ExpectedWeeks: 10}
+}
+
+// onTrack is halfway through a ten week schedule with half the course done, steady scores and a
+// visit yesterday: no signal fires.
+func onTrack() models.RiskInput {
+	return models.RiskInput{
+		UserID:           "u",
+		CourseID:         "c",
+		EnrolledAt:       *daysAgo(35),
+		LastAccessedAt:   daysAgo(1),
+		Progress:         50,
+		Lessons:          10,
+		LessonsCompleted: 5,
+		Scores:           []int{80, 80, 80},
+	}
+}
+
+func TestScoreEnrollmentRiskSignals(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(in *models.RiskInput, s *models.RiskSettings)
+		reasons []string
+		score   int
+		flagged bool
+	}{
+		{"on track", func(in *models.RiskInput, s *models.RiskSettings) {}, nil, 0, false},
+		{"inactive", func(in *models.RiskInput, s *models.RiskSettings) { in.LastAccessedAt = daysAgo(8) }, []string{"inactivity"}, 30, false},
+		{"inactive exactly the limit", func(in *models.RiskInput, s *models.RiskSettings) { in.LastAccessedAt = daysAgo(7) }, []string{"inactivity"}, 30, false},
+		{"inactive just under the limit", func(in *models.RiskInput, s *models.RiskSettings) { in.LastAccessedAt = daysAgo(6.9) }, nil, 0, false},
+		{"never opened the course", func(in *models.RiskInput, s *models.RiskSettings) { in.LastAccessedAt = nil }, []string{"inactivity"}, 30, false},
+		{"falling scores", func(in *models.RiskInput, s *models.RiskSettings) { in.Scores = []int{90, 85, 60, 55} }, []string{"declining_scores"}, 25, false},
+		{"small drop", func(in *models.RiskInput, s *models.RiskSettings) { in.Scores = []int{80, 75, 70} }, nil, 0, false},
+		{"two scores are no trend", func(in *models.RiskInput, s *models.RiskSettings) { in.Scores = []int{90, 20} }, nil, 0, false},
+		{"old drop outside the window", func(in *models.RiskInput, s *models.RiskSettings) {
+			in.Scores = []int{100, 100, 100, 70, 70, 70, 70, 70, 70}
+		}, nil, 0, false},
+		{"missed lessons", func(in *models.RiskInput, s *models.RiskSettings) { in.LessonsCompleted = 2 }, []string{"missed_lessons"}, 25, false},
+		{"low progress", func(in *models.RiskInput, s *models.RiskSettings) { in.Progress = 25 }, []string{"pace"}, 20, false},
+		{"no schedule", func(in *models.RiskInput, s *models.RiskSettings) {
+			s.ExpectedWeeks = 0
+			in.Progress, in.LessonsCompleted = 0, 0
+		}, nil, 0, false},
+		{"every signal", func(in *models.RiskInput, s *models.RiskSettings) {
+			in.LastAccessedAt = daysAgo(10)
+			in.Scores = []int{90, 85, 60, 55}
+			in.LessonsCompleted, in.Progress = 0, 0
+		}, []string{"inactivity", "missed_lessons", "pace", "declining_scores"}, 100, true},
+		{"completed", func(in *models.RiskInput, s *models.RiskSettings) {
+			in.LastAccessedAt = daysAgo(10)
+			in.LessonsCompleted, in.Progress = 0, 0
+			in.CompletedAt = daysAgo(10)
+		}, nil, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, s := onTrack(), riskSettings()
+			tt.change(&in, &s)
+			risk := ScoreEnrollmentRisk(in, s, riskNow)
+
+			var reasons []string
+			points := 0
+			for _, r := range risk.Reasons {
+				reasons = append(reasons, r.Kind)
+				points += r.Points
+			}
+			if !reflect.DeepEqual(reasons, tt.reasons) {
+				t.Errorf("reasons = %v, want %v", reasons, tt.reasons)
+			}
+			if risk.Score != tt.score || points != tt.score {
+				t.Errorf("score = %d (reasons add up to %d), want %d", risk.Score, points, tt.score)
+			}
+			if risk.Flagged != tt.flagged {
+				t.Errorf("flagged = %v, want %v", risk.Flagged, tt.flagged)
+			}
+		})
+	}
+}
+
+func TestScoreEnrollmentRiskThreshold(t *testing.T) {
+	// Inactivity and low progress together score 50.
+	in := onTrack()
+	in.LastAccessedAt = daysAgo(8)
+	in.Progress = 25
+
+	tests := []struct {
+		threshold int
+		flagged   bool
+	}{
+		{1, true},
+		{30, true},
+		{50, true},
+		{51, false},
+		{100, false},
+	}
+	for _, tt := range tests {
+		s := riskSettings()
+		s.Threshold = tt.threshold
+		risk := ScoreEnrollmentRisk(in, s, riskNow)
+		if risk.Score != 50 || risk.Flagged != tt.flagged {
+			t.Errorf("threshold %d: score %d, flagged %v; want 50, %v", tt.threshold, risk.Score, risk.Flagged, tt.flagged)
+		}
+	}
+}
+
+func TestScoreEnrollmentRiskIsDeterministic(t *testing.T) {
+	in := onTrack()
+	in.LastAccessedAt = daysAgo(9)
+	in.Scores = []int{95, 90, 85, 60, 50, 45, 40}
+	in.LessonsCompleted, in.Progress = 1, 10
+	scores := append([]int(nil), in.Scores...)
+
+	first := ScoreEnrollmentRisk(in, riskSettings(), riskNow)
+	for i := 0; i < 10; i++ {
+		if again := ScoreEnrollmentRisk(in, riskSettings(), riskNow); !reflect.DeepEqual(again, first) {
+			t.Fatalf("run %d = %+v, want %+v", i+2, again, first)
+		}
+	}
+	if !reflect.DeepEqual(in.Scores, scores) {
+		t.Errorf("scores changed to %v", in.Scores)
+	}
+	if first.ComputedAt != riskNow {
+		t.Errorf("computed at %v, want the time passed in", first.ComputedAt)
+	}
+}
+
+func TestRiskDigestText(t *testing.T) {
+	title, body := riskDigestText(models.RiskDigest{Courses: []models.RiskDigestCourse{
+		{CourseTitle: "Алгебра", Students: make([]models.EnrollmentRisk, 2)},
+		{CourseTitle: "Физика", Students: make([]models.EnrollmentRisk, 1)},
+	}})
+	if title != "3 students need attention" || body != "At risk this week: Алгебра (2), Физика (1)" {
+		t.Errorf("text = %q, %q", title, body)
+	}
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+const (
+	FormatGIFT      = "gift"
+	FormatMoodleXML = "moodle_xml"
+	FormatCSV       = "csv"
+)
+
+var questionTypes = map[string]bool{"multiple_choice": true, "true_false": true, "short_answer": true, "essay": true}
+var difficulties = map[string]bool{"easy": true, "medium": true, "hard": true}
+
+// Localized option texts used for true/false questions, which have no text of their own
+// in GIFT and Moodle XML.
+var (
+	trueOption  = models.QuestionOptionRequest{OptionText: "Верно", OptionTextKK: "Дұрыс"}
+	falseOption = models.QuestionOptionRequest{OptionText: "Неверно", OptionTextKK: "Бұрыс"}
+)
+
+// trueFalseTexts recognises the option texts of true/false questions written by hand, so
+// exports don't depend on the order the options were entered in.
+var trueFalseTexts = map[string]bool{
+	"верно": true, "да": true, "дұрыс": true, "иә": true, "true": true, "yes": true,
+	"неверно": false, "нет": false, "бұрыс": false, "жоқ": false, "false": false, "no": false,
+}
+
+// trueFalseAnswer reports whether the answer to a true/false question is "true". It goes by
+// the text of the correct option, then of the wrong ones; only when no text is recognised
+// does it fall back to the import convention of "true" first.
+func trueFalseAnswer(q models.Question) bool {
+	for _, wantCorrect := range []bool{true, false} {
+		for _, option := range q.Options {
+			if option.IsCorrect != wantCorrect {
+				continue
+			}
+			for _, text := range []string{option.OptionText, option.OptionTextKK} {
+				if isTrue, ok := trueFalseTexts[strings.ToLower(strings.TrimSpace(text))]; ok {
+					return isTrue == wantCorrect
+				}
+			}
+		}
+	}
+	return len(q.Options) > 0 && q.Options[0].IsCorrect
+}
+
+// importedQuestion is a parsed question together with the line it started on.
+type importedQuestion struct {
+	line     int
+	question models.QuestionRequest
+}
+
+// importResult collects questions and issues while a file is parsed.
+type importResult struct {
+	questions []importedQuestion
+	issues    []models.ImportIssue
+	found     int
+}
+
+func (r *importResult) warn(line int, format string, args ...interface{}) {
+	r.issues = append(r.issues, models.ImportIssue{Line: line, Severity: "warning", Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *importResult) skip(line int, format string, args ...interface{}) {
+	r.issues = append(r.issues, models.ImportIssue{Line: line, Severity: "error", Message: fmt.Sprintf(format, args...)})
+}
+
+func IsValidDifficulty(difficulty string) bool {
+	return difficulties[difficulty]
+}
+
+// ValidateQuestionRequest fills defaults and returns a user-facing message for the first
+// problem found, or an empty string.
+func ValidateQuestionRequest(req *models.QuestionRequest) string {
+	if req.QuestionText == "" {
+		return "Question text is required"
+	}
+	if !questionTypes[req.QuestionType] {
+		return "Unknown question type"
+	}
+	if req.Difficulty == "" {
+		req.Difficulty = "medium"
+	}
+	if !difficulties[req.Difficulty] {
+		return "Difficulty must be easy, medium or hard"
+	}
+	if req.Points <= 0 {
+		req.Points = 1
+	}
+
+	if req.QuestionType == "multiple_choice" || req.QuestionType == "true_false" {
+		if len(req.Options) < 2 {
+			return "Choice questions need at least two options"
+		}
+		hasCorrect := false
+		for _, option := range req.Options {
+			if option.OptionText == "" {
+				return "Option text is required"
+			}
+			hasCorrect = hasCorrect || option.IsCorrect
+		}
+		if !hasCorrect {
+			return "At least one option must be correct"
+		}
+	}
+	if req.QuestionType == "short_answer" && req.CorrectAnswer == "" {
+		return "Short answer questions need a correct answer"
+	}
+	return ""
+}
+
+// ImportQuestions parses a file in one of the supported formats. Every construct that cannot
+// be represented is reported with its line; questions that fail validation are left out of
+// the returned slice. Nothing is written, so the report doubles as a dry run.
+func ImportQuestions(format string, data []byte) ([]models.QuestionRequest, *models.ImportReport, error) {
+	var result *importResult
+	var err error
+
+	switch format {
+	case FormatGIFT:
+		result = parseGIFT(string(data))
+	case FormatMoodleXML:
+		result, err = parseMoodleXML(data)
+	case FormatCSV:
+		result, err = parseCSV(data)
+	default:
+		return nil, nil, fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var questions []models.QuestionRequest
+	for _, imported := range result.questions {
+		q := imported.question
+		if msg := ValidateQuestionRequest(&q); msg != "" {
+			result.skip(imported.line, "%s", msg)
+			continue
+		}
+		questions = append(questions, q)
+	}
+
+	report := &models.ImportReport{
+		Format:   format,
+		Found:    result.found,
+		Imported: len(questions),
+		Skipped:  result.found - len(questions),
+		Issues:   result.issues,
+	}
+	if report.Issues == nil {
+		report.Issues = []models.ImportIssue{}
+	}
+	return questions, report, nil
+}
+
+// ExportQuestions renders questions in the requested format and returns the body together
+// with its content type and file extension.
+func ExportQuestions(format string, questions []models.Question) ([]byte, string, string, error) {
+	switch format {
+	case FormatGIFT:
+		return []byte(writeGIFT(questions)), "text/plain; charset=utf-8", "gift", nil
+	case FormatMoodleXML:
+		body, err := writeMoodleXML(questions)
+		return body, "application/xml; charset=utf-8", "xml", err
+	case FormatCSV:
+		body, err := writeCSV(questions)
+		return body, "text/csv; charset=utf-8", "csv", err
+	}
+	return nil, "", "", fmt.Errorf("unsupported format %q", format)
+}
+
+// Moodle's multi-language content filter marks translations with
+// <span lang="xx" class="multilang"> (core filter) or {mlang xx}...{mlang} (filter_multilang2).
+var (
+	multilangSpan = regexp.MustCompile(`(?s)<span\s+(?:lang="([a-zA-Z_-]+)"\s+class="multilang"|class="multilang"\s+lang="([a-zA-Z_-]+)")\s*>(.*?)</span>`)
+	multilangTag  = regexp.MustCompile(`(?s)\{mlang\s+([a-zA-Z_-]+)\}(.*?)\{mlang\}`)
+)
+
+// splitMultilang extracts the Russian and Kazakh variants of a text. Text without language
+// markers is treated as Russian. Other languages are returned so they can be reported.
+func splitMultilang(text string) (ru, kk string, dropped []string) {
+	variants := map[string]string{}
+	var order []string
+
+	for _, m := range multilangSpan.FindAllStringSubmatch(text, -1) {
+		lang := m[1]
+		if lang == "" {
+			lang = m[2]
+		}
+		if _, seen := variants[lang]; !seen {
+			order = append(order, lang)
+		}
+		variants[lang] += m[3]
+	}
+	for _, m := range multilangTag.FindAllStringSubmatch(text, -1) {
+		if _, seen := variants[m[1]]; !seen {
+			order = append(order, m[1])
+		}
+		variants[m[1]] += m[2]
+	}
+
+	if len(variants) == 0 {
+		return strings.TrimSpace(text), "", nil
+	}
+
+	for _, lang := range order {
+		switch lang {
+		case "ru":
+			ru = strings.TrimSpace(variants[lang])
+		case "kk":
+			kk = strings.TrimSpace(variants[lang])
+		default:
+			dropped = append(dropped, lang)
+		}
+	}
+	if ru == "" {
+		// A file with kk and en only still needs a primary text.
+		ru, kk = kk, ""
+		if ru == "" && len(order) > 0 {
+			ru = strings.TrimSpace(variants[order[0]])
+		}
+	}
+	return ru, kk, dropped
+}
+
+// joinMultilang is the inverse of splitMultilang, using the core Moodle filter syntax.
+func joinMultilang(ru, kk string) string {
+	if kk == "" {
+		return ru
+	}
+	return `<span lang="ru" class="multilang">` + ru + `</span><span lang="kk" class="multilang">` + kk + `</span>`
+}
+
+// localized splits a text into language variants and reports dropped languages.
+func (r *importResult) localized(line int, text string) (string, string) {
+	ru, kk, dropped := splitMultilang(text)
+	for _, lang := range dropped {
+		r.warn(line, "text in language %q is not supported and was dropped", lang)
+	}
+	return ru, kk
+}
+
+// categoryPath turns a Moodle category such as "$course$/top/Математика/Алгебра" into a
+// subject and topic.
+func categoryPath(path string) (subject, topic string) {
+	var parts []string
+	for _, part := range strings.Split(path, "/") {
+		part = strings.TrimSpace(part)
+		if part == "" || part == "top" || strings.HasPrefix(part, "$") {
+			continue
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) > 0 {
+		subject = parts[0]
+	}
+	if len(parts) > 1 {
+		topic = parts[len(parts)-1]
+	}
+	return subject, topic
+}
+
+func categoryFor(q models.Question) string {
+	path := "$course$/top"
+	if q.Subject != "" {
+		path += "/" + q.Subject
+	}
+	if q.Topic != "" {
+		path += "/" + q.Topic
+	}
+	return path
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+// formatSample covers every question type, Kazakh translations and bank tags. The true/false
+// questions list their options in both orders.
+func formatSample() []models.Question {
+	return []models.Question{
+		{
+			QuestionType: "multiple_choice", Subject: "Математика", Topic: "Алгебра", Difficulty: "hard", Points: 2,
+			QuestionText: "2 + 2 = ?", QuestionTextKK: "2 + 2 = ?",
+			Explanation: "Сложение", ExplanationKK: "Қосу",
+			Options: []models.QuestionOption{
+				{OptionText: "3", OptionTextKK: "үш"},
+				{OptionText: "4", OptionTextKK: "төрт", IsCorrect: true},
+				{OptionText: "5 {или} 6", OptionTextKK: "бес"},
+			},
+		},
+		{
+			QuestionType: "true_false", Subject: "Математика", Topic: "Алгебра", Difficulty: "easy", Points: 1,
+			QuestionText: "Ноль чётный",
+			Options:      []models.QuestionOption{{OptionText: "Верно", IsCorrect: true}, {OptionText: "Неверно"}},
+		},
+		{
+			QuestionType: "true_false", Subject: "История", Difficulty: "medium", Points: 1,
+			QuestionText: "Астана основана в 1830 году",
+			Options:      []models.QuestionOption{{OptionText: "Неверно", IsCorrect: true}, {OptionText: "Верно"}},
+		},
+		{
+			QuestionType: "short_answer", Subject: "История", Difficulty: "medium", Points: 3,
+			QuestionText: "Столица Казахстана?", CorrectAnswer: "Астана",
+		},
+		{
+			QuestionType: "essay", Subject: "История", Difficulty: "hard", Points: 5,
+			QuestionText: "Опишите значение Шёлкового пути",
+		},
+	}
+}
+
+// canonical reduces an imported or exported question to what every format keeps. True/false
+// questions are compared by the text of their correct option, since formats store them as a
+// bare true or false and imports always list "Верно" first.
+func canonical(q models.QuestionRequest) models.QuestionRequest {
+	if q.QuestionType == "true_false" {
+		for _, option := range q.Options {
+			if option.IsCorrect {
+				q.CorrectAnswer = option.OptionText
+			}
+		}
+		q.Options = nil
+	}
+	return q
+}
+
+func toRequest(q models.Question) models.QuestionRequest {
+	req := models.QuestionRequest{
+		QuestionText: q.QuestionText, QuestionTextKK: q.QuestionTextKK, QuestionType: q.QuestionType,
+		CorrectAnswer: q.CorrectAnswer, Explanation: q.Explanation, ExplanationKK: q.ExplanationKK,
+		Points: q.Points, Difficulty: q.Difficulty, Subject: q.Subject, Topic: q.Topic,
+	}
+	for _, option := range q.Options {
+		req.Options = append(req.Options, models.QuestionOptionRequest{
+			OptionText: option.OptionText, OptionTextKK: option.OptionTextKK, IsCorrect: option.IsCorrect,
+		})
+	}
+	return req
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	for _, format := range []string{FormatGIFT, FormatMoodleXML, FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			sample := formatSample()
+			body, _, _, err := ExportQuestions(format, sample)
+			if err != nil {
+				t.Fatalf("export: %v", err)
+			}
+
+			imported, report, err := ImportQuestions(format, body)
+			if err != nil {
+				t.Fatalf("import: %v", err)
+			}
+			for _, issue := range report.Issues {
+				t.Errorf("line %d: %s: %s", issue.Line, issue.Severity, issue.Message)
+			}
+			if len(imported) != len(sample) {
+				t.Fatalf("imported %d questions, want %d\n%s", len(imported), len(sample), body)
+			}
+
+			for i := range sample {
+				want, got := canonical(toRequest(sample[i])), canonical(imported[i])
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("question %d changed in the round trip:\n got %+v\nwant %+v", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTrueFalseAnswer(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []models.QuestionOption
+		want    bool
+	}{
+		{"true first", []models.QuestionOption{{OptionText: "Верно", IsCorrect: true}, {OptionText: "Неверно"}}, true},
+		{"false first, true correct", []models.QuestionOption{{OptionText: "Неверно"}, {OptionText: "Верно", IsCorrect: true}}, true},
+		{"false first and correct", []models.QuestionOption{{OptionText: "Неверно", IsCorrect: true}, {OptionText: "Верно"}}, false},
+		{"kazakh only", []models.QuestionOption{{OptionTextKK: "Иә"}, {OptionTextKK: "Жоқ", IsCorrect: true}}, false},
+		{"recognised by the wrong option", []models.QuestionOption{{OptionText: "Так и есть", IsCorrect: true}, {OptionText: "False"}}, true},
+		{"unrecognised texts go by position", []models.QuestionOption{{OptionText: "A"}, {OptionText: "B", IsCorrect: true}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trueFalseAnswer(models.Question{Options: tt.options}); got != tt.want {
+				t.Errorf("trueFalseAnswer = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGIFT(t *testing.T) {
+	data := strings.Join([]string{
+		"$CATEGORY: $course$/top/Физика/Механика",
+		"",
+		"::Q1:: Скорость света {",
+		"\t~%50%Быстрая",
+		"\t=Очень быстрая",
+		"\t~Медленная#нет",
+		"}",
+		"",
+		"Сколько будет 2+2? {#4}",
+		"",
+		"Соедините {=a -> b =c -> d}",
+		"",
+		"Опишите опыт {}",
+	}, "\n")
+
+	imported, report, err := ImportQuestions(FormatGIFT, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Found != 4 || report.Imported != 2 || report.Skipped != 2 {
+		t.Fatalf("found/imported/skipped = %d/%d/%d, want 4/2/2", report.Found, report.Imported, report.Skipped)
+	}
+
+	mc := imported[0]
+	if mc.QuestionType != "multiple_choice" || mc.Subject != "Физика" || mc.Topic != "Механика" {
+		t.Errorf("first question = %+v", mc)
+	}
+	if len(mc.Options) != 3 || !mc.Options[0].IsCorrect || !mc.Options[1].IsCorrect || mc.Options[2].IsCorrect {
+		t.Errorf("options = %+v, want partial credit imported as correct", mc.Options)
+	}
+	if imported[1].QuestionType != "essay" {
+		t.Errorf("second question type = %q, want essay", imported[1].QuestionType)
+	}
+
+	var errorLines []int
+	for _, issue := range report.Issues {
+		if issue.Severity == "error" {
+			errorLines = append(errorLines, issue.Line)
+		}
+	}
+	if !reflect.DeepEqual(errorLines, []int{9, 11}) {
+		t.Errorf("skipped lines = %v, want [9 11]", errorLines)
+	}
+}
+
+func TestParseCSVReportsBadRows(t *testing.T) {
+	data := "type,question_ru,correct,option1_ru,option2_ru\n" +
+		"true_false,Земля круглая,maybe,,\n" +
+		"multiple_choice,Выберите,2,один,два\n" +
+		"essay,,,,\n"
+
+	imported, report, err := ImportQuestions(FormatCSV, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported) != 1 || imported[0].QuestionType != "multiple_choice" || !imported[0].Options[1].IsCorrect {
+		t.Fatalf("imported = %+v", imported)
+	}
+	if report.Found != 3 || report.Skipped != 2 {
+		t.Errorf("found/skipped = %d/%d, want 3/2", report.Found, report.Skipped)
+	}
+}
+
+func TestParseCSVRequiresColumns(t *testing.T) {
+	if _, _, err := ImportQuestions(FormatCSV, []byte("type,subject\nessay,x\n")); err == nil {
+		t.Error("expected an error for a header without question_ru")
+	}
+}
+
+func TestParseMoodleXMLDropsUnsupportedLanguages(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<quiz>
+  <question type="shortanswer">
+    <name><text>q</text></name>
+    <questiontext format="html"><text><![CDATA[<span lang="ru" class="multilang">Столица?</span><span lang="en" class="multilang">Capital?</span>]]></text></questiontext>
+    <answer fraction="100"><text>Астана</text></answer>
+  </question>
+  <question type="matching">
+    <name><text>m</text></name>
+    <questiontext format="html"><text>Соедините</text></questiontext>
+  </question>
+</quiz>`
+
+	imported, report, err := ImportQuestions(FormatMoodleXML, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported) != 1 || imported[0].QuestionText != "Столица?" || imported[0].CorrectAnswer != "Астана" {
+		t.Fatalf("imported = %+v", imported)
+	}
+	if report.Skipped != 1 {
+		t.Errorf("skipped = %d, want 1", report.Skipped)
+	}
+	warned := false
+	for _, issue := range report.Issues {
+		warned = warned || (issue.Severity == "warning" && strings.Contains(issue.Message, `"en"`))
+	}
+	if !warned {
+		t.Errorf("issues = %+v, want a warning about the dropped English text", report.Issues)
+	}
+}
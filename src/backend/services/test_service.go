@@ -0,0 +1,282 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+var (
+	ErrNoQuestions        = errors.New("test has no questions")
+	ErrNotEnoughQuestions = errors.New("question bank has too few questions for the test rules")
+	ErrRulesUnsatisfiable = errors.New("question rules cannot be satisfied from the bank")
+	ErrAttemptClosed      = errors.New("attempt is already completed")
+	ErrQuestionNotInTest  = errors.New("question was not drawn for this attempt")
+)
+
+// StartTestAttempt assembles the question set for a new attempt: the test's fixed questions
+// first, then a random draw from the question bank for each rule. Options of every question
+// are shuffled and the resulting order is stored with the attempt. The attempt fails with
+// ErrNotEnoughQuestions rather than start with fewer questions than the rules ask for.
+func StartTestAttempt(ctx context.Context, userID string, test *models.Test) (*models.TestAttempt, error) {
+	fixed, err := repository.GetTestQuestions(ctx, test.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := repository.GetQuestionRules(ctx, test.ID)
+	if err != nil {
+		return nil, err
+	}
+	reqs := make([]models.QuestionRuleRequest, len(rules))
+	for i, rule := range rules {
+		reqs[i] = models.QuestionRuleRequest{Subject: rule.Subject, Topic: rule.Topic, Difficulty: rule.Difficulty, QuestionCount: rule.QuestionCount}
+	}
+	picked, missing, err := matchQuestionRules(ctx, test.CourseID, reqs)
+	if err != nil {
+		return nil, err
+	}
+	if missing > 0 {
+		return nil, fmt.Errorf("%w: %d missing", ErrNotEnoughQuestions, missing)
+	}
+
+	questions := fixed
+	var ids []string
+	for _, ruleIDs := range picked {
+		ids = append(ids, ruleIDs...)
+	}
+	if len(ids) > 0 {
+		drawn, err := repository.GetQuestionsByIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[string]models.Question, len(drawn))
+		for _, q := range drawn {
+			byID[q.ID] = q
+		}
+		for _, id := range ids {
+			q, ok := byID[id]
+			if !ok {
+				return nil, fmt.Errorf("%w: question %s is gone", ErrNotEnoughQuestions, id)
+			}
+			questions = append(questions, q)
+		}
+	}
+
+	if len(questions) == 0 {
+		return nil, ErrNoQuestions
+	}
+
+	attemptQuestions := make([]models.AttemptQuestion, len(questions))
+	for i, q := range questions {
+		byID := make(map[string]models.QuestionOption, len(q.Options))
+		for _, option := range q.Options {
+			byID[option.ID] = option
+		}
+		shuffled := make([]models.QuestionOption, 0, len(q.Options))
+		for _, id := range ShuffleOptionIDs(q.Options) {
+			shuffled = append(shuffled, byID[id])
+		}
+		q.Options = shuffled
+		attemptQuestions[i] = models.AttemptQuestion{Question: q, OrderIndex: i}
+	}
+
+	return repository.CreateAttempt(ctx, userID, test.ID, attemptQuestions)
+}
+
+// CheckQuestionRules reports ErrRulesUnsatisfiable unless the bank can supply every rule at
+// once. Rules that overlap draw from the same questions, so they are checked together.
+func CheckQuestionRules(ctx context.Context, courseID string, rules []models.QuestionRuleRequest) error {
+	_, missing, err := matchQuestionRules(ctx, courseID, rules)
+	if err != nil {
+		return err
+	}
+	if missing > 0 {
+		need := 0
+		for _, rule := range rules {
+			need += rule.QuestionCount
+		}
+		return fmt.Errorf("%w: the rules need %d questions together, but the bank can supply only %d",
+			ErrRulesUnsatisfiable, need, need-missing)
+	}
+	return nil
+}
+
+// matchQuestionRules draws random bank questions for every rule and returns them per rule,
+// with the number of questions the bank could not supply.
+func matchQuestionRules(ctx context.Context, courseID string, rules []models.QuestionRuleRequest) ([][]string, int, error) {
+	candidates := make([][]string, len(rules))
+	counts := make([]int, len(rules))
+	for i, rule := range rules {
+		filter := models.QuestionBankFilter{Subject: rule.Subject, Topic: rule.Topic, Difficulty: rule.Difficulty}
+		ids, err := repository.GetBankQuestionIDs(ctx, courseID, filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		candidates[i] = ids
+		counts[i] = rule.QuestionCount
+	}
+	picked, missing := assignRuleQuestions(candidates, counts)
+	return picked, missing, nil
+}
+
+// assignRuleQuestions picks counts[i] distinct questions out of candidates[i] for every rule
+// and reports how many it could not pick. A question can match several rules, so taking
+// the first free candidate is not enough: a question held by one rule is handed over when
+// that rule has another candidate left (augmenting paths of a bipartite matching), which
+// picks as many questions as the bank allows. Candidates are tried in the given order.
+func assignRuleQuestions(candidates [][]string, counts []int) ([][]string, int) {
+	var slots []int
+	for rule, count := range counts {
+		for i := 0; i < count; i++ {
+			slots = append(slots, rule)
+		}
+	}
+
+	holder := make(map[string]int)
+	var assign func(slot int, seen map[string]bool) bool
+	assign = func(slot int, seen map[string]bool) bool {
+		for _, id := range candidates[slots[slot]] {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if other, held := holder[id]; !held || assign(other, seen) {
+				holder[id] = slot
+				return true
+			}
+		}
+		return false
+	}
+
+	missing := 0
+	for slot := range slots {
+		if !assign(slot, make(map[string]bool)) {
+			missing++
+		}
+	}
+
+	picked := make([][]string, len(counts))
+	for rule, ids := range candidates {
+		for _, id := range ids {
+			if slot, held := holder[id]; held && slots[slot] == rule {
+				picked[rule] = append(picked[rule], id)
+			}
+		}
+	}
+	return picked, missing
+}
+
+// SubmitTestAttempt grades the answers against the questions drawn for the attempt.
+// Unanswered questions count as wrong; the score is the percentage of points earned.
+func SubmitTestAttempt(ctx context.Context, attempt *models.TestAttempt, req []models.AnswerRequest) (*models.TestAttempt, error) {
+	if attempt.Status != "in_progress" {
+		return nil, ErrAttemptClosed
+	}
+
+	questions, err := repository.GetAttemptQuestions(ctx, attempt.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	given := make(map[string]models.AnswerRequest, len(req))
+	for _, answer := range req {
+		given[answer.QuestionID] = answer
+	}
+
+	byID := make(map[string]models.Question, len(questions))
+	for _, q := range questions {
+		byID[q.ID] = q.Question
+	}
+	for id := range given {
+		if _, ok := byID[id]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrQuestionNotInTest, id)
+		}
+	}
+
+	var answers []models.UserAnswer
+	earned, total := 0, 0
+	for _, q := range questions {
+		total += q.Points
+		answer, ok := given[q.ID]
+		if !ok {
+			continue
+		}
+		isCorrect, points := GradeAnswer(q.Question, answer.Answer)
+		earned += points
+		answers = append(answers, models.UserAnswer{
+			AttemptID:        attempt.ID,
+			QuestionID:       q.ID,
+			Answer:           answer.Answer,
+			IsCorrect:        isCorrect,
+			PointsEarned:     points,
+			TimeSpentSeconds: answer.TimeSpentSeconds,
+		})
+	}
+
+	completed, err := repository.CompleteAttempt(ctx, attempt.ID, answers, ScorePercent(earned, total))
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, ErrAttemptClosed
+		}
+		return nil, err
+	}
+
+	ScheduleItemAnalysis(completed.TestID)
+	recordStudyTime(ctx, completed.UserID, attemptStudySeconds(completed))
+
+	courseID, err := repository.GetTestCourseID(ctx, completed.TestID)
+	if err != nil {
+		log.Printf("Course progress error for attempt %s: %v", completed.ID, err)
+	} else if err := RecomputeCourseProgress(ctx, completed.UserID, courseID); err != nil {
+		log.Printf("Course progress error for attempt %s: %v", completed.ID, err)
+	}
+
+	rescored, err := RescoreAttemptIntegrity(ctx, completed)
+	if err != nil {
+		log.Printf("Integrity rescoring error for attempt %s: %v", completed.ID, err)
+		rescored = completed
+	}
+	if courseID != "" {
+		recordLeaderboardScore(ctx, rescored, courseID)
+		AwardTestXP(ctx, rescored, courseID)
+	}
+	PublishRealtime(ctx, UserTopic(rescored.UserID), models.RealtimeGradingResult, models.GradingResult{
+		AttemptID: rescored.ID,
+		TestID:    rescored.TestID,
+		CourseID:  courseID,
+		Score:     rescored.Score,
+		Status:    rescored.Status,
+	})
+	notifyTestGraded(ctx, rescored, courseID)
+	EmitWebhookEvent(ctx, models.WebhookAttemptGraded, courseID, map[string]interface{}{
+		"attempt_id":   rescored.ID,
+		"test_id":      rescored.TestID,
+		"course_id":    courseID,
+		"score":        rescored.Score,
+		"status":       rescored.Status,
+		"completed_at": rescored.CompletedAt,
+		"user":         webhookUser(ctx, rescored.UserID),
+	})
+	return rescored, nil
+}
+
+// HideAnswerKey strips correctness information from questions shown during an attempt.
+func HideAnswerKey(questions []models.AttemptQuestion) {
+	for i := range questions {
+		hideQuestionKey(&questions[i].Question)
+	}
+}
+
+func hideQuestionKey(question *models.Question) {
+	question.CorrectAnswer = ""
+	question.Explanation = ""
+	question.ExplanationKK = ""
+	for j := range question.Options {
+		question.Options[j].IsCorrect = false
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import "testing"
+
+func TestAssignRuleQuestions(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates [][]string
+		counts     []int
+		missing    int
+	}{
+		{
+			name:       "disjoint rules",
+			candidates: [][]string{{"a1", "a2", "a3"}, {"b1", "b2"}},
+			counts:     []int{2, 2},
+		},
+		{
+			name:       "broad rule first would take the narrow rule's questions",
+			candidates: [][]string{{"h1", "h2", "e1", "e2"}, {"h1", "h2"}},
+			counts:     []int{2, 2},
+		},
+		{
+			name:       "same filter twice shares one pool",
+			candidates: [][]string{{"q1", "q2", "q3"}, {"q1", "q2", "q3"}},
+			counts:     []int{2, 2},
+			missing:    1,
+		},
+		{
+			name:       "rule with too few candidates",
+			candidates: [][]string{{"q1"}},
+			counts:     []int{3},
+			missing:    2,
+		},
+		{
+			name:       "empty bank",
+			candidates: [][]string{{}},
+			counts:     []int{1},
+			missing:    1,
+		},
+		{
+			name: "no rules",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			picked, missing := assignRuleQuestions(tt.candidates, tt.counts)
+			if missing != tt.missing {
+				t.Fatalf("missing = %d, want %d", missing, tt.missing)
+			}
+
+			seen := map[string]bool{}
+			total := 0
+			for rule, ids := range picked {
+				if len(ids) > tt.counts[rule] {
+					t.Errorf("rule %d got %d questions, asked for %d", rule, len(ids), tt.counts[rule])
+				}
+				allowed := map[string]bool{}
+				for _, id := range tt.candidates[rule] {
+					allowed[id] = true
+				}
+				for _, id := range ids {
+					if !allowed[id] {
+						t.Errorf("rule %d got %s, which does not match it", rule, id)
+					}
+					if seen[id] {
+						t.Errorf("%s was picked twice", id)
+					}
+					seen[id] = true
+					total++
+				}
+			}
+
+			want := -tt.missing
+			for _, count := range tt.counts {
+				want += count
+			}
+			if total != want {
+				t.Errorf("picked %d questions, want %d", total, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+var ErrInvalidUsername = errors.New("invalid username")
+
+var (
+	usernamePattern    = regexp.MustCompile(`^[a-z0-9_]{3,30}$`)
+	repeatedUnderscore = regexp.MustCompile(`_+`)
+)
+
+// cyrillicLatin transliterates the Russian and Kazakh alphabets for generated usernames.
+var cyrillicLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'ә': "a", 'ғ': "g", 'қ': "q", 'ң': "n", 'ө': "o", 'ұ': "u", 'ү': "u", 'һ': "h", 'і': "i",
+}
+
+// usernameBase derives a username from a user's name, e.g. "Айгерим Нурланова" becomes
+// "aigerim_nurlanova". Names with nothing usable give "user".
+func usernameBase(firstName, lastName string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(firstName) + " " + strings.TrimSpace(lastName)) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case cyrillicLatin[r] != "":
+			b.WriteString(cyrillicLatin[r])
+		case unicode.IsSpace(r) || r == '-' || r == '_' || r == '.':
+			b.WriteRune('_')
+		}
+	}
+	base := strings.Trim(repeatedUnderscore.ReplaceAllString(b.String(), "_"), "_")
+	if len(base) > 24 {
+		base = strings.TrimRight(base[:24], "_")
+	}
+	if len(base) < 3 {
+		return "user"
+	}
+	return base
+}
+
+// AssignUsername gives a user without one a username based on their name, adding the lowest
+// free number when the name is taken.
+func AssignUsername(ctx context.Context, user *models.User) (string, error) {
+	base := usernameBase(user.FirstName, user.LastName)
+	for attempt := 0; attempt < 3; attempt++ {
+		taken, err := repository.GetUsernamesWithPrefix(ctx, base)
+		if err != nil {
+			return "", err
+		}
+		used := make(map[string]bool, len(taken))
+		for _, name := range taken {
+			used[name] = true
+		}
+		name := base
+		for n := 2; used[name]; n++ {
+			name = base + strconv.Itoa(n)
+		}
+
+		err = repository.SetUsername(ctx, user.ID, name)
+		if errors.Is(err, models.ErrConflict) {
+			// Someone took it in the meantime.
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		user.Username = name
+		return name, nil
+	}
+	return "", fmt.Errorf("could not find a free username for %q", base)
+}
+
+// ChangeUsername replaces the user's username with one they chose.
+func ChangeUsername(ctx context.Context, userID, username string) (string, error) {
+	username = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(username), "@"))
+	if !usernamePattern.MatchString(username) {
+		return "", fmt.Errorf("%w: use 3 to 30 latin letters, digits or underscores", ErrInvalidUsername)
+	}
+	if err := repository.SetUsername(ctx, userID, username); err != nil {
+		if errors.Is(err, models.ErrConflict) {
+			return "", fmt.Errorf("%w: username is already taken", ErrInvalidUsername)
+		}
+		return "", err
+	}
+	return username, nil
+}
+
+// BackfillUsernames assigns usernames, in the background, to the users registered before
+// usernames existed.
+func BackfillUsernames() {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+
+		assigned := 0
+		for {
+			users, err := repository.GetUsersWithoutUsername(ctx, 100)
+			if err != nil {
+				log.Printf("Username backfill error: %v", err)
+				return
+			}
+			if len(users) == 0 {
+				break
+			}
+			for i := range users {
+				if _, err := AssignUsername(ctx, &users[i]); err != nil {
+					log.Printf("Username backfill error: %v", err)
+					return
+				}
+				assigned++
+			}
+		}
+		if assigned > 0 {
+			log.Printf("Username backfill finished, %d users updated", assigned)
+		}
+	}()
+}
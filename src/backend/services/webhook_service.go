@@ -0,0 +1,340 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+	"github.com/nnn20040/shabytdiplomwork/src/backend/repository"
+)
+
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+const (
+	webhookBatchSize       = 20
+	webhookLease           = 5 * time.Minute
+	webhookRetryBase       = 30 * time.Second
+	webhookRetryMaximum    = 12 * time.Hour
+	webhookResponseLimit   = 1024
+	webhookMinSecretLength = 16
+)
+
+// webhookWake nudges the dispatcher when new deliveries are queued, so they go out without
+// waiting for the next poll.
+var webhookWake = make(chan struct{}, 1)
+
+func wakeWebhookDispatcher() {
+	select {
+	case webhookWake <- struct{}{}:
+	default:
+	}
+}
+
+// checkWebhook validates a request. A secret is only required to be long enough when given.
+func checkWebhook(ctx context.Context, req *models.WebhookRequest) error {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	if req.Secret != "" && len(req.Secret) < webhookMinSecretLength {
+		return fmt.Errorf("%w: secret must be at least %d characters", ErrInvalidWebhook, webhookMinSecretLength)
+	}
+	if len(req.EventTypes) == 0 {
+		return fmt.Errorf("%w: at least one event type is required", ErrInvalidWebhook)
+	}
+	known := make(map[string]bool, len(models.WebhookEventTypes))
+	for _, t := range models.WebhookEventTypes {
+		known[t] = true
+	}
+	seen := map[string]bool{}
+	eventTypes := req.EventTypes[:0]
+	for _, t := range req.EventTypes {
+		if !known[t] {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidWebhook, t)
+		}
+		if !seen[t] {
+			seen[t] = true
+			eventTypes = append(eventTypes, t)
+		}
+	}
+	req.EventTypes = eventTypes
+	if req.CourseID != "" {
+		if _, err := repository.GetCourseTeacherID(ctx, req.CourseID); err != nil {
+			if errors.Is(err, models.ErrNotFound) {
+				return fmt.Errorf("%w: course not found", ErrInvalidWebhook)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateWebhook adds a webhook, generating its secret unless one is given. The secret is
+// returned this once.
+func CreateWebhook(ctx context.Context, createdBy string, req models.WebhookRequest) (*models.Webhook, error) {
+	if err := checkWebhook(ctx, &req); err != nil {
+		return nil, err
+	}
+	if req.Secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		req.Secret = "whsec_" + hex.EncodeToString(b)
+	}
+	return repository.CreateWebhook(ctx, createdBy, req)
+}
+
+func UpdateWebhook(ctx context.Context, webhookID string, req models.WebhookRequest) (*models.Webhook, error) {
+	if err := checkWebhook(ctx, &req); err != nil {
+		return nil, err
+	}
+	w, err := repository.UpdateWebhook(ctx, webhookID, req)
+	if err != nil {
+		return nil, err
+	}
+	w.Secret = ""
+	if w.IsActive {
+		wakeWebhookDispatcher()
+	}
+	return w, nil
+}
+
+func GetWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	webhooks, err := repository.GetWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+func GetWebhook(ctx context.Context, webhookID string) (*models.Webhook, error) {
+	w, err := repository.GetWebhook(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	w.Secret = ""
+	return w, nil
+}
+
+// GetWebhookDeliveries lists a webhook's deliveries, newest first.
+func GetWebhookDeliveries(ctx context.Context, webhookID string, page, limit int) (*models.WebhookDeliveryPage, error) {
+	if _, err := repository.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	items, total, err := repository.GetWebhookDeliveryPage(ctx, webhookID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	return &models.WebhookDeliveryPage{Items: items, Page: page, Limit: limit, Total: total}, nil
+}
+
+// RedeliverWebhook sends an earlier delivery of the webhook again, as a new delivery.
+func RedeliverWebhook(ctx context.Context, webhookID, deliveryID string) (*models.WebhookDelivery, error) {
+	d, err := repository.RedeliverWebhookDelivery(ctx, webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	wakeWebhookDispatcher()
+	return d, nil
+}
+
+// EmitWebhookEvent queues an event for the webhooks subscribed to it. Delivery happens in the
+// background and failures are logged, so an event never fails or slows down the action that
+// caused it.
+func EmitWebhookEvent(ctx context.Context, eventType, courseID string, data interface{}) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Printf("Webhook event error for %s: %v", eventType, err)
+		return
+	}
+	event := models.WebhookEvent{
+		ID:        "evt_" + hex.EncodeToString(b),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Webhook event error for %s: %v", eventType, err)
+		return
+	}
+	queued, err := repository.EnqueueWebhookEvent(ctx, event.ID, eventType, courseID, payload)
+	if err != nil {
+		log.Printf("Webhook event error for %s: %v", eventType, err)
+		return
+	}
+	if queued > 0 {
+		wakeWebhookDispatcher()
+	}
+}
+
+// webhookUser looks up a student for an event. Events still go out without one.
+func webhookUser(ctx context.Context, userID string) *models.WebhookUser {
+	user, err := repository.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Printf("Webhook event error for user %s: %v", userID, err)
+		return &models.WebhookUser{ID: userID}
+	}
+	return &models.WebhookUser{ID: user.ID, Email: user.Email, FirstName: user.FirstName, LastName: user.LastName}
+}
+
+// EmitEnrollmentCreated sends the enrollment.created event of a new enrollment.
+func EmitEnrollmentCreated(ctx context.Context, enrollment *models.Enrollment, student *models.User) {
+	EmitWebhookEvent(ctx, models.WebhookEnrollmentCreated, enrollment.CourseID, map[string]interface{}{
+		"enrollment": enrollment,
+		"user":       models.WebhookUser{ID: student.ID, Email: student.Email, FirstName: student.FirstName, LastName: student.LastName},
+	})
+}
+
+// signWebhook returns the signature of a delivery: the hex HMAC-SHA256, keyed with the
+// webhook's secret, of the timestamp, a dot and the body. Receivers recompute it to check the
+// sender and reject old timestamps to stop replays.
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+var webhookClient = &http.Client{
+	// Redirects are not followed: the signature was made for the configured URL.
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+// sendWebhook posts a delivery. It returns the response code, nil when no response came back,
+// and the start of the response body.
+func sendWebhook(ctx context.Context, w models.Webhook, d models.WebhookDelivery) (*int, string, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Shabyt-Webhooks/1.0")
+	req.Header.Set("X-Shabyt-Event", d.EventType)
+	req.Header.Set("X-Shabyt-Delivery", d.ID)
+	req.Header.Set("X-Shabyt-Timestamp", timestamp)
+	req.Header.Set("X-Shabyt-Signature", signWebhook(w.Secret, timestamp, d.Payload))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	// Stored as text, which can't hold NUL bytes or invalid UTF-8.
+	body := strings.ToValidUTF8(strings.ReplaceAll(string(b), "\x00", ""), "")
+	code := resp.StatusCode
+	if code < 200 || code > 299 {
+		return &code, body, fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return &code, body, nil
+}
+
+// DispatchWebhooks sends a batch of due deliveries in parallel and returns how many were
+// delivered. Failed ones are retried with backoff until WEBHOOK_MAX_ATTEMPTS (8 by default).
+func DispatchWebhooks(ctx context.Context) (int, error) {
+	deliveries, webhooks, err := repository.ClaimWebhookDeliveries(ctx, webhookBatchSize, webhookLease)
+	if err != nil {
+		return 0, err
+	}
+	maxAttempts := envInt("WEBHOOK_MAX_ATTEMPTS", 8)
+	timeout := envDuration("WEBHOOK_TIMEOUT", 10*time.Second)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	delivered := 0
+	for i := range deliveries {
+		wg.Add(1)
+		go func(d models.WebhookDelivery, w models.Webhook) {
+			defer wg.Done()
+			sendCtx, cancel := context.WithTimeout(ctx, timeout)
+			code, body, err := sendWebhook(sendCtx, w, d)
+			cancel()
+
+			var retryAt *time.Time
+			lastError := ""
+			if err != nil {
+				lastError = err.Error()
+				if d.Attempts < maxAttempts {
+					t := time.Now().Add(retryDelay(d.Attempts, webhookRetryBase, webhookRetryMaximum))
+					retryAt = &t
+				}
+				log.Printf("Webhook delivery %s to %s failed (attempt %d): %v", d.ID, w.URL, d.Attempts, err)
+			}
+			if err := repository.RecordWebhookAttempt(ctx, d.ID, err == nil, code, body, lastError, retryAt); err != nil {
+				log.Printf("Webhook dispatcher error: %v", err)
+			}
+			if err == nil {
+				mu.Lock()
+				delivered++
+				mu.Unlock()
+			}
+		}(deliveries[i], webhooks[i])
+	}
+	wg.Wait()
+	return delivered, nil
+}
+
+// StartWebhookDispatcher sends queued webhook deliveries as they are queued, and every
+// WEBHOOK_POLL_INTERVAL for retries. Finished deliveries are kept for
+// WEBHOOK_DELIVERY_RETENTION (30 days by default).
+func StartWebhookDispatcher() {
+	interval := envDuration("WEBHOOK_POLL_INTERVAL", 30*time.Second)
+	retention := envDuration("WEBHOOK_DELIVERY_RETENTION", 30*24*time.Hour)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-webhookWake:
+			}
+			for {
+				ctx, cancel := context.WithTimeout(context.Background(), 2*webhookLease)
+				n, err := DispatchWebhooks(ctx)
+				cancel()
+				if err != nil {
+					log.Printf("Webhook dispatcher error: %v", err)
+				}
+				// A batch delivered in full may have more behind it.
+				if err != nil || n < webhookBatchSize {
+					break
+				}
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			if _, err := repository.DeleteWebhookDeliveriesBefore(ctx, time.Now().Add(-retention)); err != nil {
+				log.Printf("Webhook delivery pruning error: %v", err)
+			}
+			cancel()
+		}
+	}()
+}
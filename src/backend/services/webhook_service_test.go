@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nnn20040/shabytdiplomwork/src/backend/models"
+)
+
+func TestSignWebhook(t *testing.T) {
+	body := []byte(`{"event":"test.completed","data":{"score":90}}`)
+	// printf '%s' '1700000000.<body>' | openssl dgst -sha256 -hmac whsec_test
+	want := "sha256=85269cc13be021e67a72fd5ce1d5df002b43e371f7fcd385ee7e4b4c71045ddd"
+	if got := signWebhook("whsec_test", "1700000000", body); got != want {
+		t.Errorf("signature = %s, want %s", got, want)
+	}
+
+	for name, sig := range map[string]string{
+		"secret":    signWebhook("whsec_other", "1700000000", body),
+		"timestamp": signWebhook("whsec_test", "1700000001", body),
+		"body":      signWebhook("whsec_test", "1700000000", append(body, ' ')),
+	} {
+		if sig == want {
+			t.Errorf("changing the %s kept the signature", name)
+		}
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{8, 64 * time.Minute},
+		{11, 512 * time.Minute},
+		{12, 12 * time.Hour},
+		{100, 12 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts, webhookRetryBase, webhookRetryMaximum); got != tt.want {
+			t.Errorf("retry delay after %d attempts = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestSendWebhookSignsTheRequest(t *testing.T) {
+	var header http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("ok\x00"))
+	}))
+	defer server.Close()
+
+	hook := models.Webhook{URL: server.URL, Secret: "whsec_test"}
+	delivery := models.WebhookDelivery{ID: "d1", EventType: "test.completed", Payload: []byte(`{"a":1}`)}
+	code, response, err := sendWebhook(context.Background(), hook, delivery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code == nil || *code != http.StatusAccepted || response != "ok" {
+		t.Errorf("response = %v %q, want 202 \"ok\"", code, response)
+	}
+	if string(body) != `{"a":1}` || header.Get("X-Shabyt-Event") != "test.completed" || header.Get("X-Shabyt-Delivery") != "d1" {
+		t.Errorf("request = %q with headers %v", body, header)
+	}
+	if want := signWebhook("whsec_test", header.Get("X-Shabyt-Timestamp"), body); header.Get("X-Shabyt-Signature") != want {
+		t.Errorf("signature = %q, want %q", header.Get("X-Shabyt-Signature"), want)
+	}
+}
+
+func TestSendWebhookFailsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/elsewhere", http.StatusFound)
+	}))
+	defer server.Close()
+
+	code, _, err := sendWebhook(context.Background(), models.Webhook{URL: server.URL}, models.WebhookDelivery{})
+	if err == nil || code == nil || *code != http.StatusFound {
+		t.Errorf("code = %v, err = %v; want the redirect reported as a failure", code, err)
+	}
+}